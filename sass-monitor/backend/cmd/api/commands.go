@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/services"
+)
+
+const commandUsage = `Usage:
+  api                                         start the API server
+  api collectors                              list collectors and backfill series
+  api collect <name>                          run a collector once
+  api backfill -from YYYY-MM-DD -to YYYY-MM-DD [-series a,b]
+                                              backfill daily historical series`
+
+// runCommand 执行命令行子命令，结果以JSON输出到标准输出
+func runCommand(ctx context.Context, dbManager *database.DatabaseManager, args []string) error {
+	switch args[0] {
+	case "collectors":
+		return printJSON(map[string][]string{
+			"collectors":      services.CollectorNames(),
+			"backfill_series": services.BackfillSeriesNames(),
+		})
+	case "collect":
+		if len(args) != 2 {
+			return fmt.Errorf("collect requires exactly one collector name\n%s", commandUsage)
+		}
+		result, err := services.NewDataCollector(dbManager).RunCollector(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if err := printJSON(result); err != nil {
+			return err
+		}
+		if result.Error != "" {
+			return fmt.Errorf("collector %s failed: %s", result.Collector, result.Error)
+		}
+		return nil
+	case "backfill":
+		flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+		from := flags.String("from", "", "first day to backfill (YYYY-MM-DD)")
+		to := flags.String("to", "", "last day to backfill (YYYY-MM-DD)")
+		series := flags.String("series", "", "comma separated series, defaults to all")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		start, end, err := services.ParseBackfillRange(*from, *to)
+		if err != nil {
+			return err
+		}
+		var seriesNames []string
+		for _, name := range strings.Split(*series, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				seriesNames = append(seriesNames, name)
+			}
+		}
+
+		result, err := services.NewBackfillService(dbManager).Backfill(ctx, seriesNames, start, end, nil)
+		if err != nil {
+			return err
+		}
+		return printJSON(result)
+	default:
+		return fmt.Errorf("unknown command '%s'\n%s", args[0], commandUsage)
+	}
+}
+
+// printJSON 以缩进JSON输出结果
+func printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
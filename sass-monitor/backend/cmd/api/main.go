@@ -62,6 +62,23 @@ func main() {
 		log.Fatalf("Failed to auto migrate database: %v", err)
 	}
 
+	// 注册通过API添加的监控目标
+	if err := services.NewMonitoringTargetService(dbManager).LoadTargets(context.Background()); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// 命令行模式：执行一次采集或回填后退出，不启动调度器和HTTP服务
+	if len(os.Args) > 1 {
+		err := runCommand(context.Background(), dbManager, os.Args[1:])
+		if closeErr := dbManager.Close(); closeErr != nil {
+			log.Printf("Error closing database connections: %v", closeErr)
+		}
+		if err != nil {
+			log.Fatalf("Command failed: %v", err)
+		}
+		return
+	}
+
 	// 初始化任务调度器
 	scheduler := services.NewTaskScheduler(dbManager, cfg)
 	if err := scheduler.Start(); err != nil {
@@ -69,11 +86,24 @@ func main() {
 		// 调度器启动失败不应该阻止服务器启动
 	}
 
+	// 外部写入指标的缓冲写入器
+	metricWriter := services.NewMetricWriter(dbManager)
+	metricWriter.Start()
+
+	// 后台任务队列，任务处理函数在setupRoutes中注册
+	jobQueue := services.NewJobQueue(dbManager)
+
 	// 确保在程序退出时关闭资源
 	defer func() {
 		log.Println("Stopping scheduler...")
 		scheduler.Stop()
 
+		log.Println("Stopping job queue...")
+		jobQueue.Stop()
+
+		log.Println("Flushing buffered metrics...")
+		metricWriter.Stop()
+
 		if err := dbManager.Close(); err != nil {
 			log.Printf("Error closing database connections: %v", err)
 		}
@@ -86,7 +116,8 @@ func main() {
 	setupMiddleware(router, cfg)
 
 	// 设置路由
-	setupRoutes(router, dbManager, cfg)
+	setupRoutes(router, dbManager, cfg, scheduler, metricWriter, jobQueue)
+	jobQueue.Start()
 
 	// 创建HTTP服务器
 	server := &http.Server{
@@ -149,7 +180,7 @@ func setupMiddleware(router *gin.Engine, cfg *config.Config) {
 }
 
 // setupRoutes 设置路由
-func setupRoutes(router *gin.Engine, dbManager *database.DatabaseManager, cfg *config.Config) {
+func setupRoutes(router *gin.Engine, dbManager *database.DatabaseManager, cfg *config.Config, scheduler *services.TaskScheduler, metricWriter *services.MetricWriter, jobQueue *services.JobQueue) {
 	// 健康检查端点
 	router.GET("/health", func(c *gin.Context) {
 		healthStatus := dbManager.HealthCheck()
@@ -167,12 +198,14 @@ func setupRoutes(router *gin.Engine, dbManager *database.DatabaseManager, cfg *c
 				"status":    "healthy",
 				"timestamp": time.Now(),
 				"version":   "1.0.0",
+				"scheduler": scheduler.LeaderStatus(),
 			})
 		} else {
 			c.JSON(http.StatusServiceUnavailable, gin.H{
 				"status":    "unhealthy",
 				"timestamp": time.Now(),
 				"details":   healthStatus,
+				"scheduler": scheduler.LeaderStatus(),
 			})
 		}
 	})
@@ -182,14 +215,48 @@ func setupRoutes(router *gin.Engine, dbManager *database.DatabaseManager, cfg *c
 	{
 		// 创建处理器
 		authHandler := handlers.NewAuthHandler(dbManager.SaasMonitorDB, cfg)
-		dashboardHandler := handlers.NewDashboardHandler(dbManager, cfg)
-		monitoringHandler := handlers.NewMonitoringHandler(dbManager, cfg)
-		organizationService := services.NewOrganizationService(dbManager)
+		organizationStorageService := services.NewOrganizationStorageService(dbManager)
+		dashboardHandler := handlers.NewDashboardHandler(dbManager, organizationStorageService, cfg)
+		revenueService := services.NewRevenueService(dbManager)
+		revenueHandler := handlers.NewRevenueHandler(revenueService)
+		cohortService := services.NewCohortService(dbManager, revenueService)
+		cohortHandler := handlers.NewCohortHandler(cohortService)
+		trialFunnelService := services.NewTrialFunnelService(dbManager, revenueService)
+		trialHandler := handlers.NewTrialHandler(trialFunnelService)
+		postgreSQLActivityService := services.NewPostgreSQLActivityService(dbManager)
+		monitoringHandler := handlers.NewMonitoringHandler(dbManager, cfg, scheduler, postgreSQLActivityService)
+		organizationService := services.NewOrganizationService(dbManager, organizationStorageService)
 		organizationHandler := handlers.NewOrganizationHandler(organizationService, cfg)
 		subscriptionPlanService := services.NewSubscriptionPlanService(dbManager)
 		subscriptionPlanHandler := handlers.NewSubscriptionPlanHandler(subscriptionPlanService, cfg)
 		userService := services.NewUserService(dbManager)
 		userHandler := handlers.NewUserHandler(userService)
+		postgreSQLMaintenanceService := services.NewPostgreSQLMaintenanceService(dbManager)
+		postgreSQLReplicationService := services.NewPostgreSQLReplicationService(dbManager)
+		postgreSQLHandler := handlers.NewPostgreSQLHandler(postgreSQLActivityService, postgreSQLMaintenanceService, postgreSQLReplicationService)
+		clickHouseHealthService := services.NewClickHouseHealthService(dbManager)
+		clickHouseQueryAnalyticsService := services.NewClickHouseQueryAnalyticsService(dbManager)
+		clickHouseHandler := handlers.NewClickHouseHandler(clickHouseHealthService, clickHouseQueryAnalyticsService)
+		mySQLMonitorService := services.NewMySQLMonitorService(dbManager)
+		mySQLHandler := handlers.NewMySQLHandler(mySQLMonitorService)
+		redisMonitorService := services.NewRedisMonitorService(dbManager)
+		redisHandler := handlers.NewRedisHandler(redisMonitorService)
+		probeService := scheduler.ProbeService()
+		probeHandler := handlers.NewProbeHandler(probeService)
+		monitoringTargetService := services.NewMonitoringTargetService(dbManager)
+		monitoringTargetHandler := handlers.NewMonitoringTargetHandler(monitoringTargetService)
+		infrastructureService := services.NewInfrastructureService(dbManager)
+		infrastructureHandler := handlers.NewInfrastructureHandler(infrastructureService)
+		ingestService := services.NewIngestService(dbManager, metricWriter)
+		ingestHandler := handlers.NewIngestHandler(ingestService, metricWriter, cfg)
+		otlpService := services.NewOTLPService(dbManager, metricWriter, ingestService)
+		otlpHandler := handlers.NewOTLPHandler(otlpService, cfg)
+		backfillService := services.NewBackfillService(dbManager)
+		jobQueue.Register(services.BackfillJobType, backfillService.RunJob)
+		jobQueue.Register(services.CollectorJobType, scheduler.RunCollectorJob)
+		collectorHandler := handlers.NewCollectorHandler(scheduler, backfillService, jobQueue)
+		jobHandler := handlers.NewJobHandler(jobQueue)
+		schedulerHandler := handlers.NewSchedulerHandler(scheduler)
 
 		// 认证路由（无需JWT）
 		authGroup := v1.Group("/auth")
@@ -199,6 +266,20 @@ func setupRoutes(router *gin.Engine, dbManager *database.DatabaseManager, cfg *c
 			authGroup.GET("/refresh", authHandler.RefreshToken)
 		}
 
+		// 指标写入路由（使用静态ingest令牌认证）
+		ingestGroup := v1.Group("")
+		ingestGroup.Use(middleware.IngestTokenMiddleware(cfg.Monitoring.Ingest.Tokens))
+		{
+			ingestGroup.POST("/ingest/metrics", ingestHandler.IngestMetrics)
+		}
+
+		// OTLP/HTTP使用规范的默认路径，导出端配置OTEL_EXPORTER_OTLP_ENDPOINT为服务地址即可
+		otlpGroup := router.Group("/v1")
+		otlpGroup.Use(middleware.IngestTokenMiddleware(cfg.Monitoring.Ingest.Tokens))
+		{
+			otlpGroup.POST("/metrics", otlpHandler.ExportMetrics)
+		}
+
 		// 需要认证的路由
 		protectedGroup := v1.Group("")
 		protectedGroup.Use(middleware.JWTMiddleware(cfg.Server.JWTSecret))
@@ -215,6 +296,10 @@ func setupRoutes(router *gin.Engine, dbManager *database.DatabaseManager, cfg *c
 				dashboardGroup.GET("/organizations", dashboardHandler.GetOrganizations)
 				dashboardGroup.GET("/organizations/:id/metrics", dashboardHandler.GetOrganizationMetrics)
 				dashboardGroup.GET("/database-status", dashboardHandler.GetDatabaseStatus)
+				dashboardGroup.GET("/revenue", revenueHandler.GetRevenue)
+				dashboardGroup.GET("/revenue/movements", revenueHandler.GetRevenueMovements)
+				dashboardGroup.GET("/cohorts", cohortHandler.GetCohorts)
+				dashboardGroup.GET("/trials", trialHandler.GetTrialFunnel)
 			}
 
 			// 监控数据
@@ -225,7 +310,27 @@ func setupRoutes(router *gin.Engine, dbManager *database.DatabaseManager, cfg *c
 				monitoringGroup.GET("/organizations", monitoringHandler.GetOrganizations)
 				monitoringGroup.GET("/organizations/overview", monitoringHandler.GetOrganizationOverview)
 				monitoringGroup.GET("/organizations/:id/usage", monitoringHandler.GetOrganizationUsage)
+				monitoringGroup.GET("/organizations/:id/infrastructure", infrastructureHandler.GetOrganizationInfrastructure)
 				monitoringGroup.GET("/databases", monitoringHandler.GetDatabaseInfo)
+				monitoringGroup.GET("/databases/postgresql/:name/activity", postgreSQLHandler.GetActivity)
+				monitoringGroup.GET("/databases/postgresql/:name/blocking", postgreSQLHandler.GetBlocking)
+				monitoringGroup.GET("/databases/postgresql/:name/maintenance", postgreSQLHandler.GetMaintenance)
+				monitoringGroup.GET("/databases/postgresql/:name/replication", postgreSQLHandler.GetReplication)
+				monitoringGroup.GET("/databases/mysql/:name/status", mySQLHandler.GetStatus)
+				monitoringGroup.GET("/databases/mysql/:name/digests", mySQLHandler.GetDigests)
+				monitoringGroup.GET("/databases/clickhouse/:name/merges", clickHouseHandler.GetMerges)
+				monitoringGroup.GET("/databases/clickhouse/:name/mutations", clickHouseHandler.GetMutations)
+				monitoringGroup.GET("/databases/clickhouse/:name/parts", clickHouseHandler.GetParts)
+				monitoringGroup.GET("/databases/clickhouse/:name/replicas", clickHouseHandler.GetReplicas)
+				monitoringGroup.GET("/databases/clickhouse/:name/disks", clickHouseHandler.GetDisks)
+				monitoringGroup.GET("/databases/clickhouse/:name/queries", clickHouseHandler.GetQueryAnalytics)
+				monitoringGroup.GET("/databases/clickhouse/:name/queries/failed", clickHouseHandler.GetFailedQueries)
+				monitoringGroup.GET("/databases/redis/:name/info", redisHandler.GetInfo)
+				monitoringGroup.GET("/databases/redis/:name/slowlog", redisHandler.GetSlowLogs)
+				monitoringGroup.GET("/databases/redis/:name/events", redisHandler.GetEvents)
+				monitoringGroup.GET("/ingest/stats", ingestHandler.GetIngestStats)
+				monitoringGroup.GET("/probes", probeHandler.GetProbes)
+				monitoringGroup.POST("/probes/run", probeHandler.RunProbes)
 				monitoringGroup.GET("/alerts", monitoringHandler.GetAlerts)
 				monitoringGroup.POST("/alerts", monitoringHandler.CreateAlert)
 				monitoringGroup.PUT("/alerts/:id", monitoringHandler.UpdateAlert)
@@ -248,6 +353,7 @@ func setupRoutes(router *gin.Engine, dbManager *database.DatabaseManager, cfg *c
 
 				// 组织订阅查询（只读）
 				organizationGroup.GET("/:id/subscriptions", organizationHandler.GetOrganizationSubscriptions)
+				organizationGroup.GET("/:id/subscriptions/timeline", organizationHandler.GetOrganizationSubscriptionTimeline)
 
 				// 发送订阅到期提醒邮件（预留功能）
 				organizationGroup.POST("/:id/send-expiry-reminder", organizationHandler.SendExpiryReminder)
@@ -280,9 +386,25 @@ func setupRoutes(router *gin.Engine, dbManager *database.DatabaseManager, cfg *c
 			systemGroup := protectedGroup.Group("/system")
 			{
 				systemGroup.GET("/health", monitoringHandler.GetSystemHealth)
+				systemGroup.GET("/health/history", monitoringHandler.GetSystemHealthHistory)
 				systemGroup.GET("/logs", monitoringHandler.GetSystemLogs)
 				systemGroup.GET("/configs", monitoringHandler.GetSystemConfigs)
 				systemGroup.PUT("/configs", monitoringHandler.UpdateSystemConfigs)
+				systemGroup.GET("/jobs", schedulerHandler.GetJobs)
+				systemGroup.GET("/jobs/:name", schedulerHandler.GetJob)
+				systemGroup.GET("/jobs/:name/runs", schedulerHandler.GetJobRuns)
+				systemGroup.POST("/jobs/:name/pause", middleware.RequireRole("admin", "super_admin"), schedulerHandler.PauseJob)
+				systemGroup.POST("/jobs/:name/resume", middleware.RequireRole("admin", "super_admin"), schedulerHandler.ResumeJob)
+				systemGroup.POST("/jobs/:name/trigger", middleware.RequireRole("admin", "super_admin"), schedulerHandler.TriggerJob)
+				systemGroup.PUT("/jobs/:name/schedule", middleware.RequireRole("admin", "super_admin"), schedulerHandler.UpdateJobSchedule)
+			}
+
+			// 后台任务
+			jobGroup := protectedGroup.Group("/jobs")
+			{
+				jobGroup.GET("", jobHandler.GetJobs)
+				jobGroup.GET("/:id", jobHandler.GetJob)
+				jobGroup.POST("/:id/cancel", middleware.RequireRole("admin", "super_admin"), jobHandler.CancelJob)
 			}
 
 			// 用户管理（只读模式）
@@ -302,6 +424,21 @@ func setupRoutes(router *gin.Engine, dbManager *database.DatabaseManager, cfg *c
 				userGroup.PUT("/:id", userHandler.UpdateUser)
 				userGroup.DELETE("/:id", userHandler.DeleteUser)
 			}
+
+			// 运维操作：手动触发采集和历史数据回填（后台任务）、动态监控目标管理
+			adminGroup := protectedGroup.Group("/admin")
+			adminGroup.Use(middleware.RequireRole("admin", "super_admin"))
+			{
+				adminGroup.GET("/collectors", collectorHandler.GetCollectors)
+				adminGroup.POST("/collectors/:name/run", collectorHandler.RunCollector)
+				adminGroup.POST("/backfill", collectorHandler.Backfill)
+				adminGroup.GET("/targets", monitoringTargetHandler.GetTargets)
+				adminGroup.POST("/targets", monitoringTargetHandler.CreateTarget)
+				adminGroup.GET("/targets/:id", monitoringTargetHandler.GetTarget)
+				adminGroup.PUT("/targets/:id", monitoringTargetHandler.UpdateTarget)
+				adminGroup.DELETE("/targets/:id", monitoringTargetHandler.DeleteTarget)
+				adminGroup.POST("/targets/:id/test", monitoringTargetHandler.TestTarget)
+			}
 		}
 	}
 
@@ -324,6 +461,11 @@ func autoMigrate(dbManager *database.DatabaseManager) error {
 
 	log.Println("Database connections validated, starting migration...")
 
+	// 旧版本的慢查询唯一索引不含节点地址，需在建新索引前删除
+	if err := migrateRedisSlowLogIndex(dbManager); err != nil {
+		return fmt.Errorf("failed to migrate redis slow log index: %w", err)
+	}
+
 	// 迁移Sass监控数据库表
 	if err := dbManager.SaasMonitorDB.AutoMigrate(
 		&models.AdminUser{},
@@ -332,6 +474,14 @@ func autoMigrate(dbManager *database.DatabaseManager) error {
 		&models.ResourceMetric{},
 		&models.MonitoringLog{},
 		&models.SystemHealth{},
+		&models.SystemHealthHistory{},
+		&models.RedisSlowLog{},
+		&models.MonitoringTarget{},
+		&models.IngestionWatermark{},
+		&models.IngestedRow{},
+		&models.ScheduledJob{},
+		&models.JobRun{},
+		&models.BackgroundJob{},
 	); err != nil {
 		return fmt.Errorf("failed to migrate saas_monitor database: %w", err)
 	}
@@ -343,6 +493,53 @@ func autoMigrate(dbManager *database.DatabaseManager) error {
 		log.Printf("Warning: Failed to execute init SQL: %v", err)
 	}
 
+	// 统一旧版本写入的指标标签
+	if err := migrateMetricTags(dbManager); err != nil {
+		log.Printf("Warning: Failed to migrate metric tags: %v", err)
+	}
+
+	// 为light_admin中监控查询使用的表达式创建索引
+	if err := migrateLightAdminIndexes(dbManager); err != nil {
+		log.Printf("Warning: Failed to create light_admin indexes: %v", err)
+	}
+
+	// 创建主机默认告警规则
+	if err := services.EnsureHostAlertRules(dbManager); err != nil {
+		log.Printf("Warning: Failed to create host alert rules: %v", err)
+	}
+
+	return nil
+}
+
+// migrateRedisSlowLogIndex 删除旧版本按(实例, ID, 执行时间)建立的唯一索引，
+// 否则集群中不同节点ID和时间相同的慢查询会被当作重复丢弃；新索引由AutoMigrate创建
+func migrateRedisSlowLogIndex(dbManager *database.DatabaseManager) error {
+	return dbManager.SaasMonitorDB.Exec("DROP INDEX IF EXISTS idx_redis_slowlog_entry").Error
+}
+
+// migrateLightAdminIndexes 为订阅时间线按metadata.organization_id查询webhook_events创建表达式索引；
+// light_admin是业务库，使用CONCURRENTLY避免建索引时阻塞写入
+func migrateLightAdminIndexes(dbManager *database.DatabaseManager) error {
+	if dbManager.LightAdminDB == nil {
+		return nil
+	}
+	return dbManager.LightAdminDB.Exec(`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_webhook_events_metadata_org
+ON webhook_events ((event_data#>>'{data,object,metadata,organization_id}'))`).Error
+}
+
+// migrateMetricTags 将旧版本写入的table标签改名为table_name，使按标签过滤时新旧数据一致
+func migrateMetricTags(dbManager *database.DatabaseManager) error {
+	// 没有参数时gorm不替换?，这里是jsonb的键存在运算符，可以使用GIN索引
+	result := dbManager.SaasMonitorDB.Exec(`
+UPDATE resource_metrics
+SET tags = (tags - 'table') || jsonb_build_object('table_name', tags->'table')
+WHERE tags ? 'table'`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Renamed the table tag to table_name on %d metrics", result.RowsAffected)
+	}
 	return nil
 }
 
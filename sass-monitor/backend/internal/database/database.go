@@ -9,6 +9,7 @@ import (
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -26,11 +27,23 @@ type DatabaseManager struct {
 	SaasMonitorDB *gorm.DB
 	LightAdminDB  *gorm.DB
 
+	// 额外的PostgreSQL监控目标（如备库）
+	PostgreSQL map[string]*gorm.DB
+
+	// MySQL/MariaDB监控目标
+	MySQL map[string]*gorm.DB
+
 	// ClickHouse connections
 	ClickHouse map[string]clickhouse.Conn
 
-	// Redis
-	RedisClient *redis.Client
+	// Redis（RedisClient为默认实例的客户端）
+	RedisClient redis.UniversalClient
+	Redis       map[string]*RedisInstance
+
+	// 运行时可增删的监控目标（见targets.go），mu保护上述map
+	mu                  sync.RWMutex
+	clickHouseDatabases map[string]string
+	dynamicTargets      map[string]bool
 }
 
 // GetDatabaseManager 获取数据库管理器单例
@@ -38,7 +51,13 @@ func GetDatabaseManager(cfg *config.Config) *DatabaseManager {
 	once.Do(func() {
 		dbManager = &DatabaseManager{
 			Config:     cfg,
+			PostgreSQL: make(map[string]*gorm.DB),
+			MySQL:      make(map[string]*gorm.DB),
 			ClickHouse: make(map[string]clickhouse.Conn),
+			Redis:      make(map[string]*RedisInstance),
+
+			clickHouseDatabases: make(map[string]string),
+			dynamicTargets:      make(map[string]bool),
 		}
 	})
 	return dbManager
@@ -51,6 +70,11 @@ func (dm *DatabaseManager) Initialize() error {
 		return fmt.Errorf("failed to initialize PostgreSQL: %w", err)
 	}
 
+	// 初始化MySQL监控目标
+	if err := dm.initMySQL(); err != nil {
+		return fmt.Errorf("failed to initialize MySQL: %w", err)
+	}
+
 	// 初始化ClickHouse连接
 	if err := dm.initClickHouse(); err != nil {
 		return fmt.Errorf("failed to initialize ClickHouse: %w", err)
@@ -108,31 +132,143 @@ func (dm *DatabaseManager) initPostgreSQL() error {
 
 	dm.LightAdminDB = lightAdminDB
 
+	// 连接额外的PostgreSQL监控目标
+	if err := dm.initAdditionalPostgreSQL(gormConfig); err != nil {
+		return err
+	}
+
 	log.Println("PostgreSQL connections established")
 	return nil
 }
 
+// initAdditionalPostgreSQL 初始化额外的PostgreSQL监控目标
+// 目标不可用时不阻止启动，由健康检查报告其状态
+func (dm *DatabaseManager) initAdditionalPostgreSQL(gormConfig *gorm.Config) error {
+	for _, pgConfig := range dm.Config.Databases.Additional {
+		if pgConfig.Name == "" {
+			return fmt.Errorf("additional PostgreSQL target requires a name")
+		}
+		if pgConfig.Name == "saas_monitor" || pgConfig.Name == "light_admin" {
+			return fmt.Errorf("additional PostgreSQL target name '%s' is reserved", pgConfig.Name)
+		}
+		if pgConfig.Role != "" && pgConfig.Role != "primary" && pgConfig.Role != "replica" {
+			return fmt.Errorf("additional PostgreSQL target '%s' has invalid role '%s' (expected primary or replica)", pgConfig.Name, pgConfig.Role)
+		}
+
+		db, err := openPostgreSQLTarget(pgConfig, gormConfig)
+		if err != nil {
+			return err
+		}
+
+		dm.PostgreSQL[pgConfig.Name] = db
+		log.Printf("PostgreSQL target registered for %s", pgConfig.Name)
+	}
+
+	return nil
+}
+
+// openPostgreSQLTarget 打开PostgreSQL监控目标连接，目标不可达时仅记录警告
+func openPostgreSQLTarget(pgConfig config.DatabaseConnectionConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
+	if pgConfig.Type == "" {
+		pgConfig.Type = "postgres"
+	}
+	if pgConfig.SSLMode == "" {
+		pgConfig.SSLMode = "disable"
+	}
+	if pgConfig.MaxOpenConns == 0 {
+		pgConfig.MaxOpenConns = 5
+	}
+	if pgConfig.MaxIdleConns == 0 {
+		pgConfig.MaxIdleConns = 2
+	}
+
+	targetConfig := *gormConfig
+	targetConfig.DisableAutomaticPing = true
+
+	db, err := gorm.Open(postgres.Open(pgConfig.GetDSN()), &targetConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL target %s: %w", pgConfig.Name, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PostgreSQL target %s underlying sql.DB: %w", pgConfig.Name, err)
+	}
+
+	sqlDB.SetMaxOpenConns(pgConfig.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(pgConfig.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	if err := sqlDB.Ping(); err != nil {
+		log.Printf("Warning: PostgreSQL target %s is not reachable: %v", pgConfig.Name, err)
+	}
+
+	return db, nil
+}
+
+// initMySQL 初始化MySQL/MariaDB监控目标，目标不可用时不阻止启动
+func (dm *DatabaseManager) initMySQL() error {
+	for _, mysqlConfig := range dm.Config.Databases.MySQL {
+		if mysqlConfig.Name == "" {
+			return fmt.Errorf("MySQL target requires a name")
+		}
+
+		db, err := openMySQLTarget(mysqlConfig)
+		if err != nil {
+			return err
+		}
+
+		dm.MySQL[mysqlConfig.Name] = db
+		log.Printf("MySQL target registered for %s", mysqlConfig.Name)
+	}
+
+	return nil
+}
+
+// openMySQLTarget 打开MySQL监控目标连接，目标不可达时仅记录警告
+func openMySQLTarget(mysqlConfig config.DatabaseConnectionConfig) (*gorm.DB, error) {
+	mysqlConfig.Type = "mysql"
+	if mysqlConfig.MaxOpenConns == 0 {
+		mysqlConfig.MaxOpenConns = 3
+	}
+	if mysqlConfig.MaxIdleConns == 0 {
+		mysqlConfig.MaxIdleConns = 1
+	}
+
+	if err := config.ValidateMySQLSSLMode(mysqlConfig.SSLMode); err != nil {
+		return nil, fmt.Errorf("invalid MySQL target %s: %w", mysqlConfig.Name, err)
+	}
+
+	db, err := gorm.Open(mysql.Open(mysqlConfig.GetDSN()), &gorm.Config{
+		Logger:               logger.Default.LogMode(logger.Warn),
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MySQL target %s: %w", mysqlConfig.Name, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MySQL target %s underlying sql.DB: %w", mysqlConfig.Name, err)
+	}
+
+	sqlDB.SetMaxOpenConns(mysqlConfig.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(mysqlConfig.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	if err := sqlDB.Ping(); err != nil {
+		log.Printf("Warning: MySQL target %s is not reachable: %v", mysqlConfig.Name, err)
+	}
+
+	return db, nil
+}
+
 // initClickHouse 初始化ClickHouse连接
 func (dm *DatabaseManager) initClickHouse() error {
 	for _, chConfig := range dm.Config.ClickHouse {
-		// 创建ClickHouse连接选项
-		options := clickhouse.Options{
-			Addr: []string{fmt.Sprintf("%s:%d", chConfig.Host, chConfig.Port)},
-			Auth: clickhouse.Auth{
-				Database: chConfig.Database,
-				Username: chConfig.User,
-				Password: chConfig.Password,
-			},
-			Settings: clickhouse.Settings{
-				"max_execution_time": 60,
-			},
-			DialTimeout: 30 * time.Second,
-		}
-
-		// 建立连接
-		conn, err := clickhouse.Open(&options)
+		conn, err := openClickHouse(chConfig)
 		if err != nil {
-			return fmt.Errorf("failed to connect to ClickHouse %s: %w", chConfig.Name, err)
+			return err
 		}
 
 		// 验证连接
@@ -141,36 +277,80 @@ func (dm *DatabaseManager) initClickHouse() error {
 		}
 
 		dm.ClickHouse[chConfig.Name] = conn
+		dm.clickHouseDatabases[chConfig.Name] = chConfig.Database
 		log.Printf("ClickHouse connection established for %s", chConfig.Name)
 	}
 
 	return nil
 }
 
+// openClickHouse 创建ClickHouse连接（不验证连通性）
+func openClickHouse(chConfig config.ClickHouseConfig) (clickhouse.Conn, error) {
+	options := clickhouse.Options{
+		Addr: []string{fmt.Sprintf("%s:%d", chConfig.Host, chConfig.Port)},
+		Auth: clickhouse.Auth{
+			Database: chConfig.Database,
+			Username: chConfig.User,
+			Password: chConfig.Password,
+		},
+		Settings: clickhouse.Settings{
+			"max_execution_time": 60,
+		},
+		DialTimeout: 30 * time.Second,
+	}
+
+	conn, err := clickhouse.Open(&options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ClickHouse %s: %w", chConfig.Name, err)
+	}
+	return conn, nil
+}
+
 // initRedis 初始化Redis连接
 func (dm *DatabaseManager) initRedis() error {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     dm.Config.Redis.GetRedisAddr(),
-		Password: dm.Config.Redis.Password,
-		DB:       dm.Config.Redis.Database,
-		PoolSize: dm.Config.Redis.PoolSize,
-	})
+	instance, err := newRedisInstance("default", dm.Config.Redis)
+	if err != nil {
+		return err
+	}
 
 	// 验证连接
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := rdb.Ping(ctx).Err(); err != nil {
+	if err := instance.Client.Ping(ctx).Err(); err != nil {
 		return fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	dm.RedisClient = rdb
-	log.Println("Redis connection established")
+	dm.Redis["default"] = instance
+	dm.RedisClient = instance.Client
+	log.Printf("Redis connection established (%s)", instance.Mode())
+
+	// 额外的命名Redis实例不可用时不阻止启动，由健康检查报告其状态
+	for _, redisConfig := range dm.Config.RedisInstances {
+		if redisConfig.Name == "" || redisConfig.Name == "default" {
+			return fmt.Errorf("additional Redis instance requires a name other than 'default'")
+		}
+
+		instance, err := newRedisInstance(redisConfig.Name, redisConfig)
+		if err != nil {
+			return err
+		}
+		if err := instance.Client.Ping(ctx).Err(); err != nil {
+			log.Printf("Warning: Redis instance %s is not reachable: %v", redisConfig.Name, err)
+		}
+
+		dm.Redis[redisConfig.Name] = instance
+		log.Printf("Redis instance registered for %s (%s)", redisConfig.Name, instance.Mode())
+	}
+
 	return nil
 }
 
 // Close 关闭所有数据库连接
 func (dm *DatabaseManager) Close() error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
 	var errors []error
 
 	// 关闭PostgreSQL连接
@@ -190,6 +370,22 @@ func (dm *DatabaseManager) Close() error {
 		}
 	}
 
+	for name, db := range dm.PostgreSQL {
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				errors = append(errors, fmt.Errorf("failed to close PostgreSQL %s: %w", name, err))
+			}
+		}
+	}
+
+	for name, db := range dm.MySQL {
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				errors = append(errors, fmt.Errorf("failed to close MySQL %s: %w", name, err))
+			}
+		}
+	}
+
 	// 关闭ClickHouse连接
 	for name, conn := range dm.ClickHouse {
 		if err := conn.Close(); err != nil {
@@ -198,9 +394,9 @@ func (dm *DatabaseManager) Close() error {
 	}
 
 	// 关闭Redis连接
-	if dm.RedisClient != nil {
-		if err := dm.RedisClient.Close(); err != nil {
-			errors = append(errors, fmt.Errorf("failed to close Redis: %w", err))
+	for name, instance := range dm.Redis {
+		if err := instance.Close(); err != nil {
+			errors = append(errors, fmt.Errorf("failed to close Redis %s: %w", name, err))
 		}
 	}
 
@@ -214,6 +410,9 @@ func (dm *DatabaseManager) Close() error {
 
 // GetClickHouseConnection 获取指定名称的ClickHouse连接
 func (dm *DatabaseManager) GetClickHouseConnection(name string) (clickhouse.Conn, error) {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
 	conn, exists := dm.ClickHouse[name]
 	if !exists {
 		return nil, fmt.Errorf("ClickHouse connection '%s' not found", name)
@@ -221,55 +420,205 @@ func (dm *DatabaseManager) GetClickHouseConnection(name string) (clickhouse.Conn
 	return conn, nil
 }
 
+// GetClickHouseDatabase 获取指定ClickHouse连接配置的数据库名
+func (dm *DatabaseManager) GetClickHouseDatabase(name string) string {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	if database, exists := dm.clickHouseDatabases[name]; exists {
+		return database
+	}
+	return name
+}
+
+// GetClickHouseConnections 获取所有ClickHouse连接的快照
+func (dm *DatabaseManager) GetClickHouseConnections() map[string]clickhouse.Conn {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	conns := make(map[string]clickhouse.Conn, len(dm.ClickHouse))
+	for name, conn := range dm.ClickHouse {
+		conns[name] = conn
+	}
+	return conns
+}
+
+// GetRedisInstance 获取指定名称的Redis实例
+func (dm *DatabaseManager) GetRedisInstance(name string) (*RedisInstance, error) {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	instance, exists := dm.Redis[name]
+	if !exists {
+		return nil, fmt.Errorf("Redis instance '%s' not found", name)
+	}
+	return instance, nil
+}
+
+// GetRedisInstances 获取所有Redis实例的快照
+func (dm *DatabaseManager) GetRedisInstances() map[string]*RedisInstance {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	instances := make(map[string]*RedisInstance, len(dm.Redis))
+	for name, instance := range dm.Redis {
+		instances[name] = instance
+	}
+	return instances
+}
+
+// GetPostgreSQLConnection 获取指定名称的PostgreSQL连接
+func (dm *DatabaseManager) GetPostgreSQLConnection(name string) (*gorm.DB, error) {
+	switch name {
+	case "saas_monitor":
+		if dm.SaasMonitorDB != nil {
+			return dm.SaasMonitorDB, nil
+		}
+	case "light_admin":
+		if dm.LightAdminDB != nil {
+			return dm.LightAdminDB, nil
+		}
+	default:
+		dm.mu.RLock()
+		db, exists := dm.PostgreSQL[name]
+		dm.mu.RUnlock()
+		if exists {
+			return db, nil
+		}
+	}
+	return nil, fmt.Errorf("PostgreSQL connection '%s' not found", name)
+}
+
+// GetPostgreSQLTargets 获取需要采集的PostgreSQL监控目标（light_admin及额外目标）
+func (dm *DatabaseManager) GetPostgreSQLTargets() map[string]*gorm.DB {
+	targets := make(map[string]*gorm.DB)
+	if dm.LightAdminDB != nil {
+		targets["light_admin"] = dm.LightAdminDB
+	}
+
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	for name, db := range dm.PostgreSQL {
+		targets[name] = db
+	}
+	return targets
+}
+
+// GetMySQLConnection 获取指定名称的MySQL连接
+func (dm *DatabaseManager) GetMySQLConnection(name string) (*gorm.DB, error) {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	db, exists := dm.MySQL[name]
+	if !exists {
+		return nil, fmt.Errorf("MySQL connection '%s' not found", name)
+	}
+	return db, nil
+}
+
+// GetMySQLTargets 获取所有MySQL监控目标的快照
+func (dm *DatabaseManager) GetMySQLTargets() map[string]*gorm.DB {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	targets := make(map[string]*gorm.DB, len(dm.MySQL))
+	for name, db := range dm.MySQL {
+		targets[name] = db
+	}
+	return targets
+}
+
+// ComponentCheck 单个组件的检查结果
+type ComponentCheck struct {
+	Err     error
+	Latency time.Duration // 本次检查（Ping）的耗时
+}
+
 // HealthCheck 检查所有数据库连接健康状态
 func (dm *DatabaseManager) HealthCheck() map[string]error {
 	status := make(map[string]error)
+	for component, check := range dm.CheckComponents(context.Background()) {
+		status[component] = check.Err
+	}
+	return status
+}
 
-	// 检查Sass监控数据库
-	if dm.SaasMonitorDB != nil {
-		if sqlDB, err := dm.SaasMonitorDB.DB(); err == nil {
-			if err := sqlDB.Ping(); err != nil {
-				status["saas_monitor"] = err
-			} else {
-				status["saas_monitor"] = nil
+// CheckComponents 逐个Ping所有组件并测量响应时间
+func (dm *DatabaseManager) CheckComponents(ctx context.Context) map[string]ComponentCheck {
+	results := make(map[string]ComponentCheck)
+
+	// timed 执行一次检查并记录耗时
+	timed := func(component string, ping func() error) {
+		start := time.Now()
+		err := ping()
+		results[component] = ComponentCheck{Err: err, Latency: time.Since(start)}
+	}
+	pingGorm := func(db *gorm.DB) func() error {
+		return func() error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
 			}
-		} else {
-			status["saas_monitor"] = err
+			return sqlDB.PingContext(ctx)
 		}
 	}
 
+	// 检查Sass监控数据库
+	if dm.SaasMonitorDB != nil {
+		timed("saas_monitor", pingGorm(dm.SaasMonitorDB))
+	}
+
 	// 检查Light Admin数据库
 	if dm.LightAdminDB != nil {
-		if sqlDB, err := dm.LightAdminDB.DB(); err == nil {
-			if err := sqlDB.Ping(); err != nil {
-				status["light_admin"] = err
-			} else {
-				status["light_admin"] = nil
-			}
-		} else {
-			status["light_admin"] = err
+		timed("light_admin", pingGorm(dm.LightAdminDB))
+	}
+
+	// 检查额外的PostgreSQL目标
+	for name, db := range dm.GetPostgreSQLTargets() {
+		if name == "light_admin" {
+			continue
 		}
+		timed(fmt.Sprintf("postgresql_%s", name), pingGorm(db))
+	}
+
+	// 检查MySQL目标
+	for name, db := range dm.GetMySQLTargets() {
+		timed(fmt.Sprintf("mysql_%s", name), pingGorm(db))
 	}
 
 	// 检查ClickHouse连接
-	for name, conn := range dm.ClickHouse {
-		if err := conn.Ping(context.Background()); err != nil {
-			status[fmt.Sprintf("clickhouse_%s", name)] = err
-		} else {
-			status[fmt.Sprintf("clickhouse_%s", name)] = nil
-		}
+	for name, conn := range dm.GetClickHouseConnections() {
+		timed(fmt.Sprintf("clickhouse_%s", name), func() error {
+			return conn.Ping(ctx)
+		})
 	}
 
-	// 检查Redis连接
-	if dm.RedisClient != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cancel()
-		if err := dm.RedisClient.Ping(ctx).Err(); err != nil {
-			status["redis"] = err
-		} else {
-			status["redis"] = nil
+	// 检查Redis连接（默认实例为redis，其他实例为redis_<name>；cluster/sentinel模式逐节点报告）
+	redisCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	for name, instance := range dm.GetRedisInstances() {
+		component := "redis"
+		if name != "default" {
+			component = fmt.Sprintf("redis_%s", name)
+		}
+		timed(component, func() error {
+			return instance.Client.Ping(redisCtx).Err()
+		})
+
+		if instance.Mode() == "standalone" {
+			continue
+		}
+		nodes, err := instance.Nodes(redisCtx)
+		if err != nil {
+			results[fmt.Sprintf("%s_topology", component)] = ComponentCheck{Err: err}
+			continue
+		}
+		for _, node := range nodes {
+			timed(fmt.Sprintf("%s_%s_%s", component, node.Role, node.Addr), func() error {
+				return node.Client.Ping(redisCtx).Err()
+			})
 		}
 	}
 
-	return status
-}
\ No newline at end of file
+	return results
+}
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"sass-monitor/pkg/config"
+)
+
+// Redis节点角色
+const (
+	RedisRoleMaster   = "master"
+	RedisRoleReplica  = "replica"
+	RedisRoleSentinel = "sentinel"
+)
+
+// RedisInstance 命名Redis实例（standalone、cluster或sentinel）
+type RedisInstance struct {
+	Name   string
+	Config config.RedisConfig
+	Client redis.UniversalClient
+
+	mu              sync.Mutex
+	sentinelClients []*redis.SentinelClient
+	nodeClients     map[string]*redis.Client
+}
+
+// RedisNode 实例中的单个节点
+type RedisNode struct {
+	Addr   string
+	Role   string // master, replica, sentinel；standalone模式为空，由INFO确定
+	Client *redis.Client
+}
+
+// newRedisInstance 根据部署模式创建Redis实例客户端
+func newRedisInstance(name string, cfg config.RedisConfig) (*RedisInstance, error) {
+	instance := &RedisInstance{
+		Name:        name,
+		Config:      cfg,
+		nodeClients: make(map[string]*redis.Client),
+	}
+
+	switch cfg.GetMode() {
+	case "standalone":
+		instance.Client = redis.NewClient(&redis.Options{
+			Addr:     cfg.GetRedisAddr(),
+			Password: cfg.Password,
+			DB:       cfg.Database,
+			PoolSize: cfg.PoolSize,
+		})
+	case "cluster":
+		instance.Client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    cfg.GetRedisAddrs(),
+			Password: cfg.Password,
+			PoolSize: cfg.PoolSize,
+		})
+	case "sentinel":
+		if cfg.MasterName == "" {
+			return nil, fmt.Errorf("Redis instance '%s' in sentinel mode requires master_name", name)
+		}
+		instance.Client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    cfg.GetRedisAddrs(),
+			SentinelPassword: cfg.SentinelPassword,
+			Password:         cfg.Password,
+			DB:               cfg.Database,
+			PoolSize:         cfg.PoolSize,
+		})
+		for _, addr := range cfg.GetRedisAddrs() {
+			instance.sentinelClients = append(instance.sentinelClients, redis.NewSentinelClient(&redis.Options{
+				Addr:     addr,
+				Password: cfg.SentinelPassword,
+			}))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Redis mode '%s' for instance '%s'", cfg.Mode, name)
+	}
+
+	return instance, nil
+}
+
+// Mode 获取实例部署模式
+func (ri *RedisInstance) Mode() string {
+	return ri.Config.GetMode()
+}
+
+// Nodes 发现实例的所有节点
+func (ri *RedisInstance) Nodes(ctx context.Context) ([]RedisNode, error) {
+	switch ri.Mode() {
+	case "cluster":
+		return ri.clusterNodes(ctx, ri.Client.(*redis.ClusterClient))
+	case "sentinel":
+		return ri.sentinelNodes(ctx)
+	default:
+		return []RedisNode{{Addr: ri.Config.GetRedisAddr(), Client: ri.Client.(*redis.Client)}}, nil
+	}
+}
+
+// clusterNodes 获取cluster中的所有主节点和从节点
+func (ri *RedisInstance) clusterNodes(ctx context.Context, client *redis.ClusterClient) ([]RedisNode, error) {
+	var mu sync.Mutex
+	var nodes []RedisNode
+	collect := func(role string) func(ctx context.Context, node *redis.Client) error {
+		return func(ctx context.Context, node *redis.Client) error {
+			mu.Lock()
+			defer mu.Unlock()
+			nodes = append(nodes, RedisNode{Addr: node.Options().Addr, Role: role, Client: node})
+			return nil
+		}
+	}
+
+	if err := client.ForEachMaster(ctx, collect(RedisRoleMaster)); err != nil {
+		return nil, fmt.Errorf("failed to list cluster masters: %w", err)
+	}
+	if err := client.ForEachSlave(ctx, collect(RedisRoleReplica)); err != nil {
+		return nil, fmt.Errorf("failed to list cluster replicas: %w", err)
+	}
+	return nodes, nil
+}
+
+// sentinelNodes 通过哨兵获取当前主节点、从节点以及哨兵自身
+func (ri *RedisInstance) sentinelNodes(ctx context.Context) ([]RedisNode, error) {
+	var nodes []RedisNode
+	var masterAddr string
+	var replicas []map[string]string
+	var lastErr error
+
+	for _, sentinel := range ri.sentinelClients {
+		addr, err := sentinel.GetMasterAddrByName(ctx, ri.Config.MasterName).Result()
+		if err != nil || len(addr) != 2 {
+			lastErr = err
+			continue
+		}
+		masterAddr = net.JoinHostPort(addr[0], addr[1])
+
+		replicas, err = sentinel.Replicas(ctx, ri.Config.MasterName).Result()
+		if err != nil {
+			lastErr = err
+		}
+		break
+	}
+	if masterAddr == "" {
+		return nil, fmt.Errorf("failed to resolve master '%s' from sentinels: %v", ri.Config.MasterName, lastErr)
+	}
+
+	nodes = append(nodes, RedisNode{Addr: masterAddr, Role: RedisRoleMaster, Client: ri.nodeClient(masterAddr, ri.Config.Password, ri.Config.Database)})
+	for _, replica := range replicas {
+		addr := net.JoinHostPort(replica["ip"], replica["port"])
+		nodes = append(nodes, RedisNode{Addr: addr, Role: RedisRoleReplica, Client: ri.nodeClient(addr, ri.Config.Password, ri.Config.Database)})
+	}
+	for _, addr := range ri.Config.GetRedisAddrs() {
+		nodes = append(nodes, RedisNode{Addr: addr, Role: RedisRoleSentinel, Client: ri.nodeClient(addr, ri.Config.SentinelPassword, 0)})
+	}
+	return nodes, nil
+}
+
+// nodeClient 获取（或创建）指定节点的直连客户端
+func (ri *RedisInstance) nodeClient(addr, password string, db int) *redis.Client {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	if client, exists := ri.nodeClients[addr]; exists {
+		return client
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+		PoolSize: 2,
+	})
+	ri.nodeClients[addr] = client
+	return client
+}
+
+// Close 关闭实例及节点客户端
+func (ri *RedisInstance) Close() error {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	var firstErr error
+	record := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	record(ri.Client.Close())
+	for _, sentinel := range ri.sentinelClients {
+		record(sentinel.Close())
+	}
+	for _, client := range ri.nodeClients {
+		record(client.Close())
+	}
+	return firstErr
+}
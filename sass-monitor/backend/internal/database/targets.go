@@ -0,0 +1,327 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"sass-monitor/pkg/config"
+)
+
+// 监控目标类型
+const (
+	TargetTypePostgreSQL = "postgresql"
+	TargetTypeClickHouse = "clickhouse"
+	TargetTypeRedis      = "redis"
+	TargetTypeMySQL      = "mysql"
+)
+
+// ErrTargetExists 同类型同名的目标已注册
+var ErrTargetExists = errors.New("monitoring target already exists")
+
+// TargetConfig 运行时注册的监控目标连接配置
+type TargetConfig struct {
+	Type     string
+	Name     string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+	SSLMode  string // postgresql、mysql
+
+	// 仅redis
+	Mode             string
+	Addrs            []string
+	MasterName       string
+	SentinelPassword string
+}
+
+func (tc TargetConfig) postgreSQLConfig() config.DatabaseConnectionConfig {
+	return config.DatabaseConnectionConfig{
+		Name:     tc.Name,
+		Type:     "postgres",
+		Host:     tc.Host,
+		Port:     tc.Port,
+		User:     tc.User,
+		Password: tc.Password,
+		Database: tc.Database,
+		SSLMode:  tc.SSLMode,
+		ReadOnly: true,
+	}
+}
+
+func (tc TargetConfig) mySQLConfig() config.DatabaseConnectionConfig {
+	return config.DatabaseConnectionConfig{
+		Name:     tc.Name,
+		Type:     "mysql",
+		Host:     tc.Host,
+		Port:     tc.Port,
+		User:     tc.User,
+		Password: tc.Password,
+		Database: tc.Database,
+		SSLMode:  tc.SSLMode,
+		ReadOnly: true,
+	}
+}
+
+func (tc TargetConfig) clickHouseConfig() config.ClickHouseConfig {
+	return config.ClickHouseConfig{
+		Name:     tc.Name,
+		Host:     tc.Host,
+		Port:     tc.Port,
+		User:     tc.User,
+		Password: tc.Password,
+		Database: tc.Database,
+	}
+}
+
+func (tc TargetConfig) redisConfig() config.RedisConfig {
+	database, _ := strconv.Atoi(tc.Database)
+	return config.RedisConfig{
+		Name:             tc.Name,
+		Mode:             tc.Mode,
+		Host:             tc.Host,
+		Port:             strconv.Itoa(tc.Port),
+		Password:         tc.Password,
+		Database:         database,
+		PoolSize:         5,
+		Addrs:            tc.Addrs,
+		MasterName:       tc.MasterName,
+		SentinelPassword: tc.SentinelPassword,
+	}
+}
+
+// targetKey 目标在dynamicTargets中的键
+func targetKey(targetType, name string) string {
+	return targetType + "/" + name
+}
+
+// HasTarget 检查指定类型和名称的目标是否已注册（包括配置文件中的目标）
+func (dm *DatabaseManager) HasTarget(targetType, name string) bool {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.hasTargetLocked(targetType, name)
+}
+
+func (dm *DatabaseManager) hasTargetLocked(targetType, name string) bool {
+	switch targetType {
+	case TargetTypePostgreSQL:
+		if name == "saas_monitor" || name == "light_admin" {
+			return true
+		}
+		_, exists := dm.PostgreSQL[name]
+		return exists
+	case TargetTypeMySQL:
+		_, exists := dm.MySQL[name]
+		return exists
+	case TargetTypeClickHouse:
+		_, exists := dm.ClickHouse[name]
+		return exists
+	case TargetTypeRedis:
+		_, exists := dm.Redis[name]
+		return exists
+	}
+	return false
+}
+
+// IsDynamicTarget 检查目标是否为运行时注册（而非配置文件定义）
+func (dm *DatabaseManager) IsDynamicTarget(targetType, name string) bool {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.dynamicTargets[targetKey(targetType, name)]
+}
+
+// TestTarget 建立临时连接并验证目标可达，不注册连接
+func (dm *DatabaseManager) TestTarget(ctx context.Context, target TargetConfig) error {
+	switch target.Type {
+	case TargetTypePostgreSQL:
+		db, err := openPostgreSQLTarget(target.postgreSQLConfig(), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			return err
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		defer sqlDB.Close()
+		return sqlDB.PingContext(ctx)
+	case TargetTypeMySQL:
+		db, err := openMySQLTarget(target.mySQLConfig())
+		if err != nil {
+			return err
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		defer sqlDB.Close()
+		return sqlDB.PingContext(ctx)
+	case TargetTypeClickHouse:
+		conn, err := openClickHouse(target.clickHouseConfig())
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return conn.Ping(ctx)
+	case TargetTypeRedis:
+		instance, err := newRedisInstance(target.Name, target.redisConfig())
+		if err != nil {
+			return err
+		}
+		defer instance.Close()
+		return instance.Client.Ping(ctx).Err()
+	default:
+		return fmt.Errorf("unsupported target type '%s'", target.Type)
+	}
+}
+
+// AddTarget 运行时注册监控目标，目标不可达时仍注册并由健康检查报告
+func (dm *DatabaseManager) AddTarget(ctx context.Context, target TargetConfig) error {
+	return dm.ReplaceTarget(ctx, "", "", target)
+}
+
+// ReplaceTarget 按新配置注册监控目标，并替换运行时注册的previousType/previousName目标（为空时只注册）。
+// 先建立新连接，成功后才注销旧连接，新连接建立失败时旧连接保持注册
+func (dm *DatabaseManager) ReplaceTarget(ctx context.Context, previousType, previousName string, target TargetConfig) error {
+	dm.mu.RLock()
+	conflict := dm.replaceConflictLocked(previousType, previousName, target)
+	dm.mu.RUnlock()
+	if conflict {
+		return fmt.Errorf("%w: %s target '%s'", ErrTargetExists, target.Type, target.Name)
+	}
+
+	// register在持有锁时写入连接，closeConn在名称冲突时释放新建的连接
+	var register func()
+	var closeConn func() error
+	switch target.Type {
+	case TargetTypePostgreSQL:
+		db, err := openPostgreSQLTarget(target.postgreSQLConfig(), &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)})
+		if err != nil {
+			return err
+		}
+		register = func() { dm.PostgreSQL[target.Name] = db }
+		closeConn = func() error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		}
+	case TargetTypeMySQL:
+		db, err := openMySQLTarget(target.mySQLConfig())
+		if err != nil {
+			return err
+		}
+		register = func() { dm.MySQL[target.Name] = db }
+		closeConn = func() error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		}
+	case TargetTypeClickHouse:
+		conn, err := openClickHouse(target.clickHouseConfig())
+		if err != nil {
+			return err
+		}
+		if err := conn.Ping(ctx); err != nil {
+			log.Printf("Warning: ClickHouse target %s is not reachable: %v", target.Name, err)
+		}
+		register = func() {
+			dm.ClickHouse[target.Name] = conn
+			dm.clickHouseDatabases[target.Name] = target.Database
+		}
+		closeConn = conn.Close
+	case TargetTypeRedis:
+		instance, err := newRedisInstance(target.Name, target.redisConfig())
+		if err != nil {
+			return err
+		}
+		if err := instance.Client.Ping(ctx).Err(); err != nil {
+			log.Printf("Warning: Redis instance %s is not reachable: %v", target.Name, err)
+		}
+		register = func() { dm.Redis[target.Name] = instance }
+		closeConn = instance.Close
+	default:
+		return fmt.Errorf("unsupported target type '%s'", target.Type)
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if dm.replaceConflictLocked(previousType, previousName, target) {
+		closeConn()
+		return fmt.Errorf("%w: %s target '%s'", ErrTargetExists, target.Type, target.Name)
+	}
+	if dm.dynamicTargets[targetKey(previousType, previousName)] {
+		if err := dm.removeTargetLocked(previousType, previousName); err != nil {
+			log.Printf("Error closing monitoring target %s %s: %v", previousType, previousName, err)
+		}
+	}
+	register()
+	dm.dynamicTargets[targetKey(target.Type, target.Name)] = true
+
+	log.Printf("Monitoring target registered: %s %s", target.Type, target.Name)
+	return nil
+}
+
+// replaceConflictLocked 新目标是否与已注册的目标重名（被替换的同名运行时目标除外）
+func (dm *DatabaseManager) replaceConflictLocked(previousType, previousName string, target TargetConfig) bool {
+	if previousType == target.Type && previousName == target.Name && dm.dynamicTargets[targetKey(previousType, previousName)] {
+		return false
+	}
+	return dm.hasTargetLocked(target.Type, target.Name)
+}
+
+// RemoveTarget 注销运行时注册的监控目标并关闭连接，配置文件中的目标不可移除
+func (dm *DatabaseManager) RemoveTarget(targetType, name string) error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.removeTargetLocked(targetType, name)
+}
+
+func (dm *DatabaseManager) removeTargetLocked(targetType, name string) error {
+	key := targetKey(targetType, name)
+	if !dm.dynamicTargets[key] {
+		return fmt.Errorf("dynamic %s target '%s' not found", targetType, name)
+	}
+	delete(dm.dynamicTargets, key)
+
+	var err error
+	switch targetType {
+	case TargetTypePostgreSQL:
+		if db, exists := dm.PostgreSQL[name]; exists {
+			if sqlDB, dbErr := db.DB(); dbErr == nil {
+				err = sqlDB.Close()
+			}
+			delete(dm.PostgreSQL, name)
+		}
+	case TargetTypeMySQL:
+		if db, exists := dm.MySQL[name]; exists {
+			if sqlDB, dbErr := db.DB(); dbErr == nil {
+				err = sqlDB.Close()
+			}
+			delete(dm.MySQL, name)
+		}
+	case TargetTypeClickHouse:
+		if conn, exists := dm.ClickHouse[name]; exists {
+			err = conn.Close()
+			delete(dm.ClickHouse, name)
+			delete(dm.clickHouseDatabases, name)
+		}
+	case TargetTypeRedis:
+		if instance, exists := dm.Redis[name]; exists {
+			err = instance.Close()
+			delete(dm.Redis, name)
+		}
+	}
+
+	log.Printf("Monitoring target removed: %s %s", targetType, name)
+	return err
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type ClickHouseHandler struct {
+	healthService    *services.ClickHouseHealthService
+	analyticsService *services.ClickHouseQueryAnalyticsService
+}
+
+func NewClickHouseHandler(healthService *services.ClickHouseHealthService, analyticsService *services.ClickHouseQueryAnalyticsService) *ClickHouseHandler {
+	return &ClickHouseHandler{
+		healthService:    healthService,
+		analyticsService: analyticsService,
+	}
+}
+
+// GetMerges 获取ClickHouse正在进行的merge
+func (h *ClickHouseHandler) GetMerges(c *gin.Context) {
+	name := c.Param("name")
+
+	merges, err := h.healthService.GetMerges(c.Request.Context(), name)
+	if err != nil {
+		respondServiceError(c, "Failed to get merges", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connection": name,
+		"merges":     merges,
+	})
+}
+
+// GetMutations 获取ClickHouse未完成的mutation
+func (h *ClickHouseHandler) GetMutations(c *gin.Context) {
+	name := c.Param("name")
+
+	mutations, err := h.healthService.GetMutations(c.Request.Context(), name)
+	if err != nil {
+		respondServiceError(c, "Failed to get mutations", err)
+		return
+	}
+
+	stuck := 0
+	for _, mutation := range mutations {
+		if mutation.Stuck {
+			stuck++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connection": name,
+		"mutations":  mutations,
+		"stuck":      stuck,
+	})
+}
+
+// GetParts 获取ClickHouse各表活跃part数量
+func (h *ClickHouseHandler) GetParts(c *gin.Context) {
+	name := c.Param("name")
+
+	parts, err := h.healthService.GetParts(c.Request.Context(), name)
+	if err != nil {
+		respondServiceError(c, "Failed to get parts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connection": name,
+		"tables":     parts,
+	})
+}
+
+// GetReplicas 获取ClickHouse复制表状态
+func (h *ClickHouseHandler) GetReplicas(c *gin.Context) {
+	name := c.Param("name")
+
+	replicas, err := h.healthService.GetReplicas(c.Request.Context(), name)
+	if err != nil {
+		respondServiceError(c, "Failed to get replicas", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connection": name,
+		"replicas":   replicas,
+	})
+}
+
+// GetDisks 获取ClickHouse磁盘空间
+func (h *ClickHouseHandler) GetDisks(c *gin.Context) {
+	name := c.Param("name")
+
+	disks, err := h.healthService.GetDisks(c.Request.Context(), name)
+	if err != nil {
+		respondServiceError(c, "Failed to get disks", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connection": name,
+		"disks":      disks,
+	})
+}
+
+// GetQueryAnalytics 获取ClickHouse查询分析（按用户、规范化查询或组织分组）
+func (h *ClickHouseHandler) GetQueryAnalytics(c *gin.Context) {
+	name := c.Param("name")
+	windowMinutes, _ := strconv.Atoi(c.DefaultQuery("window_minutes", "60"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	filter := services.QueryAnalyticsFilter{
+		GroupBy:        c.DefaultQuery("group_by", services.QueryGroupByUser),
+		Window:         time.Duration(windowMinutes) * time.Minute,
+		User:           c.Query("user"),
+		OrganizationID: c.Query("organization_id"),
+		Limit:          limit,
+	}
+
+	stats, err := h.analyticsService.GetQueryAnalytics(c.Request.Context(), name, filter)
+	if err != nil {
+		if strings.Contains(err.Error(), "unsupported group_by") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		respondServiceError(c, "Failed to get query analytics", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connection":     name,
+		"group_by":       filter.GroupBy,
+		"window_minutes": windowMinutes,
+		"groups":         stats,
+	})
+}
+
+// GetFailedQueries 获取ClickHouse按异常码分组的失败查询
+func (h *ClickHouseHandler) GetFailedQueries(c *gin.Context) {
+	name := c.Param("name")
+	windowMinutes, _ := strconv.Atoi(c.DefaultQuery("window_minutes", "60"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	stats, err := h.analyticsService.GetFailedQueries(c.Request.Context(), name, time.Duration(windowMinutes)*time.Minute, limit)
+	if err != nil {
+		respondServiceError(c, "Failed to get failed queries", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connection":     name,
+		"window_minutes": windowMinutes,
+		"failures":       stats,
+	})
+}
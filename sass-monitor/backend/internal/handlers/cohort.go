@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type CohortHandler struct {
+	cohortService *services.CohortService
+}
+
+func NewCohortHandler(cohortService *services.CohortService) *CohortHandler {
+	return &CohortHandler{
+		cohortService: cohortService,
+	}
+}
+
+// GetCohorts 获取注册月份、首次付费月份留存和流失分析；format=csv时导出，table选择导出retention（默认）、paid_retention或churn；
+// refresh=true时忽略缓存重新计算
+func (h *CohortHandler) GetCohorts(c *gin.Context) {
+	months, err := strconv.Atoi(c.DefaultQuery("months", "12"))
+	if err != nil || months <= 0 || months > services.MaxCohortMonths {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("months must be between 1 and %d", services.MaxCohortMonths),
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	table := c.DefaultQuery("table", "retention")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be json or csv",
+		})
+		return
+	}
+	if table != "retention" && table != "paid_retention" && table != "churn" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "table must be retention, paid_retention or churn",
+		})
+		return
+	}
+
+	report, err := h.cohortService.GetReport(c.Request.Context(), months, c.Query("refresh") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get cohort analysis",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	setAnalyticsCacheHeaders(c, report.GeneratedAt, report.ExpiresAt)
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	var rows [][]string
+	switch table {
+	case "retention":
+		rows = append(rows, []string{"cohort", "organizations", "month_offset", "active_organizations", "active_rate", "paying_organizations", "paying_rate", "mrr"})
+		for _, cohort := range report.Cohorts {
+			for _, retention := range cohort.Retention {
+				rows = append(rows, []string{
+					cohort.Cohort,
+					strconv.Itoa(cohort.Organizations),
+					strconv.Itoa(retention.MonthOffset),
+					strconv.Itoa(retention.ActiveOrganizations),
+					formatCSVFloat(retention.ActiveRate),
+					strconv.Itoa(retention.PayingOrganizations),
+					formatCSVFloat(retention.PayingRate),
+					formatCSVFloat(retention.MRR),
+				})
+			}
+		}
+	case "paid_retention":
+		rows = append(rows, []string{"cohort", "organizations", "starting_mrr", "month_offset", "paying_organizations", "logo_retention", "mrr", "revenue_retention"})
+		for _, cohort := range report.PaidCohorts {
+			for _, retention := range cohort.Retention {
+				rows = append(rows, []string{
+					cohort.Cohort,
+					strconv.Itoa(cohort.Organizations),
+					formatCSVFloat(cohort.StartingMRR),
+					strconv.Itoa(retention.MonthOffset),
+					strconv.Itoa(retention.PayingOrganizations),
+					formatCSVFloat(retention.LogoRetention),
+					formatCSVFloat(retention.MRR),
+					formatCSVFloat(retention.RevenueRetention),
+				})
+			}
+		}
+	default:
+		rows = append(rows, []string{"month", "starting_organizations", "churned_organizations", "logo_churn_rate", "starting_mrr", "churned_mrr", "contraction_mrr", "expansion_mrr", "revenue_churn_rate", "net_revenue_retention"})
+		for _, churn := range report.Churn {
+			rows = append(rows, []string{
+				churn.Month,
+				strconv.Itoa(churn.StartingOrganizations),
+				strconv.Itoa(churn.ChurnedOrganizations),
+				formatCSVFloat(churn.LogoChurnRate),
+				formatCSVFloat(churn.StartingMRR),
+				formatCSVFloat(churn.ChurnedMRR),
+				formatCSVFloat(churn.ContractionMRR),
+				formatCSVFloat(churn.ExpansionMRR),
+				formatCSVFloat(churn.RevenueChurnRate),
+				formatCSVFloat(churn.NetRevenueRetention),
+			})
+		}
+	}
+
+	if err := writeCSV(c, fmt.Sprintf("cohorts-%s-%s.csv", table, report.GeneratedAt.Format("20060102")), rows); err != nil {
+		// 响应头已发送，只能记录错误（客户端断开等）
+		_ = c.Error(err)
+	}
+}
+
+// setAnalyticsCacheHeaders 设置分析报表的缓存响应头，报表在服务端缓存时允许客户端缓存到同一时间
+func setAnalyticsCacheHeaders(c *gin.Context, generatedAt time.Time, expiresAt *time.Time) {
+	c.Header("Last-Modified", generatedAt.UTC().Format(http.TimeFormat))
+	if expiresAt != nil {
+		maxAge := int(time.Until(*expiresAt).Seconds())
+		if maxAge < 0 {
+			maxAge = 0
+		}
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	}
+}
+
+// writeCSV 以附件形式返回CSV，返回写入响应时的错误
+func writeCSV(c *gin.Context, filename string, rows [][]string) error {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// formatCSVFloat 导出CSV时的数值格式
+func formatCSVFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
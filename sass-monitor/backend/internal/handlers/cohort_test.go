@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failingResponseWriter 写入响应体时失败（如客户端已断开）
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingResponseWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestWriteCSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rows := [][]string{{"cohort", "organizations"}, {"2026-03", "2"}}
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	if err := writeCSV(c, "cohorts.csv", rows); err != nil {
+		t.Fatalf("writeCSV() error = %v", err)
+	}
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "cohort,organizations\n2026-03,2\n" {
+		t.Errorf("response = %d %q", recorder.Code, recorder.Body.String())
+	}
+	if got := recorder.Header().Get("Content-Disposition"); got != `attachment; filename="cohorts.csv"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	c, _ = gin.CreateTestContext(failingResponseWriter{httptest.NewRecorder()})
+	if err := writeCSV(c, "cohorts.csv", rows); err == nil {
+		t.Error("writeCSV() should return the response write error")
+	}
+}
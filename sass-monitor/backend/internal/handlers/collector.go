@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type CollectorHandler struct {
+	scheduler       *services.TaskScheduler
+	backfillService *services.BackfillService
+	jobQueue        *services.JobQueue
+}
+
+func NewCollectorHandler(scheduler *services.TaskScheduler, backfillService *services.BackfillService, jobQueue *services.JobQueue) *CollectorHandler {
+	return &CollectorHandler{
+		scheduler:       scheduler,
+		backfillService: backfillService,
+		jobQueue:        jobQueue,
+	}
+}
+
+// BackfillRequest 回填请求参数，日期格式YYYY-MM-DD，series为空时回填全部序列
+type BackfillRequest struct {
+	Series []string `json:"series"`
+	From   string   `json:"from" binding:"required"`
+	To     string   `json:"to" binding:"required"`
+}
+
+// GetCollectors 获取可手动触发的采集器和可回填的序列
+func (h *CollectorHandler) GetCollectors(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"collectors":      services.CollectorNames(),
+		"backfill_series": services.BackfillSeriesNames(),
+	})
+}
+
+// RunCollector 提交手动采集后台任务，返回202和任务信息，结果通过/jobs/:id查询
+func (h *CollectorHandler) RunCollector(c *gin.Context) {
+	name := c.Param("name")
+	if err := services.ValidateCollector(name); err != nil {
+		respondServiceError(c, "Failed to run collector", err)
+		return
+	}
+
+	job, err := h.jobQueue.Enqueue(c.Request.Context(), services.CollectorJobType, services.CollectorJobPayload{
+		Collector: name,
+	}, services.EnqueueOptions{MaxAttempts: 1, CreatedBy: currentUsername(c)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to enqueue collector job",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// Backfill 提交回填后台任务，返回202和任务信息，进度和结果通过/jobs/:id查询
+func (h *CollectorHandler) Backfill(c *gin.Context) {
+	var req BackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	from, to, err := services.ParseBackfillRange(req.From, req.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := h.backfillService.Validate(req.Series, from, to); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid backfill request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	job, err := h.jobQueue.Enqueue(c.Request.Context(), services.BackfillJobType, services.BackfillJobPayload{
+		Series: req.Series,
+		From:   req.From,
+		To:     req.To,
+	}, services.EnqueueOptions{CreatedBy: currentUsername(c)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to enqueue backfill job",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// currentUsername 当前登录用户名，未登录时返回nil
+func currentUsername(c *gin.Context) *string {
+	username := c.GetString("username")
+	if username == "" {
+		return nil
+	}
+	return &username
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,18 +13,21 @@ import (
 
 	"sass-monitor/internal/database"
 	"sass-monitor/internal/models"
+	"sass-monitor/internal/services"
 	"sass-monitor/pkg/config"
 )
 
 type DashboardHandler struct {
-	dbManager *database.DatabaseManager
-	config    *config.Config
+	dbManager      *database.DatabaseManager
+	storageService *services.OrganizationStorageService
+	config         *config.Config
 }
 
-func NewDashboardHandler(dbManager *database.DatabaseManager, cfg *config.Config) *DashboardHandler {
+func NewDashboardHandler(dbManager *database.DatabaseManager, storageService *services.OrganizationStorageService, cfg *config.Config) *DashboardHandler {
 	return &DashboardHandler{
-		dbManager: dbManager,
-		config:    cfg,
+		dbManager:      dbManager,
+		storageService: storageService,
+		config:         cfg,
 	}
 }
 
@@ -73,29 +77,32 @@ type DatabaseStatusResponse struct {
 
 // DatabaseInfo 数据库基础信息
 type DatabaseInfo struct {
-	Status         string  `json:"status"`
-	Connections    int     `json:"connections"`
-	MaxConnections int     `json:"max_connections"`
-	DatabaseSize   float64 `json:"database_size"`
-	ResponseTime   int     `json:"response_time"`
+	Status          string  `json:"status"`
+	Connections     int     `json:"connections"`
+	MaxConnections  int     `json:"max_connections"`
+	DatabaseSize    float64 `json:"database_size"`
+	ResponseTime    int     `json:"response_time"`
+	ResponseTimeP95 int     `json:"response_time_p95"`
 }
 
 // ClickHouseInfo ClickHouse信息
 type ClickHouseInfo struct {
-	Status       string  `json:"status"`
-	DatabaseSize float64 `json:"database_size"`
-	TableCount   int     `json:"table_count"`
-	RowCount     int64   `json:"row_count"`
-	ResponseTime int     `json:"response_time"`
+	Status          string  `json:"status"`
+	DatabaseSize    float64 `json:"database_size"`
+	TableCount      int     `json:"table_count"`
+	RowCount        int64   `json:"row_count"`
+	ResponseTime    int     `json:"response_time"`
+	ResponseTimeP95 int     `json:"response_time_p95"`
 }
 
 // RedisInfo Redis信息
 type RedisInfo struct {
-	Status        string  `json:"status"`
-	UsedMemory    float64 `json:"used_memory"`
-	MaxMemory     float64 `json:"max_memory"`
-	ConnectedClients int  `json:"connected_clients"`
-	ResponseTime  int     `json:"response_time"`
+	Status           string  `json:"status"`
+	UsedMemory       float64 `json:"used_memory"`
+	MaxMemory        float64 `json:"max_memory"`
+	ConnectedClients int     `json:"connected_clients"`
+	ResponseTime     int     `json:"response_time"`
+	ResponseTimeP95  int     `json:"response_time_p95"`
 }
 
 // GetOverview 获取仪表板概览
@@ -130,13 +137,18 @@ func (h *DashboardHandler) GetOverview(c *gin.Context) {
 		overview.TotalSubscriptions = totalSubs
 	}
 
-	// 检查系统健康状态
+	// 检查系统健康状态（采集器记录的状态包含warning/critical，未记录的组件使用实时检查结果）
+	var healthRecords []models.SystemHealth
+	h.dbManager.SaasMonitorDB.Find(&healthRecords)
+	for _, record := range healthRecords {
+		overview.SystemHealth[record.ComponentName] = record.Status
+	}
 	healthStatus := h.dbManager.HealthCheck()
 	for component, status := range healthStatus {
-		if status == nil {
-			overview.SystemHealth[component] = "healthy"
-		} else {
+		if status != nil {
 			overview.SystemHealth[component] = "unhealthy"
+		} else if _, recorded := overview.SystemHealth[component]; !recorded {
+			overview.SystemHealth[component] = "healthy"
 		}
 	}
 
@@ -238,6 +250,11 @@ func (h *DashboardHandler) GetOrganizations(c *gin.Context) {
 		}
 	}
 
+	// 填充存储使用情况（MB）
+	services.FillOrganizationStorage(c.Request.Context(), h.storageService, organizations, func(org *OrganizationInfo) (string, *float64) {
+		return org.ID, &org.StorageUsage
+	})
+
 	c.JSON(http.StatusOK, organizations)
 }
 
@@ -290,6 +307,7 @@ func (h *DashboardHandler) GetOrganizationMetrics(c *gin.Context) {
 		TotalSubs      int64   `json:"total_subscriptions"`
 		ActiveSubs     int64   `json:"active_subscriptions"`
 		MonthlyRevenue float64 `json:"monthly_revenue"`
+		AnnualRevenue  float64 `json:"annual_revenue"`
 	}
 
 	h.dbManager.LightAdminDB.Table("subscription_users").
@@ -300,12 +318,17 @@ func (h *DashboardHandler) GetOrganizationMetrics(c *gin.Context) {
 		Where("organization_id = ? AND status = ?", orgID, "active").
 		Count(&subStats.ActiveSubs)
 
-	// 计算月收入（这里需要根据实际的计费逻辑）
-	h.dbManager.LightAdminDB.Table("subscription_users su").
-		Joins("INNER JOIN subscription_plans sp ON su.plan_id = sp.id").
-		Where("su.organization_id = ? AND su.status = ? AND su.billing_cycle = ?", orgID, "active", "monthly").
-		Select("COALESCE(SUM(sp.pricing_monthly), 0)").
-		Scan(&subStats.MonthlyRevenue)
+	// 月收入为各计费周期归一化后的MRR（不含试用）
+	revenue, err := services.NewRevenueService(h.dbManager).GetOrganizationRevenue(c.Request.Context(), orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get organization revenue",
+			"details": err.Error(),
+		})
+		return
+	}
+	subStats.MonthlyRevenue = revenue.MRR
+	subStats.AnnualRevenue = revenue.ARR
 
 	// 获取工作空间统计
 	var workspaceCount int64
@@ -315,14 +338,25 @@ func (h *DashboardHandler) GetOrganizationMetrics(c *gin.Context) {
 
 	// 获取资源使用情况
 	var resourceUsage struct {
-		StorageUsage float64 `json:"storage_usage_mb"`
-		QueryCount   int64   `json:"query_count_today"`
+		StorageUsage float64                              `json:"storage_usage_mb"`
+		QueryCount   int64                                `json:"query_count_today"`
+		Databases    []services.OrganizationDatabaseUsage `json:"databases"`
 	}
 
-	// 这里需要实现实际的资源统计逻辑
-	// 目前使用示例数据
-	resourceUsage.StorageUsage = 512.5
-	resourceUsage.QueryCount = 1250
+	// 来自组织存储统计（各数据库最近一次统计之和）
+	usage, err := h.storageService.GetUsage(c.Request.Context(), orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get organization resource usage",
+			"details": err.Error(),
+		})
+		return
+	}
+	if orgUsage, exists := usage[orgID]; exists {
+		resourceUsage.StorageUsage = orgUsage.StorageMB
+		resourceUsage.QueryCount = orgUsage.QueryCountToday
+		resourceUsage.Databases = orgUsage.Databases
+	}
 
 	metrics := gin.H{
 		"organization": org,
@@ -335,27 +369,51 @@ func (h *DashboardHandler) GetOrganizationMetrics(c *gin.Context) {
 	c.JSON(http.StatusOK, metrics)
 }
 
-// GetDatabaseStatus 获取数据库状态
+// GetDatabaseStatus 获取数据库状态（状态和响应时间来自采集器最近一次健康检查）
 func (h *DashboardHandler) GetDatabaseStatus(c *gin.Context) {
 	response := DatabaseStatusResponse{
 		ClickHouse: make(map[string]ClickHouseInfo),
 	}
 
+	var healthRecords []models.SystemHealth
+	h.dbManager.SaasMonitorDB.Find(&healthRecords)
+	health := make(map[string]models.SystemHealth, len(healthRecords))
+	for _, record := range healthRecords {
+		health[record.ComponentName] = record
+	}
+	// componentHealth 获取组件的状态、响应时间和p95，尚未检查过的组件状态为unknown
+	componentHealth := func(component string) (string, int, int) {
+		record, exists := health[component]
+		if !exists {
+			return "unknown", 0, 0
+		}
+		responseTime, p95 := 0, 0
+		if record.ResponseTime != nil {
+			responseTime = *record.ResponseTime
+		}
+		if record.ResponseTimeP95 != nil {
+			p95 = *record.ResponseTimeP95
+		}
+		return record.Status, responseTime, p95
+	}
+
 	// PostgreSQL状态
 	if sqlDB, err := h.dbManager.LightAdminDB.DB(); err == nil {
 		stats := sqlDB.Stats()
+		status, responseTime, p95 := componentHealth("light_admin")
 		response.PostgreSQL = DatabaseInfo{
-			Status:         "healthy",
-			Connections:    stats.OpenConnections,
-			MaxConnections: h.dbManager.Config.Databases.LightAdmin.MaxOpenConns,
-			ResponseTime:   10, // 示例响应时间
+			Status:          status,
+			Connections:     stats.OpenConnections,
+			MaxConnections:  h.dbManager.Config.Databases.LightAdmin.MaxOpenConns,
+			ResponseTime:    responseTime,
+			ResponseTimeP95: p95,
 		}
 
-		// 获取数据库大小
+		// 获取数据库大小（MB）
 		var dbSize float64
 		h.dbManager.LightAdminDB.Raw(`
-			SELECT pg_size_pretty(pg_database_size(?)) as size
-		`, "light_admin").Scan(&dbSize)
+			SELECT pg_database_size(current_database()) / 1024.0 / 1024.0 as size
+		`).Scan(&dbSize)
 		response.PostgreSQL.DatabaseSize = dbSize
 	} else {
 		response.PostgreSQL = DatabaseInfo{
@@ -364,9 +422,12 @@ func (h *DashboardHandler) GetDatabaseStatus(c *gin.Context) {
 	}
 
 	// ClickHouse状态
-	for name, conn := range h.dbManager.ClickHouse {
+	for name, conn := range h.dbManager.GetClickHouseConnections() {
+		status, responseTime, p95 := componentHealth(fmt.Sprintf("clickhouse_%s", name))
 		info := ClickHouseInfo{
-			Status: "healthy",
+			Status:          status,
+			ResponseTime:    responseTime,
+			ResponseTimeP95: p95,
 		}
 
 		// 获取数据库大小和表数量
@@ -394,36 +455,35 @@ func (h *DashboardHandler) GetDatabaseStatus(c *gin.Context) {
 			rows.Close()
 		}
 
-		info.ResponseTime = 15 // 示例响应时间
 		response.ClickHouse[name] = info
 	}
 
 	// Redis状态
 	if h.dbManager.RedisClient != nil {
-		info := h.dbManager.RedisClient.Info(c.Request.Context())
-		if info.Err() == nil {
+		status, responseTime, p95 := componentHealth("redis")
+		if infoText, err := h.dbManager.RedisClient.Info(c.Request.Context(), "clients", "memory").Result(); err == nil {
 			response.Redis = RedisInfo{
-				Status:          "healthy",
-				ConnectedClients: 5, // 示例数据
-				ResponseTime:    2,  // 示例响应时间
+				Status:          status,
+				ResponseTime:    responseTime,
+				ResponseTimeP95: p95,
 			}
 
-			// 获取内存使用情况
-			if memInfo, err := h.dbManager.RedisClient.Info(c.Request.Context(), "memory").Result(); err == nil {
-				// 解析内存使用信息
-				if strings.Contains(memInfo, "used_memory:") {
-					// 简单解析used_memory字段
-					lines := strings.Split(memInfo, "\r\n")
-					for _, line := range lines {
-						if strings.HasPrefix(line, "used_memory:") {
-							parts := strings.Split(line, ":")
-							if len(parts) == 2 {
-								if usedMem, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err == nil {
-									response.Redis.UsedMemory = usedMem / (1024 * 1024) // MB
-								}
-							}
-							break
-						}
+			// 解析连接数和内存使用
+			for _, line := range strings.Split(infoText, "\r\n") {
+				key, value, found := strings.Cut(line, ":")
+				if !found {
+					continue
+				}
+				switch key {
+				case "connected_clients":
+					response.Redis.ConnectedClients, _ = strconv.Atoi(strings.TrimSpace(value))
+				case "used_memory":
+					if usedMem, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+						response.Redis.UsedMemory = usedMem / (1024 * 1024) // MB
+					}
+				case "maxmemory":
+					if maxMem, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+						response.Redis.MaxMemory = maxMem / (1024 * 1024) // MB
 					}
 				}
 			}
@@ -435,4 +495,4 @@ func (h *DashboardHandler) GetDatabaseStatus(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
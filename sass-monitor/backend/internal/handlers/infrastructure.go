@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type InfrastructureHandler struct {
+	infrastructureService *services.InfrastructureService
+}
+
+func NewInfrastructureHandler(infrastructureService *services.InfrastructureService) *InfrastructureHandler {
+	return &InfrastructureHandler{
+		infrastructureService: infrastructureService,
+	}
+}
+
+// GetOrganizationInfrastructure 获取组织的主机、容器和进程指标
+func (h *InfrastructureHandler) GetOrganizationInfrastructure(c *gin.Context) {
+	organizationID := c.Param("id")
+
+	window, err := time.ParseDuration(c.DefaultQuery("window", "1h"))
+	if err != nil || window <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid window, expected a duration such as 15m or 1h",
+		})
+		return
+	}
+
+	infrastructure, err := h.infrastructureService.GetOrganizationInfrastructure(c.Request.Context(), organizationID, c.Query("workspace_id"), window)
+	if err != nil {
+		respondServiceError(c, "Failed to get organization infrastructure", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, infrastructure)
+}
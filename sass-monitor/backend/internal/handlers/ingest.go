@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+	"sass-monitor/pkg/config"
+)
+
+type IngestHandler struct {
+	ingestService *services.IngestService
+	writer        *services.MetricWriter
+	config        *config.Config
+}
+
+func NewIngestHandler(ingestService *services.IngestService, writer *services.MetricWriter, cfg *config.Config) *IngestHandler {
+	return &IngestHandler{
+		ingestService: ingestService,
+		writer:        writer,
+		config:        cfg,
+	}
+}
+
+// IngestMetrics 批量写入数据点：JSON lines（application/x-ndjson）或Influx line protocol（text/plain），
+// 可通过format参数显式指定；来源由令牌绑定，用于限流和作为database_name，source参数只能与之一致
+func (h *IngestHandler) IngestMetrics(c *gin.Context) {
+	source := c.GetString("ingest_source")
+	if requested := c.Query("source"); requested != "" && requested != source {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("The ingest token is bound to source '%s' and cannot write to '%s'", source, requested),
+		})
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		switch contentType {
+		case "application/x-ndjson", "application/jsonl", "application/json":
+			format = services.IngestFormatJSONLines
+		case "text/plain":
+			format = services.IngestFormatLineProtocol
+		default:
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{
+				"error": "Content-Type must be application/x-ndjson or text/plain, or set the format parameter to jsonl or influx",
+			})
+			return
+		}
+	}
+
+	body, err := readIngestBody(c, h.config.Monitoring.Ingest.MaxBodyBytes)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == errBodyTooLarge {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.ingestService.Ingest(c.Request.Context(), source, format, c.Query("precision"), body)
+	if err != nil {
+		status := http.StatusBadRequest
+		var rateLimitErr *services.IngestRateLimitError
+		var batchTooLargeErr *services.IngestBatchTooLargeError
+		switch {
+		case errors.As(err, &batchTooLargeErr):
+			status = http.StatusRequestEntityTooLarge
+		case errors.As(err, &rateLimitErr):
+			status = http.StatusTooManyRequests
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+		case errors.Is(err, services.ErrMetricBufferFull):
+			status = http.StatusServiceUnavailable
+			c.Header("Retry-After", "1")
+		}
+		response := gin.H{
+			"error": err.Error(),
+		}
+		if result != nil {
+			response["result"] = result
+		}
+		c.JSON(status, response)
+		return
+	}
+
+	status := http.StatusAccepted
+	if result.Accepted == 0 && result.Rejected > 0 {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, result)
+}
+
+// GetIngestStats 获取缓冲写入器统计
+func (h *IngestHandler) GetIngestStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.writer.Stats())
+}
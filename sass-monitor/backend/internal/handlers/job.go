@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"sass-monitor/internal/services"
+)
+
+type JobHandler struct {
+	jobQueue *services.JobQueue
+}
+
+func NewJobHandler(jobQueue *services.JobQueue) *JobHandler {
+	return &JobHandler{
+		jobQueue: jobQueue,
+	}
+}
+
+// GetJobs 获取后台任务列表，支持按type和status过滤
+func (h *JobHandler) GetJobs(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	jobs, total, err := h.jobQueue.List(c.Request.Context(), services.JobListFilter{
+		Type:     c.Query("type"),
+		Status:   c.Query("status"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		respondServiceError(c, "Failed to get jobs", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":      jobs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"types":     h.jobQueue.JobTypes(),
+	})
+}
+
+// GetJob 获取后台任务状态、进度和结果
+func (h *JobHandler) GetJob(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid job ID",
+		})
+		return
+	}
+
+	job, err := h.jobQueue.Get(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, "Failed to get job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJob 取消后台任务，执行中的任务返回时状态仍为running，由worker中断后置为cancelled
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid job ID",
+		})
+		return
+	}
+
+	job, err := h.jobQueue.Cancel(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrJobFinished) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		respondServiceError(c, "Failed to cancel job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,18 +17,23 @@ import (
 
 	"sass-monitor/internal/database"
 	"sass-monitor/internal/models"
+	"sass-monitor/internal/services"
 	"sass-monitor/pkg/config"
 )
 
 type MonitoringHandler struct {
-	dbManager *database.DatabaseManager
-	config    *config.Config
+	dbManager       *database.DatabaseManager
+	config          *config.Config
+	activityService *services.PostgreSQLActivityService
+	scheduler       *services.TaskScheduler
 }
 
-func NewMonitoringHandler(dbManager *database.DatabaseManager, cfg *config.Config) *MonitoringHandler {
+func NewMonitoringHandler(dbManager *database.DatabaseManager, cfg *config.Config, scheduler *services.TaskScheduler, activityService *services.PostgreSQLActivityService) *MonitoringHandler {
 	return &MonitoringHandler{
-		dbManager: dbManager,
-		config:    cfg,
+		dbManager:       dbManager,
+		config:          cfg,
+		activityService: activityService,
+		scheduler:       scheduler,
 	}
 }
 
@@ -65,6 +75,15 @@ func (h *MonitoringHandler) GetMetrics(c *gin.Context) {
 		return
 	}
 
+	tagFilters, groupByTag, err := parseTagQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid tag parameters",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	offset := (req.Page - 1) * req.PageSize
 
 	query := h.dbManager.SaasMonitorDB.Model(&models.ResourceMetric{})
@@ -87,6 +106,43 @@ func (h *MonitoringHandler) GetMetrics(c *gin.Context) {
 	if req.EndTime != nil {
 		query = query.Where("collected_at <= ?", *req.EndTime)
 	}
+	query = applyTagFilters(query, tagFilters)
+
+	// 按标签分组时返回每组每个指标的聚合值
+	if groupByTag != "" {
+		groupExpr := tagExpression(groupByTag)
+		// 新会话使计数和分组查询各自复用过滤条件，互不影响
+		query = query.Session(&gorm.Session{})
+		var groups []metricTagGroup
+		var total int64
+		h.dbManager.SaasMonitorDB.Table("(?) AS grouped", query.
+			Select(groupExpr+" AS tag_value, metric_name").
+			Group(groupExpr+", metric_name")).Count(&total)
+		if err := query.Select(groupExpr + ` AS tag_value, metric_name, MAX(unit) AS unit,
+				(ARRAY_AGG(metric_value ORDER BY collected_at DESC))[1] AS latest_value,
+				AVG(metric_value) AS avg_value, MIN(metric_value) AS min_value, MAX(metric_value) AS max_value,
+				COUNT(*) AS sample_count, MAX(collected_at) AS last_collected_at`).
+			Group(groupExpr + ", metric_name").
+			Order("tag_value, metric_name").
+			Offset(offset).
+			Limit(req.PageSize).
+			Scan(&groups).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to group metrics by tag",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"group_by":  "tag." + groupByTag,
+			"groups":    groups,
+			"total":     total,
+			"page":      req.Page,
+			"page_size": req.PageSize,
+		})
+		return
+	}
 
 	var metrics []models.ResourceMetric
 	var total int64
@@ -119,28 +175,41 @@ func (h *MonitoringHandler) GetMetricsHistory(c *gin.Context) {
 		hours = 24
 	}
 
+	tagFilters, groupByTag, err := parseTagQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid tag parameters",
+			"details": err.Error(),
+		})
+		return
+	}
+	tagQuery := len(tagFilters) > 0 || groupByTag != ""
+
 	endTime := time.Now()
 	startTime := endTime.Add(-time.Duration(hours) * time.Hour)
 
 	// 构建查询条件
 	var results []gin.H
 
-	switch databaseType {
-	case "postgresql":
+	switch {
+	case databaseType == "postgresql" && !tagQuery:
 		// PostgreSQL指标历史
 		results = h.getPostgreSQLMetrics(databaseName, metricType, startTime, endTime)
-	case "clickhouse":
+	case databaseType == "clickhouse" && !tagQuery:
 		// ClickHouse指标历史
 		results = h.getClickHouseMetrics(databaseName, metricType, startTime, endTime)
-	case "redis":
+	case databaseType == "redis" && !tagQuery:
 		// Redis指标历史
 		results = h.getRedisMetrics(metricType, startTime, endTime)
 	default:
-		// 从监控数据库获取历史数据
+		// 从监控数据库获取历史数据（带标签过滤或分组时所有类型都走这里）
 		var metrics []models.ResourceMetric
 		query := h.dbManager.SaasMonitorDB.Model(&models.ResourceMetric{}).
-			Where("database_type = ? AND collected_at BETWEEN ? AND ?",
-				databaseType, startTime, endTime)
+			Where("collected_at BETWEEN ? AND ?", startTime, endTime)
+
+		if databaseType != "" || !tagQuery {
+			query = query.Where("database_type = ?", databaseType)
+		}
 
 		if databaseName != "" {
 			query = query.Where("database_name = ?", databaseName)
@@ -151,16 +220,22 @@ func (h *MonitoringHandler) GetMetricsHistory(c *gin.Context) {
 		if orgID != "" {
 			query = query.Where("organization_id = ?", orgID)
 		}
+		query = applyTagFilters(query, tagFilters)
 
 		query.Order("collected_at ASC").Find(&metrics)
 
 		for _, metric := range metrics {
-			results = append(results, gin.H{
+			point := gin.H{
 				"timestamp": metric.CollectedAt.Unix(),
 				"value":     metric.MetricValue,
 				"metric_name": metric.MetricName,
 				"unit":      metric.Unit,
-			})
+			}
+			// 分组时每个数据点带上分组标签值，前端据此拆分为多条曲线
+			if groupByTag != "" {
+				point["group"] = metricTagValue(metric.Tags, groupByTag)
+			}
+			results = append(results, point)
 		}
 	}
 
@@ -170,10 +245,107 @@ func (h *MonitoringHandler) GetMetricsHistory(c *gin.Context) {
 		"metric_type":   metricType,
 		"start_time":    startTime.Unix(),
 		"end_time":      endTime.Unix(),
+		"group_by":      groupByLabel(groupByTag),
 		"data":          results,
 	})
 }
 
+// tagKeyPattern 标签键允许的字符，键会拼入SQL表达式，因此严格限制
+var tagKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.:/-]{1,100}$`)
+
+// metricTagGroup 按标签分组的指标聚合结果
+type metricTagGroup struct {
+	TagValue        *string   `json:"tag_value"`
+	MetricName      string    `json:"metric_name"`
+	Unit            string    `json:"unit"`
+	LatestValue     float64   `json:"latest_value"`
+	AvgValue        float64   `json:"avg_value"`
+	MinValue        float64   `json:"min_value"`
+	MaxValue        float64   `json:"max_value"`
+	SampleCount     int64     `json:"sample_count"`
+	LastCollectedAt time.Time `json:"last_collected_at"`
+}
+
+// parseTagQuery 解析tag.<key>=<value>过滤参数和group_by=tag.<key>分组参数
+func parseTagQuery(c *gin.Context) (map[string]string, string, error) {
+	filters := make(map[string]string)
+	for param, values := range c.Request.URL.Query() {
+		if !strings.HasPrefix(param, "tag.") || len(values) == 0 {
+			continue
+		}
+		key := strings.TrimPrefix(param, "tag.")
+		if !tagKeyPattern.MatchString(key) {
+			return nil, "", fmt.Errorf("invalid tag key '%s'", key)
+		}
+		filters[key] = values[0]
+	}
+
+	groupBy := c.Query("group_by")
+	if groupBy == "" {
+		return filters, "", nil
+	}
+	key := strings.TrimPrefix(groupBy, "tag.")
+	if key == groupBy || !tagKeyPattern.MatchString(key) {
+		return nil, "", fmt.Errorf("group_by must be tag.<key>, got '%s'", groupBy)
+	}
+	return filters, key, nil
+}
+
+// applyTagFilters 按标签过滤，使用tags @>包含查询以利用GIN索引idx_resource_metrics_tags
+func applyTagFilters(query *gorm.DB, filters map[string]string) *gorm.DB {
+	for key, value := range filters {
+		documents := tagFilterDocuments(key, value)
+		conditions := make([]string, len(documents))
+		args := make([]interface{}, len(documents))
+		for i, document := range documents {
+			conditions[i] = "tags @> ?::jsonb"
+			args[i] = document
+		}
+		query = query.Where(strings.Join(conditions, " OR "), args...)
+	}
+	return query
+}
+
+// tagFilterDocuments 标签过滤的包含文档。标签按原类型存储，过滤值可解析为数字或布尔时同时匹配该类型的标签
+func tagFilterDocuments(key, value string) []string {
+	candidates := []interface{}{value}
+	var typed interface{}
+	if err := json.Unmarshal([]byte(value), &typed); err == nil {
+		switch typed.(type) {
+		case float64, bool:
+			candidates = append(candidates, json.RawMessage(value))
+		}
+	}
+
+	documents := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		document, err := json.Marshal(map[string]interface{}{key: candidate})
+		if err != nil {
+			continue
+		}
+		documents = append(documents, string(document))
+	}
+	return documents
+}
+
+// tagExpression 标签取值的SQL表达式，key须已通过tagKeyPattern校验
+func tagExpression(key string) string {
+	return "tags->>'" + key + "'"
+}
+
+// metricTagValue 从标签JSON中取出指定键的值，与tags->>key一致
+func metricTagValue(tags, key string) string {
+	return services.ParseMetricTags(tags)[key]
+}
+
+// groupByLabel 响应中的group_by字段，未分组时为空
+func groupByLabel(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "tag." + key
+}
+
 // GetOrganizations 获取组织列表（用于监控筛选）
 func (h *MonitoringHandler) GetOrganizations(c *gin.Context) {
 	var organizations []models.AuthOrganization
@@ -277,16 +449,19 @@ func (h *MonitoringHandler) GetDatabaseInfo(c *gin.Context) {
 
 	switch dbType {
 	case "postgresql":
-		response = h.getPostgreSQLInfo()
+		response = h.getPostgreSQLInfo(c.Request.Context())
 	case "clickhouse":
 		response = h.getClickHouseInfo()
 	case "redis":
 		response = h.getRedisInfo()
+	case "mysql":
+		response = h.getMySQLInfo()
 	default:
 		response = gin.H{
-			"postgresql": h.getPostgreSQLInfo(),
+			"postgresql": h.getPostgreSQLInfo(c.Request.Context()),
 			"clickhouse": h.getClickHouseInfo(),
 			"redis":      h.getRedisInfo(),
+			"mysql":      h.getMySQLInfo(),
 		}
 	}
 
@@ -476,6 +651,46 @@ func (h *MonitoringHandler) GetSystemHealth(c *gin.Context) {
 	})
 }
 
+// GetSystemHealthHistory 获取组件健康状态变化记录
+func (h *MonitoringHandler) GetSystemHealthHistory(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	query := h.dbManager.SaasMonitorDB.Model(&models.SystemHealthHistory{})
+	if component := c.Query("component"); component != "" {
+		query = query.Where("component_name = ?", component)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if since := c.Query("since"); since != "" {
+		sinceTime, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid since, expected RFC3339 time",
+			})
+			return
+		}
+		query = query.Where("changed_at >= ?", sinceTime)
+	}
+
+	var history []models.SystemHealthHistory
+	if err := query.Order("changed_at DESC").Limit(limit).Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get system health history",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history": history,
+		"total":   len(history),
+	})
+}
+
 // GetSystemLogs 获取系统日志
 func (h *MonitoringHandler) GetSystemLogs(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -538,6 +753,20 @@ func (h *MonitoringHandler) UpdateSystemConfigs(c *gin.Context) {
 		return
 	}
 
+	// collect_interval会应用到数据采集任务的调度，先校验
+	collectInterval := 0
+	if value, ok := req["collect_interval"]; ok {
+		minutes, err := strconv.Atoi(fmt.Sprintf("%v", value))
+		if err != nil || minutes <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "collect_interval must be a positive number of minutes",
+				"config_key": "collect_interval",
+			})
+			return
+		}
+		collectInterval = minutes
+	}
+
 	// 使用事务确保所有配置更新成功
 	tx := h.dbManager.SaasMonitorDB.Begin()
 	defer func() {
@@ -596,6 +825,17 @@ func (h *MonitoringHandler) UpdateSystemConfigs(c *gin.Context) {
 		return
 	}
 
+	// 无需重启，立即按新的采集间隔调度
+	if collectInterval > 0 {
+		if err := h.scheduler.ApplyCollectInterval(collectInterval); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Configurations saved but failed to apply collect_interval",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "System configurations updated successfully",
 	})
@@ -617,20 +857,62 @@ func (h *MonitoringHandler) getRedisMetrics(metricType string, start, end time.T
 	return []gin.H{}
 }
 
-func (h *MonitoringHandler) getPostgreSQLInfo() gin.H {
-	// 返回PostgreSQL详细信息
+func (h *MonitoringHandler) getPostgreSQLInfo(ctx context.Context) gin.H {
+	// 返回PostgreSQL详细信息（实时健康检查和会话活动汇总）
+	healthStatus := h.dbManager.HealthCheck()
+
+	status := "healthy"
+	databases := []string{"light_admin", "saas_monitor"}
+	healthKeys := map[string]string{"light_admin": "light_admin", "saas_monitor": "saas_monitor"}
+	// 额外的PostgreSQL监控目标（如备库及通过API添加的目标）
+	var additional []string
+	for name := range h.dbManager.GetPostgreSQLTargets() {
+		if name != "light_admin" {
+			additional = append(additional, name)
+		}
+	}
+	sort.Strings(additional)
+	for _, name := range additional {
+		databases = append(databases, name)
+		healthKeys[name] = "postgresql_" + name
+	}
+
+	details := gin.H{}
+	for _, name := range databases {
+		dbInfo := gin.H{"status": "healthy"}
+		if err, exists := healthStatus[healthKeys[name]]; !exists || err != nil {
+			dbInfo["status"] = "unhealthy"
+			if err != nil {
+				dbInfo["error"] = err.Error()
+			}
+			status = "unhealthy"
+		} else if summary, err := h.activityService.GetActivitySummary(ctx, name); err == nil {
+			// 存在阻塞或超过5分钟的长事务时标记为warning
+			dbInfo["activity"] = summary
+			if summary.BlockedSessions > 0 || summary.OldestTransactionSeconds > 300 {
+				dbInfo["status"] = "warning"
+				if status == "healthy" {
+					status = "warning"
+				}
+			}
+		}
+		details[name] = dbInfo
+	}
+
 	return gin.H{
-		"status": "healthy",
-		"databases": []string{"light_admin", "saas_monitor"},
+		"status":    status,
+		"databases": databases,
+		"details":   details,
 	}
 }
 
 func (h *MonitoringHandler) getClickHouseInfo() gin.H {
 	// 返回ClickHouse详细信息
 	databases := []string{}
-	for _, chConfig := range h.config.ClickHouse {
-		databases = append(databases, chConfig.Database)
+	for name := range h.dbManager.GetClickHouseConnections() {
+		databases = append(databases, h.dbManager.GetClickHouseDatabase(name))
 	}
+	sort.Strings(databases)
 
 	return gin.H{
 		"status": "healthy",
@@ -638,11 +920,43 @@ func (h *MonitoringHandler) getClickHouseInfo() gin.H {
 	}
 }
 
+func (h *MonitoringHandler) getMySQLInfo() gin.H {
+	// 返回MySQL监控目标及健康状态
+	healthStatus := h.dbManager.HealthCheck()
+
+	status := "healthy"
+	databases := []string{}
+	details := gin.H{}
+	for name := range h.dbManager.GetMySQLTargets() {
+		databases = append(databases, name)
+		dbInfo := gin.H{"status": "healthy"}
+		if err := healthStatus["mysql_"+name]; err != nil {
+			dbInfo["status"] = "unhealthy"
+			dbInfo["error"] = err.Error()
+			status = "unhealthy"
+		}
+		details[name] = dbInfo
+	}
+	sort.Strings(databases)
+
+	return gin.H{
+		"status":    status,
+		"databases": databases,
+		"details":   details,
+	}
+}
+
 func (h *MonitoringHandler) getRedisInfo() gin.H {
 	// 返回Redis详细信息
+	instances := gin.H{}
+	for name, instance := range h.dbManager.GetRedisInstances() {
+		instances[name] = gin.H{"mode": instance.Mode(), "database": instance.Config.Database}
+	}
+
 	return gin.H{
 		"status": "healthy",
 		"database": h.config.Redis.Database,
+		"instances": instances,
 	}
 }
 
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"sass-monitor/internal/services"
+)
+
+type MonitoringTargetHandler struct {
+	targetService *services.MonitoringTargetService
+}
+
+func NewMonitoringTargetHandler(targetService *services.MonitoringTargetService) *MonitoringTargetHandler {
+	return &MonitoringTargetHandler{
+		targetService: targetService,
+	}
+}
+
+// GetTargets 获取动态监控目标列表
+func (h *MonitoringTargetHandler) GetTargets(c *gin.Context) {
+	targets, err := h.targetService.ListTargets(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, "Failed to get monitoring targets", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"targets": targets,
+		"total":   len(targets),
+	})
+}
+
+// GetTarget 获取单个监控目标
+func (h *MonitoringTargetHandler) GetTarget(c *gin.Context) {
+	target, err := h.targetService.GetTarget(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondTargetError(c, "Failed to get monitoring target", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// CreateTarget 创建监控目标（保存前测试连接）
+func (h *MonitoringTargetHandler) CreateTarget(c *gin.Context) {
+	var req services.MonitoringTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	target, err := h.targetService.CreateTarget(c.Request.Context(), &req, userUUID)
+	if err != nil {
+		respondTargetError(c, "Failed to create monitoring target", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, target)
+}
+
+// UpdateTarget 更新监控目标（保存前测试连接）
+func (h *MonitoringTargetHandler) UpdateTarget(c *gin.Context) {
+	var req services.MonitoringTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	target, err := h.targetService.UpdateTarget(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		respondTargetError(c, "Failed to update monitoring target", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// DeleteTarget 删除监控目标
+func (h *MonitoringTargetHandler) DeleteTarget(c *gin.Context) {
+	if err := h.targetService.DeleteTarget(c.Request.Context(), c.Param("id")); err != nil {
+		respondTargetError(c, "Failed to delete monitoring target", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Monitoring target deleted successfully",
+	})
+}
+
+// TestTarget 测试已保存目标的连接
+func (h *MonitoringTargetHandler) TestTarget(c *gin.Context) {
+	target, err := h.targetService.TestTarget(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondTargetError(c, "Failed to test monitoring target", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// respondTargetError 将监控目标服务错误映射为HTTP状态码
+func respondTargetError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrTargetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTargetExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTarget), errors.Is(err, services.ErrTargetConnection):
+		c.JSON(http.StatusBadRequest, gin.H{"error": message + ": " + err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message + ": " + err.Error()})
+	}
+}
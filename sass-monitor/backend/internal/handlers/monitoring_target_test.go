@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+func TestRespondTargetError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "not found", err: services.ErrTargetNotFound, want: http.StatusNotFound},
+		{name: "duplicate name", err: fmt.Errorf("%w: redis target 'cache'", services.ErrTargetExists), want: http.StatusConflict},
+		{name: "invalid request", err: fmt.Errorf("%w: unsupported target type 'kafka'", services.ErrInvalidTarget), want: http.StatusBadRequest},
+		{name: "connection test", err: fmt.Errorf("%w: dial tcp: connection refused", services.ErrTargetConnection), want: http.StatusBadRequest},
+		// 错误信息中包含not found但不是目标不存在，例如数据库驱动错误
+		{name: "unrelated not found", err: errors.New("failed to save monitoring target: relation \"monitoring_targets\" not found"), want: http.StatusInternalServerError},
+		{name: "credential key missing", err: errors.New("server.credential_key must be configured"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			respondTargetError(c, "Failed to update monitoring target", tt.err)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/models"
+)
+
+// dryRunDB 只生成SQL不连接数据库的gorm实例
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestTagFilterDocuments(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		want  []string
+	}{
+		{name: "string value", key: "table_name", value: "users", want: []string{`{"table_name":"users"}`}},
+		{name: "numeric value also matches numbers", key: "port", value: "5432", want: []string{`{"port":"5432"}`, `{"port":5432}`}},
+		{name: "decimal value", key: "ratio", value: "0.5", want: []string{`{"ratio":"0.5"}`, `{"ratio":0.5}`}},
+		{name: "boolean value also matches booleans", key: "active", value: "true", want: []string{`{"active":"true"}`, `{"active":true}`}},
+		{name: "quotes are escaped", key: "query", value: `say "hi"`, want: []string{`{"query":"say \"hi\""}`}},
+		{name: "null is matched as text only", key: "replica", value: "null", want: []string{`{"replica":"null"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tagFilterDocuments(tt.key, tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tagFilterDocuments(%q, %q) = %v, want %v", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTagFiltersUsesContainment(t *testing.T) {
+	db := dryRunDB(t)
+	statement := applyTagFilters(db.Model(&models.ResourceMetric{}).Where("metric_type = ?", "table"),
+		map[string]string{"port": "5432"}).Find(&[]models.ResourceMetric{}).Statement
+
+	wantSQL := `SELECT * FROM "resource_metrics" WHERE metric_type = $1 AND (tags @> $2::jsonb OR tags @> $3::jsonb)`
+	if got := statement.SQL.String(); got != wantSQL {
+		t.Errorf("SQL = %s\nwant %s", got, wantSQL)
+	}
+	wantVars := []interface{}{"table", `{"port":"5432"}`, `{"port":5432}`}
+	if !reflect.DeepEqual(statement.Vars, wantVars) {
+		t.Errorf("vars = %v, want %v", statement.Vars, wantVars)
+	}
+}
+
+func TestMetricTagValue(t *testing.T) {
+	tags := `{"table_name":"users","port":5432,"active":true,"replica":null}`
+	tests := map[string]string{
+		"table_name": "users",
+		"port":       "5432",
+		"active":     "true",
+		"replica":    "",
+		"missing":    "",
+	}
+	for key, want := range tests {
+		if got := metricTagValue(tags, key); got != want {
+			t.Errorf("metricTagValue(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type MySQLHandler struct {
+	monitorService *services.MySQLMonitorService
+}
+
+func NewMySQLHandler(monitorService *services.MySQLMonitorService) *MySQLHandler {
+	return &MySQLHandler{
+		monitorService: monitorService,
+	}
+}
+
+// GetStatus 获取MySQL实例状态（全局状态、InnoDB缓冲池、复制）
+func (h *MySQLHandler) GetStatus(c *gin.Context) {
+	name := c.Param("name")
+
+	status, err := h.monitorService.GetStatus(c.Request.Context(), name)
+	if err != nil {
+		respondServiceError(c, "Failed to get MySQL status", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetDigests 获取performance_schema语句摘要统计
+func (h *MySQLHandler) GetDigests(c *gin.Context) {
+	name := c.Param("name")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	digests, err := h.monitorService.GetDigests(c.Request.Context(), name, c.Query("schema"), c.DefaultQuery("order_by", "total_latency"), limit)
+	if err != nil {
+		respondServiceError(c, "Failed to get MySQL statement digests", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"database": name,
+		"digests":  digests,
+		"total":    len(digests),
+	})
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -29,7 +31,7 @@ func (h *OrganizationHandler) GetOrganizations(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 	search := c.Query("search")
 
-	result, err := h.orgService.GetOrganizations(page, pageSize, search)
+	result, err := h.orgService.GetOrganizations(c.Request.Context(), page, pageSize, search)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get organizations: " + err.Error(),
@@ -44,7 +46,7 @@ func (h *OrganizationHandler) GetOrganizations(c *gin.Context) {
 func (h *OrganizationHandler) GetOrganizationByID(c *gin.Context) {
 	orgID := c.Param("id")
 
-	org, err := h.orgService.GetOrganizationByID(orgID)
+	org, err := h.orgService.GetOrganizationByID(c.Request.Context(), orgID)
 	if err != nil {
 		if err.Error() == "invalid organization ID format" {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -222,6 +224,66 @@ func (h *OrganizationHandler) GetOrganizationSubscriptions(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// GetOrganizationSubscriptionTimeline 获取组织的订阅生命周期时间线（升降级、续费、取消、支付和计费事件），
+// 支持from/to（YYYY-MM-DD）和source（逗号分隔）过滤
+func (h *OrganizationHandler) GetOrganizationSubscriptionTimeline(c *gin.Context) {
+	orgID := c.Param("id")
+
+	// 验证UUID格式
+	if _, err := uuid.Parse(orgID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid organization ID format",
+		})
+		return
+	}
+
+	var filter services.SubscriptionTimelineFilter
+	if from := c.Query("from"); from != "" {
+		start, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid from date, expected YYYY-MM-DD",
+			})
+			return
+		}
+		filter.From = &start
+	}
+	if to := c.Query("to"); to != "" {
+		end, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid to date, expected YYYY-MM-DD",
+			})
+			return
+		}
+		// 包含结束日期当天
+		end = end.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		filter.To = &end
+	}
+	if source := c.Query("source"); source != "" {
+		for _, item := range strings.Split(source, ",") {
+			switch item = strings.TrimSpace(item); item {
+			case services.TimelineSourceSubscription, services.TimelineSourcePayment,
+				services.TimelineSourceBilling, services.TimelineSourceWebhook:
+				filter.Sources = append(filter.Sources, item)
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Invalid source '" + item + "'",
+				})
+				return
+			}
+		}
+	}
+
+	timeline, err := h.orgService.GetSubscriptionTimeline(c.Request.Context(), orgID, filter)
+	if err != nil {
+		respondServiceError(c, "Failed to get organization subscription timeline", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
 // GetOrganizationMetrics 获取组织指标统计
 func (h *OrganizationHandler) GetOrganizationMetrics(c *gin.Context) {
 	orgID := c.Param("id")
@@ -234,7 +296,7 @@ func (h *OrganizationHandler) GetOrganizationMetrics(c *gin.Context) {
 		return
 	}
 
-	metrics, err := h.orgService.GetOrganizationMetrics(orgID)
+	metrics, err := h.orgService.GetOrganizationMetrics(c.Request.Context(), orgID)
 	if err != nil {
 		if err.Error() == "organization not found" {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -263,7 +325,7 @@ func (h *OrganizationHandler) SendExpiryReminder(c *gin.Context) {
 		return
 	}
 
-	err := h.orgService.SendExpiryReminder(orgID)
+	err := h.orgService.SendExpiryReminder(c.Request.Context(), orgID)
 	if err != nil {
 		if err.Error() == "organization not found" {
 			c.JSON(http.StatusNotFound, gin.H{
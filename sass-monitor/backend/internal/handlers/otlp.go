@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	collectormetricsv1 "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"sass-monitor/internal/services"
+	"sass-monitor/pkg/config"
+)
+
+type OTLPHandler struct {
+	otlpService *services.OTLPService
+	config      *config.Config
+}
+
+func NewOTLPHandler(otlpService *services.OTLPService, cfg *config.Config) *OTLPHandler {
+	return &OTLPHandler{
+		otlpService: otlpService,
+		config:      cfg,
+	}
+}
+
+// ExportMetrics OTLP/HTTP指标接收（POST /v1/metrics），支持application/x-protobuf和application/json，可gzip压缩，
+// 请求和响应分别为ExportMetricsServiceRequest和ExportMetricsServiceResponse
+func (h *OTLPHandler) ExportMetrics(c *gin.Context) {
+	if !h.config.Monitoring.OTLP.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "OTLP receiver is disabled",
+		})
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if contentType != "application/x-protobuf" && contentType != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": "Content-Type must be application/x-protobuf or application/json",
+		})
+		return
+	}
+
+	body, err := readIngestBody(c, h.config.Monitoring.Ingest.MaxBodyBytes)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == errBodyTooLarge {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var request collectormetricsv1.ExportMetricsServiceRequest
+	if contentType == "application/json" {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, &request)
+	} else {
+		err = proto.Unmarshal(body, &request)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid OTLP metrics payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.otlpService.IngestMetrics(c.Request.Context(), c.GetString("ingest_source"), &request)
+	if err != nil {
+		// 限流时返回429，缓冲区已满时返回503，OTLP导出端会按Retry-After重试；单批超过限额时返回413，不会重试
+		var rateLimitErr *services.IngestRateLimitError
+		var batchTooLargeErr *services.IngestBatchTooLargeError
+		switch {
+		case errors.As(err, &batchTooLargeErr):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": err.Error(),
+			})
+		case errors.As(err, &rateLimitErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Failed to store OTLP metrics",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	// 部分数据点被拒绝时返回partial_success
+	response := &collectormetricsv1.ExportMetricsServiceResponse{}
+	if rejected := result.Rejected + result.SeriesLimited; rejected > 0 {
+		var messages []string
+		if result.Rejected > 0 {
+			messages = append(messages, fmt.Sprintf("%d data points without a finite value were dropped", result.Rejected))
+		}
+		if result.SeriesLimited > 0 {
+			messages = append(messages, fmt.Sprintf("%d data points exceeding the tag combination limit were dropped", result.SeriesLimited))
+		}
+		response.PartialSuccess = &collectormetricsv1.ExportMetricsPartialSuccess{
+			RejectedDataPoints: int64(rejected),
+			ErrorMessage:       strings.Join(messages, "; "),
+		}
+	}
+
+	var responseBody []byte
+	if contentType == "application/json" {
+		responseBody, err = protojson.Marshal(response)
+	} else {
+		responseBody, err = proto.Marshal(response)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to encode OTLP response",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.Data(http.StatusOK, contentType, responseBody)
+}
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// readIngestBody 读取写入接口的请求体，支持Content-Encoding: gzip，解压后超过maxBytes时返回errBodyTooLarge
+func readIngestBody(c *gin.Context, maxBytes int64) ([]byte, error) {
+	if maxBytes <= 0 {
+		maxBytes = 8 << 20
+	}
+
+	var reader io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+	switch c.GetHeader("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding '%s'", c.GetHeader("Content-Encoding"))
+	}
+
+	var buffer bytes.Buffer
+	n, err := buffer.ReadFrom(io.LimitReader(reader, maxBytes+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errBodyTooLarge
+		}
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if n > maxBytes {
+		return nil, errBodyTooLarge
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	collectormetricsv1 "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	metricsv1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/services"
+	"sass-monitor/pkg/config"
+)
+
+// newOTLPRouter 只缓冲不落库的OTLP接收路由
+func newOTLPRouter() (*gin.Engine, *services.MetricWriter) {
+	cfg := &config.Config{}
+	cfg.Monitoring.OTLP.Enabled = true
+	cfg.Monitoring.Ingest.MaxBodyBytes = 1 << 20
+	dbManager := &database.DatabaseManager{Config: cfg}
+	writer := services.NewMetricWriter(dbManager)
+	otlpService := services.NewOTLPService(dbManager, writer, services.NewIngestService(dbManager, writer))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/metrics", func(c *gin.Context) {
+		c.Set("ingest_source", "agent")
+	}, NewOTLPHandler(otlpService, cfg).ExportMetrics)
+	return router, writer
+}
+
+// otlpGaugeRequest 一个有效数据点和一个NaN数据点
+func otlpGaugeRequest() *collectormetricsv1.ExportMetricsServiceRequest {
+	return &collectormetricsv1.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricsv1.ResourceMetrics{{
+			Resource: &resourcev1.Resource{Attributes: []*commonv1.KeyValue{{
+				Key:   "service.name",
+				Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: "checkout"}},
+			}}},
+			ScopeMetrics: []*metricsv1.ScopeMetrics{{
+				Metrics: []*metricsv1.Metric{{
+					Name: "queue_depth",
+					Data: &metricsv1.Metric_Gauge{Gauge: &metricsv1.Gauge{DataPoints: []*metricsv1.NumberDataPoint{
+						{Value: &metricsv1.NumberDataPoint_AsDouble{AsDouble: 3}},
+						{Value: &metricsv1.NumberDataPoint_AsDouble{AsDouble: math.NaN()}},
+					}}},
+				}},
+			}},
+		}},
+	}
+}
+
+func TestExportMetricsProtobuf(t *testing.T) {
+	router, writer := newOTLPRouter()
+	body, err := proto.Marshal(otlpGaugeRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/x-protobuf" {
+		t.Errorf("content type = %q, want application/x-protobuf", contentType)
+	}
+	var response collectormetricsv1.ExportMetricsServiceResponse
+	if err := proto.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response is not an ExportMetricsServiceResponse: %v", err)
+	}
+	if response.GetPartialSuccess().GetRejectedDataPoints() != 1 || response.GetPartialSuccess().GetErrorMessage() == "" {
+		t.Errorf("partial success = %v, want one rejected data point", response.GetPartialSuccess())
+	}
+	if stats := writer.Stats(); stats.Buffered != 1 {
+		t.Errorf("buffered = %d, want 1", stats.Buffered)
+	}
+}
+
+func TestExportMetricsJSON(t *testing.T) {
+	router, _ := newOTLPRouter()
+	// 只包含有效数据点时不返回partialSuccess
+	payload := `{"resourceMetrics":[{"scopeMetrics":[{"metrics":[{"name":"queue_depth","gauge":{"dataPoints":[{"asDouble":3}]}}]}]}]}`
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/metrics", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var response collectormetricsv1.ExportMetricsServiceResponse
+	if err := protojson.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response is not an ExportMetricsServiceResponse: %v", err)
+	}
+	if response.GetPartialSuccess() != nil {
+		t.Errorf("partial success = %v, want none", response.GetPartialSuccess())
+	}
+}
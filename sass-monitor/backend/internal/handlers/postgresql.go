@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type PostgreSQLHandler struct {
+	activityService    *services.PostgreSQLActivityService
+	maintenanceService *services.PostgreSQLMaintenanceService
+	replicationService *services.PostgreSQLReplicationService
+}
+
+func NewPostgreSQLHandler(activityService *services.PostgreSQLActivityService, maintenanceService *services.PostgreSQLMaintenanceService, replicationService *services.PostgreSQLReplicationService) *PostgreSQLHandler {
+	return &PostgreSQLHandler{
+		activityService:    activityService,
+		maintenanceService: maintenanceService,
+		replicationService: replicationService,
+	}
+}
+
+// GetActivity 获取PostgreSQL当前会话活动
+func (h *PostgreSQLHandler) GetActivity(c *gin.Context) {
+	dbName := c.Param("name")
+	minSeconds, _ := strconv.ParseFloat(c.DefaultQuery("min_seconds", "0"), 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	filter := services.ActivityFilter{
+		State:      c.Query("state"),
+		MinSeconds: minSeconds,
+		Limit:      limit,
+	}
+
+	summary, err := h.activityService.GetActivitySummary(c.Request.Context(), dbName)
+	if err != nil {
+		respondServiceError(c, "Failed to get activity summary", err)
+		return
+	}
+
+	sessions, err := h.activityService.GetActivity(c.Request.Context(), dbName, filter)
+	if err != nil {
+		respondServiceError(c, "Failed to get sessions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"database": dbName,
+		"summary":  summary,
+		"sessions": sessions,
+	})
+}
+
+// GetBlocking 获取PostgreSQL阻塞关系和阻塞链
+func (h *PostgreSQLHandler) GetBlocking(c *gin.Context) {
+	dbName := c.Param("name")
+
+	report, err := h.activityService.GetBlockingReport(c.Request.Context(), dbName)
+	if err != nil {
+		respondServiceError(c, "Failed to get blocking sessions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetMaintenance 获取PostgreSQL维护健康报告（需要关注的表、未使用和重复索引）
+func (h *PostgreSQLHandler) GetMaintenance(c *gin.Context) {
+	dbName := c.Param("name")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	report, err := h.maintenanceService.GetMaintenanceReport(c.Request.Context(), dbName, limit)
+	if err != nil {
+		respondServiceError(c, "Failed to get maintenance report", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetReplication 获取PostgreSQL流复制和WAL状态
+func (h *PostgreSQLHandler) GetReplication(c *gin.Context) {
+	dbName := c.Param("name")
+
+	status, err := h.replicationService.GetReplicationStatus(c.Request.Context(), dbName)
+	if err != nil {
+		respondServiceError(c, "Failed to get replication status", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// respondServiceError 根据错误类型返回对应的HTTP状态码
+func respondServiceError(c *gin.Context, message string, err error) {
+	if strings.Contains(err.Error(), "not found") {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": message + ": " + err.Error(),
+	})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type ProbeHandler struct {
+	probeService *services.ProbeService
+}
+
+func NewProbeHandler(probeService *services.ProbeService) *ProbeHandler {
+	return &ProbeHandler{
+		probeService: probeService,
+	}
+}
+
+// GetProbes 获取所有拨测目标的最新状态
+func (h *ProbeHandler) GetProbes(c *gin.Context) {
+	statuses, err := h.probeService.GetProbeStatus(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, "Failed to get probe status", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"probes": statuses,
+		"total":  len(statuses),
+	})
+}
+
+// RunProbes 立即执行所有拨测并返回结果
+func (h *ProbeHandler) RunProbes(c *gin.Context) {
+	results, err := h.probeService.RunAll(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, "Failed to run probes", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"total":   len(results),
+	})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type RedisHandler struct {
+	monitorService *services.RedisMonitorService
+}
+
+func NewRedisHandler(monitorService *services.RedisMonitorService) *RedisHandler {
+	return &RedisHandler{
+		monitorService: monitorService,
+	}
+}
+
+// GetInfo 获取Redis实例完整INFO信息
+func (h *RedisHandler) GetInfo(c *gin.Context) {
+	name := c.Param("name")
+
+	report, err := h.monitorService.GetInfo(c.Request.Context(), name)
+	if err != nil {
+		respondServiceError(c, "Failed to get Redis info", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetSlowLogs 分页浏览Redis慢查询日志
+func (h *RedisHandler) GetSlowLogs(c *gin.Context) {
+	name := c.Param("name")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	minDurationMs, _ := strconv.ParseFloat(c.DefaultQuery("min_duration_ms", "0"), 64)
+
+	filter := services.SlowLogFilter{
+		Node:          c.Query("node"),
+		Command:       c.Query("command"),
+		MinDurationMs: minDurationMs,
+		Page:          page,
+		PageSize:      pageSize,
+	}
+	if startTime, err := time.Parse(time.RFC3339, c.Query("start_time")); err == nil {
+		filter.StartTime = &startTime
+	}
+	if endTime, err := time.Parse(time.RFC3339, c.Query("end_time")); err == nil {
+		filter.EndTime = &endTime
+	}
+
+	logs, total, err := h.monitorService.GetSlowLogs(c.Request.Context(), name, filter)
+	if err != nil {
+		respondServiceError(c, "Failed to get Redis slowlog", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance":  name,
+		"slowlogs":  logs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetEvents 获取Redis角色变化和故障转移事件
+func (h *RedisHandler) GetEvents(c *gin.Context) {
+	name := c.Param("name")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	events, err := h.monitorService.GetEvents(c.Request.Context(), name, limit)
+	if err != nil {
+		respondServiceError(c, "Failed to get Redis events", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance": name,
+		"events":   events,
+	})
+}
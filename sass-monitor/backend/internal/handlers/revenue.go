@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type RevenueHandler struct {
+	revenueService *services.RevenueService
+}
+
+func NewRevenueHandler(revenueService *services.RevenueService) *RevenueHandler {
+	return &RevenueHandler{
+		revenueService: revenueService,
+	}
+}
+
+// GetRevenue 获取当前MRR/ARR及按计费周期、计划和组织的分解
+func (h *RevenueHandler) GetRevenue(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 0 || limit > 500 {
+		limit = 20
+	}
+
+	summary, err := h.revenueService.GetSummary(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get revenue",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetRevenueMovements 获取最近几个月的MRR变动（new/expansion/contraction/churned）
+func (h *RevenueHandler) GetRevenueMovements(c *gin.Context) {
+	months, err := strconv.Atoi(c.DefaultQuery("months", "12"))
+	if err != nil || months <= 0 || months > services.MaxRevenueMovementMonths {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("months must be between 1 and %d", services.MaxRevenueMovementMonths),
+		})
+		return
+	}
+
+	movements, err := h.revenueService.GetMovements(c.Request.Context(), months)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get revenue movements",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"months":    months,
+		"movements": movements,
+	})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type SchedulerHandler struct {
+	scheduler *services.TaskScheduler
+}
+
+func NewSchedulerHandler(scheduler *services.TaskScheduler) *SchedulerHandler {
+	return &SchedulerHandler{
+		scheduler: scheduler,
+	}
+}
+
+// JobScheduleRequest 修改调度表达式请求参数
+type JobScheduleRequest struct {
+	Schedule string `json:"schedule" binding:"required"`
+}
+
+// GetJobs 获取所有调度任务及其上次/下次执行时间和结果
+func (h *SchedulerHandler) GetJobs(c *gin.Context) {
+	jobs := h.scheduler.ListJobs(c.Request.Context())
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":              jobs,
+		"total":             len(jobs),
+		"scheduler_running": h.scheduler.IsRunning(),
+		"leader":            h.scheduler.LeaderStatus(),
+	})
+}
+
+// GetJob 获取单个调度任务
+func (h *SchedulerHandler) GetJob(c *gin.Context) {
+	job, err := h.scheduler.GetJob(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondServiceError(c, "Failed to get job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GetJobRuns 获取调度任务的执行记录
+func (h *SchedulerHandler) GetJobRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	runs, err := h.scheduler.GetJobRuns(c.Request.Context(), c.Param("name"), limit)
+	if err != nil {
+		respondServiceError(c, "Failed to get job runs", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs":  runs,
+		"total": len(runs),
+	})
+}
+
+// PauseJob 暂停调度任务
+func (h *SchedulerHandler) PauseJob(c *gin.Context) {
+	job, err := h.scheduler.PauseJob(c.Param("name"))
+	if err != nil {
+		respondServiceError(c, "Failed to pause job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// ResumeJob 恢复调度任务
+func (h *SchedulerHandler) ResumeJob(c *gin.Context) {
+	job, err := h.scheduler.ResumeJob(c.Param("name"))
+	if err != nil {
+		respondServiceError(c, "Failed to resume job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// TriggerJob 立即执行一次调度任务（非leader副本转交leader执行），任务正在执行时返回409
+func (h *SchedulerHandler) TriggerJob(c *gin.Context) {
+	job, err := h.scheduler.TriggerJob(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		if errors.Is(err, services.ErrJobRunning) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		respondServiceError(c, "Failed to trigger job", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// UpdateJobSchedule 修改调度任务的cron表达式
+func (h *SchedulerHandler) UpdateJobSchedule(c *gin.Context) {
+	var req JobScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	job, err := h.scheduler.UpdateJobSchedule(c.Param("name"), req.Schedule)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSchedule) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		respondServiceError(c, "Failed to update job schedule", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/internal/services"
+)
+
+type TrialHandler struct {
+	trialFunnelService *services.TrialFunnelService
+}
+
+func NewTrialHandler(trialFunnelService *services.TrialFunnelService) *TrialHandler {
+	return &TrialHandler{
+		trialFunnelService: trialFunnelService,
+	}
+}
+
+// GetTrialFunnel 获取试用转付费漏斗和即将到期的试用名单；
+// days为统计最近多少天开始的试用，ending_within为名单的到期范围（天），refresh=true时忽略缓存
+func (h *TrialHandler) GetTrialFunnel(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "90"))
+	if err != nil || days <= 0 || days > services.MaxTrialFunnelDays {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("days must be between 1 and %d", services.MaxTrialFunnelDays),
+		})
+		return
+	}
+	endingWithin, err := strconv.Atoi(c.DefaultQuery("ending_within", "7"))
+	if err != nil || endingWithin <= 0 || endingWithin > services.MaxTrialEndingWithin {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("ending_within must be between 1 and %d", services.MaxTrialEndingWithin),
+		})
+		return
+	}
+
+	funnel, err := h.trialFunnelService.GetFunnel(c.Request.Context(), days, endingWithin, c.Query("refresh") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get trial funnel",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	setAnalyticsCacheHeaders(c, funnel.GeneratedAt, funnel.ExpiresAt)
+	c.JSON(http.StatusOK, funnel)
+}
@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"sass-monitor/pkg/config"
 )
 
 // JWTClaims JWT声明
@@ -95,6 +98,48 @@ func JWTMiddleware(jwtSecret string) gin.HandlerFunc {
 	}
 }
 
+// IngestTokenMiddleware 指标写入接口的静态令牌认证（Authorization: Bearer <token>），未配置令牌时拒绝所有请求；
+// 认证通过后将令牌绑定的来源写入ingest_source，未配置来源的令牌使用default
+func IngestTokenMiddleware(tokens []config.IngestTokenConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(tokens) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Metric ingestion is disabled: no ingest tokens configured",
+			})
+			c.Abort()
+			return
+		}
+
+		const bearerPrefix = "Bearer "
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, bearerPrefix) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Authorization header with bearer ingest token is required",
+			})
+			c.Abort()
+			return
+		}
+
+		provided := []byte(authHeader[len(bearerPrefix):])
+		for _, token := range tokens {
+			if token.Token != "" && subtle.ConstantTimeCompare(provided, []byte(token.Token)) == 1 {
+				source := token.Source
+				if source == "" {
+					source = "default"
+				}
+				c.Set("ingest_source", source)
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid ingest token",
+		})
+		c.Abort()
+	}
+}
+
 // RequireRole 角色权限检查中间件
 func RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"sass-monitor/pkg/config"
+)
+
+func TestIngestTokenMiddlewareBindsSource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(IngestTokenMiddleware([]config.IngestTokenConfig{
+		{Token: "agent-token", Source: "agent"},
+		{Token: "legacy-token"},
+	}))
+	router.POST("/ingest", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("ingest_source"))
+	})
+
+	tests := []struct {
+		name       string
+		auth       string
+		wantStatus int
+		wantSource string
+	}{
+		{name: "bound source", auth: "Bearer agent-token", wantStatus: http.StatusOK, wantSource: "agent"},
+		{name: "token without source", auth: "Bearer legacy-token", wantStatus: http.StatusOK, wantSource: "default"},
+		{name: "unknown token", auth: "Bearer other", wantStatus: http.StatusUnauthorized},
+		{name: "missing header", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantSource != "" && w.Body.String() != tt.wantSource {
+				t.Errorf("source = %q, want %q", w.Body.String(), tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestIngestTokenMiddlewareWithoutTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(IngestTokenMiddleware(nil))
+	router.POST("/ingest", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+	req.Header.Set("Authorization", "Bearer anything")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
@@ -64,11 +64,19 @@ type ResourceMetric struct {
 	MetricName    string    `gorm:"not null;size:100;index" json:"metric_name"` // 具体的指标名称
 	MetricValue   float64   `gorm:"not null" json:"metric_value"`
 	Unit          string    `gorm:"size:20" json:"unit"` // 单位：MB, GB, %, count, ms
-	Tags          string    `gorm:"type:jsonb" json:"tags"` // 额外的标签JSON
+	Tags          string    `gorm:"type:jsonb;index:idx_resource_metrics_tags,type:gin" json:"tags"` // 额外的标签JSON（GIN索引，支持按标签过滤）
 	CollectedAt   time.Time `gorm:"not null;index" json:"collected_at"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
+// BeforeCreate 未设置标签时写入空JSON对象（tags列为jsonb，不接受空字符串）
+func (m *ResourceMetric) BeforeCreate(tx *gorm.DB) error {
+	if m.Tags == "" {
+		m.Tags = "{}"
+	}
+	return nil
+}
+
 // MonitoringLog 监控日志模型
 type MonitoringLog struct {
 	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
@@ -86,14 +94,131 @@ type SystemHealth struct {
 	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	ComponentName string    `gorm:"not null;size:100;uniqueIndex" json:"component_name"` // postgresql, clickhouse_traces等
 	ComponentType string    `gorm:"not null;size:50" json:"component_type"` // database, cache, message_queue
-	Status        string    `gorm:"not null;size:20" json:"status"` // healthy, warning, critical, down
+	Status        string    `gorm:"not null;size:20" json:"status"` // healthy, warning, critical, unhealthy
 	ResponseTime  *int      `json:"response_time"` // 响应时间(毫秒)
+	ResponseTimeP50 *int    `json:"response_time_p50"` // 最近检查的响应时间分位数(毫秒)
+	ResponseTimeP95 *int    `json:"response_time_p95"`
+	ResponseTimeP99 *int    `json:"response_time_p99"`
 	ErrorMessage  *string   `json:"error_message"`
 	LastCheckedAt time.Time `gorm:"not null;index" json:"last_checked_at"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// SystemHealthHistory 组件健康状态变化记录
+type SystemHealthHistory struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ComponentName  string    `gorm:"not null;size:100;index" json:"component_name"`
+	ComponentType  string    `gorm:"not null;size:50" json:"component_type"`
+	PreviousStatus string    `gorm:"size:20" json:"previous_status"` // 首次检查时为空
+	Status         string    `gorm:"not null;size:20" json:"status"`
+	ResponseTime   *int      `json:"response_time"`
+	ErrorMessage   *string   `json:"error_message"`
+	ChangedAt      time.Time `gorm:"not null;index" json:"changed_at"`
+}
+
+// RedisSlowLog Redis慢查询日志（来自SLOWLOG GET），按实例、节点、ID和执行时间去重
+type RedisSlowLog struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	InstanceName   string    `gorm:"not null;size:100;uniqueIndex:idx_redis_slowlog_node_entry" json:"instance_name"`
+	NodeAddr       string    `gorm:"not null;size:100;default:'';uniqueIndex:idx_redis_slowlog_node_entry" json:"node_addr"`
+	SlowLogID      int64     `gorm:"not null;uniqueIndex:idx_redis_slowlog_node_entry" json:"slowlog_id"`
+	ExecutedAt     time.Time `gorm:"not null;index;uniqueIndex:idx_redis_slowlog_node_entry" json:"executed_at"`
+	DurationMicros int64     `gorm:"not null;index" json:"duration_micros"`
+	Command        string    `gorm:"size:100;index" json:"command"` // 命令名称，如GET、HGETALL
+	Args           string    `gorm:"type:text" json:"args"`         // 完整命令参数（Redis已截断过长参数）
+	ClientAddr     string    `gorm:"size:100" json:"client_addr"`
+	ClientName     string    `gorm:"size:100" json:"client_name"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// MonitoringTarget 通过API管理的监控目标，凭据加密存储
+type MonitoringTarget struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name              string     `gorm:"not null;size:100;uniqueIndex:idx_monitoring_target_name" json:"name"`
+	TargetType        string     `gorm:"not null;size:20;uniqueIndex:idx_monitoring_target_name" json:"target_type"` // postgresql, clickhouse, redis, mysql
+	Host              string     `gorm:"size:255" json:"host"`
+	Port              int        `json:"port"`
+	Username          string     `gorm:"size:100" json:"username"`
+	PasswordEncrypted string     `gorm:"type:text" json:"-"`
+	DatabaseName      string     `gorm:"size:100" json:"database_name"`
+	Options           string     `gorm:"type:jsonb;default:'{}'" json:"options"` // ssl_mode、redis mode/addrs/master_name等
+	Enabled           bool       `gorm:"default:true" json:"enabled"`
+	LastTestStatus    string     `gorm:"size:20" json:"last_test_status"` // success, failed
+	LastTestError     string     `gorm:"type:text" json:"last_test_error"`
+	LastTestedAt      *time.Time `json:"last_tested_at"`
+	CreatedBy         uuid.UUID  `json:"created_by"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// IngestionWatermark 增量导入外部表的水位线，(LastUpdatedAt, LastID)为已导入的最后一行
+type IngestionWatermark struct {
+	Source        string    `gorm:"primary_key;size:100" json:"source"` // 来源表，如light_admin.host_metrics
+	LastUpdatedAt time.Time `gorm:"not null" json:"last_updated_at"`
+	LastID        string    `gorm:"size:64" json:"last_id"`
+	RowsIngested  int64     `gorm:"default:0" json:"rows_ingested"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// IngestedRow 水位线回退窗口内已导入的外部表行，重新读取窗口时用于去重，超出窗口后清理
+type IngestedRow struct {
+	Source       string    `gorm:"primary_key;size:100" json:"source"`
+	RowID        string    `gorm:"primary_key;size:64" json:"row_id"`
+	RowUpdatedAt time.Time `gorm:"primary_key" json:"row_updated_at"` // 同一行更新后作为新数据导入
+}
+
+// ScheduledJob 调度任务的调度配置和最近一次执行结果，调度表达式修改后持久化并立即生效
+type ScheduledJob struct {
+	Name           string     `gorm:"primary_key;size:100" json:"name"`
+	Schedule       string     `gorm:"not null;size:100" json:"schedule"` // cron表达式，支持@every 5m等描述符
+	Paused         bool       `gorm:"default:false" json:"paused"`
+	LastRunAt      *time.Time `json:"last_run_at"`
+	LastDurationMs *int64     `json:"last_duration_ms"`
+	LastStatus     string     `gorm:"size:20" json:"last_status"` // success, failed
+	LastError      *string    `gorm:"type:text" json:"last_error"`
+	NextRunAt      *time.Time `json:"next_run_at"` // 由执行定时任务的副本写入，其他副本查询状态时使用
+	// 非leader副本收到的手动触发请求，由leader同步调度配置时执行并清除
+	TriggerRequestedAt *time.Time `json:"trigger_requested_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// JobRun 调度任务执行记录
+type JobRun struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	JobName    string     `gorm:"not null;size:100;index" json:"job_name"`
+	Trigger    string     `gorm:"not null;size:20" json:"trigger"` // schedule, manual
+	Status     string     `gorm:"not null;size:20" json:"status"`  // running, success, failed
+	Error      *string    `gorm:"type:text" json:"error"`
+	StartedAt  time.Time  `gorm:"not null;index" json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	DurationMs *int64     `json:"duration_ms"`
+}
+
+// BackgroundJob 后台任务队列中的任务，由JobQueue的worker通过SKIP LOCKED领取执行
+type BackgroundJob struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Type            string     `gorm:"not null;size:100;index" json:"type"`
+	Payload         string     `gorm:"type:jsonb;not null;default:'{}'" json:"payload"`
+	Status          string     `gorm:"not null;size:20;index:idx_background_jobs_queue,priority:1" json:"status"` // queued, running, succeeded, failed, cancelled
+	RunAt           time.Time  `gorm:"not null;index:idx_background_jobs_queue,priority:2" json:"run_at"`       // 排队任务的最早执行时间（重试退避）
+	Attempts        int        `gorm:"default:0" json:"attempts"`
+	MaxAttempts     int        `gorm:"default:3" json:"max_attempts"`
+	Progress        float64    `gorm:"default:0" json:"progress"` // 0-100
+	ProgressMessage string     `gorm:"size:255" json:"progress_message"`
+	Result          *string    `gorm:"type:jsonb" json:"result"`
+	Error           *string    `gorm:"type:text" json:"error"`
+	CancelRequested bool       `gorm:"default:false" json:"cancel_requested"`
+	LockedBy        *string    `gorm:"size:100" json:"locked_by"`
+	HeartbeatAt     *time.Time `json:"heartbeat_at"`
+	CreatedBy       *string    `gorm:"size:100" json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	StartedAt       *time.Time `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
 // TableName 指定表名
 func (AdminUser) TableName() string {
 	return "admin_users"
@@ -117,4 +242,36 @@ func (MonitoringLog) TableName() string {
 
 func (SystemHealth) TableName() string {
 	return "system_health"
-}
\ No newline at end of file
+}
+
+func (SystemHealthHistory) TableName() string {
+	return "system_health_history"
+}
+
+func (MonitoringTarget) TableName() string {
+	return "monitoring_targets"
+}
+
+func (IngestionWatermark) TableName() string {
+	return "ingestion_watermarks"
+}
+
+func (IngestedRow) TableName() string {
+	return "ingested_rows"
+}
+
+func (ScheduledJob) TableName() string {
+	return "scheduled_jobs"
+}
+
+func (JobRun) TableName() string {
+	return "job_runs"
+}
+
+func (BackgroundJob) TableName() string {
+	return "background_jobs"
+}
+
+func (RedisSlowLog) TableName() string {
+	return "redis_slowlogs"
+}
@@ -147,6 +147,51 @@ type Payment struct {
 	UpdatedAt     time.Time  `gorm:"default:now()" json:"updated_at"`
 }
 
+// HostMetric 对应light_admin.host_metrics表（由产品agent写入，每台主机一行并持续更新）
+type HostMetric struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	HostName        string    `gorm:"not null" json:"host_name"`
+	OperatingSystem *string   `gorm:"size:64" json:"operating_system"`
+	HostStatus      *int64    `json:"host_status"`
+	CPUUsage        *float64  `gorm:"column:cpu_usage" json:"cpu_usage"`
+	MemoryUsage     *float64  `json:"memory_usage"`
+	CPULoad         *float64  `gorm:"column:cpu_load" json:"cpu_load"`
+	ProcessorCores  *int16    `json:"processor_cores"`
+	TotalMemoryGB   *float64  `gorm:"column:total_memory_gb" json:"total_memory_gb"`
+	Platform        *string   `gorm:"size:255" json:"platform"`
+}
+
+// ContainerMetric 对应light_admin.container_metrics表
+type ContainerMetric struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	ContainerName string    `gorm:"not null" json:"container_name"`
+	ContainerID   *string   `json:"container_id"`
+	Status        *int64    `json:"status"`
+	Image         string    `json:"image"`
+	IP            string    `gorm:"column:ip" json:"ip"`
+	HostName      string    `gorm:"not null" json:"host_name"`
+	CPUUsage      *float64  `gorm:"column:cpu_usage" json:"cpu_usage"`
+	MemoryUsage   *float64  `json:"memory_usage"`
+}
+
+// ProcessMetric 对应light_admin.process_metrics表
+type ProcessMetric struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	ProcessName string    `gorm:"not null" json:"process_name"`
+	Status      *int64    `json:"status"`
+	HostName    string    `gorm:"not null" json:"host_name"`
+	CPUUsage    *float64  `gorm:"column:cpu_usage" json:"cpu_usage"`
+	MemoryUsage *float64  `json:"memory_usage"`
+	ProcessUser *string   `json:"process_user"`
+	PID         int64     `gorm:"column:pid" json:"pid"`
+}
+
 // 指定表名
 func (AuthOrganization) TableName() string {
 	return "auth_organizations"
@@ -186,4 +231,16 @@ func (OrgBilling) TableName() string {
 
 func (Payment) TableName() string {
 	return "payments"
-}
\ No newline at end of file
+}
+
+func (HostMetric) TableName() string {
+	return "host_metrics"
+}
+
+func (ContainerMetric) TableName() string {
+	return "container_metrics"
+}
+
+func (ProcessMetric) TableName() string {
+	return "process_metrics"
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"sass-monitor/internal/database"
+)
+
+// analyticsCache 业务分析报表的进程内缓存：报表需要扫描全部组织和订阅，结果在TTL内复用，ttl为0时不缓存
+type analyticsCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]analyticsCacheEntry
+}
+
+type analyticsCacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+func newAnalyticsCache(ttlSeconds int) *analyticsCache {
+	return &analyticsCache{
+		ttl:     time.Duration(ttlSeconds) * time.Second,
+		entries: make(map[string]analyticsCacheEntry),
+	}
+}
+
+// get 获取未过期的缓存结果
+func (c *analyticsCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// expiry 新缓存结果的过期时间，不缓存时返回nil
+func (c *analyticsCache) expiry() *time.Time {
+	if c.ttl <= 0 {
+		return nil
+	}
+	expiresAt := time.Now().Add(c.ttl)
+	return &expiresAt
+}
+
+// set 缓存结果直到expiresAt，expiresAt为nil时不缓存；缓存的值之后不能再修改
+func (c *analyticsCache) set(key string, value interface{}, expiresAt *time.Time) {
+	if expiresAt == nil {
+		return
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for existing, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, existing)
+		}
+	}
+	c.entries[key] = analyticsCacheEntry{value: value, expiresAt: *expiresAt}
+}
+
+// analyticsDataVersion 业务分析源数据的版本：订阅、组织和计划的行数及最近更新时间，
+// 作为缓存键的一部分，数据变化后缓存自动失效（包括其他副本或外部系统写入的变化）
+func analyticsDataVersion(ctx context.Context, dbManager *database.DatabaseManager) (string, error) {
+	var version struct {
+		Subscriptions          int64
+		SubscriptionsUpdatedAt *time.Time
+		Organizations          int64
+		PlansUpdatedAt         *time.Time
+	}
+	if err := dbManager.LightAdminDB.WithContext(ctx).Raw(`
+		SELECT
+			(SELECT count(*) FROM subscription_users) AS subscriptions,
+			(SELECT max(updated_at) FROM subscription_users) AS subscriptions_updated_at,
+			(SELECT count(*) FROM auth_organizations) AS organizations,
+			(SELECT max(updated_at) FROM subscription_plans) AS plans_updated_at`).
+		Scan(&version).Error; err != nil {
+		return "", fmt.Errorf("failed to query analytics data version: %w", err)
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%d/%s/%d/%s", version.Subscriptions, formatTime(version.SubscriptionsUpdatedAt),
+		version.Organizations, formatTime(version.PlansUpdatedAt)), nil
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// maxBackfillDays 单次回填允许的最大天数
+const maxBackfillDays = 731
+
+// backfillDateLayout 回填日期参数格式
+const backfillDateLayout = "2006-01-02"
+
+// ErrInvalidBackfill 回填参数无效（未知序列、日期范围错误等）
+var ErrInvalidBackfill = errors.New("invalid backfill request")
+
+// backfillTags 回填数据点的标签，用于与实时采集的数据区分，重复回填时按此标签替换
+var backfillTags = map[string]interface{}{"backfill": "true"}
+
+// backfillSeries 可由light_admin的created_at/start_date/end_date重建的历史序列，
+// 指标与collectPostgreSQLUserStats、collectPostgreSQLOrganizationStats写入的一致
+type backfillSeries struct {
+	metricType      string
+	metricName      string
+	unit            string
+	perOrganization bool
+	// countSQL 截至某时刻的计数，参数为该时刻（可出现多次）；按组织统计时返回organization_id和value两列
+	countSQL string
+	args     int
+}
+
+var backfillSeriesDefs = map[string]backfillSeries{
+	"organization_count": {
+		metricType: "organization_count",
+		metricName: "total_organizations",
+		unit:       "count",
+		countSQL:   `SELECT COUNT(*) AS value FROM auth_organizations WHERE created_at < ?`,
+		args:       1,
+	},
+	"user_count": {
+		metricType: "user_count",
+		metricName: "total_users",
+		unit:       "count",
+		countSQL:   `SELECT COUNT(*) AS value FROM auth_users WHERE created_at < ?`,
+		args:       1,
+	},
+	// 历史状态无法还原，按订阅起止日期判断当时是否有效
+	"subscription_count": {
+		metricType: "subscription_count",
+		metricName: "active_subscriptions",
+		unit:       "count",
+		countSQL:   `SELECT COUNT(*) AS value FROM subscription_users WHERE start_date < ? AND (end_date IS NULL OR end_date >= ?)`,
+		args:       2,
+	},
+	"organization_workspaces": {
+		metricType:      "organization_workspaces",
+		metricName:      "workspace_count",
+		unit:            "count",
+		perOrganization: true,
+		countSQL: `SELECT organization_id::text AS organization_id, COUNT(*) AS value
+			FROM auth_workspaces WHERE created_at < ? GROUP BY organization_id`,
+		args: 1,
+	},
+	"organization_subscriptions": {
+		metricType:      "organization_subscriptions",
+		metricName:      "active_subscriptions",
+		unit:            "count",
+		perOrganization: true,
+		countSQL: `SELECT organization_id, COUNT(*) AS value FROM subscription_users
+			WHERE start_date < ? AND (end_date IS NULL OR end_date >= ?) GROUP BY organization_id`,
+		args: 2,
+	},
+}
+
+// BackfillSeriesNames 获取可回填的序列名称
+func BackfillSeriesNames() []string {
+	names := make([]string, 0, len(backfillSeriesDefs))
+	for name := range backfillSeriesDefs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseBackfillRange 解析YYYY-MM-DD格式的回填起止日期（本地时区，包含两端）
+func ParseBackfillRange(from, to string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(backfillDateLayout, from, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from date '%s', expected YYYY-MM-DD", from)
+	}
+	end, err := time.ParseInLocation(backfillDateLayout, to, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to date '%s', expected YYYY-MM-DD", to)
+	}
+	return start, end, nil
+}
+
+type BackfillService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewBackfillService(dbManager *database.DatabaseManager) *BackfillService {
+	return &BackfillService{
+		dbManager: dbManager,
+	}
+}
+
+// BackfillResult 回填结果，Points为各序列写入的数据点数
+type BackfillResult struct {
+	From       string         `json:"from"`
+	To         string         `json:"to"`
+	Days       int            `json:"days"`
+	Points     map[string]int `json:"points"`
+	DurationMs int64          `json:"duration_ms"`
+}
+
+// BackfillJobType 回填的后台任务类型
+const BackfillJobType = "backfill"
+
+// BackfillJobPayload 回填任务参数，日期格式YYYY-MM-DD
+type BackfillJobPayload struct {
+	Series []string `json:"series"`
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+}
+
+// BackfillProgressFunc 回填进度回调，每完成一个序列调用一次
+type BackfillProgressFunc func(completed, total int, series string)
+
+// Validate 检查回填参数，参数无效时返回ErrInvalidBackfill
+func (s *BackfillService) Validate(series []string, from, to time.Time) error {
+	_, _, _, err := planBackfill(series, from, to)
+	return err
+}
+
+// planBackfill 校验参数并计算回填的序列、起始日期和每天的截止时刻（查询条件为created_at < 截止时刻）
+func planBackfill(series []string, from, to time.Time) ([]string, time.Time, []time.Time, error) {
+	if len(series) == 0 {
+		series = BackfillSeriesNames()
+	}
+	for _, name := range series {
+		if _, ok := backfillSeriesDefs[name]; !ok {
+			return nil, time.Time{}, nil, fmt.Errorf("%w: unknown series '%s'", ErrInvalidBackfill, name)
+		}
+	}
+
+	now := time.Now()
+	if to.After(now) {
+		to = now
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local)
+	if from.After(to) {
+		return nil, time.Time{}, nil, fmt.Errorf("%w: from date must not be after to date", ErrInvalidBackfill)
+	}
+
+	var cutoffs []time.Time
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		cutoff := day.AddDate(0, 0, 1)
+		if cutoff.After(now) {
+			cutoff = now
+		}
+		cutoffs = append(cutoffs, cutoff)
+	}
+	if len(cutoffs) > maxBackfillDays {
+		return nil, time.Time{}, nil, fmt.Errorf("%w: range of %d days exceeds the limit of %d days", ErrInvalidBackfill, len(cutoffs), maxBackfillDays)
+	}
+	return series, from, cutoffs, nil
+}
+
+// RunJob 回填后台任务的处理函数，按序列上报进度
+func (s *BackfillService) RunJob(ctx context.Context, execution *JobExecution) (interface{}, error) {
+	var payload BackfillJobPayload
+	if err := execution.Bind(&payload); err != nil {
+		return nil, err
+	}
+	from, to, err := ParseBackfillRange(payload.From, payload.To)
+	if err != nil {
+		return nil, NonRetryable(err)
+	}
+
+	result, err := s.Backfill(ctx, payload.Series, from, to, func(completed, total int, series string) {
+		execution.ReportProgress(float64(completed)*100/float64(total), fmt.Sprintf("backfilled %s (%d/%d)", series, completed, total))
+	})
+	if errors.Is(err, ErrInvalidBackfill) {
+		return nil, NonRetryable(err)
+	}
+	return result, err
+}
+
+// Backfill 按天重建[from, to]期间的历史序列，每天一个数据点，CollectedAt为当天结束时刻（当天为当前时间）。
+// 同一序列在该期间内已有的回填数据点会被替换，实时采集的数据点不受影响；series为空时回填全部序列，progress可为nil
+func (s *BackfillService) Backfill(ctx context.Context, series []string, from, to time.Time, progress BackfillProgressFunc) (*BackfillResult, error) {
+	startedAt := time.Now()
+	series, from, cutoffs, err := planBackfill(series, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	organizationNames, err := lightAdminOrganizationNames(ctx, s.dbManager)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BackfillResult{
+		From:   from.Format(backfillDateLayout),
+		To:     from.AddDate(0, 0, len(cutoffs)-1).Format(backfillDateLayout),
+		Days:   len(cutoffs),
+		Points: make(map[string]int, len(series)),
+	}
+	for i, name := range series {
+		count, err := s.backfillSeries(ctx, backfillSeriesDefs[name], from, cutoffs, organizationNames)
+		if err != nil {
+			return nil, fmt.Errorf("failed to backfill %s: %w", name, err)
+		}
+		result.Points[name] = count
+		log.Printf("Backfilled %d points for %s (%s ~ %s)", count, name, result.From, result.To)
+		if progress != nil {
+			progress(i+1, len(series), name)
+		}
+	}
+	result.DurationMs = time.Since(startedAt).Milliseconds()
+	return result, nil
+}
+
+// backfillSeries 重建单个序列并替换期间内已有的回填数据点
+func (s *BackfillService) backfillSeries(ctx context.Context, def backfillSeries, from time.Time, cutoffs []time.Time, organizationNames map[string]string) (int, error) {
+	var metrics []models.ResourceMetric
+	for _, cutoff := range cutoffs {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		args := make([]interface{}, def.args)
+		for i := range args {
+			args[i] = cutoff
+		}
+		// 数据点时间为当天最后一秒，避免落到下一天
+		collectedAt := cutoff.Add(-time.Second)
+
+		if !def.perOrganization {
+			var value int64
+			if err := s.dbManager.LightAdminDB.WithContext(ctx).Raw(def.countSQL, args...).Scan(&value).Error; err != nil {
+				return 0, err
+			}
+			metrics = append(metrics, s.backfillMetric(def, nil, float64(value), collectedAt, nil))
+			continue
+		}
+
+		var rows []struct {
+			OrganizationID string
+			Value          int64
+		}
+		if err := s.dbManager.LightAdminDB.WithContext(ctx).Raw(def.countSQL, args...).Scan(&rows).Error; err != nil {
+			return 0, err
+		}
+		for _, row := range rows {
+			orgID := row.OrganizationID
+			tags := map[string]interface{}{"organization_name": organizationNames[orgID]}
+			metrics = append(metrics, s.backfillMetric(def, &orgID, float64(row.Value), collectedAt, tags))
+		}
+	}
+
+	err := s.dbManager.SaasMonitorDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("database_type = ? AND database_name = ? AND metric_type = ? AND metric_name = ? AND collected_at BETWEEN ? AND ? AND tags @> ?::jsonb",
+			"postgresql", "light_admin", def.metricType, def.metricName,
+			from, cutoffs[len(cutoffs)-1], formatMetricTags(backfillTags)).
+			Delete(&models.ResourceMetric{}).Error; err != nil {
+			return err
+		}
+		if len(metrics) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(metrics, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(metrics), nil
+}
+
+// backfillMetric 构建回填数据点
+func (s *BackfillService) backfillMetric(def backfillSeries, orgID *string, value float64, collectedAt time.Time, extraTags map[string]interface{}) models.ResourceMetric {
+	tags := make(map[string]interface{}, len(backfillTags)+len(extraTags))
+	for key, tagValue := range backfillTags {
+		tags[key] = tagValue
+	}
+	for key, tagValue := range extraTags {
+		tags[key] = tagValue
+	}
+	return models.ResourceMetric{
+		OrganizationID: orgID,
+		DatabaseType:   "postgresql",
+		DatabaseName:   "light_admin",
+		MetricType:     def.metricType,
+		MetricName:     def.metricName,
+		MetricValue:    value,
+		Unit:           def.unit,
+		Tags:           formatMetricTags(tags),
+		CollectedAt:    collectedAt,
+	}
+}
+
+// lightAdminOrganizationNames 组织ID到名称的映射，用于按组织统计时补充组织名称
+func lightAdminOrganizationNames(ctx context.Context, dbManager *database.DatabaseManager) (map[string]string, error) {
+	var organizations []models.AuthOrganization
+	if err := dbManager.LightAdminDB.WithContext(ctx).Select("id, name").Find(&organizations).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch organizations: %w", err)
+	}
+	names := make(map[string]string, len(organizations))
+	for _, org := range organizations {
+		names[org.ID.String()] = org.Name
+	}
+	return names, nil
+}
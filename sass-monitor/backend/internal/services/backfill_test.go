@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// backfillDay 本地时区某天零点，与ParseBackfillRange一致
+func backfillDay(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+func TestParseBackfillRange(t *testing.T) {
+	from, to, err := ParseBackfillRange("2026-01-01", "2026-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !from.Equal(backfillDay(2026, 1, 1)) || !to.Equal(backfillDay(2026, 1, 31)) {
+		t.Errorf("ParseBackfillRange() = %v, %v", from, to)
+	}
+	for _, dates := range [][2]string{{"2026/01/01", "2026-01-31"}, {"2026-01-01", "yesterday"}} {
+		if _, _, err := ParseBackfillRange(dates[0], dates[1]); err == nil {
+			t.Errorf("ParseBackfillRange(%q, %q) should fail", dates[0], dates[1])
+		}
+	}
+}
+
+func TestPlanBackfill(t *testing.T) {
+	series, from, cutoffs, err := planBackfill([]string{"user_count"}, backfillDay(2026, 1, 1).Add(15*time.Hour), backfillDay(2026, 1, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(series, []string{"user_count"}) || !from.Equal(backfillDay(2026, 1, 1)) {
+		t.Errorf("planBackfill() series = %v, from = %v", series, from)
+	}
+	// 每天的截止时刻为次日零点，查询条件为created_at < 截止时刻
+	wantCutoffs := []time.Time{backfillDay(2026, 1, 2), backfillDay(2026, 1, 3), backfillDay(2026, 1, 4)}
+	if !reflect.DeepEqual(cutoffs, wantCutoffs) {
+		t.Errorf("planBackfill() cutoffs = %v, want %v", cutoffs, wantCutoffs)
+	}
+
+	series, _, _, err = planBackfill(nil, backfillDay(2026, 1, 1), backfillDay(2026, 1, 1))
+	if err != nil || !reflect.DeepEqual(series, BackfillSeriesNames()) {
+		t.Errorf("planBackfill() without series = %v, %v; want all series", series, err)
+	}
+}
+
+func TestPlanBackfillClampsToNow(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	_, _, cutoffs, err := planBackfill(nil, today.AddDate(0, 0, -1), today.AddDate(0, 0, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cutoffs) != 2 {
+		t.Fatalf("planBackfill() returned %d days, want yesterday and today", len(cutoffs))
+	}
+	if !cutoffs[0].Equal(today) || cutoffs[1].Before(now) || cutoffs[1].After(time.Now()) {
+		t.Errorf("planBackfill() cutoffs = %v, want %v and the current time", cutoffs, today)
+	}
+}
+
+func TestPlanBackfillInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []string
+		from   time.Time
+		to     time.Time
+	}{
+		{name: "unknown series", series: []string{"user_count", "revenue"}, from: backfillDay(2026, 1, 1), to: backfillDay(2026, 1, 2)},
+		{name: "from after to", from: backfillDay(2026, 1, 2), to: backfillDay(2026, 1, 1)},
+		{name: "range too long", from: backfillDay(2023, 1, 1), to: backfillDay(2023, 1, 1).AddDate(0, 0, maxBackfillDays)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := planBackfill(tt.series, tt.from, tt.to); !errors.Is(err, ErrInvalidBackfill) {
+				t.Errorf("planBackfill() error = %v, want ErrInvalidBackfill", err)
+			}
+		})
+	}
+	if _, _, _, err := planBackfill(nil, backfillDay(2023, 1, 1), backfillDay(2023, 1, 1).AddDate(0, 0, maxBackfillDays-1)); err != nil {
+		t.Errorf("planBackfill() with %d days error = %v", maxBackfillDays, err)
+	}
+}
+
+func TestBackfillMetricTags(t *testing.T) {
+	orgID := "org-1"
+	metric := (&BackfillService{}).backfillMetric(backfillSeriesDefs["organization_workspaces"], &orgID, 3, backfillDay(2026, 1, 1),
+		map[string]interface{}{"organization_name": "Acme"})
+	if metric.MetricType != "organization_workspaces" || metric.MetricName != "workspace_count" || metric.MetricValue != 3 {
+		t.Errorf("backfillMetric() = %+v", metric)
+	}
+	if want := `{"backfill":"true","organization_name":"Acme"}`; metric.Tags != want {
+		t.Errorf("backfillMetric() tags = %s, want %s", metric.Tags, want)
+	}
+}
@@ -0,0 +1,438 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// ClickHouse健康判断阈值
+const (
+	stuckMutationMinutes         = 60   // 超过该时长未完成的mutation视为卡住
+	defaultPartsToDelayInsert    = 150  // 读取merge_tree_settings失败时的默认值
+	defaultPartsToThrowInsert    = 300  // 读取merge_tree_settings失败时的默认值
+	replicaDelayWarningSeconds   = 300  // 副本延迟告警阈值
+	diskFreePercentWarning       = 15.0 // 磁盘剩余空间告警阈值
+	clickHouseHealthTopTableSize = 20   // 采集时按表输出指标的最大表数量
+)
+
+// ClickHouseHealthService ClickHouse merge、mutation、part、副本和磁盘健康查询服务（只读）
+type ClickHouseHealthService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewClickHouseHealthService(dbManager *database.DatabaseManager) *ClickHouseHealthService {
+	return &ClickHouseHealthService{
+		dbManager: dbManager,
+	}
+}
+
+// ClickHouseMerge 正在进行的merge（来自system.merges）
+type ClickHouseMerge struct {
+	Table          string  `json:"table" ch:"table"`
+	ElapsedSeconds float64 `json:"elapsed_seconds" ch:"elapsed"`
+	Progress       float64 `json:"progress" ch:"progress"`
+	NumParts       int64   `json:"num_parts" ch:"num_parts"`
+	ResultPartName string  `json:"result_part_name" ch:"result_part_name"`
+	TotalSizeBytes int64   `json:"total_size_bytes" ch:"total_size_bytes"`
+	MemoryUsage    int64   `json:"memory_usage" ch:"memory_usage"`
+	IsMutation     bool    `json:"is_mutation" ch:"is_mutation"`
+}
+
+// ClickHouseMutation 未完成的mutation（来自system.mutations）
+type ClickHouseMutation struct {
+	Table            string    `json:"table" ch:"table"`
+	MutationID       string    `json:"mutation_id" ch:"mutation_id"`
+	Command          string    `json:"command" ch:"command"`
+	CreateTime       time.Time `json:"create_time" ch:"create_time"`
+	AgeSeconds       float64   `json:"age_seconds" ch:"age_seconds"`
+	PartsToDo        int64     `json:"parts_to_do" ch:"parts_to_do"`
+	LatestFailedPart string    `json:"latest_failed_part" ch:"latest_failed_part"`
+	LatestFailReason string    `json:"latest_fail_reason" ch:"latest_fail_reason"`
+	Stuck            bool      `json:"stuck" ch:"-"`
+}
+
+// ClickHouseTableParts 表的活跃part统计
+type ClickHouseTableParts struct {
+	Table               string `json:"table" ch:"table"`
+	ActiveParts         int64  `json:"active_parts" ch:"active_parts"`
+	Partitions          int64  `json:"partitions" ch:"partitions"`
+	MaxPartsInPartition int64  `json:"max_parts_in_partition" ch:"max_parts_in_partition"`
+	PartsToDelayInsert  int64  `json:"parts_to_delay_insert" ch:"-"`
+	PartsToThrowInsert  int64  `json:"parts_to_throw_insert" ch:"-"`
+	TooManyPartsRisk    string `json:"too_many_parts_risk" ch:"-"` // none, warning, critical
+}
+
+// ClickHouseReplica 复制表状态（来自system.replicas）
+type ClickHouseReplica struct {
+	Table            string `json:"table" ch:"table"`
+	ReplicaName      string `json:"replica_name" ch:"replica_name"`
+	IsReadonly       bool   `json:"is_readonly" ch:"is_readonly"`
+	IsSessionExpired bool   `json:"is_session_expired" ch:"is_session_expired"`
+	QueueSize        int64  `json:"queue_size" ch:"queue_size"`
+	InsertsInQueue   int64  `json:"inserts_in_queue" ch:"inserts_in_queue"`
+	MergesInQueue    int64  `json:"merges_in_queue" ch:"merges_in_queue"`
+	AbsoluteDelay    int64  `json:"absolute_delay" ch:"absolute_delay"`
+	TotalReplicas    int64  `json:"total_replicas" ch:"total_replicas"`
+	ActiveReplicas   int64  `json:"active_replicas" ch:"active_replicas"`
+	Unhealthy        bool   `json:"unhealthy" ch:"-"` // 只读、会话过期或延迟超过阈值
+}
+
+// ClickHouseDisk 磁盘空间（来自system.disks）
+type ClickHouseDisk struct {
+	Name        string  `json:"name" ch:"name"`
+	Path        string  `json:"path" ch:"path"`
+	FreeBytes   int64   `json:"free_bytes" ch:"free_space"`
+	TotalBytes  int64   `json:"total_bytes" ch:"total_space"`
+	FreePercent float64 `json:"free_percent" ch:"free_percent"`
+	LowSpace    bool    `json:"low_space" ch:"-"`
+}
+
+// GetMerges 获取正在进行的merge
+func (s *ClickHouseHealthService) GetMerges(ctx context.Context, name string) ([]ClickHouseMerge, error) {
+	conn, err := s.dbManager.GetClickHouseConnection(name)
+	if err != nil {
+		return nil, err
+	}
+	return queryClickHouseMerges(ctx, conn, s.dbManager.GetClickHouseDatabase(name))
+}
+
+// GetMutations 获取未完成的mutation
+func (s *ClickHouseHealthService) GetMutations(ctx context.Context, name string) ([]ClickHouseMutation, error) {
+	conn, err := s.dbManager.GetClickHouseConnection(name)
+	if err != nil {
+		return nil, err
+	}
+	return queryClickHouseMutations(ctx, conn, s.dbManager.GetClickHouseDatabase(name))
+}
+
+// GetParts 获取各表活跃part数量和too-many-parts风险
+func (s *ClickHouseHealthService) GetParts(ctx context.Context, name string) ([]ClickHouseTableParts, error) {
+	conn, err := s.dbManager.GetClickHouseConnection(name)
+	if err != nil {
+		return nil, err
+	}
+	return queryClickHouseParts(ctx, conn, s.dbManager.GetClickHouseDatabase(name))
+}
+
+// GetReplicas 获取复制表状态
+func (s *ClickHouseHealthService) GetReplicas(ctx context.Context, name string) ([]ClickHouseReplica, error) {
+	conn, err := s.dbManager.GetClickHouseConnection(name)
+	if err != nil {
+		return nil, err
+	}
+	return queryClickHouseReplicas(ctx, conn, s.dbManager.GetClickHouseDatabase(name))
+}
+
+// GetDisks 获取磁盘空间
+func (s *ClickHouseHealthService) GetDisks(ctx context.Context, name string) ([]ClickHouseDisk, error) {
+	conn, err := s.dbManager.GetClickHouseConnection(name)
+	if err != nil {
+		return nil, err
+	}
+	return queryClickHouseDisks(ctx, conn)
+}
+
+// queryClickHouseMerges 查询指定数据库正在进行的merge
+func queryClickHouseMerges(ctx context.Context, conn clickhouse.Conn, database string) ([]ClickHouseMerge, error) {
+	merges := []ClickHouseMerge{}
+	err := conn.Select(ctx, &merges, `
+		SELECT
+			table,
+			elapsed,
+			progress,
+			toInt64(num_parts) AS num_parts,
+			result_part_name,
+			toInt64(total_size_bytes_compressed) AS total_size_bytes,
+			toInt64(memory_usage) AS memory_usage,
+			toBool(is_mutation) AS is_mutation
+		FROM system.merges
+		WHERE database = ?
+		ORDER BY elapsed DESC
+	`, database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system.merges: %w", err)
+	}
+	return merges, nil
+}
+
+// queryClickHouseMutations 查询指定数据库未完成的mutation，并标记卡住的mutation
+func queryClickHouseMutations(ctx context.Context, conn clickhouse.Conn, database string) ([]ClickHouseMutation, error) {
+	mutations := []ClickHouseMutation{}
+	err := conn.Select(ctx, &mutations, `
+		SELECT
+			table,
+			mutation_id,
+			command,
+			create_time,
+			toFloat64(now() - create_time) AS age_seconds,
+			parts_to_do,
+			latest_failed_part,
+			latest_fail_reason
+		FROM system.mutations
+		WHERE database = ? AND is_done = 0
+		ORDER BY create_time
+	`, database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system.mutations: %w", err)
+	}
+
+	for i := range mutations {
+		mutations[i].Stuck = mutations[i].LatestFailReason != "" ||
+			mutations[i].AgeSeconds > stuckMutationMinutes*60
+	}
+	return mutations, nil
+}
+
+// queryClickHouseParts 查询指定数据库各表活跃part数量，按单分区最大part数排序
+func queryClickHouseParts(ctx context.Context, conn clickhouse.Conn, database string) ([]ClickHouseTableParts, error) {
+	parts := []ClickHouseTableParts{}
+	err := conn.Select(ctx, &parts, `
+		SELECT
+			table,
+			toInt64(sum(parts)) AS active_parts,
+			toInt64(count()) AS partitions,
+			toInt64(max(parts)) AS max_parts_in_partition
+		FROM (
+			SELECT table, partition_id, count() AS parts
+			FROM system.parts
+			WHERE database = ? AND active = 1
+			GROUP BY table, partition_id
+		)
+		GROUP BY table
+		ORDER BY max_parts_in_partition DESC
+	`, database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system.parts: %w", err)
+	}
+
+	delayInsert, throwInsert := queryClickHousePartsLimits(ctx, conn)
+	for i := range parts {
+		parts[i].PartsToDelayInsert = delayInsert
+		parts[i].PartsToThrowInsert = throwInsert
+		switch {
+		case parts[i].MaxPartsInPartition >= delayInsert:
+			parts[i].TooManyPartsRisk = "critical"
+		case parts[i].MaxPartsInPartition >= delayInsert/2:
+			parts[i].TooManyPartsRisk = "warning"
+		default:
+			parts[i].TooManyPartsRisk = "none"
+		}
+	}
+	return parts, nil
+}
+
+// queryClickHousePartsLimits 读取parts_to_delay_insert和parts_to_throw_insert设置
+func queryClickHousePartsLimits(ctx context.Context, conn clickhouse.Conn) (int64, int64) {
+	delayInsert, throwInsert := int64(defaultPartsToDelayInsert), int64(defaultPartsToThrowInsert)
+
+	var settings []struct {
+		Name  string `ch:"name"`
+		Value string `ch:"value"`
+	}
+	err := conn.Select(ctx, &settings, `
+		SELECT name, value
+		FROM system.merge_tree_settings
+		WHERE name IN ('parts_to_delay_insert', 'parts_to_throw_insert')
+	`)
+	if err != nil {
+		return delayInsert, throwInsert
+	}
+
+	for _, setting := range settings {
+		value, err := strconv.ParseInt(setting.Value, 10, 64)
+		if err != nil || value <= 0 {
+			continue
+		}
+		if setting.Name == "parts_to_delay_insert" {
+			delayInsert = value
+		} else {
+			throwInsert = value
+		}
+	}
+	return delayInsert, throwInsert
+}
+
+// queryClickHouseReplicas 查询指定数据库复制表状态
+func queryClickHouseReplicas(ctx context.Context, conn clickhouse.Conn, database string) ([]ClickHouseReplica, error) {
+	replicas := []ClickHouseReplica{}
+	err := conn.Select(ctx, &replicas, `
+		SELECT
+			table,
+			replica_name,
+			toBool(is_readonly) AS is_readonly,
+			toBool(is_session_expired) AS is_session_expired,
+			toInt64(queue_size) AS queue_size,
+			toInt64(inserts_in_queue) AS inserts_in_queue,
+			toInt64(merges_in_queue) AS merges_in_queue,
+			toInt64(absolute_delay) AS absolute_delay,
+			toInt64(total_replicas) AS total_replicas,
+			toInt64(active_replicas) AS active_replicas
+		FROM system.replicas
+		WHERE database = ?
+		ORDER BY absolute_delay DESC
+	`, database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system.replicas: %w", err)
+	}
+
+	for i := range replicas {
+		replicas[i].Unhealthy = replicas[i].IsReadonly || replicas[i].IsSessionExpired ||
+			replicas[i].AbsoluteDelay > replicaDelayWarningSeconds
+	}
+	return replicas, nil
+}
+
+// queryClickHouseDisks 查询磁盘空间
+func queryClickHouseDisks(ctx context.Context, conn clickhouse.Conn) ([]ClickHouseDisk, error) {
+	disks := []ClickHouseDisk{}
+	err := conn.Select(ctx, &disks, `
+		SELECT
+			name,
+			path,
+			toInt64(free_space) AS free_space,
+			toInt64(total_space) AS total_space,
+			if(total_space = 0, 0, free_space / total_space * 100) AS free_percent
+		FROM system.disks
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system.disks: %w", err)
+	}
+
+	for i := range disks {
+		disks[i].LowSpace = disks[i].TotalBytes > 0 && disks[i].FreePercent < diskFreePercentWarning
+	}
+	return disks, nil
+}
+
+// collectClickHouseHealth 采集ClickHouse merge、mutation、part、副本和磁盘指标
+func (dc *DataCollector) collectClickHouseHealth(ctx context.Context, dbName string, conn clickhouse.Conn) error {
+	database := dc.dbManager.GetClickHouseDatabase(dbName)
+	now := time.Now()
+	newMetric := func(metricType, metricName string, value float64, unit string, tags map[string]interface{}) models.ResourceMetric {
+		metric := models.ResourceMetric{
+			DatabaseType: "clickhouse",
+			DatabaseName: dbName,
+			MetricType:   metricType,
+			MetricName:   metricName,
+			MetricValue:  value,
+			Unit:         unit,
+			CollectedAt:  now,
+		}
+		if tags != nil {
+			metric.Tags = dc.formatTags(tags)
+		}
+		return metric
+	}
+
+	var metrics []models.ResourceMetric
+
+	// merge
+	if merges, err := queryClickHouseMerges(ctx, conn, database); err != nil {
+		log.Printf("Error collecting ClickHouse merges for %s: %v", dbName, err)
+	} else {
+		maxElapsed := 0.0
+		for _, merge := range merges {
+			if merge.ElapsedSeconds > maxElapsed {
+				maxElapsed = merge.ElapsedSeconds
+			}
+		}
+		metrics = append(metrics,
+			newMetric("merge", "active_merges", float64(len(merges)), "count", nil),
+			newMetric("merge", "longest_merge_seconds", maxElapsed, "seconds", nil),
+		)
+	}
+
+	// mutation
+	if mutations, err := queryClickHouseMutations(ctx, conn, database); err != nil {
+		log.Printf("Error collecting ClickHouse mutations for %s: %v", dbName, err)
+	} else {
+		stuck := 0
+		for _, mutation := range mutations {
+			if mutation.Stuck {
+				stuck++
+			}
+		}
+		metrics = append(metrics,
+			newMetric("mutation", "pending_mutations", float64(len(mutations)), "count", nil),
+			newMetric("mutation", "stuck_mutations", float64(stuck), "count", nil),
+		)
+	}
+
+	// part
+	if parts, err := queryClickHouseParts(ctx, conn, database); err != nil {
+		log.Printf("Error collecting ClickHouse parts for %s: %v", dbName, err)
+	} else {
+		maxParts := int64(0)
+		for i, table := range parts {
+			if table.MaxPartsInPartition > maxParts {
+				maxParts = table.MaxPartsInPartition
+			}
+			if i >= clickHouseHealthTopTableSize {
+				continue
+			}
+			tags := map[string]interface{}{"table_name": table.Table, "risk": table.TooManyPartsRisk}
+			metrics = append(metrics,
+				newMetric("parts", "active_parts_"+sanitizeMetricName(table.Table), float64(table.ActiveParts), "count", tags),
+				newMetric("parts", "max_parts_in_partition_"+sanitizeMetricName(table.Table), float64(table.MaxPartsInPartition), "count", tags),
+			)
+		}
+		metrics = append(metrics, newMetric("parts", "max_parts_in_partition", float64(maxParts), "count", nil))
+	}
+
+	// 副本
+	if replicas, err := queryClickHouseReplicas(ctx, conn, database); err != nil {
+		log.Printf("Error collecting ClickHouse replicas for %s: %v", dbName, err)
+	} else {
+		readonly := 0
+		maxDelay, totalQueue := int64(0), int64(0)
+		for i, replica := range replicas {
+			if replica.IsReadonly {
+				readonly++
+			}
+			if replica.AbsoluteDelay > maxDelay {
+				maxDelay = replica.AbsoluteDelay
+			}
+			totalQueue += replica.QueueSize
+			if i >= clickHouseHealthTopTableSize {
+				continue
+			}
+			tags := map[string]interface{}{"table_name": replica.Table, "replica": replica.ReplicaName}
+			metrics = append(metrics,
+				newMetric("replication", "replica_queue_size_"+sanitizeMetricName(replica.Table), float64(replica.QueueSize), "count", tags),
+				newMetric("replication", "replica_absolute_delay_"+sanitizeMetricName(replica.Table), float64(replica.AbsoluteDelay), "seconds", tags),
+			)
+		}
+		metrics = append(metrics,
+			newMetric("replication", "readonly_replicas", float64(readonly), "count", nil),
+			newMetric("replication", "max_replica_delay_seconds", float64(maxDelay), "seconds", nil),
+			newMetric("replication", "replica_queue_size", float64(totalQueue), "count", nil),
+		)
+	}
+
+	// 磁盘
+	if disks, err := queryClickHouseDisks(ctx, conn); err != nil {
+		log.Printf("Error collecting ClickHouse disks for %s: %v", dbName, err)
+	} else {
+		for _, disk := range disks {
+			tags := map[string]interface{}{"disk": disk.Name, "path": disk.Path}
+			metrics = append(metrics,
+				newMetric("disk", "disk_free_percent_"+sanitizeMetricName(disk.Name), disk.FreePercent, "percent", tags),
+				newMetric("disk", "disk_free_mb_"+sanitizeMetricName(disk.Name), float64(disk.FreeBytes)/(1024*1024), "MB", tags),
+			)
+		}
+	}
+
+	if len(metrics) == 0 {
+		return nil
+	}
+	return dc.dbManager.SaasMonitorDB.Create(metrics).Error
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// 查询分析分组维度
+const (
+	QueryGroupByUser         = "user"
+	QueryGroupByQuery        = "query"
+	QueryGroupByOrganization = "organization"
+)
+
+// clickHouseOrganizationExpr 从log_comment（JSON或organization_id=xxx）或自定义设置SQL_organization_id中提取组织ID
+const clickHouseOrganizationExpr = `coalesce(
+	nullIf(if(isValidJSON(log_comment), JSONExtractString(log_comment, 'organization_id'), ''), ''),
+	nullIf(extract(log_comment, 'organization_id=([0-9a-zA-Z-]+)'), ''),
+	nullIf(trim(BOTH '\'' FROM Settings['SQL_organization_id']), ''),
+	'')`
+
+// clickHouseQueryGroupExprs 分组维度对应的分组表达式
+var clickHouseQueryGroupExprs = map[string]string{
+	QueryGroupByUser:         "user",
+	QueryGroupByQuery:        "toString(normalized_query_hash)",
+	QueryGroupByOrganization: clickHouseOrganizationExpr,
+}
+
+// ClickHouseQueryAnalyticsService ClickHouse查询分析服务（基于system.query_log）
+type ClickHouseQueryAnalyticsService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewClickHouseQueryAnalyticsService(dbManager *database.DatabaseManager) *ClickHouseQueryAnalyticsService {
+	return &ClickHouseQueryAnalyticsService{
+		dbManager: dbManager,
+	}
+}
+
+// QueryAnalyticsFilter 查询分析过滤条件
+type QueryAnalyticsFilter struct {
+	GroupBy        string        // user, query, organization
+	Window         time.Duration // 统计时间窗口
+	User           string
+	OrganizationID string
+	Limit          int
+}
+
+// QueryGroupStat 分组查询统计
+type QueryGroupStat struct {
+	GroupKey      string  `json:"group_key" ch:"group_key"`
+	SampleQuery   string  `json:"sample_query,omitempty" ch:"sample_query"` // 仅按query分组时返回规范化后的查询
+	QueryCount    int64   `json:"query_count" ch:"query_count"`
+	FailedCount   int64   `json:"failed_count" ch:"failed_count"`
+	P50DurationMs float64 `json:"p50_duration_ms" ch:"p50_duration_ms"`
+	P95DurationMs float64 `json:"p95_duration_ms" ch:"p95_duration_ms"`
+	P99DurationMs float64 `json:"p99_duration_ms" ch:"p99_duration_ms"`
+	TotalDuration float64 `json:"total_duration_ms" ch:"total_duration_ms"`
+	ReadRows      int64   `json:"read_rows" ch:"read_rows"`
+	ReadBytes     int64   `json:"read_bytes" ch:"read_bytes"`
+	MaxMemory     int64   `json:"max_memory_bytes" ch:"max_memory_bytes"`
+}
+
+// FailedQueryStat 失败查询按异常码统计
+type FailedQueryStat struct {
+	ExceptionCode   int64     `json:"exception_code" ch:"exception_code"`
+	FailedCount     int64     `json:"failed_count" ch:"failed_count"`
+	Users           []string  `json:"users" ch:"users"`
+	SampleException string    `json:"sample_exception" ch:"sample_exception"`
+	SampleQuery     string    `json:"sample_query" ch:"sample_query"`
+	LastSeen        time.Time `json:"last_seen" ch:"last_seen"`
+}
+
+// GetQueryAnalytics 获取按用户、规范化查询或组织分组的查询统计
+func (s *ClickHouseQueryAnalyticsService) GetQueryAnalytics(ctx context.Context, name string, filter QueryAnalyticsFilter) ([]QueryGroupStat, error) {
+	conn, err := s.dbManager.GetClickHouseConnection(name)
+	if err != nil {
+		return nil, err
+	}
+	return queryClickHouseQueryGroups(ctx, conn, s.dbManager.GetClickHouseDatabase(name), filter)
+}
+
+// GetFailedQueries 获取按异常码分组的失败查询
+func (s *ClickHouseQueryAnalyticsService) GetFailedQueries(ctx context.Context, name string, window time.Duration, limit int) ([]FailedQueryStat, error) {
+	conn, err := s.dbManager.GetClickHouseConnection(name)
+	if err != nil {
+		return nil, err
+	}
+	return queryClickHouseFailedQueries(ctx, conn, s.dbManager.GetClickHouseDatabase(name), window, limit)
+}
+
+// queryClickHouseQueryGroups 按分组维度统计query_log
+func queryClickHouseQueryGroups(ctx context.Context, conn clickhouse.Conn, database string, filter QueryAnalyticsFilter) ([]QueryGroupStat, error) {
+	if filter.GroupBy == "" {
+		filter.GroupBy = QueryGroupByUser
+	}
+	groupExpr, ok := clickHouseQueryGroupExprs[filter.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported group_by: %s", filter.GroupBy)
+	}
+	if filter.Window <= 0 {
+		filter.Window = time.Hour
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	sampleExpr := "''"
+	if filter.GroupBy == QueryGroupByQuery {
+		sampleExpr = "any(normalizeQuery(query))"
+	}
+
+	since := time.Now().Add(-filter.Window)
+	conditions := `
+		event_date >= toDate(?)
+		AND event_time >= ?
+		AND type != 'QueryStart'
+		AND (current_database = ? OR has(databases, ?))`
+	args := []interface{}{since, since, database, database}
+
+	if filter.User != "" {
+		conditions += " AND user = ?"
+		args = append(args, filter.User)
+	}
+	if filter.OrganizationID != "" {
+		conditions += " AND " + clickHouseOrganizationExpr + " = ?"
+		args = append(args, filter.OrganizationID)
+	}
+	args = append(args, filter.Limit)
+
+	query := fmt.Sprintf(`
+		SELECT
+			%s AS group_key,
+			%s AS sample_query,
+			toInt64(count()) AS query_count,
+			toInt64(countIf(type IN ('ExceptionBeforeStart', 'ExceptionWhileProcessing'))) AS failed_count,
+			quantile(0.5)(query_duration_ms) AS p50_duration_ms,
+			quantile(0.95)(query_duration_ms) AS p95_duration_ms,
+			quantile(0.99)(query_duration_ms) AS p99_duration_ms,
+			toFloat64(sum(query_duration_ms)) AS total_duration_ms,
+			toInt64(sum(read_rows)) AS read_rows,
+			toInt64(sum(read_bytes)) AS read_bytes,
+			toInt64(max(memory_usage)) AS max_memory_bytes
+		FROM system.query_log
+		WHERE %s
+		GROUP BY group_key
+		ORDER BY total_duration_ms DESC
+		LIMIT ?
+	`, groupExpr, sampleExpr, conditions)
+
+	stats := []QueryGroupStat{}
+	if err := conn.Select(ctx, &stats, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to query system.query_log: %w", err)
+	}
+	return stats, nil
+}
+
+// queryClickHouseFailedQueries 按异常码统计失败查询
+func queryClickHouseFailedQueries(ctx context.Context, conn clickhouse.Conn, database string, window time.Duration, limit int) ([]FailedQueryStat, error) {
+	if window <= 0 {
+		window = time.Hour
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	since := time.Now().Add(-window)
+
+	stats := []FailedQueryStat{}
+	err := conn.Select(ctx, &stats, `
+		SELECT
+			toInt64(exception_code) AS exception_code,
+			toInt64(count()) AS failed_count,
+			groupUniqArray(10)(user) AS users,
+			any(exception) AS sample_exception,
+			any(normalizeQuery(query)) AS sample_query,
+			max(event_time) AS last_seen
+		FROM system.query_log
+		WHERE event_date >= toDate(?)
+			AND event_time >= ?
+			AND type IN ('ExceptionBeforeStart', 'ExceptionWhileProcessing')
+			AND (current_database = ? OR has(databases, ?))
+		GROUP BY exception_code
+		ORDER BY failed_count DESC
+		LIMIT ?
+	`, since, since, database, database, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed queries from system.query_log: %w", err)
+	}
+	return stats, nil
+}
+
+// collectClickHouseQueryAnalytics 采集按用户和组织分组的查询统计及失败查询。
+// 指标是截至采集时间最近1小时的滑动窗口统计（gauge），相邻采集的窗口互相重叠，
+// 只能取某一时刻的值，不能按时间累加；window_end标签记录窗口结束时间
+func (dc *DataCollector) collectClickHouseQueryAnalytics(ctx context.Context, dbName string, conn clickhouse.Conn) error {
+	database := dc.dbManager.GetClickHouseDatabase(dbName)
+	now := time.Now()
+	window := time.Hour
+	windowEnd := now.UTC().Format(time.RFC3339)
+
+	var metrics []models.ResourceMetric
+	newMetric := func(metricName string, value float64, unit string, tags map[string]interface{}) models.ResourceMetric {
+		return models.ResourceMetric{
+			DatabaseType: "clickhouse",
+			DatabaseName: dbName,
+			MetricType:   "query_analytics",
+			MetricName:   metricName,
+			MetricValue:  value,
+			Unit:         unit,
+			Tags:         dc.formatTags(tags),
+			CollectedAt:  now,
+		}
+	}
+
+	for _, groupBy := range []string{QueryGroupByUser, QueryGroupByOrganization} {
+		stats, err := queryClickHouseQueryGroups(ctx, conn, database, QueryAnalyticsFilter{
+			GroupBy: groupBy,
+			Window:  window,
+			Limit:   20,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, stat := range stats {
+			// 未标记组织的查询不单独记录组织维度指标
+			if groupBy == QueryGroupByOrganization && stat.GroupKey == "" {
+				continue
+			}
+			suffix := groupBy + "_" + sanitizeMetricName(stat.GroupKey)
+			tags := map[string]interface{}{groupBy: stat.GroupKey, "window": "1h", "window_end": windowEnd}
+			metrics = append(metrics,
+				newMetric("queries_1h_"+suffix, float64(stat.QueryCount), "count", tags),
+				newMetric("failed_queries_1h_"+suffix, float64(stat.FailedCount), "count", tags),
+				newMetric("p95_duration_ms_1h_"+suffix, stat.P95DurationMs, "ms", tags),
+				newMetric("p99_duration_ms_1h_"+suffix, stat.P99DurationMs, "ms", tags),
+				newMetric("read_mb_1h_"+suffix, float64(stat.ReadBytes)/(1024*1024), "MB", tags),
+				newMetric("max_memory_mb_1h_"+suffix, float64(stat.MaxMemory)/(1024*1024), "MB", tags),
+			)
+		}
+	}
+
+	failed, err := queryClickHouseFailedQueries(ctx, conn, database, window, 20)
+	if err != nil {
+		return err
+	}
+	for _, stat := range failed {
+		tags := map[string]interface{}{"exception_code": stat.ExceptionCode, "window": "1h", "window_end": windowEnd}
+		metrics = append(metrics, newMetric(fmt.Sprintf("failed_queries_1h_code_%d", stat.ExceptionCode), float64(stat.FailedCount), "count", tags))
+	}
+
+	if len(metrics) == 0 {
+		return nil
+	}
+	return dc.dbManager.SaasMonitorDB.Create(metrics).Error
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"sass-monitor/internal/database"
+)
+
+// MaxCohortMonths 留存分析最多统计的月数
+const MaxCohortMonths = 36
+
+// CohortService 按组织注册月份（auth_organizations.created_at）和首次付费月份分组的留存分析，
+// 以及按月的logo流失率、收入流失率和净收入留存（NRR）
+type CohortService struct {
+	dbManager      *database.DatabaseManager
+	revenueService *RevenueService
+	cache          *analyticsCache
+}
+
+func NewCohortService(dbManager *database.DatabaseManager, revenueService *RevenueService) *CohortService {
+	return &CohortService{
+		dbManager:      dbManager,
+		revenueService: revenueService,
+		cache:          newAnalyticsCache(dbManager.Config.Monitoring.Analytics.CacheTTLSeconds),
+	}
+}
+
+// CohortRetention 注册后第MonthOffset个月月末（当月为当前时间）的留存
+type CohortRetention struct {
+	MonthOffset         int     `json:"month_offset"`
+	ActiveOrganizations int     `json:"active_organizations"` // 有有效订阅（含试用）的组织
+	ActiveRate          float64 `json:"active_rate"`          // 百分比
+	PayingOrganizations int     `json:"paying_organizations"` // 有付费订阅的组织
+	PayingRate          float64 `json:"paying_rate"`          // 百分比
+	MRR                 float64 `json:"mrr"`
+}
+
+// OrganizationCohort 同一月份注册的组织
+type OrganizationCohort struct {
+	Cohort        string            `json:"cohort"` // YYYY-MM
+	Organizations int               `json:"organizations"`
+	Retention     []CohortRetention `json:"retention"`
+}
+
+// PaidCohortRetention 首次付费后第MonthOffset个月月末（当月为当前时间）的付费留存
+type PaidCohortRetention struct {
+	MonthOffset         int     `json:"month_offset"`
+	PayingOrganizations int     `json:"paying_organizations"`
+	LogoRetention       float64 `json:"logo_retention"` // 百分比
+	MRR                 float64 `json:"mrr"`
+	RevenueRetention    float64 `json:"revenue_retention"` // MRR / 首次付费时的MRR，百分比
+}
+
+// PaidCohort 同一月份首次付费（试用期结束后开始计费）的组织
+type PaidCohort struct {
+	Cohort        string                `json:"cohort"` // YYYY-MM
+	Organizations int                   `json:"organizations"`
+	StartingMRR   float64               `json:"starting_mrr"` // 各组织首次付费时的MRR之和
+	Retention     []PaidCohortRetention `json:"retention"`
+}
+
+// ChurnRate 单月的流失和留存率（百分比），以月初付费组织和MRR为基数
+type ChurnRate struct {
+	Month                 string  `json:"month"` // YYYY-MM
+	StartingOrganizations int     `json:"starting_organizations"`
+	ChurnedOrganizations  int     `json:"churned_organizations"`
+	LogoChurnRate         float64 `json:"logo_churn_rate"`
+	StartingMRR           float64 `json:"starting_mrr"`
+	ChurnedMRR            float64 `json:"churned_mrr"`
+	ContractionMRR        float64 `json:"contraction_mrr"`
+	ExpansionMRR          float64 `json:"expansion_mrr"`
+	RevenueChurnRate      float64 `json:"revenue_churn_rate"`    // (churned + contraction) / starting
+	NetRevenueRetention   float64 `json:"net_revenue_retention"` // (starting + expansion - contraction - churned) / starting
+}
+
+// CohortReport 留存和流失分析结果，ExpiresAt为缓存过期时间（未缓存时为空）
+type CohortReport struct {
+	Months                   int                  `json:"months"`
+	Cohorts                  []OrganizationCohort `json:"cohorts"`      // 按注册月份
+	PaidCohorts              []PaidCohort         `json:"paid_cohorts"` // 按首次付费月份
+	Churn                    []ChurnRate          `json:"churn"`
+	NetRevenueRetention12M   float64              `json:"net_revenue_retention_12m"`   // 12个月前付费组织当前MRR / 当时MRR
+	GrossRevenueRetention12M float64              `json:"gross_revenue_retention_12m"` // 同上，但每个组织最多计入当时的MRR
+	GeneratedAt              time.Time            `json:"generated_at"`
+	ExpiresAt                *time.Time           `json:"expires_at,omitempty"`
+}
+
+// GetReport 获取最近months个月（含当月）的留存和流失分析，refresh为true时忽略缓存重新计算
+func (s *CohortService) GetReport(ctx context.Context, months int, refresh bool) (*CohortReport, error) {
+	if months <= 0 || months > MaxCohortMonths {
+		return nil, fmt.Errorf("months must be between 1 and %d", MaxCohortMonths)
+	}
+
+	// 缓存键包含源数据版本，订阅或组织变化后（包括其他副本或外部系统写入）重新计算
+	version, err := analyticsDataVersion(ctx, s.dbManager)
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := fmt.Sprintf("cohorts:%d:%s", months, version)
+	if !refresh {
+		if cached, ok := s.cache.get(cacheKey); ok {
+			return cached.(*CohortReport), nil
+		}
+	}
+
+	now := time.Now()
+	firstCohort := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -(months - 1), 0)
+
+	var organizations []struct {
+		ID        string
+		CreatedAt time.Time
+	}
+	if err := s.dbManager.LightAdminDB.WithContext(ctx).Table("auth_organizations").
+		Select("id::text AS id, created_at").
+		Where("created_at >= ?", firstCohort).
+		Scan(&organizations).Error; err != nil {
+		return nil, fmt.Errorf("failed to query organizations: %w", err)
+	}
+
+	subscriptions, err := s.revenueService.loadSubscriptions(ctx, "", true)
+	if err != nil {
+		return nil, err
+	}
+	subscriptionsByOrg := make(map[string][]revenueSubscription)
+	for _, subscription := range subscriptions {
+		subscriptionsByOrg[subscription.OrganizationID] = append(subscriptionsByOrg[subscription.OrganizationID], subscription)
+	}
+
+	cohortMembers := make(map[string][]string)
+	for _, organization := range organizations {
+		cohort := organization.CreatedAt.In(time.Local).Format("2006-01")
+		cohortMembers[cohort] = append(cohortMembers[cohort], organization.ID)
+	}
+
+	report := &CohortReport{
+		Months:      months,
+		Cohorts:     make([]OrganizationCohort, 0, months),
+		PaidCohorts: paidCohorts(subscriptionsByOrg, firstCohort, months, now),
+		Churn:       make([]ChurnRate, 0, months),
+		GeneratedAt: now,
+	}
+	for i := 0; i < months; i++ {
+		cohortStart := firstCohort.AddDate(0, i, 0)
+		members := cohortMembers[cohortStart.Format("2006-01")]
+		cohort := OrganizationCohort{
+			Cohort:        cohortStart.Format("2006-01"),
+			Organizations: len(members),
+			Retention:     []CohortRetention{},
+		}
+		for offset, at := range cohortCheckpoints(cohortStart, now) {
+			cohort.Retention = append(cohort.Retention, cohortRetentionAt(offset, members, subscriptionsByOrg, at))
+		}
+		report.Cohorts = append(report.Cohorts, cohort)
+	}
+
+	for _, movement := range revenueMovements(subscriptions, months, now) {
+		report.Churn = append(report.Churn, ChurnRate{
+			Month:                 movement.Month,
+			StartingOrganizations: movement.StartingOrganizations,
+			ChurnedOrganizations:  movement.ChurnedOrganizations,
+			LogoChurnRate:         percentage(float64(movement.ChurnedOrganizations), float64(movement.StartingOrganizations)),
+			StartingMRR:           movement.StartingMRR,
+			ChurnedMRR:            movement.ChurnedMRR,
+			ContractionMRR:        movement.ContractionMRR,
+			ExpansionMRR:          movement.ExpansionMRR,
+			RevenueChurnRate:      percentage(movement.ChurnedMRR+movement.ContractionMRR, movement.StartingMRR),
+			NetRevenueRetention:   percentage(movement.StartingMRR+movement.ExpansionMRR-movement.ContractionMRR-movement.ChurnedMRR, movement.StartingMRR),
+		})
+	}
+
+	// 12个月净收入留存和总收入留存，只统计12个月前已付费的组织
+	baseline := organizationMRRAt(subscriptions, now.AddDate(-1, 0, 0))
+	current := organizationMRRAt(subscriptions, now)
+	var baselineMRR, retainedMRR, grossRetainedMRR float64
+	for orgID, before := range baseline {
+		after := current[orgID]
+		baselineMRR += before
+		retainedMRR += after
+		grossRetainedMRR += math.Min(before, after)
+	}
+	report.NetRevenueRetention12M = percentage(retainedMRR, baselineMRR)
+	report.GrossRevenueRetention12M = percentage(grossRetainedMRR, baselineMRR)
+
+	report.ExpiresAt = s.cache.expiry()
+	s.cache.set(cacheKey, report, report.ExpiresAt)
+	return report, nil
+}
+
+// cohortCheckpoints 从cohortStart所在月起每个月月末的统计时间，当月为now；
+// 只包含在now之前开始的月份
+func cohortCheckpoints(cohortStart, now time.Time) []time.Time {
+	var checkpoints []time.Time
+	for offset := 0; cohortStart.AddDate(0, offset, 0).Before(now); offset++ {
+		at := cohortStart.AddDate(0, offset+1, 0)
+		if at.After(now) {
+			at = now
+		}
+		checkpoints = append(checkpoints, at)
+	}
+	return checkpoints
+}
+
+// paidCohorts 按首次付费月份统计firstCohort起months个月的付费留存：组织的首次付费时间为最早的付费订阅
+// 开始计费的时间（试用期结束后），流失后重新付费的组织仍属于最初的月份
+func paidCohorts(subscriptionsByOrg map[string][]revenueSubscription, firstCohort time.Time, months int, now time.Time) []PaidCohort {
+	members := make(map[string][]string)
+	startingMRR := make(map[string]float64)
+	for orgID, subscriptions := range subscriptionsByOrg {
+		var firstPaid time.Time
+		for _, subscription := range subscriptions {
+			at := subscription.paidFrom()
+			if subscription.mrr() <= 0 || !subscription.payingAt(at) {
+				continue
+			}
+			if firstPaid.IsZero() || at.Before(firstPaid) {
+				firstPaid = at
+			}
+		}
+		if firstPaid.IsZero() || firstPaid.Before(firstCohort) || firstPaid.After(now) {
+			continue
+		}
+		cohort := firstPaid.In(time.Local).Format("2006-01")
+		members[cohort] = append(members[cohort], orgID)
+		startingMRR[cohort] += organizationMRRAt(subscriptions, firstPaid)[orgID]
+	}
+
+	cohorts := make([]PaidCohort, 0, months)
+	for i := 0; i < months; i++ {
+		cohortStart := firstCohort.AddDate(0, i, 0)
+		month := cohortStart.Format("2006-01")
+		cohort := PaidCohort{
+			Cohort:        month,
+			Organizations: len(members[month]),
+			StartingMRR:   roundMoney(startingMRR[month]),
+			Retention:     []PaidCohortRetention{},
+		}
+		for offset, at := range cohortCheckpoints(cohortStart, now) {
+			retention := cohortRetentionAt(offset, members[month], subscriptionsByOrg, at)
+			cohort.Retention = append(cohort.Retention, PaidCohortRetention{
+				MonthOffset:         offset,
+				PayingOrganizations: retention.PayingOrganizations,
+				LogoRetention:       retention.PayingRate,
+				MRR:                 retention.MRR,
+				RevenueRetention:    percentage(retention.MRR, cohort.StartingMRR),
+			})
+		}
+		cohorts = append(cohorts, cohort)
+	}
+	return cohorts
+}
+
+// cohortRetentionAt 统计一组组织在at时刻的留存
+func cohortRetentionAt(offset int, members []string, subscriptionsByOrg map[string][]revenueSubscription, at time.Time) CohortRetention {
+	retention := CohortRetention{MonthOffset: offset}
+	for _, orgID := range members {
+		active := false
+		var mrr float64
+		for _, subscription := range subscriptionsByOrg[orgID] {
+			if subscription.liveAt(at) {
+				active = true
+			}
+			if subscription.payingAt(at) {
+				mrr += subscription.mrr()
+			}
+		}
+		if active {
+			retention.ActiveOrganizations++
+		}
+		if mrr > 0 {
+			retention.PayingOrganizations++
+			retention.MRR += mrr
+		}
+	}
+	retention.ActiveRate = percentage(float64(retention.ActiveOrganizations), float64(len(members)))
+	retention.PayingRate = percentage(float64(retention.PayingOrganizations), float64(len(members)))
+	retention.MRR = roundMoney(retention.MRR)
+	return retention
+}
+
+// percentage 百分比，保留两位小数，total为0时返回0
+func percentage(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(part/total*10000) / 100
+}
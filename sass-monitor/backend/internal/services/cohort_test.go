@@ -0,0 +1,124 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCohortCheckpoints(t *testing.T) {
+	march := revenueDate(2026, 3, 1)
+	tests := []struct {
+		name string
+		now  time.Time
+		want []time.Time
+	}{
+		{
+			name: "current month ends now",
+			now:  revenueDate(2026, 3, 15),
+			want: []time.Time{revenueDate(2026, 3, 15)},
+		},
+		{
+			name: "month starting exactly now is not included",
+			now:  revenueDate(2026, 4, 1),
+			want: []time.Time{revenueDate(2026, 4, 1)},
+		},
+		{
+			name: "completed months end at the next month start",
+			now:  revenueDate(2026, 5, 2),
+			want: []time.Time{revenueDate(2026, 4, 1), revenueDate(2026, 5, 1), revenueDate(2026, 5, 2)},
+		},
+		{
+			name: "cohort in the future",
+			now:  revenueDate(2026, 2, 20),
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cohortCheckpoints(march, tt.now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cohortCheckpoints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCohortRetentionAtBoundaries(t *testing.T) {
+	monthEnd := revenueDate(2026, 4, 1)
+	justAfter := monthEnd.Add(time.Second)
+	subscriptionsByOrg := map[string][]revenueSubscription{
+		// 在统计时间结束的订阅不计入
+		"ends_at_checkpoint": {{OrganizationID: "ends_at_checkpoint", Status: "cancelled", BillingCycle: "monthly", PricingMonthly: 10, StartDate: revenueDate(2026, 3, 1), EndDate: &monthEnd}},
+		"ends_after":         {{OrganizationID: "ends_after", Status: "cancelled", BillingCycle: "monthly", PricingMonthly: 20, StartDate: revenueDate(2026, 3, 1), EndDate: &justAfter}},
+		// 在统计时间开始的订阅计入
+		"starts_at_checkpoint": {{OrganizationID: "starts_at_checkpoint", Status: "active", BillingCycle: "monthly", PricingMonthly: 30, StartDate: monthEnd}},
+		// 试用中：有效但不付费
+		"trialing": {{OrganizationID: "trialing", Status: "trial", BillingCycle: "monthly", PricingMonthly: 40, StartDate: revenueDate(2026, 3, 20)}},
+	}
+	members := []string{"ends_at_checkpoint", "ends_after", "starts_at_checkpoint", "trialing", "no_subscription"}
+
+	got := cohortRetentionAt(0, members, subscriptionsByOrg, monthEnd)
+	want := CohortRetention{
+		MonthOffset:         0,
+		ActiveOrganizations: 3,
+		ActiveRate:          60,
+		PayingOrganizations: 2,
+		PayingRate:          40,
+		MRR:                 50,
+	}
+	if got != want {
+		t.Errorf("cohortRetentionAt() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPaidCohorts(t *testing.T) {
+	aprilChurn := revenueDate(2026, 4, 15)
+	mayChurn := revenueDate(2026, 5, 1)
+	subscriptions := []revenueSubscription{
+		// 3月20日开始试用，4月3日首次付费，属于4月
+		{OrganizationID: "trial_converted", Status: "active", BillingCycle: "monthly", PricingMonthly: 100, StartDate: revenueDate(2026, 3, 20), TrialDaysUsed: 14},
+		// 4月1日零点首次付费，属于4月；5月1日零点流失，不计入4月末
+		{OrganizationID: "month_start", Status: "cancelled", BillingCycle: "yearly", PricingYearly: 600, StartDate: revenueDate(2026, 4, 1), EndDate: &mayChurn},
+		// 3月首次付费，4月流失后5月重新付费，仍属于3月
+		{OrganizationID: "returning", Status: "cancelled", BillingCycle: "monthly", PricingMonthly: 40, StartDate: revenueDate(2026, 3, 31), EndDate: &aprilChurn},
+		{OrganizationID: "returning", Status: "active", BillingCycle: "monthly", PricingMonthly: 60, StartDate: revenueDate(2026, 5, 10)},
+		// 统计范围之前首次付费的组织不属于任何队列
+		{OrganizationID: "before_window", Status: "active", BillingCycle: "monthly", PricingMonthly: 70, StartDate: revenueDate(2026, 2, 28)},
+		// 从未付费
+		{OrganizationID: "trial_only", Status: "trial", BillingCycle: "monthly", PricingMonthly: 90, StartDate: revenueDate(2026, 4, 2)},
+	}
+	subscriptionsByOrg := make(map[string][]revenueSubscription)
+	for _, subscription := range subscriptions {
+		subscriptionsByOrg[subscription.OrganizationID] = append(subscriptionsByOrg[subscription.OrganizationID], subscription)
+	}
+
+	got := paidCohorts(subscriptionsByOrg, revenueDate(2026, 3, 1), 3, revenueDate(2026, 5, 20))
+	want := []PaidCohort{
+		{
+			Cohort:        "2026-03",
+			Organizations: 1,
+			StartingMRR:   40,
+			Retention: []PaidCohortRetention{
+				{MonthOffset: 0, PayingOrganizations: 1, LogoRetention: 100, MRR: 40, RevenueRetention: 100},
+				{MonthOffset: 1, PayingOrganizations: 0, LogoRetention: 0, MRR: 0, RevenueRetention: 0},
+				{MonthOffset: 2, PayingOrganizations: 1, LogoRetention: 100, MRR: 60, RevenueRetention: 150},
+			},
+		},
+		{
+			Cohort:        "2026-04",
+			Organizations: 2,
+			StartingMRR:   150,
+			Retention: []PaidCohortRetention{
+				{MonthOffset: 0, PayingOrganizations: 1, LogoRetention: 50, MRR: 100, RevenueRetention: 66.67},
+				{MonthOffset: 1, PayingOrganizations: 1, LogoRetention: 50, MRR: 100, RevenueRetention: 66.67},
+			},
+		},
+		{
+			Cohort:    "2026-05",
+			Retention: []PaidCohortRetention{{MonthOffset: 0}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("paidCohorts() =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -2,10 +2,12 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
-	"strconv"
+	"math"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
@@ -16,45 +18,101 @@ import (
 )
 
 type DataCollector struct {
-	dbManager *database.DatabaseManager
+	dbManager      *database.DatabaseManager
+	walRate        *counterRateSampler // PostgreSQL WAL位置的生成速率
+	redisTopology  *redisTopologyTracker
+	hostCPU        *hostCPUSampler
+	mysqlQuestions *counterRateSampler // MySQL Questions计数器的每秒速率
+	mysqlDigests   *counterRateSampler // MySQL语句摘要执行次数的每秒速率
+	storageRunAt   time.Time          // 上次统计组织存储的时间
+	runMu          sync.Mutex         // 定时采集与手动触发的采集串行执行，避免采样器并发访问
 }
 
 func NewDataCollector(dbManager *database.DatabaseManager) *DataCollector {
 	return &DataCollector{
-		dbManager: dbManager,
+		dbManager:      dbManager,
+		walRate:        newCounterRateSampler(),
+		redisTopology:  newRedisTopologyTracker(),
+		hostCPU:        &hostCPUSampler{},
+		mysqlQuestions: newCounterRateSampler(),
+		mysqlDigests:   newCounterRateSampler(),
 	}
 }
 
 // CollectAllData 采集所有监控数据
 func (dc *DataCollector) CollectAllData(ctx context.Context) error {
+	dc.runMu.Lock()
+	defer dc.runMu.Unlock()
+
 	log.Println("Starting data collection...")
 
-	// 采集PostgreSQL数据
-	if err := dc.collectPostgreSQLData(ctx); err != nil {
-		log.Printf("Error collecting PostgreSQL data: %v", err)
+	storageConfig := dc.dbManager.Config.Monitoring.Storage
+	steps := []struct {
+		name    string
+		enabled bool
+		collect func(ctx context.Context) error
+	}{
+		// 采集PostgreSQL、MySQL、ClickHouse和Redis数据
+		{"PostgreSQL data", true, dc.collectPostgreSQLData},
+		{"MySQL data", true, dc.collectMySQLData},
+		{"ClickHouse data", true, dc.collectClickHouseData},
+		{"Redis data", true, dc.collectRedisData},
+		// 采集主机指标
+		{"host metrics", dc.dbManager.Config.Monitoring.Host.Enabled, dc.collectHostMetrics},
+		// 导入light_admin基础设施指标
+		{"infrastructure metrics", dc.dbManager.Config.Monitoring.Infrastructure.Enabled, dc.collectInfrastructureMetrics},
+		// 按组织统计存储量（按storage.interval降低频率）
+		{"organization storage", storageConfig.Enabled && time.Since(dc.storageRunAt) >= time.Duration(storageConfig.Interval)*time.Minute, func(ctx context.Context) error {
+			dc.storageRunAt = time.Now()
+			return dc.collectOrganizationStorage(ctx)
+		}},
+		// 采集系统健康状态
+		{"system health", true, dc.collectSystemHealth},
+	}
+	for _, step := range steps {
+		if !step.enabled {
+			continue
+		}
+		// 调度器失去leader身份时context被取消，不再写入后续指标
+		if err := ctx.Err(); err != nil {
+			log.Printf("Data collection stopped: %v", err)
+			return err
+		}
+		if err := step.collect(ctx); err != nil {
+			log.Printf("Error collecting %s: %v", step.name, err)
+		}
 	}
 
-	// 采集ClickHouse数据
-	if err := dc.collectClickHouseData(ctx); err != nil {
-		log.Printf("Error collecting ClickHouse data: %v", err)
+	log.Println("Data collection completed")
+	return nil
+}
+
+// collectPostgreSQLData 采集PostgreSQL监控数据
+func (dc *DataCollector) collectPostgreSQLData(ctx context.Context) error {
+	// light_admin统计失败不影响按监控目标采集的会话和复制状态
+	var errs []string
+	if err := dc.collectPostgreSQLStats(ctx); err != nil {
+		errs = append(errs, err.Error())
 	}
 
-	// 采集Redis数据
-	if err := dc.collectRedisData(ctx); err != nil {
-		log.Printf("Error collecting Redis data: %v", err)
+	// 获取会话、锁和长事务统计
+	if err := dc.collectPostgreSQLActivity(ctx); err != nil {
+		errs = append(errs, fmt.Sprintf("failed to collect PostgreSQL activity: %v", err))
 	}
 
-	// 采集系统健康状态
-	if err := dc.collectSystemHealth(ctx); err != nil {
-		log.Printf("Error collecting system health: %v", err)
+	// 获取流复制和WAL统计
+	if err := dc.collectPostgreSQLReplication(ctx); err != nil {
+		errs = append(errs, fmt.Sprintf("failed to collect PostgreSQL replication: %v", err))
 	}
 
-	log.Println("Data collection completed")
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
 	return nil
 }
 
-// collectPostgreSQLData 采集PostgreSQL监控数据
-func (dc *DataCollector) collectPostgreSQLData(ctx context.Context) error {
+// collectPostgreSQLStats 采集light_admin的连接、大小和业务统计
+func (dc *DataCollector) collectPostgreSQLStats(ctx context.Context) error {
 	// 获取数据库连接统计
 	if err := dc.collectPostgreSQLConnections(ctx); err != nil {
 		return fmt.Errorf("failed to collect PostgreSQL connections: %w", err)
@@ -83,6 +141,22 @@ func (dc *DataCollector) collectPostgreSQLData(ctx context.Context) error {
 	return nil
 }
 
+// collectPostgreSQLTargets 对每个PostgreSQL监控目标执行collect，单个目标失败时记录日志并继续采集其他目标
+func (dc *DataCollector) collectPostgreSQLTargets(ctx context.Context, kind string, collect func(ctx context.Context, dbName string, db *gorm.DB) error) error {
+	targets := dc.dbManager.GetPostgreSQLTargets()
+	failed := 0
+	for dbName, db := range targets {
+		if err := collect(ctx, dbName, db); err != nil {
+			log.Printf("Error collecting PostgreSQL %s for %s: %v", kind, dbName, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s collection failed for %d of %d targets", kind, failed, len(targets))
+	}
+	return nil
+}
+
 // collectPostgreSQLConnections 采集PostgreSQL连接数据
 func (dc *DataCollector) collectPostgreSQLConnections(ctx context.Context) error {
 	sqlDB, err := dc.dbManager.LightAdminDB.DB()
@@ -141,27 +215,9 @@ func (dc *DataCollector) collectPostgreSQLDatabaseSize(ctx context.Context) erro
 	return dc.dbManager.SaasMonitorDB.Create(&metric).Error
 }
 
-// collectPostgreSQLTableSize 采集PostgreSQL表大小统计
+// collectPostgreSQLTableSize 采集PostgreSQL表大小及维护健康统计
 func (dc *DataCollector) collectPostgreSQLTableSize(ctx context.Context) error {
-	var tableStats []struct {
-		TableName string  `gorm:"column:tablename"`
-		Size      float64 `gorm:"column:size_mb"`
-		RowCount  int64   `gorm:"column:row_count"`
-	}
-
-	err := dc.dbManager.LightAdminDB.Raw(`
-		SELECT
-			schemaname||'.'||tablename as tablename,
-			pg_size_pretty(pg_total_relation_size(schemaname||'.'||tablename)) as size,
-			pg_total_relation_size(schemaname||'.'||tablename) / (1024*1024) as size_mb,
-			COALESCE(n_tup_ins, 0) as row_count
-		FROM pg_tables t
-		LEFT JOIN pg_stat_user_tables s ON t.tablename = s.relname
-		WHERE schemaname = 'public'
-		ORDER BY pg_total_relation_size(schemaname||'.'||tablename) DESC
-		LIMIT 20
-	`).Scan(&tableStats).Error
-
+	tableStats, err := queryTableMaintenanceStats(dc.dbManager.LightAdminDB.WithContext(ctx), 20)
 	if err != nil {
 		return err
 	}
@@ -174,10 +230,10 @@ func (dc *DataCollector) collectPostgreSQLTableSize(ctx context.Context) error {
 			DatabaseName: "light_admin",
 			MetricType:   "storage",
 			MetricName:   fmt.Sprintf("table_size_%s", stat.TableName),
-			MetricValue:  stat.Size,
+			MetricValue:  stat.SizeMB,
 			Unit:         "MB",
 			CollectedAt:  time.Now(),
-			Tags:         dc.formatTags(map[string]interface{}{"table": stat.TableName}),
+			Tags:         dc.formatTags(map[string]interface{}{"table_name": stat.TableName}),
 		}
 
 		// 行数指标
@@ -189,16 +245,108 @@ func (dc *DataCollector) collectPostgreSQLTableSize(ctx context.Context) error {
 			MetricValue:  float64(stat.RowCount),
 			Unit:         "count",
 			CollectedAt:  time.Now(),
-			Tags:         dc.formatTags(map[string]interface{}{"table": stat.TableName}),
+			Tags:         dc.formatTags(map[string]interface{}{"table_name": stat.TableName}),
+		}
+
+		metrics := []models.ResourceMetric{sizeMetric, rowMetric}
+
+		// 维护健康指标：死元组占比、顺序扫描占比、估算膨胀、vacuum/analyze间隔
+		maintenanceValues := []struct {
+			name  string
+			value *float64
+			unit  string
+		}{
+			{"dead_tuple_ratio", &stat.DeadTupleRatio, "ratio"},
+			{"seq_scan_ratio", &stat.SeqScanRatio, "ratio"},
+			{"estimated_bloat_mb", &stat.EstimatedBloatMB, "MB"},
+			{"vacuum_age_hours", stat.VacuumAgeHours, "hours"},
+			{"analyze_age_hours", stat.AnalyzeAgeHours, "hours"},
+		}
+		for _, mv := range maintenanceValues {
+			if mv.value == nil {
+				continue
+			}
+			metrics = append(metrics, models.ResourceMetric{
+				DatabaseType: "postgresql",
+				DatabaseName: "light_admin",
+				MetricType:   "maintenance",
+				MetricName:   fmt.Sprintf("%s_%s", mv.name, stat.TableName),
+				MetricValue:  *mv.value,
+				Unit:         mv.unit,
+				CollectedAt:  time.Now(),
+				Tags:         dc.formatTags(map[string]interface{}{"table_name": stat.TableName}),
+			})
 		}
 
 		// 批量创建指标
-		if err := dc.dbManager.SaasMonitorDB.Create([]models.ResourceMetric{sizeMetric, rowMetric}).Error; err != nil {
+		if err := dc.dbManager.SaasMonitorDB.Create(metrics).Error; err != nil {
 			log.Printf("Error creating table metrics for %s: %v", stat.TableName, err)
 		}
 	}
 
-	return nil
+	return dc.collectPostgreSQLIndexHealth(ctx)
+}
+
+// collectPostgreSQLIndexHealth 采集未使用索引和重复索引的数量及占用空间
+func (dc *DataCollector) collectPostgreSQLIndexHealth(ctx context.Context) error {
+	db := dc.dbManager.LightAdminDB.WithContext(ctx)
+
+	unused, err := queryUnusedIndexes(db)
+	if err != nil {
+		return err
+	}
+	duplicates, err := queryDuplicateIndexes(db)
+	if err != nil {
+		return err
+	}
+
+	var unusedSize, duplicateSize float64
+	for _, index := range unused {
+		unusedSize += index.SizeMB
+	}
+	for _, group := range duplicates {
+		duplicateSize += group.SizeMB
+	}
+
+	now := time.Now()
+	return dc.dbManager.SaasMonitorDB.Create([]models.ResourceMetric{
+		{
+			DatabaseType: "postgresql",
+			DatabaseName: "light_admin",
+			MetricType:   "maintenance",
+			MetricName:   "unused_indexes",
+			MetricValue:  float64(len(unused)),
+			Unit:         "count",
+			CollectedAt:  now,
+		},
+		{
+			DatabaseType: "postgresql",
+			DatabaseName: "light_admin",
+			MetricType:   "maintenance",
+			MetricName:   "unused_indexes_size_mb",
+			MetricValue:  unusedSize,
+			Unit:         "MB",
+			CollectedAt:  now,
+		},
+		{
+			DatabaseType: "postgresql",
+			DatabaseName: "light_admin",
+			MetricType:   "maintenance",
+			MetricName:   "duplicate_index_groups",
+			MetricValue:  float64(len(duplicates)),
+			Unit:         "count",
+			CollectedAt:  now,
+		},
+		{
+			DatabaseType: "postgresql",
+			DatabaseName: "light_admin",
+			MetricType:   "maintenance",
+			MetricName:   "duplicate_indexes_size_mb",
+			MetricValue:  duplicateSize,
+			Unit:         "MB",
+			CollectedAt:  now,
+		},
+	}).Error
 }
 
 // collectPostgreSQLUserStats 采集用户和订阅统计
@@ -239,13 +387,12 @@ func (dc *DataCollector) collectPostgreSQLUserStats(ctx context.Context) error {
 		Where("status = ?", "active").
 		Count(&subStats.ActiveSubs)
 
-	// 计算月收入
-	dc.dbManager.LightAdminDB.Raw(`
-		SELECT COALESCE(SUM(pricing_monthly), 0)
-		FROM subscription_users su
-		JOIN subscription_plans sp ON su.plan_id = sp.id
-		WHERE su.status = 'active' AND su.billing_cycle = 'monthly'
-	`).Scan(&subStats.MonthlyRevenue)
+	// 计算月收入（各计费周期归一化后的MRR，不含试用）
+	revenue, err := NewRevenueService(dc.dbManager).GetSummary(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("failed to calculate revenue: %w", err)
+	}
+	subStats.MonthlyRevenue = revenue.MRR
 
 	// 创建组织统计指标
 	orgMetric := models.ResourceMetric{
@@ -293,14 +440,36 @@ func (dc *DataCollector) collectPostgreSQLUserStats(ctx context.Context) error {
 		CollectedAt:  time.Now(),
 	}
 
+	// 创建年度经常性收入指标
+	arrMetric := models.ResourceMetric{
+		DatabaseType: "postgresql",
+		DatabaseName: "light_admin",
+		MetricType:   "revenue",
+		MetricName:   "annual_recurring_revenue",
+		MetricValue:  revenue.ARR,
+		Unit:         "USD",
+		CollectedAt:  time.Now(),
+	}
+
+	// 创建付费组织数指标
+	payingMetric := models.ResourceMetric{
+		DatabaseType: "postgresql",
+		DatabaseName: "light_admin",
+		MetricType:   "revenue",
+		MetricName:   "paying_organizations",
+		MetricValue:  float64(revenue.PayingOrganizations),
+		Unit:         "count",
+		CollectedAt:  time.Now(),
+	}
+
 	return dc.dbManager.SaasMonitorDB.Create([]models.ResourceMetric{
-		orgMetric, userMetric, subMetric, revenueMetric,
+		orgMetric, userMetric, subMetric, revenueMetric, arrMetric, payingMetric,
 	}).Error
 }
 
 // collectClickHouseData 采集ClickHouse监控数据
 func (dc *DataCollector) collectClickHouseData(ctx context.Context) error {
-	for dbName, conn := range dc.dbManager.ClickHouse {
+	for dbName, conn := range dc.dbManager.GetClickHouseConnections() {
 		if err := dc.collectClickHouseDatabaseData(ctx, dbName, conn); err != nil {
 			log.Printf("Error collecting ClickHouse data for %s: %v", dbName, err)
 		}
@@ -325,6 +494,16 @@ func (dc *DataCollector) collectClickHouseDatabaseData(ctx context.Context, dbNa
 		return err
 	}
 
+	// 获取按用户和组织分组的查询分析（query_log未启用时跳过）
+	if err := dc.collectClickHouseQueryAnalytics(ctx, dbName, conn); err != nil {
+		log.Printf("Error collecting ClickHouse query analytics for %s: %v", dbName, err)
+	}
+
+	// 获取merge、mutation、part、副本和磁盘健康指标
+	if err := dc.collectClickHouseHealth(ctx, dbName, conn); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -428,7 +607,7 @@ func (dc *DataCollector) collectClickHouseTableStats(ctx context.Context, dbName
 			MetricValue:  float64(totalBytes) / (1024 * 1024), // MB
 			Unit:         "MB",
 			CollectedAt:  time.Now(),
-			Tags:         dc.formatTags(map[string]interface{}{"table": tableName}),
+			Tags:         dc.formatTags(map[string]interface{}{"table_name": tableName}),
 		}
 
 		// 行数指标
@@ -440,7 +619,7 @@ func (dc *DataCollector) collectClickHouseTableStats(ctx context.Context, dbName
 			MetricValue:  float64(totalRows),
 			Unit:         "count",
 			CollectedAt:  time.Now(),
-			Tags:         dc.formatTags(map[string]interface{}{"table": tableName}),
+			Tags:         dc.formatTags(map[string]interface{}{"table_name": tableName}),
 		}
 
 		if err := dc.dbManager.SaasMonitorDB.Create([]models.ResourceMetric{sizeMetric, rowMetric}).Error; err != nil {
@@ -506,143 +685,241 @@ func (dc *DataCollector) collectClickHouseQueryStats(ctx context.Context, dbName
 
 // collectRedisData 采集Redis监控数据
 func (dc *DataCollector) collectRedisData(ctx context.Context) error {
-	if dc.dbManager.RedisClient == nil {
+	instances := dc.dbManager.GetRedisInstances()
+	if len(instances) == 0 {
 		return fmt.Errorf("Redis client not initialized")
 	}
 
-	// 获取Redis信息
-	info := dc.dbManager.RedisClient.Info(ctx)
-	if info.Err() != nil {
-		return info.Err()
+	for name, instance := range instances {
+		if err := dc.collectRedisInstance(ctx, instance); err != nil {
+			log.Printf("Error collecting Redis data for %s: %v", name, err)
+		}
 	}
+	return nil
+}
+
+// collectSystemHealth 采集系统健康状态
+func (dc *DataCollector) collectSystemHealth(ctx context.Context) error {
+	healthConfig := dc.dbManager.Config.Monitoring.Health
+	checks := dc.dbManager.CheckComponents(ctx)
+	now := time.Now()
 
-	// 解析Redis信息
-	redisInfo := info.Val()
+	// 成功的检查记录响应时间样本，用于计算分位数
+	var samples []models.ResourceMetric
+	for component, check := range checks {
+		if check.Err != nil {
+			continue
+		}
+		samples = append(samples, models.ResourceMetric{
+			DatabaseType: "system_health",
+			DatabaseName: component,
+			MetricType:   "health",
+			MetricName:   "response_time_ms",
+			MetricValue:  float64(check.Latency.Microseconds()) / 1000,
+			Unit:         "ms",
+			Tags:         dc.formatTags(map[string]interface{}{"component_type": dc.getComponentType(component)}),
+			CollectedAt:  now,
+		})
+	}
+	if len(samples) > 0 {
+		if err := dc.dbManager.SaasMonitorDB.CreateInBatches(samples, 100).Error; err != nil {
+			log.Printf("Failed to save health check samples: %v", err)
+		}
+	}
+	percentiles := dc.healthLatencyPercentiles(ctx, healthConfig.PercentileSamples)
 
-	// 提取关键指标
-	memoryUsed := dc.extractRedisMetric(redisInfo, "used_memory:")
-	_ = dc.extractRedisMetric(redisInfo, "maxmemory:") // 最大内存（暂不使用）
-	connectedClients := dc.extractRedisMetric(redisInfo, "connected_clients:")
-	keyspaceHits := dc.extractRedisMetric(redisInfo, "keyspace_hits:")
-	keyspaceMisses := dc.extractRedisMetric(redisInfo, "keyspace_misses:")
+	for component, check := range checks {
+		status := "healthy"
+		responseTime := int(check.Latency.Milliseconds())
+		errorMessage := ""
 
-	// 计算命中率
-	totalRequests := keyspaceHits + keyspaceMisses
-	hitRate := float64(0)
-	if totalRequests > 0 {
-		hitRate = float64(keyspaceHits) / float64(totalRequests) * 100
+		if check.Err != nil {
+			status = "unhealthy"
+			errorMessage = check.Err.Error()
+		} else {
+			warning, critical := healthConfig.LatencyThresholds(component)
+			status = classifyLatency(responseTime, warning, critical)
+		}
+
+		dc.recordSystemHealth(component, dc.getComponentType(component), status, responseTime, errorMessage)
+
+		if p, exists := percentiles[component]; exists {
+			dc.dbManager.SaasMonitorDB.Model(&models.SystemHealth{}).
+				Where("component_name = ?", component).
+				Updates(map[string]interface{}{
+					"response_time_p50": int(math.Round(p.P50)),
+					"response_time_p95": int(math.Round(p.P95)),
+					"response_time_p99": int(math.Round(p.P99)),
+				})
+		}
 	}
 
-	// 内存使用指标
-	memoryMetric := models.ResourceMetric{
-		DatabaseType: "redis",
-		DatabaseName: "default",
-		MetricType:   "memory",
-		MetricName:   "used_memory_bytes",
-		MetricValue:  float64(memoryUsed),
-		Unit:         "bytes",
-		CollectedAt:  time.Now(),
+	return nil
+}
+
+// classifyLatency 按延迟阈值划分健康状态，阈值<=0表示不启用
+func classifyLatency(responseTime, warning, critical int) string {
+	if critical > 0 && responseTime >= critical {
+		return "critical"
+	}
+	if warning > 0 && responseTime >= warning {
+		return "warning"
 	}
+	return "healthy"
+}
 
-	// 连接数指标
-	connectionsMetric := models.ResourceMetric{
-		DatabaseType: "redis",
-		DatabaseName: "default",
-		MetricType:   "connection",
-		MetricName:   "connected_clients",
-		MetricValue:  float64(connectedClients),
-		Unit:         "count",
-		CollectedAt:  time.Now(),
+// latencyPercentiles 响应时间分位数（毫秒）
+type latencyPercentiles struct {
+	Component string
+	P50       float64
+	P95       float64
+	P99       float64
+}
+
+// healthLatencyPercentiles 计算每个组件最近sampleCount次成功检查的响应时间分位数
+func (dc *DataCollector) healthLatencyPercentiles(ctx context.Context, sampleCount int) map[string]latencyPercentiles {
+	if sampleCount <= 0 {
+		sampleCount = 60
 	}
 
-	// 命中率指标
-	hitRateMetric := models.ResourceMetric{
-		DatabaseType: "redis",
-		DatabaseName: "default",
-		MetricType:   "performance",
-		MetricName:   "hit_rate_percent",
-		MetricValue:  hitRate,
-		Unit:         "percent",
-		CollectedAt:  time.Now(),
+	var rows []latencyPercentiles
+	err := dc.dbManager.SaasMonitorDB.WithContext(ctx).Raw(`
+		SELECT
+			database_name AS component,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY metric_value) AS p50,
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY metric_value) AS p95,
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY metric_value) AS p99
+		FROM (
+			SELECT database_name, metric_value,
+				ROW_NUMBER() OVER (PARTITION BY database_name ORDER BY collected_at DESC) AS rn
+			FROM resource_metrics
+			WHERE database_type = 'system_health'
+				AND metric_name = 'response_time_ms'
+				AND collected_at >= ?
+		) recent
+		WHERE rn <= ?
+		GROUP BY database_name
+	`, time.Now().Add(-24*time.Hour), sampleCount).Scan(&rows).Error
+	if err != nil {
+		log.Printf("Failed to calculate health check percentiles: %v", err)
+		return nil
 	}
 
-	return dc.dbManager.SaasMonitorDB.Create([]models.ResourceMetric{
-		memoryMetric, connectionsMetric, hitRateMetric,
-	}).Error
+	percentiles := make(map[string]latencyPercentiles, len(rows))
+	for _, row := range rows {
+		percentiles[row.Component] = row
+	}
+	return percentiles
 }
 
-// collectSystemHealth 采集系统健康状态
-func (dc *DataCollector) collectSystemHealth(ctx context.Context) error {
-	healthStatus := dc.dbManager.HealthCheck()
+// formatTags 将标签序列化为JSON字符串，保留数值、布尔值的类型，并正确转义进程名、镜像名等中的特殊字符
+func (dc *DataCollector) formatTags(tags map[string]interface{}) string {
+	return formatMetricTags(tags)
+}
 
-	for component, err := range healthStatus {
-		status := "healthy"
-		responseTime := int(0)
-		errorMessage := ""
+// formatMetricTags 见formatTags，供不依赖DataCollector的写入路径使用；无法序列化的值（如NaN）转为字符串
+func formatMetricTags(tags map[string]interface{}) string {
+	if len(tags) == 0 {
+		return "{}"
+	}
+	if encoded, err := json.Marshal(tags); err == nil {
+		return string(encoded)
+	}
 
-		if err != nil {
-			status = "unhealthy"
-			errorMessage = err.Error()
+	values := make(map[string]interface{}, len(tags))
+	for key, value := range tags {
+		if _, err := json.Marshal(value); err != nil {
+			values[key] = fmt.Sprint(value)
 		} else {
-			// 模拟响应时间测量
-			start := time.Now()
-			switch component {
-			case "saas_monitor":
-				dc.dbManager.SaasMonitorDB.Exec("SELECT 1")
-			case "light_admin":
-				dc.dbManager.LightAdminDB.Exec("SELECT 1")
-			case "redis":
-				dc.dbManager.RedisClient.Ping(ctx)
-			}
-			responseTime = int(time.Since(start).Milliseconds())
+			values[key] = value
 		}
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
+}
 
-		// 查找或创建健康记录
-		var healthRecord models.SystemHealth
-		result := dc.dbManager.SaasMonitorDB.Where("component_name = ?", component).First(&healthRecord)
-
-		now := time.Now()
-		if result.Error == gorm.ErrRecordNotFound {
-			// 创建新记录
-			healthRecord = models.SystemHealth{
-				ComponentName: component,
-				ComponentType: dc.getComponentType(component),
-				Status:        status,
-				ResponseTime:  &responseTime,
-				ErrorMessage:  &errorMessage,
-				LastCheckedAt: now,
-				CreatedAt:     now,
-				UpdatedAt:     now,
-			}
-			dc.dbManager.SaasMonitorDB.Create(&healthRecord)
-		} else {
-			// 更新现有记录
-			healthRecord.Status = status
-			healthRecord.ResponseTime = &responseTime
-			healthRecord.ErrorMessage = &errorMessage
-			healthRecord.LastCheckedAt = now
-			healthRecord.UpdatedAt = now
-			dc.dbManager.SaasMonitorDB.Save(&healthRecord)
+// ParseMetricTags 解析标签JSON，非字符串值取其JSON文本，与PostgreSQL中tags->>key的结果一致；null值忽略
+func ParseMetricTags(tags string) map[string]string {
+	values := make(map[string]string)
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(tags), &raw); err != nil {
+		return values
+	}
+	for key, value := range raw {
+		var text string
+		switch {
+		case json.Unmarshal(value, &text) == nil:
+			values[key] = text
+		case string(value) != "null":
+			values[key] = string(value)
 		}
 	}
+	return values
+}
 
-	return nil
+// sanitizeMetricName 将任意字符串转换为可用于指标名称的形式
+func sanitizeMetricName(name string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune('_')
+		}
+	}
+	return strings.Trim(builder.String(), "_")
 }
 
-// formatTags 格式化标签为JSON字符串
-func (dc *DataCollector) formatTags(tags map[string]interface{}) string {
-	// 简单的JSON格式化
-	result := "{"
-	first := true
-	for key, value := range tags {
-		if !first {
-			result += ","
+// recordSystemHealth 查找或创建组件健康记录并更新状态，状态变化时写入system_health_history
+func (dc *DataCollector) recordSystemHealth(component, componentType, status string, responseTime int, errorMessage string) {
+	var healthRecord models.SystemHealth
+	result := dc.dbManager.SaasMonitorDB.Where("component_name = ?", component).First(&healthRecord)
+
+	now := time.Now()
+	previousStatus := ""
+	changed := true
+	if result.Error == gorm.ErrRecordNotFound {
+		// 创建新记录
+		healthRecord = models.SystemHealth{
+			ComponentName: component,
+			ComponentType: componentType,
+			Status:        status,
+			ResponseTime:  &responseTime,
+			ErrorMessage:  &errorMessage,
+			LastCheckedAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		dc.dbManager.SaasMonitorDB.Create(&healthRecord)
+	} else {
+		// 更新现有记录
+		previousStatus = healthRecord.Status
+		changed = previousStatus != status
+		healthRecord.Status = status
+		healthRecord.ResponseTime = &responseTime
+		healthRecord.ErrorMessage = &errorMessage
+		healthRecord.LastCheckedAt = now
+		healthRecord.UpdatedAt = now
+		dc.dbManager.SaasMonitorDB.Save(&healthRecord)
+	}
+
+	if changed {
+		history := models.SystemHealthHistory{
+			ComponentName:  component,
+			ComponentType:  componentType,
+			PreviousStatus: previousStatus,
+			Status:         status,
+			ResponseTime:   &responseTime,
+			ErrorMessage:   &errorMessage,
+			ChangedAt:      now,
+		}
+		if err := dc.dbManager.SaasMonitorDB.Create(&history).Error; err != nil {
+			log.Printf("Failed to record health transition for %s: %v", component, err)
 		}
-		result += fmt.Sprintf(`"%s":"%v"`, key, value)
-		first = false
 	}
-	result += "}"
-	return result
 }
 
 // getComponentType 根据组件名称获取组件类型
@@ -650,12 +927,15 @@ func (dc *DataCollector) getComponentType(componentName string) string {
 	if componentName == "saas_monitor" || componentName == "light_admin" {
 		return "database"
 	}
-	if componentName == "redis" {
+	if componentName == "redis" || strings.HasPrefix(componentName, "redis_") {
 		return "cache"
 	}
-	if strings.Contains(componentName, "clickhouse") {
+	if strings.Contains(componentName, "clickhouse") || strings.Contains(componentName, "postgresql") || strings.HasPrefix(componentName, "mysql_") {
 		return "database"
 	}
+	if strings.HasPrefix(componentName, "probe_") {
+		return "probe"
+	}
 	return "unknown"
 }
 
@@ -772,19 +1052,3 @@ func (dc *DataCollector) collectPostgreSQLOrganizationStats(ctx context.Context)
 
 	return nil
 }
-
-// extractRedisMetric 从Redis信息中提取指定指标的值
-func (dc *DataCollector) extractRedisMetric(info, metric string) int64 {
-	lines := strings.Split(info, "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, metric) {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				if value, err := strconv.ParseInt(parts[1], 10, 64); err == nil {
-					return value
-				}
-			}
-		}
-	}
-	return 0
-}
\ No newline at end of file
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// CollectorAll 执行全部采集（与定时采集相同）
+const CollectorAll = "all"
+
+// CollectorJobType 手动触发采集的后台任务类型
+const CollectorJobType = "collector"
+
+// CollectorJobPayload 手动采集任务参数
+type CollectorJobPayload struct {
+	Collector string `json:"collector"`
+}
+
+// collectorFuncs 可单独触发的采集器
+var collectorFuncs = map[string]func(dc *DataCollector, ctx context.Context) error{
+	"postgresql":     (*DataCollector).collectPostgreSQLData,
+	"mysql":          (*DataCollector).collectMySQLData,
+	"clickhouse":     (*DataCollector).collectClickHouseData,
+	"redis":          (*DataCollector).collectRedisData,
+	"host":           (*DataCollector).collectHostMetrics,
+	"infrastructure": (*DataCollector).collectInfrastructureMetrics,
+	"organization_storage": func(dc *DataCollector, ctx context.Context) error {
+		dc.storageRunAt = time.Now()
+		return dc.collectOrganizationStorage(ctx)
+	},
+	"system_health": (*DataCollector).collectSystemHealth,
+}
+
+// CollectorNames 获取可触发的采集器名称
+func CollectorNames() []string {
+	names := make([]string, 0, len(collectorFuncs)+1)
+	for name := range collectorFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{CollectorAll}, names...)
+}
+
+// ValidateCollector 检查采集器名称，不存在时返回not found错误
+func ValidateCollector(name string) error {
+	if _, ok := collectorFuncs[name]; !ok && name != CollectorAll {
+		return fmt.Errorf("collector '%s' not found", name)
+	}
+	return nil
+}
+
+// CollectorRunResult 单次采集结果
+type CollectorRunResult struct {
+	Collector  string    `json:"collector"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// RunCollector 立即执行一次指定的采集器，不受enabled开关和采集间隔限制；
+// 与定时采集共用同一个DataCollector时会等待正在进行的采集完成
+func (dc *DataCollector) RunCollector(ctx context.Context, name string) (*CollectorRunResult, error) {
+	if err := ValidateCollector(name); err != nil {
+		return nil, err
+	}
+	collect := collectorFuncs[name]
+
+	result := &CollectorRunResult{
+		Collector: name,
+		StartedAt: time.Now(),
+	}
+	var err error
+	if name == CollectorAll {
+		err = dc.CollectAllData(ctx)
+	} else {
+		dc.runMu.Lock()
+		err = collect(dc, ctx)
+		dc.runMu.Unlock()
+	}
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		log.Printf("Manual run of collector %s failed: %v", name, err)
+	} else {
+		log.Printf("Manual run of collector %s completed in %dms", name, result.DurationMs)
+	}
+	return result, nil
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCollectorNames(t *testing.T) {
+	names := CollectorNames()
+	if len(names) != len(collectorFuncs)+1 || names[0] != CollectorAll {
+		t.Fatalf("CollectorNames() = %v, want %q first followed by every collector", names, CollectorAll)
+	}
+	for _, name := range names {
+		if err := ValidateCollector(name); err != nil {
+			t.Errorf("ValidateCollector(%q) = %v", name, err)
+		}
+	}
+}
+
+func TestRunCollectorUnknown(t *testing.T) {
+	if _, err := (&DataCollector{}).RunCollector(context.Background(), "mongodb"); err == nil {
+		t.Error("RunCollector() with an unknown collector should fail")
+	}
+}
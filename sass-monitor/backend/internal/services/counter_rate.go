@@ -0,0 +1,55 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// counterSample 累计计数器的一次采样
+type counterSample struct {
+	value     float64
+	sampledAt time.Time
+}
+
+// counterRateSampler 记录各累计计数器（WAL位置、Questions、语句执行次数等）上一次的采样，计算每秒速率
+type counterRateSampler struct {
+	mu      sync.Mutex
+	samples map[string]counterSample
+}
+
+func newCounterRateSampler() *counterRateSampler {
+	return &counterRateSampler{
+		samples: make(map[string]counterSample),
+	}
+}
+
+// rate 记录新的采样并返回与上一次采样之间的每秒增量，首次采样返回false
+func (s *counterRateSampler) rate(key string, value float64, now time.Time) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, exists := s.samples[key]
+	s.samples[key] = counterSample{value: value, sampledAt: now}
+
+	if !exists {
+		return 0, false
+	}
+	elapsed := now.Sub(prev.sampledAt).Seconds()
+	// 计数器回退（如主备切换、重启或统计被重置）时丢弃本次速率
+	if elapsed <= 0 || value < prev.value {
+		return 0, false
+	}
+	return (value - prev.value) / elapsed, true
+}
+
+// prune 删除before之前的采样，避免已消失的键（如不再进入TOP N的语句）一直占用内存
+func (s *counterRateSampler) prune(before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, sample := range s.samples {
+		if sample.sampledAt.Before(before) {
+			delete(s.samples, key)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCounterRateSamplerRate(t *testing.T) {
+	sampler := newCounterRateSampler()
+	now := time.Now()
+
+	if _, ok := sampler.rate("primary", 1000, now); ok {
+		t.Fatal("first sample should not report a rate")
+	}
+	rate, ok := sampler.rate("primary", 3000, now.Add(2*time.Second))
+	if !ok || rate != 1000 {
+		t.Errorf("rate = %v, %v; want 1000, true", rate, ok)
+	}
+	// 主备切换后LSN回退时丢弃本次速率
+	if _, ok := sampler.rate("primary", 500, now.Add(4*time.Second)); ok {
+		t.Error("rate should be discarded when the counter goes backwards")
+	}
+	if _, ok := sampler.rate("replica", 10, now); ok {
+		t.Error("keys should be sampled independently")
+	}
+}
+
+func TestCounterRateSamplerPrune(t *testing.T) {
+	sampler := newCounterRateSampler()
+	now := time.Now()
+
+	sampler.rate("stale", 10, now.Add(-2*time.Hour))
+	sampler.rate("active", 10, now)
+	sampler.prune(now.Add(-time.Hour))
+
+	if _, ok := sampler.rate("stale", 20, now.Add(time.Minute)); ok {
+		t.Error("pruned key should start over without a rate")
+	}
+	if rate, ok := sampler.rate("active", 70, now.Add(time.Minute)); !ok || rate != 1 {
+		t.Errorf("rate = %v, %v; want 1, true", rate, ok)
+	}
+}
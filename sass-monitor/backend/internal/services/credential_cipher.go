@@ -0,0 +1,70 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"sass-monitor/pkg/config"
+)
+
+// credentialCipher 使用AES-256-GCM加密监控目标凭据
+type credentialCipher struct {
+	aead cipher.AEAD
+}
+
+// newCredentialCipher 根据credential_key派生加密密钥；不使用jwt_secret，轮换JWT密钥不影响已保存的凭据
+func newCredentialCipher(cfg *config.Config) (*credentialCipher, error) {
+	secret := cfg.Server.CredentialKey
+	if secret == "" {
+		return nil, fmt.Errorf("server.credential_key must be configured to store monitoring target credentials")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &credentialCipher{aead: aead}, nil
+}
+
+// Encrypt 加密明文，返回base64(nonce+密文)；空字符串不加密
+func (cc *credentialCipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	nonce := make([]byte, cc.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := cc.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密Encrypt的输出
+func (cc *credentialCipher) Decrypt(encoded string) (string, error) {
+	if encoded == "" {
+		return "", nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted credential: %w", err)
+	}
+	nonceSize := cc.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("invalid encrypted credential")
+	}
+	plaintext, err := cc.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt credential (credential_key changed?): %w", err)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+	"sass-monitor/pkg/config"
+)
+
+// hostCPUSampleInterval 首次采集没有历史样本时两次读取/proc/stat的间隔
+const hostCPUSampleInterval = time.Second
+
+// hostCPUTimes /proc/stat中cpu行的累计时间（单位：jiffies）
+type hostCPUTimes struct {
+	total  uint64
+	idle   uint64 // idle + iowait
+	iowait uint64
+}
+
+// hostCPUSampler 保存上一次CPU样本，用于计算两次采集之间的使用率
+type hostCPUSampler struct {
+	mu       sync.Mutex
+	previous *hostCPUTimes
+}
+
+// usage 返回与上一次样本之间的CPU使用率和iowait占比，首次调用返回false
+func (s *hostCPUSampler) usage(current hostCPUTimes) (float64, float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.previous
+	s.previous = &current
+	if previous == nil || current.total <= previous.total {
+		return 0, 0, false
+	}
+
+	totalDelta := float64(current.total - previous.total)
+	idleDelta := math.Min(float64(counterDelta(current.idle, previous.idle)), totalDelta)
+	iowaitDelta := math.Min(float64(counterDelta(current.iowait, previous.iowait)), totalDelta)
+	return (totalDelta - idleDelta) / totalDelta * 100, iowaitDelta / totalDelta * 100, true
+}
+
+// counterDelta 累计计数的增量，计数变小时返回0（proc(5)：iowait可能减小）
+func counterDelta(current, previous uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+	return current - previous
+}
+
+// HostDiskUsage 挂载点磁盘使用情况
+type HostDiskUsage struct {
+	MountPoint   string
+	TotalBytes   uint64
+	FreeBytes    uint64 // 非特权用户可用空间
+	UsedPercent  float64
+	InodesTotal  uint64
+	InodesFree   uint64
+	InodePercent float64
+}
+
+// readHostCPUTimes 读取/proc/stat的汇总cpu行和CPU核数
+func readHostCPUTimes(procPath string) (hostCPUTimes, int, error) {
+	file, err := os.Open(filepath.Join(procPath, "stat"))
+	if err != nil {
+		return hostCPUTimes{}, 0, err
+	}
+	defer file.Close()
+
+	var times hostCPUTimes
+	found := false
+	cores := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if fields[0] != "cpu" {
+			cores++
+			continue
+		}
+
+		// cpu user nice system idle iowait irq softirq steal guest guest_nice
+		// guest和guest_nice已计入user和nice，不重复累加
+		for i, field := range fields[1:] {
+			if i >= 8 {
+				break
+			}
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return hostCPUTimes{}, 0, fmt.Errorf("invalid /proc/stat cpu field %q: %w", field, err)
+			}
+			times.total += value
+			if i == 3 || i == 4 {
+				times.idle += value
+			}
+			if i == 4 {
+				times.iowait = value
+			}
+		}
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return hostCPUTimes{}, 0, err
+	}
+	if !found {
+		return hostCPUTimes{}, 0, fmt.Errorf("cpu line not found in %s", filepath.Join(procPath, "stat"))
+	}
+	return times, cores, nil
+}
+
+// readHostMemInfo 读取/proc/meminfo（单位：字节）
+func readHostMemInfo(procPath string) (map[string]uint64, error) {
+	file, err := os.Open(filepath.Join(procPath, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	memInfo := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, rest, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 1 && fields[1] == "kB" {
+			value *= 1024
+		}
+		memInfo[key] = value
+	}
+	return memInfo, scanner.Err()
+}
+
+// readHostLoadAvg 读取/proc/loadavg的1、5、15分钟负载
+func readHostLoadAvg(procPath string) ([3]float64, error) {
+	var loads [3]float64
+
+	data, err := os.ReadFile(filepath.Join(procPath, "loadavg"))
+	if err != nil {
+		return loads, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return loads, fmt.Errorf("unexpected loadavg format: %q", string(data))
+	}
+	for i := 0; i < 3; i++ {
+		if loads[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return loads, err
+		}
+	}
+	return loads, nil
+}
+
+// hostMountMetricSuffix 挂载点在指标名称中的后缀，根目录为root
+func hostMountMetricSuffix(mountPoint string) string {
+	if suffix := sanitizeMetricName(mountPoint); suffix != "" {
+		return suffix
+	}
+	return "root"
+}
+
+// resolveHostName 获取主机指标使用的名称
+func resolveHostName(hostConfig config.HostConfig) string {
+	if hostConfig.Name != "" {
+		return hostConfig.Name
+	}
+	if name, err := os.Hostname(); err == nil {
+		return name
+	}
+	return "localhost"
+}
+
+// EnsureHostAlertRules 根据CPU、内存和磁盘阈值创建主机默认告警规则（已存在的规则不覆盖）
+// 阈值优先使用monitoring_configs中的配置，其次使用配置文件
+func EnsureHostAlertRules(dbManager *database.DatabaseManager) error {
+	cfg := dbManager.Config.Monitoring
+	if !cfg.Host.Enabled {
+		return nil
+	}
+	host := resolveHostName(cfg.Host)
+
+	threshold := func(configKey string, fallback int) float64 {
+		var monitoringConfig models.MonitoringConfig
+		if err := dbManager.SaasMonitorDB.Where("config_key = ?", configKey).First(&monitoringConfig).Error; err == nil {
+			if value, err := strconv.ParseFloat(monitoringConfig.ConfigValue, 64); err == nil {
+				return value
+			}
+		}
+		return float64(fallback)
+	}
+
+	type hostRule struct {
+		name        string
+		description string
+		metricName  string
+		threshold   float64
+		severity    string
+	}
+	rules := []hostRule{
+		{"主机CPU使用率告警", "当主机CPU使用率超过阈值时触发告警", "cpu_usage_percent", threshold("cpu_threshold", cfg.Alerts.CPUThreshold), "warning"},
+		{"主机内存使用率告警", "当主机内存使用率超过阈值时触发告警", "memory_usage_percent", threshold("memory_threshold", cfg.Alerts.MemoryThreshold), "warning"},
+	}
+	diskThreshold := threshold("disk_threshold", cfg.Alerts.DiskThreshold)
+	for _, mountPoint := range cfg.Host.MountPoints {
+		rules = append(rules, hostRule{
+			fmt.Sprintf("主机磁盘使用率告警(%s)", mountPoint),
+			fmt.Sprintf("当挂载点%s磁盘使用率超过阈值时触发告警", mountPoint),
+			"disk_usage_percent_" + hostMountMetricSuffix(mountPoint),
+			diskThreshold,
+			"critical",
+		})
+	}
+
+	for _, rule := range rules {
+		if rule.threshold <= 0 {
+			continue
+		}
+
+		var count int64
+		if err := dbManager.SaasMonitorDB.Model(&models.AlertRule{}).
+			Where("target_type = ? AND target_name = ? AND metric_name = ?", "host", host, rule.metricName).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		alertRule := models.AlertRule{
+			Name:               rule.name,
+			Description:        rule.description,
+			RuleType:           "system",
+			TargetType:         "host",
+			TargetName:         host,
+			MetricName:         rule.metricName,
+			Operator:           ">",
+			Threshold:          rule.threshold,
+			Severity:           rule.severity,
+			Enabled:            cfg.Alerts.Enabled,
+			NotificationConfig: "{}",
+			CreatedBy:          uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		}
+		if err := dbManager.SaasMonitorDB.Create(&alertRule).Error; err != nil {
+			return fmt.Errorf("failed to create host alert rule %s: %w", rule.metricName, err)
+		}
+		// enabled列有默认值true，创建时false会被忽略
+		if !cfg.Alerts.Enabled {
+			dbManager.SaasMonitorDB.Model(&alertRule).Update("enabled", false)
+		}
+	}
+
+	return nil
+}
+
+// collectHostMetrics 采集主机CPU、内存、负载和磁盘指标
+func (dc *DataCollector) collectHostMetrics(ctx context.Context) error {
+	hostConfig := dc.dbManager.Config.Monitoring.Host
+	procPath := hostConfig.ProcPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+
+	host := resolveHostName(hostConfig)
+	now := time.Now()
+	newMetric := func(metricType, metricName string, value float64, unit string, tags map[string]interface{}) models.ResourceMetric {
+		metric := models.ResourceMetric{
+			DatabaseType: "host",
+			DatabaseName: host,
+			MetricType:   metricType,
+			MetricName:   metricName,
+			MetricValue:  value,
+			Unit:         unit,
+			CollectedAt:  now,
+		}
+		if tags != nil {
+			metric.Tags = dc.formatTags(tags)
+		}
+		return metric
+	}
+
+	var metrics []models.ResourceMetric
+	var errs []string
+
+	// CPU
+	cpuTimes, cores, err := readHostCPUTimes(procPath)
+	if err == nil {
+		usage, iowait, ok := dc.hostCPU.usage(cpuTimes)
+		if !ok {
+			// 没有历史样本时短暂等待后再次采样
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(hostCPUSampleInterval):
+			}
+			if cpuTimes, _, err = readHostCPUTimes(procPath); err == nil {
+				usage, iowait, ok = dc.hostCPU.usage(cpuTimes)
+			}
+		}
+		if ok {
+			metrics = append(metrics,
+				newMetric("cpu", "cpu_usage_percent", usage, "percent", nil),
+				newMetric("cpu", "cpu_iowait_percent", iowait, "percent", nil),
+			)
+		}
+		metrics = append(metrics, newMetric("cpu", "cpu_cores", float64(cores), "count", nil))
+	}
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("cpu: %v", err))
+	}
+
+	// 内存
+	if memInfo, err := readHostMemInfo(procPath); err != nil {
+		errs = append(errs, fmt.Sprintf("memory: %v", err))
+	} else if total := memInfo["MemTotal"]; total > 0 {
+		available, exists := memInfo["MemAvailable"]
+		if !exists {
+			// 旧内核没有MemAvailable时近似计算
+			available = memInfo["MemFree"] + memInfo["Buffers"] + memInfo["Cached"]
+		}
+		metrics = append(metrics,
+			newMetric("memory", "memory_total_mb", float64(total)/(1024*1024), "MB", nil),
+			newMetric("memory", "memory_available_mb", float64(available)/(1024*1024), "MB", nil),
+			newMetric("memory", "memory_usage_percent", float64(total-available)/float64(total)*100, "percent", nil),
+		)
+		if swapTotal := memInfo["SwapTotal"]; swapTotal > 0 {
+			metrics = append(metrics, newMetric("memory", "swap_usage_percent", float64(swapTotal-memInfo["SwapFree"])/float64(swapTotal)*100, "percent", nil))
+		}
+	}
+
+	// 负载
+	if loads, err := readHostLoadAvg(procPath); err != nil {
+		errs = append(errs, fmt.Sprintf("loadavg: %v", err))
+	} else {
+		metrics = append(metrics,
+			newMetric("load", "load1", loads[0], "load", nil),
+			newMetric("load", "load5", loads[1], "load", nil),
+			newMetric("load", "load15", loads[2], "load", nil),
+		)
+		if cores > 0 {
+			metrics = append(metrics, newMetric("load", "load1_per_core", loads[0]/float64(cores), "load", nil))
+		}
+	}
+
+	// 磁盘
+	for _, mountPoint := range hostConfig.MountPoints {
+		usage, err := readHostDiskUsage(mountPoint)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("disk %s: %v", mountPoint, err))
+			continue
+		}
+		suffix := hostMountMetricSuffix(mountPoint)
+		tags := map[string]interface{}{"mount_point": mountPoint}
+		metrics = append(metrics,
+			newMetric("disk", "disk_usage_percent_"+suffix, usage.UsedPercent, "percent", tags),
+			newMetric("disk", "disk_free_mb_"+suffix, float64(usage.FreeBytes)/(1024*1024), "MB", tags),
+			newMetric("disk", "disk_inode_usage_percent_"+suffix, usage.InodePercent, "percent", tags),
+		)
+	}
+
+	if len(metrics) > 0 {
+		if err := dc.dbManager.SaasMonitorDB.Create(metrics).Error; err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
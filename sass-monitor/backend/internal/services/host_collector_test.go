@@ -0,0 +1,196 @@
+package services
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeProcFiles 在临时目录中生成/proc文件
+func writeProcFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadHostCPUTimes(t *testing.T) {
+	tests := []struct {
+		name      string
+		stat      string
+		want      hostCPUTimes
+		wantCores int
+		wantErr   bool
+	}{
+		{
+			name: "guest time is not counted twice",
+			stat: "cpu  100 10 50 800 40 5 5 0 30 3\n" +
+				"cpu0 50 5 25 400 20 3 2 0 15 2\n" +
+				"cpu1 50 5 25 400 20 2 3 0 15 1\n" +
+				"intr 12345 0 0\n" +
+				"ctxt 67890\n",
+			want:      hostCPUTimes{total: 1010, idle: 840, iowait: 40},
+			wantCores: 2,
+		},
+		{
+			name:      "older kernels without steal",
+			stat:      "cpu 10 0 10 70 10 0 0\ncpu0 10 0 10 70 10 0 0\n",
+			want:      hostCPUTimes{total: 100, idle: 80, iowait: 10},
+			wantCores: 1,
+		},
+		{
+			name:    "missing aggregate line",
+			stat:    "cpu0 1 2 3 4 5 6 7 8\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid counter",
+			stat:    "cpu 1 2 x 4\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeProcFiles(t, map[string]string{"stat": tt.stat})
+			got, cores, err := readHostCPUTimes(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want || cores != tt.wantCores {
+				t.Errorf("readHostCPUTimes() = %+v, %d cores; want %+v, %d cores", got, cores, tt.want, tt.wantCores)
+			}
+		})
+	}
+}
+
+func TestHostCPUSamplerUsage(t *testing.T) {
+	tests := []struct {
+		name       string
+		previous   hostCPUTimes
+		current    hostCPUTimes
+		wantUsage  float64
+		wantIOWait float64
+		wantOK     bool
+	}{
+		{
+			name:       "busy and iowait share",
+			previous:   hostCPUTimes{total: 1000, idle: 800, iowait: 50},
+			current:    hostCPUTimes{total: 1200, idle: 900, iowait: 70},
+			wantUsage:  50,
+			wantIOWait: 10,
+			wantOK:     true,
+		},
+		{
+			name:       "iowait counter going backwards",
+			previous:   hostCPUTimes{total: 1000, idle: 800, iowait: 50},
+			current:    hostCPUTimes{total: 1100, idle: 840, iowait: 45},
+			wantUsage:  60,
+			wantIOWait: 0,
+			wantOK:     true,
+		},
+		{
+			name:       "idle counter going backwards",
+			previous:   hostCPUTimes{total: 1000, idle: 800, iowait: 50},
+			current:    hostCPUTimes{total: 1100, idle: 790, iowait: 40},
+			wantUsage:  100,
+			wantIOWait: 0,
+			wantOK:     true,
+		},
+		{
+			name:     "no elapsed time",
+			previous: hostCPUTimes{total: 1000, idle: 800},
+			current:  hostCPUTimes{total: 1000, idle: 800},
+			wantOK:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler := &hostCPUSampler{}
+			if _, _, ok := sampler.usage(tt.previous); ok {
+				t.Fatal("first sample should not report usage")
+			}
+			usage, iowait, ok := sampler.usage(tt.current)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if math.Abs(usage-tt.wantUsage) > 1e-9 || math.Abs(iowait-tt.wantIOWait) > 1e-9 {
+				t.Errorf("usage = %v, iowait = %v; want %v, %v", usage, iowait, tt.wantUsage, tt.wantIOWait)
+			}
+		})
+	}
+}
+
+func TestReadHostMemInfo(t *testing.T) {
+	dir := writeProcFiles(t, map[string]string{"meminfo": "MemTotal:       16384 kB\n" +
+		"MemAvailable:    4096 kB\n" +
+		"HugePages_Total:      8\n" +
+		"Broken line without separator\n" +
+		"SwapTotal:           x kB\n"})
+
+	memInfo, err := readHostMemInfo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]uint64{
+		"MemTotal":        16384 * 1024,
+		"MemAvailable":    4096 * 1024,
+		"HugePages_Total": 8,
+	}
+	if len(memInfo) != len(want) {
+		t.Errorf("meminfo = %v, want %v", memInfo, want)
+	}
+	for key, value := range want {
+		if memInfo[key] != value {
+			t.Errorf("%s = %d, want %d", key, memInfo[key], value)
+		}
+	}
+}
+
+func TestReadHostLoadAvg(t *testing.T) {
+	tests := []struct {
+		name    string
+		loadavg string
+		want    [3]float64
+		wantErr bool
+	}{
+		{name: "standard format", loadavg: "0.52 1.25 2.00 3/512 12345\n", want: [3]float64{0.52, 1.25, 2}},
+		{name: "too few fields", loadavg: "0.52 1.25\n", wantErr: true},
+		{name: "invalid number", loadavg: "0.52 high 2.00 1/1 1\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeProcFiles(t, map[string]string{"loadavg": tt.loadavg})
+			got, err := readHostLoadAvg(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("readHostLoadAvg() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostMountMetricSuffix(t *testing.T) {
+	tests := map[string]string{
+		"/":             "root",
+		"/var/lib/data": "var_lib_data",
+		"/mnt/Backup-1": "mnt_backup_1",
+	}
+	for mountPoint, want := range tests {
+		if got := hostMountMetricSuffix(mountPoint); got != want {
+			t.Errorf("hostMountMetricSuffix(%q) = %q, want %q", mountPoint, got, want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package services
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// readHostDiskUsage 当前平台不支持statfs
+func readHostDiskUsage(mountPoint string) (HostDiskUsage, error) {
+	return HostDiskUsage{}, fmt.Errorf("disk usage is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,32 @@
+//go:build linux || darwin
+
+package services
+
+import "syscall"
+
+// readHostDiskUsage 通过statfs读取挂载点磁盘使用情况
+func readHostDiskUsage(mountPoint string) (HostDiskUsage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(mountPoint, &stat); err != nil {
+		return HostDiskUsage{}, err
+	}
+
+	blockSize := uint64(stat.Bsize)
+	usage := HostDiskUsage{
+		MountPoint:  mountPoint,
+		TotalBytes:  uint64(stat.Blocks) * blockSize,
+		FreeBytes:   uint64(stat.Bavail) * blockSize,
+		InodesTotal: uint64(stat.Files),
+		InodesFree:  uint64(stat.Ffree),
+	}
+
+	// 与df一致：已用 / (已用 + 非特权可用)
+	used := (uint64(stat.Blocks) - uint64(stat.Bfree)) * blockSize
+	if used+usage.FreeBytes > 0 {
+		usage.UsedPercent = float64(used) / float64(used+usage.FreeBytes) * 100
+	}
+	if usage.InodesTotal > 0 {
+		usage.InodePercent = float64(usage.InodesTotal-usage.InodesFree) / float64(usage.InodesTotal) * 100
+	}
+	return usage, nil
+}
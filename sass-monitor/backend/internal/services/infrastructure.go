@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+	"sass-monitor/pkg/config"
+)
+
+// 导入的light_admin基础设施指标的database_type
+const (
+	InfraTypeHost      = "agent_host"
+	InfraTypeContainer = "container"
+	InfraTypeProcess   = "process"
+)
+
+// infraMaxBatchesPerRun 每次采集每张表最多导入的批次数，剩余数据在下次采集时继续
+const infraMaxBatchesPerRun = 10
+
+// infraZeroID 水位线初始ID（小于任何uuid）
+const infraZeroID = "00000000-0000-0000-0000-000000000000"
+
+// InfrastructureService 按组织/工作空间查看导入的主机、容器和进程指标
+type InfrastructureService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewInfrastructureService(dbManager *database.DatabaseManager) *InfrastructureService {
+	return &InfrastructureService{
+		dbManager: dbManager,
+	}
+}
+
+// InfrastructureResource 单个主机、容器或进程的最新指标
+type InfrastructureResource struct {
+	Name     string             `json:"name"`
+	Host     string             `json:"host"`
+	Tags     map[string]string  `json:"tags"`
+	Metrics  map[string]float64 `json:"metrics"`
+	LastSeen time.Time          `json:"last_seen"`
+}
+
+// OrganizationInfrastructure 组织的基础设施视图
+type OrganizationInfrastructure struct {
+	OrganizationID string                   `json:"organization_id"`
+	WorkspaceID    string                   `json:"workspace_id,omitempty"`
+	Window         string                   `json:"window"`
+	Hosts          []InfrastructureResource `json:"hosts"`
+	Containers     []InfrastructureResource `json:"containers"`
+	Processes      []InfrastructureResource `json:"processes"`
+}
+
+// GetOrganizationInfrastructure 获取组织（可选工作空间）在时间窗口内各资源的最新指标
+func (s *InfrastructureService) GetOrganizationInfrastructure(ctx context.Context, organizationID, workspaceID string, window time.Duration) (*OrganizationInfrastructure, error) {
+	query := `
+		SELECT DISTINCT ON (database_type, database_name, tags->>'host', tags->>'pid', metric_name)
+			database_type, database_name, metric_name, metric_value, tags::text AS tags, collected_at
+		FROM resource_metrics
+		WHERE organization_id = ?
+			AND database_type IN (?, ?, ?)
+			AND collected_at >= ?`
+	args := []interface{}{organizationID, InfraTypeHost, InfraTypeContainer, InfraTypeProcess, time.Now().Add(-window)}
+	if workspaceID != "" {
+		query += " AND tags->>'workspace_id' = ?"
+		args = append(args, workspaceID)
+	}
+	query += `
+		ORDER BY database_type, database_name, tags->>'host', tags->>'pid', metric_name, collected_at DESC`
+
+	var rows []struct {
+		DatabaseType string
+		DatabaseName string
+		MetricName   string
+		MetricValue  float64
+		Tags         string
+		CollectedAt  time.Time
+	}
+	if err := s.dbManager.SaasMonitorDB.WithContext(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query infrastructure metrics: %w", err)
+	}
+
+	// 按(类型, 名称, 主机, pid)聚合为资源
+	resources := make(map[string]*InfrastructureResource)
+	resourceTypes := make(map[string]string)
+	for _, row := range rows {
+		tags := ParseMetricTags(row.Tags)
+
+		key := strings.Join([]string{row.DatabaseType, row.DatabaseName, tags["host"], tags["pid"]}, "|")
+		resource, exists := resources[key]
+		if !exists {
+			resource = &InfrastructureResource{
+				Name:    row.DatabaseName,
+				Host:    tags["host"],
+				Tags:    tags,
+				Metrics: make(map[string]float64),
+			}
+			resources[key] = resource
+			resourceTypes[key] = row.DatabaseType
+		}
+		resource.Metrics[row.MetricName] = row.MetricValue
+		if row.CollectedAt.After(resource.LastSeen) {
+			resource.LastSeen = row.CollectedAt
+		}
+	}
+
+	result := &OrganizationInfrastructure{
+		OrganizationID: organizationID,
+		WorkspaceID:    workspaceID,
+		Window:         window.String(),
+		Hosts:          []InfrastructureResource{},
+		Containers:     []InfrastructureResource{},
+		Processes:      []InfrastructureResource{},
+	}
+	for key, resource := range resources {
+		switch resourceTypes[key] {
+		case InfraTypeHost:
+			result.Hosts = append(result.Hosts, *resource)
+		case InfraTypeContainer:
+			result.Containers = append(result.Containers, *resource)
+		case InfraTypeProcess:
+			result.Processes = append(result.Processes, *resource)
+		}
+	}
+	for _, list := range [][]InfrastructureResource{result.Hosts, result.Containers, result.Processes} {
+		sort.Slice(list, func(a, b int) bool {
+			if list[a].Host != list[b].Host {
+				return list[a].Host < list[b].Host
+			}
+			return list[a].Name < list[b].Name
+		})
+	}
+	return result, nil
+}
+
+// resolveHostTenant 根据配置的主机映射获取组织和工作空间，第一个匹配的规则生效
+func resolveHostTenant(mappings []config.HostMappingConfig, hostName string) (string, string) {
+	for _, mapping := range mappings {
+		if matched, _ := path.Match(mapping.HostPattern, hostName); matched {
+			return mapping.OrganizationID, mapping.WorkspaceID
+		}
+	}
+	return "", ""
+}
+
+// collectInfrastructureMetrics 增量导入light_admin的主机、容器和进程指标
+func (dc *DataCollector) collectInfrastructureMetrics(ctx context.Context) error {
+	infraConfig := dc.dbManager.Config.Monitoring.Infrastructure
+	mappings := infraConfig.HostMappings
+
+	// newMetric 构建带组织和主机标签的指标
+	newMetric := func(databaseType, name, hostName, metricType, metricName string, value float64, unit string, collectedAt time.Time, tags map[string]interface{}) models.ResourceMetric {
+		organizationID, workspaceID := resolveHostTenant(mappings, hostName)
+		tags["host"] = hostName
+		metric := models.ResourceMetric{
+			DatabaseType: databaseType,
+			DatabaseName: name,
+			MetricType:   metricType,
+			MetricName:   metricName,
+			MetricValue:  value,
+			Unit:         unit,
+			CollectedAt:  collectedAt,
+		}
+		if organizationID != "" {
+			metric.OrganizationID = &organizationID
+			tags["organization_id"] = organizationID
+		}
+		if workspaceID != "" {
+			tags["workspace_id"] = workspaceID
+		}
+		metric.Tags = dc.formatTags(tags)
+		return metric
+	}
+
+	var errs []string
+
+	hostRows, err := ingestLightAdminRows(ctx, dc, "light_admin.host_metrics", func(row models.HostMetric) (time.Time, string) {
+		return row.UpdatedAt, row.ID.String()
+	}, func(row models.HostMetric) []models.ResourceMetric {
+		var metrics []models.ResourceMetric
+		add := func(metricType, metricName string, value *float64, unit string) {
+			if value != nil {
+				tags := map[string]interface{}{}
+				if row.Platform != nil && *row.Platform != "" {
+					tags["platform"] = *row.Platform
+				}
+				metrics = append(metrics, newMetric(InfraTypeHost, row.HostName, row.HostName, metricType, metricName, *value, unit, row.UpdatedAt, tags))
+			}
+		}
+		add("cpu", "cpu_usage_percent", row.CPUUsage, "percent")
+		add("memory", "memory_usage_percent", row.MemoryUsage, "percent")
+		add("cpu", "cpu_load", row.CPULoad, "load")
+		if row.HostStatus != nil {
+			status := float64(*row.HostStatus)
+			add("status", "host_status", &status, "status")
+		}
+		return metrics
+	})
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("host_metrics: %v", err))
+	}
+
+	containerRows, err := ingestLightAdminRows(ctx, dc, "light_admin.container_metrics", func(row models.ContainerMetric) (time.Time, string) {
+		return row.UpdatedAt, row.ID.String()
+	}, func(row models.ContainerMetric) []models.ResourceMetric {
+		var metrics []models.ResourceMetric
+		add := func(metricType, metricName string, value *float64, unit string) {
+			if value != nil {
+				tags := map[string]interface{}{"image": row.Image}
+				if row.ContainerID != nil {
+					tags["container_id"] = *row.ContainerID
+				}
+				metrics = append(metrics, newMetric(InfraTypeContainer, row.ContainerName, row.HostName, metricType, metricName, *value, unit, row.UpdatedAt, tags))
+			}
+		}
+		add("cpu", "cpu_usage_percent", row.CPUUsage, "percent")
+		add("memory", "memory_usage_percent", row.MemoryUsage, "percent")
+		if row.Status != nil {
+			status := float64(*row.Status)
+			add("status", "container_status", &status, "status")
+		}
+		return metrics
+	})
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("container_metrics: %v", err))
+	}
+
+	processRows, err := ingestLightAdminRows(ctx, dc, "light_admin.process_metrics", func(row models.ProcessMetric) (time.Time, string) {
+		return row.UpdatedAt, row.ID.String()
+	}, func(row models.ProcessMetric) []models.ResourceMetric {
+		var metrics []models.ResourceMetric
+		add := func(metricType, metricName string, value *float64, unit string) {
+			if value != nil {
+				tags := map[string]interface{}{"pid": row.PID}
+				if row.ProcessUser != nil {
+					tags["user"] = *row.ProcessUser
+				}
+				metrics = append(metrics, newMetric(InfraTypeProcess, row.ProcessName, row.HostName, metricType, metricName, *value, unit, row.UpdatedAt, tags))
+			}
+		}
+		add("cpu", "cpu_usage_percent", row.CPUUsage, "percent")
+		add("memory", "memory_usage_percent", row.MemoryUsage, "percent")
+		return metrics
+	})
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("process_metrics: %v", err))
+	}
+
+	if hostRows+containerRows+processRows > 0 {
+		log.Printf("Infrastructure metrics ingested: %d host rows, %d container rows, %d process rows", hostRows, containerRows, processRows)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ingestedRowKey 已导入行的去重键，updated_at精确到微秒（与PostgreSQL一致）
+type ingestedRowKey struct {
+	id        string
+	updatedAt int64
+}
+
+// filterIngestedRows 去掉回退窗口内已导入的行，返回需要导入的行及其去重键
+func filterIngestedRows[T any](rows []T, position func(row T) (time.Time, string), seen map[ingestedRowKey]bool) ([]T, []ingestedRowKey) {
+	var fresh []T
+	var keys []ingestedRowKey
+	for _, row := range rows {
+		updatedAt, id := position(row)
+		key := ingestedRowKey{id: id, updatedAt: updatedAt.UnixMicro()}
+		if seen[key] {
+			continue
+		}
+		fresh = append(fresh, row)
+		keys = append(keys, key)
+	}
+	return fresh, keys
+}
+
+// positionAfter 判断(updatedAt, id)是否在水位线之后
+func positionAfter(updatedAt time.Time, id string, watermark models.IngestionWatermark) bool {
+	if !updatedAt.Equal(watermark.LastUpdatedAt) {
+		return updatedAt.After(watermark.LastUpdatedAt)
+	}
+	return id > watermark.LastID
+}
+
+// ingestLightAdminRows 按(updated_at, id)水位线分批读取light_admin表，返回导入的行数。
+// 事务提交较晚的行updated_at可能早于已保存的水位线，因此每次从水位线前回退overlap_seconds重新读取，
+// 回退窗口内已导入的行按(id, updated_at)去重；每批的指标、去重记录和水位线在同一事务中写入
+func ingestLightAdminRows[T any](ctx context.Context, dc *DataCollector, source string, position func(row T) (time.Time, string), toMetrics func(row T) []models.ResourceMetric) (int, error) {
+	infraConfig := dc.dbManager.Config.Monitoring.Infrastructure
+	batchSize := infraConfig.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	overlap := time.Duration(infraConfig.OverlapSeconds) * time.Second
+
+	var watermark models.IngestionWatermark
+	err := dc.dbManager.SaasMonitorDB.WithContext(ctx).First(&watermark, "source = ?", source).Error
+	if err == gorm.ErrRecordNotFound {
+		// 首次导入只回溯有限的时间范围，避免一次性导入全部历史
+		watermark = models.IngestionWatermark{
+			Source:        source,
+			LastUpdatedAt: time.Now().Add(-time.Duration(infraConfig.InitialLookbackMinutes) * time.Minute),
+			LastID:        infraZeroID,
+		}
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to load watermark: %w", err)
+	}
+
+	windowStart := watermark.LastUpdatedAt.Add(-overlap)
+	var ingested []models.IngestedRow
+	if err := dc.dbManager.SaasMonitorDB.WithContext(ctx).
+		Where("source = ? AND row_updated_at >= ?", source, windowStart).
+		Find(&ingested).Error; err != nil {
+		return 0, fmt.Errorf("failed to load ingested rows: %w", err)
+	}
+	seen := make(map[ingestedRowKey]bool, len(ingested))
+	for _, row := range ingested {
+		seen[ingestedRowKey{id: row.RowID, updatedAt: row.RowUpdatedAt.UnixMicro()}] = true
+	}
+
+	// 读取游标从回退窗口开始，水位线只前进不后退；只包含已导入行的批次不计入批次上限
+	cursorAt, cursorID := windowStart, infraZeroID
+	total := 0
+	for batch := 0; batch < infraMaxBatchesPerRun; {
+		var rows []T
+		if err := dc.dbManager.LightAdminDB.WithContext(ctx).
+			Where("updated_at > ? OR (updated_at = ? AND id > ?)", cursorAt, cursorAt, cursorID).
+			Order("updated_at, id").
+			Limit(batchSize).
+			Find(&rows).Error; err != nil {
+			return total, fmt.Errorf("failed to read rows: %w", err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		cursorAt, cursorID = position(rows[len(rows)-1])
+
+		fresh, keys := filterIngestedRows(rows, position, seen)
+		advanced := positionAfter(cursorAt, cursorID, watermark)
+		if len(fresh) > 0 || advanced {
+			var metrics []models.ResourceMetric
+			for _, row := range fresh {
+				metrics = append(metrics, toMetrics(row)...)
+			}
+			ingestedRows := make([]models.IngestedRow, 0, len(keys))
+			for _, key := range keys {
+				ingestedRows = append(ingestedRows, models.IngestedRow{Source: source, RowID: key.id, RowUpdatedAt: time.UnixMicro(key.updatedAt)})
+			}
+			next := watermark
+			if advanced {
+				next.LastUpdatedAt, next.LastID = cursorAt, cursorID
+			}
+			next.RowsIngested += int64(len(fresh))
+
+			err := dc.dbManager.SaasMonitorDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if len(metrics) > 0 {
+					if err := tx.CreateInBatches(metrics, 500).Error; err != nil {
+						return err
+					}
+				}
+				if len(ingestedRows) > 0 {
+					if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(ingestedRows, 500).Error; err != nil {
+						return err
+					}
+				}
+				return tx.Save(&next).Error
+			})
+			if err != nil {
+				return total, fmt.Errorf("failed to save metrics: %w", err)
+			}
+
+			watermark = next
+			for _, key := range keys {
+				seen[key] = true
+			}
+			total += len(fresh)
+			if len(fresh) > 0 {
+				batch++
+			}
+		}
+		if len(rows) < batchSize {
+			break
+		}
+	}
+
+	// 清理已超出回退窗口的去重记录
+	if err := dc.dbManager.SaasMonitorDB.WithContext(ctx).
+		Where("source = ? AND row_updated_at < ?", source, watermark.LastUpdatedAt.Add(-overlap)).
+		Delete(&models.IngestedRow{}).Error; err != nil {
+		log.Printf("Failed to prune ingested rows for %s: %v", source, err)
+	}
+	return total, nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"sass-monitor/internal/models"
+)
+
+// testLightAdminRow 模拟light_admin指标表的行
+type testLightAdminRow struct {
+	id        string
+	updatedAt time.Time
+}
+
+func testRowPosition(row testLightAdminRow) (time.Time, string) {
+	return row.updatedAt, row.id
+}
+
+func TestFilterIngestedRows(t *testing.T) {
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	seen := map[ingestedRowKey]bool{
+		{id: "a", updatedAt: base.UnixMicro()}: true,
+		{id: "b", updatedAt: base.UnixMicro()}: true,
+	}
+	rows := []testLightAdminRow{
+		{id: "a", updatedAt: base},                      // 回退窗口内已导入
+		{id: "c", updatedAt: base},                      // 提交晚于水位线的行
+		{id: "b", updatedAt: base.Add(time.Second)},     // 同一行更新后作为新数据
+		{id: "b", updatedAt: base.Add(time.Nanosecond)}, // 纳秒差异在PostgreSQL中不存在
+		{id: "d", updatedAt: base.Add(2 * time.Second)}, // 水位线之后的新行
+	}
+
+	fresh, keys := filterIngestedRows(rows, testRowPosition, seen)
+
+	wantIDs := []string{"c", "b", "d"}
+	if len(fresh) != len(wantIDs) || len(keys) != len(wantIDs) {
+		t.Fatalf("filterIngestedRows() returned %d rows and %d keys, want %d", len(fresh), len(keys), len(wantIDs))
+	}
+	for i, id := range wantIDs {
+		if fresh[i].id != id || keys[i].id != id {
+			t.Errorf("row %d = %q (key %q), want %q", i, fresh[i].id, keys[i].id, id)
+		}
+		if keys[i].updatedAt != fresh[i].updatedAt.UnixMicro() {
+			t.Errorf("key %d updatedAt = %d, want %d", i, keys[i].updatedAt, fresh[i].updatedAt.UnixMicro())
+		}
+	}
+}
+
+func TestPositionAfter(t *testing.T) {
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	watermark := models.IngestionWatermark{LastUpdatedAt: base, LastID: "m"}
+
+	tests := []struct {
+		name      string
+		updatedAt time.Time
+		id        string
+		want      bool
+	}{
+		{"later time", base.Add(time.Millisecond), "a", true},
+		{"earlier time", base.Add(-time.Millisecond), "z", false},
+		{"same time higher id", base, "n", true},
+		{"same time lower id", base, "l", false},
+		{"watermark itself", base, "m", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := positionAfter(tt.updatedAt, tt.id, watermark); got != tt.want {
+				t.Errorf("positionAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
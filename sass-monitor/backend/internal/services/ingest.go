@@ -0,0 +1,601 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// 写入格式
+const (
+	IngestFormatJSONLines    = "jsonl"
+	IngestFormatLineProtocol = "influx"
+)
+
+// IngestDatabaseType 通过写入接口上报的指标的database_type，database_name为来源
+const IngestDatabaseType = "custom"
+
+// ingestMaxErrors 响应中最多返回的错误条数
+const ingestMaxErrors = 20
+
+var (
+	ingestMetricNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.:-]{0,99}$`)
+	ingestTagKeyPattern     = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]{0,63}$`)
+)
+
+// IngestRateLimitError 来源超过每分钟写入限制
+type IngestRateLimitError struct {
+	Source     string
+	RetryAfter time.Duration
+}
+
+func (e *IngestRateLimitError) Error() string {
+	return fmt.Sprintf("ingest rate limit exceeded for source '%s', retry after %s", e.Source, e.RetryAfter.Round(time.Second))
+}
+
+// IngestBatchTooLargeError 单批数据点超过来源的每分钟限额，重试也不会成功，需要拆分批次
+type IngestBatchTooLargeError struct {
+	Source string
+	Points int
+	Limit  int
+}
+
+func (e *IngestBatchTooLargeError) Error() string {
+	return fmt.Sprintf("batch of %d points for source '%s' exceeds the rate limit of %d points per minute, split it into smaller batches", e.Points, e.Source, e.Limit)
+}
+
+// IngestPoint 单个数据点（JSON lines格式的一行）
+type IngestPoint struct {
+	Metric         string            `json:"metric"`
+	Value          *float64          `json:"value"`
+	Unit           string            `json:"unit"`
+	Type           string            `json:"type"` // metric_type，默认custom
+	OrganizationID string            `json:"organization_id"`
+	Tags           map[string]string `json:"tags"`
+	Timestamp      interface{}       `json:"timestamp"` // RFC3339字符串或unix时间戳（秒或毫秒）
+	collectedAt    time.Time
+	line           int
+}
+
+// IngestResult 写入结果
+type IngestResult struct {
+	Source   string   `json:"source"`
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors,omitempty"` // 最多返回前20条
+}
+
+func (r *IngestResult) reject(line int, format string, args ...interface{}) {
+	r.Rejected++
+	if len(r.Errors) < ingestMaxErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+	}
+}
+
+// IngestService 外部指标写入服务，按来源限流并限制标签基数
+type IngestService struct {
+	dbManager *database.DatabaseManager
+	writer    *MetricWriter
+	limiter   *ingestRateLimiter
+	series    *seriesTracker
+}
+
+func NewIngestService(dbManager *database.DatabaseManager, writer *MetricWriter) *IngestService {
+	ingestConfig := dbManager.Config.Monitoring.Ingest
+	return &IngestService{
+		dbManager: dbManager,
+		writer:    writer,
+		limiter:   newIngestRateLimiter(ingestConfig.RateLimitPerMinute),
+		series:    newSeriesTracker(ingestConfig.MaxSeriesPerMetric, ingestConfig.MaxSeriesPerSource, time.Hour),
+	}
+}
+
+// Ingest 解析并写入一批数据点；格式错误或校验失败的行单独拒绝，其余照常写入
+func (s *IngestService) Ingest(ctx context.Context, source, format, precision string, body []byte) (*IngestResult, error) {
+	if !targetNamePattern.MatchString(source) {
+		return nil, fmt.Errorf("invalid source '%s': only letters, digits, '_' and '-' are allowed", source)
+	}
+
+	result := &IngestResult{Source: source}
+	var points []IngestPoint
+	switch format {
+	case IngestFormatJSONLines:
+		points = parseJSONLines(body, result)
+	case IngestFormatLineProtocol:
+		multiplier, err := lineProtocolPrecision(precision)
+		if err != nil {
+			return nil, err
+		}
+		points = parseLineProtocol(body, multiplier, result)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+
+	now := time.Now()
+	valid := points[:0]
+	for _, point := range points {
+		if err := s.validatePoint(&point, now); err != nil {
+			result.reject(point.line, "%v", err)
+			continue
+		}
+		valid = append(valid, point)
+	}
+	if len(valid) == 0 {
+		return result, nil
+	}
+
+	if err := s.reserve(map[string]int{source: len(valid)}, now); err != nil {
+		return result, err
+	}
+
+	metrics := make([]models.ResourceMetric, 0, len(valid))
+	for _, point := range valid {
+		if !s.allowSeries(source, point.Metric, point.Tags, now) {
+			result.reject(point.line, "metric '%s' exceeds the limit of active tag combinations (%d per metric, %d per source)", point.Metric, s.series.maxPerMetric, s.series.maxPerSource)
+			continue
+		}
+
+		tags := make(map[string]interface{}, len(point.Tags))
+		for key, value := range point.Tags {
+			tags[key] = value
+		}
+		metric := models.ResourceMetric{
+			DatabaseType: IngestDatabaseType,
+			DatabaseName: source,
+			MetricType:   point.Type,
+			MetricName:   point.Metric,
+			MetricValue:  *point.Value,
+			Unit:         point.Unit,
+			Tags:         formatMetricTags(tags),
+			CollectedAt:  point.collectedAt,
+		}
+		if point.OrganizationID != "" {
+			organizationID := point.OrganizationID
+			metric.OrganizationID = &organizationID
+		}
+		metrics = append(metrics, metric)
+	}
+
+	if err := s.writer.Write(metrics); err != nil {
+		return result, err
+	}
+	result.Accepted = len(metrics)
+	return result, nil
+}
+
+// reserve 按来源消耗写入令牌（counts为各来源的数据点数），任一来源不足时都不消耗。
+// 单批超过每分钟限额时返回IngestBatchTooLargeError，令牌不足时返回IngestRateLimitError
+func (s *IngestService) reserve(counts map[string]int, now time.Time) error {
+	if s.limiter.perMinute > 0 {
+		for source, n := range counts {
+			if n > s.limiter.perMinute {
+				return &IngestBatchTooLargeError{Source: source, Points: n, Limit: s.limiter.perMinute}
+			}
+		}
+	}
+	if source, retryAfter, allowed := s.limiter.Allow(counts, now); !allowed {
+		return &IngestRateLimitError{Source: source, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// allowSeries 检查来源和指标的活跃标签组合数是否超过上限
+func (s *IngestService) allowSeries(source, metric string, tags map[string]string, now time.Time) bool {
+	return s.series.Allow(source, metric, seriesHash(tags), now)
+}
+
+// validatePoint 校验数据点并补全默认值
+func (s *IngestService) validatePoint(point *IngestPoint, now time.Time) error {
+	ingestConfig := s.dbManager.Config.Monitoring.Ingest
+
+	if !ingestMetricNamePattern.MatchString(point.Metric) {
+		return fmt.Errorf("invalid metric name '%s'", point.Metric)
+	}
+	if point.Value == nil || math.IsNaN(*point.Value) || math.IsInf(*point.Value, 0) {
+		return fmt.Errorf("metric '%s' has no finite value", point.Metric)
+	}
+	if point.Type == "" {
+		point.Type = "custom"
+	}
+	if !ingestTagKeyPattern.MatchString(point.Type) || len(point.Type) > 50 {
+		return fmt.Errorf("invalid type '%s'", point.Type)
+	}
+	if len(point.Unit) > 20 {
+		return fmt.Errorf("unit '%s' is longer than 20 characters", point.Unit)
+	}
+
+	if point.OrganizationID == "" {
+		point.OrganizationID = point.Tags["organization_id"]
+	}
+	if len(point.OrganizationID) > 255 {
+		return fmt.Errorf("organization_id is longer than 255 characters")
+	}
+
+	maxTags := ingestConfig.MaxTagsPerPoint
+	if maxTags > 0 && len(point.Tags) > maxTags {
+		return fmt.Errorf("metric '%s' has %d tags, at most %d are allowed", point.Metric, len(point.Tags), maxTags)
+	}
+	for key, value := range point.Tags {
+		if !ingestTagKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid tag key '%s'", key)
+		}
+		if len(value) > 256 {
+			return fmt.Errorf("tag '%s' value is longer than 256 characters", key)
+		}
+	}
+
+	if point.collectedAt.IsZero() {
+		collectedAt, err := parseIngestTimestamp(point.Timestamp, now)
+		if err != nil {
+			return err
+		}
+		point.collectedAt = collectedAt
+	}
+	if point.collectedAt.Before(now.AddDate(0, 0, -7)) || point.collectedAt.After(now.Add(10*time.Minute)) {
+		return fmt.Errorf("timestamp %s is outside the accepted range (last 7 days)", point.collectedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// parseIngestTimestamp 解析JSON中的时间戳，大于1e12的数字按毫秒处理
+func parseIngestTimestamp(value interface{}, now time.Time) (time.Time, error) {
+	switch ts := value.(type) {
+	case nil:
+		return now, nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp '%s', expected RFC3339 or unix time", ts)
+		}
+		return parsed, nil
+	case float64:
+		if ts > 1e12 {
+			return time.UnixMilli(int64(ts)), nil
+		}
+		seconds, fraction := math.Modf(ts)
+		return time.Unix(int64(seconds), int64(fraction*1e9)), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %v", value)
+}
+
+// parseJSONLines 解析JSON lines，每行一个IngestPoint
+func parseJSONLines(body []byte, result *IngestResult) []IngestPoint {
+	var points []IngestPoint
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var point IngestPoint
+		if err := json.Unmarshal(line, &point); err != nil {
+			result.reject(lineNumber, "invalid JSON: %v", err)
+			continue
+		}
+		point.line = lineNumber
+		points = append(points, point)
+	}
+	return points
+}
+
+// lineProtocolPrecision 时间戳精度对应的纳秒倍数
+func lineProtocolPrecision(precision string) (int64, error) {
+	switch precision {
+	case "", "ns":
+		return 1, nil
+	case "us":
+		return int64(time.Microsecond), nil
+	case "ms":
+		return int64(time.Millisecond), nil
+	case "s":
+		return int64(time.Second), nil
+	}
+	return 0, fmt.Errorf("unsupported precision '%s', expected ns, us, ms or s", precision)
+}
+
+// parseLineProtocol 解析Influx line protocol（measurement,tag=v field=1.0,count=2i [timestamp]），
+// 每个数值字段生成一个数据点，字段名为value时指标名为measurement，否则为measurement_field
+func parseLineProtocol(body []byte, precision int64, result *IngestResult) []IngestPoint {
+	var points []IngestPoint
+	for index, rawLine := range strings.Split(string(body), "\n") {
+		lineNumber := index + 1
+		line := strings.TrimSpace(rawLine)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sections := splitLineProtocol(line, ' ', true)
+		if len(sections) < 2 || len(sections) > 3 {
+			result.reject(lineNumber, "expected '<measurement>[,<tags>] <fields> [timestamp]'")
+			continue
+		}
+
+		keyParts := splitLineProtocol(sections[0], ',', false)
+		measurement := unescapeLineProtocol(keyParts[0])
+		tags := make(map[string]string, len(keyParts)-1)
+		valid := true
+		for _, part := range keyParts[1:] {
+			kv := splitLineProtocol(part, '=', false)
+			if len(kv) != 2 {
+				result.reject(lineNumber, "invalid tag '%s'", part)
+				valid = false
+				break
+			}
+			tags[unescapeLineProtocol(kv[0])] = unescapeLineProtocol(kv[1])
+		}
+		if !valid {
+			continue
+		}
+
+		var collectedAt time.Time
+		if len(sections) == 3 {
+			timestamp, err := strconv.ParseInt(sections[2], 10, 64)
+			if err != nil {
+				result.reject(lineNumber, "invalid timestamp '%s'", sections[2])
+				continue
+			}
+			collectedAt = time.Unix(0, timestamp*precision)
+		}
+
+		for _, field := range splitLineProtocol(sections[1], ',', true) {
+			kv := splitLineProtocol(field, '=', true)
+			if len(kv) != 2 {
+				result.reject(lineNumber, "invalid field '%s'", field)
+				continue
+			}
+			fieldName := unescapeLineProtocol(kv[0])
+			value, err := parseLineProtocolValue(kv[1])
+			if err != nil {
+				result.reject(lineNumber, "field '%s': %v", fieldName, err)
+				continue
+			}
+
+			metricName := measurement
+			if fieldName != "value" {
+				metricName = measurement + "_" + fieldName
+			}
+			pointTags := make(map[string]string, len(tags))
+			for key, tagValue := range tags {
+				pointTags[key] = tagValue
+			}
+			point := IngestPoint{
+				Metric:      metricName,
+				Value:       &value,
+				Tags:        pointTags,
+				collectedAt: collectedAt,
+				line:        lineNumber,
+			}
+			if collectedAt.IsZero() {
+				point.collectedAt = time.Now()
+			}
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+// parseLineProtocolValue 解析字段值：浮点数、整数(i)、无符号整数(u)、布尔值，不支持字符串字段
+func parseLineProtocolValue(raw string) (float64, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		return 0, fmt.Errorf("string fields are not supported")
+	case strings.HasSuffix(raw, "i"):
+		value, err := strconv.ParseInt(strings.TrimSuffix(raw, "i"), 10, 64)
+		return float64(value), err
+	case strings.HasSuffix(raw, "u"):
+		value, err := strconv.ParseUint(strings.TrimSuffix(raw, "u"), 10, 64)
+		return float64(value), err
+	}
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return 1, nil
+	case "f", "F", "false", "False", "FALSE":
+		return 0, nil
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
+// splitLineProtocol 按未转义的分隔符切分，respectQuotes时忽略双引号内的分隔符
+func splitLineProtocol(value string, separator byte, respectQuotes bool) []string {
+	var parts []string
+	start := 0
+	inQuotes := false
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\':
+			i++
+		case value[i] == '"' && respectQuotes:
+			inQuotes = !inQuotes
+		case value[i] == separator && !inQuotes:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// unescapeLineProtocol 去掉逗号、等号、空格前的转义符
+func unescapeLineProtocol(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	replacer := strings.NewReplacer(`\,`, ",", `\=`, "=", `\ `, " ", `\\`, `\`)
+	return replacer.Replace(value)
+}
+
+// seriesHash 标签组合的哈希
+func seriesHash(tags map[string]string) uint64 {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := fnv.New64a()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(tags[key]))
+		hash.Write([]byte{0})
+	}
+	return hash.Sum64()
+}
+
+// ingestRateLimiter 按来源的令牌桶，容量为每分钟限额
+type ingestRateLimiter struct {
+	perMinute int
+	mu        sync.Mutex
+	buckets   map[string]*ingestBucket
+	sweptAt   time.Time
+}
+
+type ingestBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// ingestBucketIdle 空闲超过该时间的桶已回满，删除后与新建的桶等价
+const ingestBucketIdle = time.Minute
+
+func newIngestRateLimiter(perMinute int) *ingestRateLimiter {
+	return &ingestRateLimiter{
+		perMinute: perMinute,
+		buckets:   make(map[string]*ingestBucket),
+	}
+}
+
+// Allow 为每个来源消耗counts中对应数量的令牌，全部足够时才消耗；
+// 任一来源不足时返回该来源和需要等待的时间。perMinute<=0表示不限流
+func (l *ingestRateLimiter) Allow(counts map[string]int, now time.Time) (string, time.Duration, bool) {
+	if l.perMinute <= 0 {
+		return "", 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.sweptAt) >= ingestBucketIdle {
+		for source, bucket := range l.buckets {
+			if now.Sub(bucket.updated) >= ingestBucketIdle {
+				delete(l.buckets, source)
+			}
+		}
+		l.sweptAt = now
+	}
+
+	capacity := float64(l.perMinute)
+	ratePerSecond := capacity / 60
+	for source, n := range counts {
+		bucket, exists := l.buckets[source]
+		if !exists {
+			bucket = &ingestBucket{tokens: capacity, updated: now}
+			l.buckets[source] = bucket
+		}
+		bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*ratePerSecond)
+		bucket.updated = now
+
+		if float64(n) > bucket.tokens {
+			missing := math.Min(float64(n), capacity) - bucket.tokens
+			return source, time.Duration(missing / ratePerSecond * float64(time.Second)), false
+		}
+	}
+	for source, n := range counts {
+		l.buckets[source].tokens -= float64(n)
+	}
+	return "", 0, true
+}
+
+// seriesTracker 记录每个来源最近出现过的标签组合，按(来源, 指标)和来源合计限制活跃组合数；
+// 超过idle未出现的组合不再计入，腾出的名额才能给新组合使用
+type seriesTracker struct {
+	maxPerMetric int
+	maxPerSource int
+	idle         time.Duration
+	mu           sync.Mutex
+	sources      map[string]*sourceSeries
+}
+
+// sourceSeries 单个来源的标签组合及最后出现时间
+type sourceSeries struct {
+	total   int
+	sweptAt time.Time
+	metrics map[string]map[uint64]time.Time
+}
+
+func newSeriesTracker(maxPerMetric, maxPerSource int, idle time.Duration) *seriesTracker {
+	return &seriesTracker{
+		maxPerMetric: maxPerMetric,
+		maxPerSource: maxPerSource,
+		idle:         idle,
+		sources:      make(map[string]*sourceSeries),
+	}
+}
+
+// Allow 已出现过的组合总是允许并刷新出现时间；上限<=0表示不限制
+func (t *seriesTracker) Allow(source, metric string, hash uint64, now time.Time) bool {
+	if t.maxPerMetric <= 0 && t.maxPerSource <= 0 {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	series, exists := t.sources[source]
+	if !exists {
+		series = &sourceSeries{sweptAt: now, metrics: make(map[string]map[uint64]time.Time)}
+		t.sources[source] = series
+	}
+	known := series.metrics[metric]
+	if _, seen := known[hash]; seen {
+		known[hash] = now
+		return true
+	}
+
+	// 每隔idle/4清理一次空闲的组合，拒绝时不逐次清理，避免达到上限后每个数据点都遍历全部组合
+	if now.Sub(series.sweptAt) >= t.idle/4 {
+		t.sweep(series, now)
+		known = series.metrics[metric]
+	}
+	if (t.maxPerMetric > 0 && len(known) >= t.maxPerMetric) || (t.maxPerSource > 0 && series.total >= t.maxPerSource) {
+		return false
+	}
+	if known == nil {
+		known = make(map[uint64]time.Time)
+		series.metrics[metric] = known
+	}
+	known[hash] = now
+	series.total++
+	return true
+}
+
+// sweep 删除来源中超过idle未出现的组合
+func (t *seriesTracker) sweep(series *sourceSeries, now time.Time) {
+	for metric, known := range series.metrics {
+		for hash, seenAt := range known {
+			if now.Sub(seenAt) >= t.idle {
+				delete(known, hash)
+				series.total--
+			}
+		}
+		if len(known) == 0 {
+			delete(series.metrics, metric)
+		}
+	}
+	series.sweptAt = now
+}
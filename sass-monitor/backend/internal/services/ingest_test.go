@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLineProtocol(t *testing.T) {
+	type wantPoint struct {
+		metric string
+		value  float64
+		tags   map[string]string
+		at     time.Time
+	}
+	tests := []struct {
+		name         string
+		body         string
+		precision    int64
+		want         []wantPoint
+		wantRejected int
+	}{
+		{
+			name:      "value field uses measurement name",
+			body:      "cpu,host=web-1 value=42.5 1700000000000000000",
+			precision: 1,
+			want: []wantPoint{
+				{metric: "cpu", value: 42.5, tags: map[string]string{"host": "web-1"}, at: time.Unix(0, 1700000000000000000)},
+			},
+		},
+		{
+			name:      "one point per field",
+			body:      "requests,route=/api count=12i,errors=3u,ok=t 1700000000",
+			precision: int64(time.Second),
+			want: []wantPoint{
+				{metric: "requests_count", value: 12, tags: map[string]string{"route": "/api"}, at: time.Unix(1700000000, 0)},
+				{metric: "requests_errors", value: 3, tags: map[string]string{"route": "/api"}, at: time.Unix(1700000000, 0)},
+				{metric: "requests_ok", value: 1, tags: map[string]string{"route": "/api"}, at: time.Unix(1700000000, 0)},
+			},
+		},
+		{
+			name:      "escaped characters in tags",
+			body:      `disk,mount=/var/lib\ data,label=a\,b\=c value=1 1700000000000`,
+			precision: int64(time.Millisecond),
+			want: []wantPoint{
+				{metric: "disk", value: 1, tags: map[string]string{"mount": "/var/lib data", "label": "a,b=c"}, at: time.UnixMilli(1700000000000)},
+			},
+		},
+		{
+			name:      "comments and blank lines are skipped",
+			body:      "# comment\n\nmem value=0.5 1\n",
+			precision: 1,
+			want: []wantPoint{
+				{metric: "mem", value: 0.5, tags: map[string]string{}, at: time.Unix(0, 1)},
+			},
+		},
+		{
+			name:         "string field rejected, other fields kept",
+			body:         `app,env=prod version="1.2 beta",uptime=30 5`,
+			precision:    int64(time.Second),
+			want:         []wantPoint{{metric: "app_uptime", value: 30, tags: map[string]string{"env": "prod"}, at: time.Unix(5, 0)}},
+			wantRejected: 1,
+		},
+		{
+			name:         "malformed lines",
+			body:         "no_fields\ncpu,host value=1\ncpu value=1 not-a-time\ncpu value=abc",
+			precision:    1,
+			wantRejected: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &IngestResult{}
+			points := parseLineProtocol([]byte(tt.body), tt.precision, result)
+			if result.Rejected != tt.wantRejected {
+				t.Errorf("rejected = %d, want %d (errors: %v)", result.Rejected, tt.wantRejected, result.Errors)
+			}
+			if len(points) != len(tt.want) {
+				t.Fatalf("got %d points, want %d", len(points), len(tt.want))
+			}
+			for i, want := range tt.want {
+				point := points[i]
+				if point.Metric != want.metric || point.Value == nil || *point.Value != want.value {
+					t.Errorf("point %d = %s %v, want %s %v", i, point.Metric, point.Value, want.metric, want.value)
+				}
+				if !reflect.DeepEqual(point.Tags, want.tags) {
+					t.Errorf("point %d tags = %v, want %v", i, point.Tags, want.tags)
+				}
+				if !point.collectedAt.Equal(want.at) {
+					t.Errorf("point %d time = %v, want %v", i, point.collectedAt, want.at)
+				}
+			}
+		})
+	}
+}
+
+func TestParseLineProtocolDefaultsTimestampToNow(t *testing.T) {
+	before := time.Now()
+	points := parseLineProtocol([]byte("cpu value=1"), 1, &IngestResult{})
+	if len(points) != 1 {
+		t.Fatalf("got %d points, want 1", len(points))
+	}
+	if points[0].collectedAt.Before(before) || points[0].collectedAt.After(time.Now()) {
+		t.Errorf("collected at %v, want the current time", points[0].collectedAt)
+	}
+}
+
+func TestLineProtocolPrecision(t *testing.T) {
+	tests := []struct {
+		precision string
+		want      int64
+		wantErr   bool
+	}{
+		{precision: "", want: 1},
+		{precision: "ns", want: 1},
+		{precision: "us", want: int64(time.Microsecond)},
+		{precision: "ms", want: int64(time.Millisecond)},
+		{precision: "s", want: int64(time.Second)},
+		{precision: "h", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := lineProtocolPrecision(tt.precision)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("lineProtocolPrecision(%q) = %d, %v; want %d, error %v", tt.precision, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestIngestReserve(t *testing.T) {
+	now := time.Now()
+	service := &IngestService{limiter: newIngestRateLimiter(60)}
+
+	var batchTooLarge *IngestBatchTooLargeError
+	if err := service.reserve(map[string]int{"agent": 61}, now); !errors.As(err, &batchTooLarge) {
+		t.Fatalf("batch above the limit: got %v, want IngestBatchTooLargeError", err)
+	}
+	if err := service.reserve(map[string]int{"agent": 60}, now); err != nil {
+		t.Fatalf("batch at the limit: %v", err)
+	}
+
+	var rateLimited *IngestRateLimitError
+	err := service.reserve(map[string]int{"agent": 30}, now.Add(10*time.Second))
+	if !errors.As(err, &rateLimited) {
+		t.Fatalf("exhausted bucket: got %v, want IngestRateLimitError", err)
+	}
+	if rateLimited.RetryAfter != 20*time.Second {
+		t.Errorf("retry after = %v, want 20s", rateLimited.RetryAfter)
+	}
+
+	// 任一来源不足时不消耗其他来源的令牌
+	if err := service.reserve(map[string]int{"other": 60, "agent": 60}, now.Add(10*time.Second)); err == nil {
+		t.Fatal("expected the agent source to be rate limited")
+	}
+	if err := service.reserve(map[string]int{"other": 60}, now.Add(10*time.Second)); err != nil {
+		t.Errorf("other source was charged for a rejected batch: %v", err)
+	}
+}
+
+func TestIngestRateLimiterEvictsIdleBuckets(t *testing.T) {
+	now := time.Now()
+	limiter := newIngestRateLimiter(60)
+	for _, source := range []string{"a", "b", "c"} {
+		if _, _, ok := limiter.Allow(map[string]int{source: 10}, now); !ok {
+			t.Fatalf("source %s should be allowed", source)
+		}
+	}
+	if _, _, ok := limiter.Allow(map[string]int{"a": 10}, now.Add(30*time.Second)); !ok {
+		t.Fatal("source a should be allowed")
+	}
+
+	// b和c空闲已满1分钟，桶已回满，清理后不影响限流结果
+	limiter.Allow(map[string]int{"a": 1}, now.Add(70*time.Second))
+	if len(limiter.buckets) != 1 || limiter.buckets["a"] == nil {
+		t.Errorf("buckets = %v, want only the active source", limiter.buckets)
+	}
+}
+
+func TestSeriesTrackerLimitsNewCombinations(t *testing.T) {
+	now := time.Now()
+	tracker := newSeriesTracker(2, 0, time.Hour)
+	hashes := []uint64{
+		seriesHash(map[string]string{"host": "a"}),
+		seriesHash(map[string]string{"host": "b"}),
+		seriesHash(map[string]string{"host": "c"}),
+	}
+	if !tracker.Allow("agent", "cpu", hashes[0], now) || !tracker.Allow("agent", "cpu", hashes[1], now) {
+		t.Fatal("first two combinations should be allowed")
+	}
+	if tracker.Allow("agent", "cpu", hashes[2], now) {
+		t.Error("third combination should be rejected")
+	}
+	if !tracker.Allow("agent", "cpu", hashes[0], now) {
+		t.Error("known combination should stay allowed")
+	}
+	if !tracker.Allow("agent", "memory", hashes[2], now) || !tracker.Allow("other", "cpu", hashes[2], now) {
+		t.Error("other metrics and sources have their own limits")
+	}
+}
+
+func TestSeriesTrackerKeepsActiveCombinations(t *testing.T) {
+	now := time.Now()
+	tracker := newSeriesTracker(2, 0, time.Hour)
+	a := seriesHash(map[string]string{"host": "a"})
+	b := seriesHash(map[string]string{"host": "b"})
+	c := seriesHash(map[string]string{"host": "c"})
+	tracker.Allow("agent", "cpu", a, now)
+	tracker.Allow("agent", "cpu", b, now)
+
+	// a持续上报，b空闲超过1小时后腾出名额
+	tracker.Allow("agent", "cpu", a, now.Add(50*time.Minute))
+	if tracker.Allow("agent", "cpu", c, now.Add(55*time.Minute)) {
+		t.Fatal("no combination has been idle for an hour yet")
+	}
+	if !tracker.Allow("agent", "cpu", c, now.Add(70*time.Minute)) {
+		t.Fatal("the idle combination should free its slot")
+	}
+	// 总数始终不超过上限：之前活跃的a和新的c都占着名额
+	if tracker.Allow("agent", "cpu", b, now.Add(75*time.Minute)) {
+		t.Error("a returning combination counts as new once it has expired")
+	}
+}
+
+func TestSeriesTrackerLimitsSource(t *testing.T) {
+	now := time.Now()
+	tracker := newSeriesTracker(0, 3, time.Hour)
+	for i, metric := range []string{"m1", "m2", "m3"} {
+		if !tracker.Allow("agent", metric, uint64(i), now) {
+			t.Fatalf("metric %s should be allowed", metric)
+		}
+	}
+	if tracker.Allow("agent", "m4", 4, now) {
+		t.Error("new metric names must not bypass the per-source limit")
+	}
+	if len(tracker.sources["agent"].metrics) != 3 {
+		t.Error("rejected metrics should not be tracked")
+	}
+}
@@ -0,0 +1,562 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// 后台任务状态
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// maxJobRetryBackoff 重试退避的上限
+const maxJobRetryBackoff = time.Hour
+
+// ErrJobFinished 任务已结束，不能取消
+var ErrJobFinished = errors.New("job has already finished")
+
+// JobHandlerFunc 后台任务处理函数，返回值序列化为JSON保存为任务结果；
+// ctx在任务被取消或服务停止时取消，处理函数应及时返回
+type JobHandlerFunc func(ctx context.Context, execution *JobExecution) (interface{}, error)
+
+// JobExecution 正在执行的任务，供处理函数读取参数和上报进度
+type JobExecution struct {
+	Job   models.BackgroundJob
+	queue *JobQueue
+}
+
+// Bind 将任务参数解析到v
+func (e *JobExecution) Bind(v interface{}) error {
+	if err := json.Unmarshal([]byte(e.Job.Payload), v); err != nil {
+		return NonRetryable(fmt.Errorf("invalid job payload: %w", err))
+	}
+	return nil
+}
+
+// ReportProgress 上报执行进度（0-100）和当前步骤说明
+func (e *JobExecution) ReportProgress(progress float64, message string) {
+	progress = math.Max(0, math.Min(progress, 100))
+	if err := e.queue.owned(e.queue.dbManager.SaasMonitorDB, e.Job.ID).Updates(map[string]interface{}{
+		"progress":         progress,
+		"progress_message": truncateString(message, 255),
+	}).Error; err != nil {
+		log.Printf("Failed to report progress of job %s: %v", e.Job.ID, err)
+	}
+}
+
+// nonRetryableError 不应重试的错误（参数错误等）
+type nonRetryableError struct {
+	err error
+}
+
+func (e *nonRetryableError) Error() string { return e.err.Error() }
+func (e *nonRetryableError) Unwrap() error { return e.err }
+
+// NonRetryable 标记错误不需要重试，任务直接失败
+func NonRetryable(err error) error {
+	return &nonRetryableError{err: err}
+}
+
+// EnqueueOptions 入队选项
+type EnqueueOptions struct {
+	MaxAttempts int     // 为0时使用job_queue.max_attempts
+	CreatedBy   *string // 提交任务的用户
+}
+
+// JobListFilter 任务列表过滤条件
+type JobListFilter struct {
+	Type     string
+	Status   string
+	Page     int
+	PageSize int
+}
+
+// JobQueue 基于PostgreSQL的持久化后台任务队列：任务保存在saas_monitor.background_jobs，
+// 各副本的worker通过FOR UPDATE SKIP LOCKED领取任务，失败后按指数退避重试，
+// 执行中定期写入心跳并检查取消请求，心跳超时的任务（所在副本已退出）会被重新排队
+type JobQueue struct {
+	dbManager    *database.DatabaseManager
+	workers      int
+	pollInterval time.Duration
+	maxAttempts  int
+	retryBackoff time.Duration
+	heartbeat    time.Duration
+	staleAfter   time.Duration
+	instanceID   string
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandlerFunc
+	running  map[uuid.UUID]context.CancelFunc
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wakeCh   chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+func NewJobQueue(dbManager *database.DatabaseManager) *JobQueue {
+	queueConfig := dbManager.Config.Monitoring.JobQueue
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := &JobQueue{
+		dbManager:    dbManager,
+		workers:      queueConfig.Workers,
+		pollInterval: time.Duration(queueConfig.PollIntervalMs) * time.Millisecond,
+		maxAttempts:  queueConfig.MaxAttempts,
+		retryBackoff: time.Duration(queueConfig.RetryBackoffSeconds) * time.Second,
+		heartbeat:    time.Duration(queueConfig.HeartbeatSeconds) * time.Second,
+		staleAfter:   time.Duration(queueConfig.StaleAfterSeconds) * time.Second,
+		instanceID:   fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		handlers:     make(map[string]JobHandlerFunc),
+		running:      make(map[uuid.UUID]context.CancelFunc),
+		ctx:          ctx,
+		cancel:       cancel,
+		wakeCh:       make(chan struct{}, 1),
+	}
+	if queue.pollInterval <= 0 {
+		queue.pollInterval = time.Second
+	}
+	if queue.maxAttempts <= 0 {
+		queue.maxAttempts = 3
+	}
+	if queue.retryBackoff <= 0 {
+		queue.retryBackoff = 30 * time.Second
+	}
+	if queue.heartbeat <= 0 {
+		queue.heartbeat = 10 * time.Second
+	}
+	if queue.staleAfter < 3*queue.heartbeat {
+		queue.staleAfter = 3 * queue.heartbeat
+	}
+	return queue
+}
+
+// Register 注册任务类型的处理函数，需在Start之前调用
+func (q *JobQueue) Register(jobType string, handler JobHandlerFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// Start 启动worker和心跳超时检查，workers为0时只入队不执行（由其他副本执行）
+func (q *JobQueue) Start() {
+	if q.workers <= 0 {
+		log.Println("Job queue workers disabled on this instance")
+		return
+	}
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		ticker := time.NewTicker(q.staleAfter / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				q.requeueStale()
+			case <-q.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Printf("Job queue started with %d workers", q.workers)
+}
+
+// Stop 停止领取任务并取消执行中的任务，被中断的任务重新排队且不计入执行次数
+func (q *JobQueue) Stop() {
+	q.stopOnce.Do(func() {
+		q.cancel()
+		q.wg.Wait()
+	})
+}
+
+// Enqueue 提交任务
+func (q *JobQueue) Enqueue(ctx context.Context, jobType string, payload interface{}, options EnqueueOptions) (*models.BackgroundJob, error) {
+	q.mu.RLock()
+	_, ok := q.handlers[jobType]
+	q.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown job type '%s'", jobType)
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	job := &models.BackgroundJob{
+		Type:        jobType,
+		Payload:     string(encoded),
+		Status:      JobStatusQueued,
+		RunAt:       time.Now(),
+		MaxAttempts: options.MaxAttempts,
+		CreatedBy:   options.CreatedBy,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = q.maxAttempts
+	}
+	if err := q.dbManager.SaasMonitorDB.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	select {
+	case q.wakeCh <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Get 获取任务
+func (q *JobQueue) Get(ctx context.Context, id uuid.UUID) (*models.BackgroundJob, error) {
+	var job models.BackgroundJob
+	if err := q.dbManager.SaasMonitorDB.WithContext(ctx).Where("id = ?", id).First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("job not found")
+		}
+		return nil, fmt.Errorf("failed to query job: %w", err)
+	}
+	return &job, nil
+}
+
+// List 分页获取任务，按创建时间倒序
+func (q *JobQueue) List(ctx context.Context, filter JobListFilter) ([]models.BackgroundJob, int64, error) {
+	query := q.dbManager.SaasMonitorDB.WithContext(ctx).Model(&models.BackgroundJob{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	var jobs []models.BackgroundJob
+	if err := query.Session(&gorm.Session{}).Order("created_at DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&jobs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	return jobs, total, nil
+}
+
+// JobTypes 获取已注册的任务类型
+func (q *JobQueue) JobTypes() []string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	types := make([]string, 0, len(q.handlers))
+	for jobType := range q.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Cancel 取消任务：排队中的任务直接取消，执行中的任务在本副本上立即中断，
+// 在其他副本上则在下一次心跳时中断
+func (q *JobQueue) Cancel(ctx context.Context, id uuid.UUID) (*models.BackgroundJob, error) {
+	db := q.dbManager.SaasMonitorDB.WithContext(ctx)
+	now := time.Now()
+
+	result := db.Model(&models.BackgroundJob{}).
+		Where("id = ? AND status = ?", id, JobStatusQueued).
+		Updates(map[string]interface{}{
+			"status":           JobStatusCancelled,
+			"cancel_requested": true,
+			"finished_at":      now,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		result = db.Model(&models.BackgroundJob{}).
+			Where("id = ? AND status = ?", id, JobStatusRunning).
+			Update("cancel_requested", true)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to cancel job: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			job, err := q.Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			if !job.CancelRequested {
+				return nil, ErrJobFinished
+			}
+			return job, nil
+		}
+
+		q.mu.RLock()
+		cancel, ok := q.running[id]
+		q.mu.RUnlock()
+		if ok {
+			cancel()
+		}
+	}
+
+	return q.Get(ctx, id)
+}
+
+// work worker循环：领取任务并执行，队列为空时等待轮询间隔或新任务入队
+func (q *JobQueue) work() {
+	defer q.wg.Done()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-timer.C:
+		case <-q.wakeCh:
+		}
+
+		for q.ctx.Err() == nil {
+			job, err := q.claim()
+			if err != nil {
+				log.Printf("Failed to claim job: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			q.execute(job)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(q.pollInterval)
+	}
+}
+
+// claim 领取一个到期的排队任务，只领取本副本注册了处理函数的类型
+func (q *JobQueue) claim() (*models.BackgroundJob, error) {
+	types := q.JobTypes()
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var job models.BackgroundJob
+	result := q.dbManager.SaasMonitorDB.WithContext(q.ctx).Raw(`
+		UPDATE background_jobs
+		SET status = ?, attempts = attempts + 1, locked_by = ?, heartbeat_at = ?,
+			started_at = COALESCE(started_at, ?), updated_at = ?
+		WHERE id = (
+			SELECT id FROM background_jobs
+			WHERE status = ? AND run_at <= ? AND type IN ?
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		JobStatusRunning, q.instanceID, now, now, now,
+		JobStatusQueued, now, types,
+	).Scan(&job)
+	if result.Error != nil {
+		if q.ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+// execute 执行任务，期间定期写入心跳并检查取消请求
+func (q *JobQueue) execute(job *models.BackgroundJob) {
+	q.mu.RLock()
+	handler := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	ctx, cancel := context.WithCancel(q.ctx)
+	defer cancel()
+	q.mu.Lock()
+	q.running[job.ID] = cancel
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, job.ID)
+		q.mu.Unlock()
+	}()
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(q.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// 任务已被重新排队（心跳曾超时）时不再属于本副本，中断执行
+				var cancelRequested bool
+				result := q.dbManager.SaasMonitorDB.Raw(
+					"UPDATE background_jobs SET heartbeat_at = ? WHERE id = ? AND locked_by = ? AND status = ? RETURNING cancel_requested",
+					time.Now(), job.ID, q.instanceID, JobStatusRunning,
+				).Scan(&cancelRequested)
+				switch {
+				case result.Error != nil:
+					log.Printf("Failed to write heartbeat of job %s: %v", job.ID, result.Error)
+				case result.RowsAffected == 0:
+					log.Printf("Job %s (%s) lost ownership, stopping execution", job.ID, job.Type)
+					cancel()
+				case cancelRequested:
+					cancel()
+				}
+			case <-heartbeatDone:
+				return
+			}
+		}
+	}()
+
+	log.Printf("Running job %s (%s), attempt %d/%d", job.ID, job.Type, job.Attempts, job.MaxAttempts)
+	result, err := q.runHandler(ctx, handler, job)
+	close(heartbeatDone)
+
+	q.finish(job, result, err)
+}
+
+// runHandler 调用处理函数，处理函数panic时转换为错误
+func (q *JobQueue) runHandler(ctx context.Context, handler JobHandlerFunc, job *models.BackgroundJob) (result interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("Job %s (%s) panicked: %v\n%s", job.ID, job.Type, recovered, debug.Stack())
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, &JobExecution{Job: *job, queue: q})
+}
+
+// finish 根据执行结果更新任务状态：成功、取消、服务停止时重新排队、按退避重试或失败
+func (q *JobQueue) finish(job *models.BackgroundJob, result interface{}, runErr error) {
+	db := q.dbManager.SaasMonitorDB
+	var cancelRequested bool
+	if runErr != nil {
+		db.Model(&models.BackgroundJob{}).Select("cancel_requested").Where("id = ?", job.ID).Scan(&cancelRequested)
+	}
+
+	// 只更新仍由本副本持有的任务，心跳超时后已被重新排队或由其他副本领取的任务不能覆盖
+	update := q.owned(db, job.ID).Updates(q.finishUpdates(job, result, runErr, cancelRequested, time.Now()))
+	if update.Error != nil {
+		log.Printf("Failed to update job %s: %v", job.ID, update.Error)
+	} else if update.RowsAffected == 0 {
+		log.Printf("Job %s (%s) lost ownership, result discarded", job.ID, job.Type)
+	}
+}
+
+// finishUpdates 任务结束时需要更新的字段
+func (q *JobQueue) finishUpdates(job *models.BackgroundJob, result interface{}, runErr error, cancelRequested bool, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{
+		"locked_by":    nil,
+		"heartbeat_at": nil,
+	}
+
+	var nonRetryable *nonRetryableError
+	switch {
+	case runErr == nil:
+		updates["status"] = JobStatusSucceeded
+		updates["progress"] = 100
+		updates["error"] = nil
+		updates["finished_at"] = now
+		if result != nil {
+			if encoded, err := json.Marshal(result); err != nil {
+				log.Printf("Failed to encode result of job %s: %v", job.ID, err)
+			} else {
+				updates["result"] = string(encoded)
+			}
+		}
+		log.Printf("Job %s (%s) succeeded", job.ID, job.Type)
+	case cancelRequested:
+		updates["status"] = JobStatusCancelled
+		updates["error"] = runErr.Error()
+		updates["finished_at"] = now
+		log.Printf("Job %s (%s) cancelled", job.ID, job.Type)
+	case q.ctx.Err() != nil:
+		// 服务停止中断的任务重新排队，不计入执行次数
+		updates["status"] = JobStatusQueued
+		updates["attempts"] = gorm.Expr("attempts - 1")
+		updates["run_at"] = now
+		log.Printf("Job %s (%s) interrupted by shutdown, requeued", job.ID, job.Type)
+	case errors.As(runErr, &nonRetryable) || job.Attempts >= job.MaxAttempts:
+		updates["status"] = JobStatusFailed
+		updates["error"] = runErr.Error()
+		updates["finished_at"] = now
+		log.Printf("Job %s (%s) failed after %d attempts: %v", job.ID, job.Type, job.Attempts, runErr)
+	default:
+		backoff := q.retryBackoff << (job.Attempts - 1)
+		if backoff <= 0 || backoff > maxJobRetryBackoff {
+			backoff = maxJobRetryBackoff
+		}
+		updates["status"] = JobStatusQueued
+		updates["error"] = runErr.Error()
+		updates["run_at"] = now.Add(backoff)
+		log.Printf("Job %s (%s) failed, retrying in %v: %v", job.ID, job.Type, backoff, runErr)
+	}
+	return updates
+}
+
+// owned 本副本正在执行的任务（locked_by为本副本且状态为running）
+func (q *JobQueue) owned(db *gorm.DB, id uuid.UUID) *gorm.DB {
+	return db.Model(&models.BackgroundJob{}).Where("id = ? AND locked_by = ? AND status = ?", id, q.instanceID, JobStatusRunning)
+}
+
+// requeueStale 心跳超时的执行中任务重新排队（已申请取消的直接取消，次数用尽的标记失败）
+func (q *JobQueue) requeueStale() {
+	now := time.Now()
+	result := q.dbManager.SaasMonitorDB.Exec(`
+		UPDATE background_jobs
+		SET status = CASE
+				WHEN cancel_requested THEN ?
+				WHEN attempts >= max_attempts THEN ?
+				ELSE ? END,
+			finished_at = CASE WHEN cancel_requested OR attempts >= max_attempts THEN ? ELSE NULL END,
+			error = ?, run_at = ?, locked_by = NULL, heartbeat_at = NULL, updated_at = ?
+		WHERE status = ? AND heartbeat_at < ?`,
+		JobStatusCancelled, JobStatusFailed, JobStatusQueued,
+		now, "worker heartbeat lost", now, now,
+		JobStatusRunning, now.Add(-q.staleAfter),
+	)
+	if result.Error != nil {
+		log.Printf("Failed to requeue stale jobs: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Recovered %d jobs with lost heartbeats", result.RowsAffected)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+	"sass-monitor/pkg/config"
+)
+
+// dryRunDB 只生成SQL不连接数据库的gorm实例，写操作不开启默认事务
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// testJobQueue 使用给定队列配置的JobQueue，不连接数据库
+func testJobQueue(queueConfig config.JobQueueConfig) *JobQueue {
+	return NewJobQueue(&database.DatabaseManager{Config: &config.Config{
+		Monitoring: config.MonitoringConfig{JobQueue: queueConfig},
+	}})
+}
+
+func TestNewJobQueueDefaults(t *testing.T) {
+	queue := testJobQueue(config.JobQueueConfig{HeartbeatSeconds: 20, StaleAfterSeconds: 30})
+	if queue.pollInterval != time.Second || queue.maxAttempts != 3 || queue.retryBackoff != 30*time.Second {
+		t.Errorf("defaults = %v, %d, %v", queue.pollInterval, queue.maxAttempts, queue.retryBackoff)
+	}
+	// 心跳超时不能短于三次心跳，否则正常执行的任务会被重新排队
+	if queue.heartbeat != 20*time.Second || queue.staleAfter != 60*time.Second {
+		t.Errorf("heartbeat = %v, staleAfter = %v; want 20s and 60s", queue.heartbeat, queue.staleAfter)
+	}
+}
+
+func TestJobQueueFinishUpdates(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	runErr := errors.New("connection reset")
+	tests := []struct {
+		name            string
+		attempts        int
+		result          interface{}
+		runErr          error
+		cancelRequested bool
+		shutdown        bool
+		want            map[string]interface{}
+	}{
+		{
+			name:     "succeeded with result",
+			attempts: 1,
+			result:   map[string]int{"points": 3},
+			want: map[string]interface{}{"status": JobStatusSucceeded, "progress": 100, "error": nil,
+				"finished_at": now, "result": `{"points":3}`},
+		},
+		{
+			name:            "cancel requested",
+			attempts:        1,
+			runErr:          context.Canceled,
+			cancelRequested: true,
+			want:            map[string]interface{}{"status": JobStatusCancelled, "error": "context canceled", "finished_at": now},
+		},
+		{
+			name:     "first retry",
+			attempts: 1,
+			runErr:   runErr,
+			want:     map[string]interface{}{"status": JobStatusQueued, "error": "connection reset", "run_at": now.Add(30 * time.Second)},
+		},
+		{
+			name:     "backoff doubles per attempt",
+			attempts: 3,
+			runErr:   runErr,
+			want:     map[string]interface{}{"status": JobStatusQueued, "error": "connection reset", "run_at": now.Add(2 * time.Minute)},
+		},
+		{
+			name:     "backoff is capped",
+			attempts: 9,
+			runErr:   runErr,
+			want:     map[string]interface{}{"status": JobStatusQueued, "error": "connection reset", "run_at": now.Add(maxJobRetryBackoff)},
+		},
+		{
+			name:     "attempts exhausted",
+			attempts: 10,
+			runErr:   runErr,
+			want:     map[string]interface{}{"status": JobStatusFailed, "error": "connection reset", "finished_at": now},
+		},
+		{
+			name:     "non-retryable error",
+			attempts: 1,
+			runErr:   NonRetryable(errors.New("unknown series")),
+			want:     map[string]interface{}{"status": JobStatusFailed, "error": "unknown series", "finished_at": now},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := testJobQueue(config.JobQueueConfig{})
+			job := &models.BackgroundJob{ID: uuid.New(), Type: "test", Attempts: tt.attempts, MaxAttempts: 10}
+			got := queue.finishUpdates(job, tt.result, tt.runErr, tt.cancelRequested, now)
+
+			tt.want["locked_by"] = nil
+			tt.want["heartbeat_at"] = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("finishUpdates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobQueueFinishUpdatesRequeuesOnShutdown(t *testing.T) {
+	queue := testJobQueue(config.JobQueueConfig{})
+	queue.cancel()
+	now := time.Now()
+	job := &models.BackgroundJob{ID: uuid.New(), Type: "test", Attempts: 3, MaxAttempts: 3}
+
+	got := queue.finishUpdates(job, nil, context.Canceled, false, now)
+	// 服务停止中断的任务不计入执行次数，即使次数已用尽也重新排队
+	if got["status"] != JobStatusQueued || got["run_at"] != now || got["attempts"] == nil {
+		t.Errorf("finishUpdates() = %v, want the job requeued without counting the attempt", got)
+	}
+	if _, ok := got["finished_at"]; ok {
+		t.Errorf("finishUpdates() = %v, requeued job should not be finished", got)
+	}
+}
+
+func TestJobQueueOwnedRequiresLock(t *testing.T) {
+	queue := testJobQueue(config.JobQueueConfig{})
+	id := uuid.New()
+	statement := queue.owned(dryRunDB(t), id).Updates(map[string]interface{}{"progress": 50}).Statement
+
+	wantSQL := `UPDATE "background_jobs" SET "progress"=$1,"updated_at"=$2 WHERE id = $3 AND locked_by = $4 AND status = $5`
+	if got := statement.SQL.String(); got != wantSQL {
+		t.Errorf("SQL = %s\nwant %s", got, wantSQL)
+	}
+	if statement.Vars[3] != queue.instanceID || statement.Vars[4] != JobStatusRunning {
+		t.Errorf("vars = %v, want the instance ID %q and status %q", statement.Vars, queue.instanceID, JobStatusRunning)
+	}
+}
+
+func TestJobQueueEnqueueUnknownType(t *testing.T) {
+	queue := testJobQueue(config.JobQueueConfig{})
+	queue.Register(BackfillJobType, func(ctx context.Context, execution *JobExecution) (interface{}, error) {
+		return nil, nil
+	})
+	if _, err := queue.Enqueue(context.Background(), "reindex", nil, EnqueueOptions{}); err == nil {
+		t.Error("Enqueue() with an unregistered type should fail")
+	}
+	if got := queue.JobTypes(); !reflect.DeepEqual(got, []string{BackfillJobType}) {
+		t.Errorf("JobTypes() = %v", got)
+	}
+}
+
+func TestJobExecutionBindInvalidPayload(t *testing.T) {
+	execution := &JobExecution{Job: models.BackgroundJob{Payload: `{"series":`}}
+	var payload BackfillJobPayload
+	err := execution.Bind(&payload)
+	var nonRetryable *nonRetryableError
+	if !errors.As(err, &nonRetryable) {
+		t.Errorf("Bind() error = %v, want a non-retryable error", err)
+	}
+}
+
+func TestJobQueueRunHandlerRecoversPanic(t *testing.T) {
+	queue := testJobQueue(config.JobQueueConfig{})
+	job := &models.BackgroundJob{ID: uuid.New(), Type: "test"}
+	_, err := queue.runHandler(context.Background(), func(ctx context.Context, execution *JobExecution) (interface{}, error) {
+		panic("boom")
+	}, job)
+	if err == nil || err.Error() != "job panicked: boom" {
+		t.Errorf("runHandler() error = %v, want the panic as an error", err)
+	}
+}
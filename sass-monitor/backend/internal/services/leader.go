@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"sass-monitor/internal/database"
+)
+
+// LeaderElector 通过saas_monitor上的会话级advisory lock进行选主：持有锁的副本为leader，
+// 锁随持有连接的会话存在，leader进程退出或连接断开后锁自动释放，其他副本在下一次竞选时接管
+type LeaderElector struct {
+	dbManager     *database.DatabaseManager
+	lockKey       int64
+	retryInterval time.Duration
+	checkInterval time.Duration // leader检查锁是否仍被本会话持有的间隔，短于retryInterval以便在其他副本接管前发现
+	instanceID    string
+	onElected     func()
+	onDemoted     func()
+
+	mu          sync.RWMutex
+	conn        *sql.Conn // 持有锁的专用连接，非leader时为nil
+	leaderSince *time.Time
+	lastError   string
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// LeaderStatus 选主状态
+type LeaderStatus struct {
+	Enabled     bool       `json:"enabled"`
+	IsLeader    bool       `json:"is_leader"`
+	InstanceID  string     `json:"instance_id"`
+	LeaderSince *time.Time `json:"leader_since,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// NewLeaderElector 创建选主器，onElected和onDemoted在选主协程中调用
+func NewLeaderElector(dbManager *database.DatabaseManager, onElected, onDemoted func()) *LeaderElector {
+	electionConfig := dbManager.Config.Monitoring.LeaderElection
+	retryInterval := time.Duration(electionConfig.RetryInterval) * time.Second
+	if retryInterval <= 0 {
+		retryInterval = 10 * time.Second
+	}
+
+	hostname, _ := os.Hostname()
+	return &LeaderElector{
+		dbManager:     dbManager,
+		lockKey:       electionConfig.LockKey,
+		retryInterval: retryInterval,
+		checkInterval: leaderCheckInterval(retryInterval),
+		instanceID:    fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		onElected:     onElected,
+		onDemoted:     onDemoted,
+		stopCh:        make(chan struct{}),
+	}
+}
+
+// leaderCheckInterval leader检查锁的间隔：retry_interval的1/5，至少1秒
+func leaderCheckInterval(retryInterval time.Duration) time.Duration {
+	interval := retryInterval / 5
+	if interval < time.Second {
+		interval = time.Second
+	}
+	if interval > retryInterval {
+		interval = retryInterval
+	}
+	return interval
+}
+
+// Start 立即竞选一次，之后非leader按retry_interval竞选，leader按更短的间隔检查锁
+func (le *LeaderElector) Start() {
+	le.wg.Add(1)
+	go func() {
+		defer le.wg.Done()
+		ticker := time.NewTicker(le.checkInterval)
+		defer ticker.Stop()
+		var lastAttempt time.Time
+		for {
+			if le.IsLeader() || time.Since(lastAttempt) >= le.retryInterval {
+				lastAttempt = time.Now()
+				le.tick()
+			}
+			select {
+			case <-ticker.C:
+			case <-le.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止竞选，leader主动释放锁以便其他副本尽快接管
+func (le *LeaderElector) Stop() {
+	le.stopOnce.Do(func() {
+		close(le.stopCh)
+		le.wg.Wait()
+
+		le.mu.Lock()
+		conn := le.conn
+		le.mu.Unlock()
+		if conn == nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", le.lockKey); err != nil {
+			log.Printf("Failed to release leader lock: %v", err)
+		}
+		le.demote("")
+		log.Printf("Instance %s released scheduler leadership", le.instanceID)
+	})
+}
+
+// tick leader检查本会话是否仍持有锁，非leader尝试获取锁
+func (le *LeaderElector) tick() {
+	le.mu.RLock()
+	conn := le.conn
+	le.mu.RUnlock()
+
+	if conn != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), le.checkInterval)
+		defer cancel()
+		// 连接断开时会话级锁已被数据库释放，其他副本可能已经接管
+		held, err := le.lockHeld(ctx, conn)
+		if err == nil && !held {
+			err = fmt.Errorf("advisory lock %d is no longer held by this session", le.lockKey)
+		}
+		if err != nil {
+			log.Printf("Instance %s lost scheduler leadership: %v", le.instanceID, err)
+			le.demote(err.Error())
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), le.retryInterval)
+	defer cancel()
+
+	acquired, err := le.tryAcquire(ctx)
+	if err != nil {
+		le.mu.Lock()
+		le.lastError = err.Error()
+		le.mu.Unlock()
+		return
+	}
+	if acquired {
+		log.Printf("Instance %s became scheduler leader", le.instanceID)
+		if le.onElected != nil {
+			le.onElected()
+		}
+	}
+}
+
+// tryAcquire 在专用连接上尝试获取advisory lock，成功时保留该连接
+func (le *LeaderElector) tryAcquire(ctx context.Context) (bool, error) {
+	sqlDB, err := le.dbManager.SaasMonitorDB.DB()
+	if err != nil {
+		return false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get connection for leader lock: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", le.lockKey).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("failed to acquire leader lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		le.mu.Lock()
+		le.lastError = ""
+		le.mu.Unlock()
+		return false, nil
+	}
+
+	now := time.Now()
+	le.mu.Lock()
+	le.conn = conn
+	le.leaderSince = &now
+	le.lastError = ""
+	le.mu.Unlock()
+	return true, nil
+}
+
+// lockHeld 在持有锁的会话上查询pg_locks，确认advisory lock仍被本会话持有
+func (le *LeaderElector) lockHeld(ctx context.Context, conn *sql.Conn) (bool, error) {
+	classID, objID := advisoryLockIDs(le.lockKey)
+	var held bool
+	err := conn.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND pid = pg_backend_pid() AND granted
+				AND classid = $1 AND objid = $2 AND objsubid = 1
+		)`, classID, objID).Scan(&held)
+	if err != nil {
+		return false, fmt.Errorf("failed to check leader lock: %w", err)
+	}
+	return held, nil
+}
+
+// advisoryLockIDs bigint键的advisory lock在pg_locks中的classid（高32位）和objid（低32位）
+func advisoryLockIDs(key int64) (int64, int64) {
+	return int64(uint64(key) >> 32), int64(uint32(key))
+}
+
+// demote 放弃leader身份并丢弃持有锁的连接（不放回连接池，避免会话仍存活时锁被池中连接继续持有）
+func (le *LeaderElector) demote(reason string) {
+	le.mu.Lock()
+	conn := le.conn
+	le.conn = nil
+	le.leaderSince = nil
+	le.lastError = reason
+	le.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+	conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	conn.Close()
+	if le.onDemoted != nil {
+		le.onDemoted()
+	}
+}
+
+// IsLeader 当前副本是否为leader
+func (le *LeaderElector) IsLeader() bool {
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	return le.conn != nil
+}
+
+// Status 获取选主状态
+func (le *LeaderElector) Status() LeaderStatus {
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	return LeaderStatus{
+		Enabled:     true,
+		IsLeader:    le.conn != nil,
+		InstanceID:  le.instanceID,
+		LeaderSince: le.leaderSince,
+		LastError:   le.lastError,
+	}
+}
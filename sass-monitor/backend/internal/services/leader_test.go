@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sass-monitor/internal/models"
+)
+
+func TestLeaderCheckInterval(t *testing.T) {
+	tests := []struct {
+		retry time.Duration
+		want  time.Duration
+	}{
+		{retry: 10 * time.Second, want: 2 * time.Second},
+		{retry: 30 * time.Second, want: 6 * time.Second},
+		{retry: 3 * time.Second, want: time.Second},
+		{retry: 500 * time.Millisecond, want: 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := leaderCheckInterval(tt.retry); got != tt.want {
+			t.Errorf("leaderCheckInterval(%v) = %v, want %v", tt.retry, got, tt.want)
+		}
+	}
+}
+
+func TestAdvisoryLockIDs(t *testing.T) {
+	tests := []struct {
+		key       int64
+		wantClass int64
+		wantObj   int64
+	}{
+		{key: 7310001, wantClass: 0, wantObj: 7310001},
+		{key: 1<<32 + 5, wantClass: 1, wantObj: 5},
+		{key: -1, wantClass: 0xFFFFFFFF, wantObj: 0xFFFFFFFF},
+	}
+	for _, tt := range tests {
+		classID, objID := advisoryLockIDs(tt.key)
+		if classID != tt.wantClass || objID != tt.wantObj {
+			t.Errorf("advisoryLockIDs(%d) = %d, %d; want %d, %d", tt.key, classID, objID, tt.wantClass, tt.wantObj)
+		}
+	}
+}
+
+// newFollowerScheduler 启用选主但尚未当选的调度器
+func newFollowerScheduler() *TaskScheduler {
+	ts := &TaskScheduler{
+		jobs:   make(map[string]*schedulerJob),
+		leader: &LeaderElector{},
+	}
+	ts.leaderCtx, ts.leaderCancel = context.WithCancel(context.Background())
+	ts.leaderCancel()
+	return ts
+}
+
+func TestSchedulerJobContextFollowsLeadership(t *testing.T) {
+	ts := newFollowerScheduler()
+	if ts.jobContext().Err() == nil {
+		t.Fatal("job context should be cancelled before the instance is elected")
+	}
+
+	ts.startLeading()
+	ctx := ts.jobContext()
+	if ctx.Err() != nil {
+		t.Fatal("job context should be usable after election")
+	}
+
+	ts.stopLeading()
+	select {
+	case <-ctx.Done():
+	default:
+		t.Fatal("demotion should cancel the context of running jobs")
+	}
+
+	ts.startLeading()
+	if ts.jobContext().Err() != nil {
+		t.Error("re-election should create a new job context")
+	}
+}
+
+func TestExecuteJobSkipsOnFollower(t *testing.T) {
+	ts := newFollowerScheduler()
+	ran := false
+	ts.registerJob("alert_checker", "检查告警规则", "@every 1m", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	job, err := ts.claimJob("alert_checker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.executeJob(job, JobTriggerSchedule)
+
+	if ran {
+		t.Error("a follower must not run scheduled jobs")
+	}
+	if job.running {
+		t.Error("skipped job should be released")
+	}
+}
+
+func TestSchedulerLeadingWithoutElection(t *testing.T) {
+	ts := &TaskScheduler{leaderCtx: context.Background()}
+	if !ts.leading(ts.jobContext()) {
+		t.Error("without leader election every instance runs jobs")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if ts.leading(ctx) {
+		t.Error("a cancelled job must not produce side effects")
+	}
+}
+
+func TestApplyPersistedJobTriggerPending(t *testing.T) {
+	requestedAt := time.Now()
+	status := JobStatus{Name: "data_cleanup"}
+
+	applyPersistedJob(&status, models.ScheduledJob{Schedule: "0 3 * * *", TriggerRequestedAt: &requestedAt})
+	if !status.TriggerPending {
+		t.Error("a trigger requested on a follower should be reported as pending")
+	}
+	applyPersistedJob(&status, models.ScheduledJob{Schedule: "0 3 * * *"})
+	if status.TriggerPending {
+		t.Error("a consumed trigger should no longer be pending")
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// ErrMetricBufferFull 写入缓冲区已满（数据库写入跟不上），调用方应稍后重试
+var ErrMetricBufferFull = errors.New("metric write buffer is full")
+
+// MetricWriter 缓冲写入ResourceMetric，达到批量大小或刷新间隔时批量写入数据库
+type MetricWriter struct {
+	dbManager     *database.DatabaseManager
+	batchSize     int
+	flushInterval time.Duration
+	maxBuffered   int
+	maxRetries    int
+
+	mu       sync.Mutex
+	buffer   []models.ResourceMetric
+	written  int64
+	dropped  int64
+	failures int // 缓冲区头部批次连续写入失败的次数
+
+	flushCh  chan struct{}
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// MetricWriterStats 写入器统计
+type MetricWriterStats struct {
+	Buffered int   `json:"buffered"`
+	Written  int64 `json:"written"`
+	Dropped  int64 `json:"dropped"`
+}
+
+func NewMetricWriter(dbManager *database.DatabaseManager) *MetricWriter {
+	ingestConfig := dbManager.Config.Monitoring.Ingest
+	writer := &MetricWriter{
+		dbManager:     dbManager,
+		batchSize:     ingestConfig.BatchSize,
+		flushInterval: time.Duration(ingestConfig.FlushIntervalMs) * time.Millisecond,
+		maxBuffered:   ingestConfig.MaxBufferedPoints,
+		maxRetries:    ingestConfig.MaxFlushRetries,
+		flushCh:       make(chan struct{}, 1),
+		stopCh:        make(chan struct{}),
+	}
+	if writer.batchSize <= 0 {
+		writer.batchSize = 500
+	}
+	if writer.flushInterval <= 0 {
+		writer.flushInterval = time.Second
+	}
+	if writer.maxBuffered < writer.batchSize {
+		writer.maxBuffered = writer.batchSize * 100
+	}
+	if writer.maxRetries <= 0 {
+		writer.maxRetries = 5
+	}
+	return writer
+}
+
+// Start 启动后台刷新
+func (w *MetricWriter) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.Flush()
+			case <-w.flushCh:
+				w.Flush()
+			case <-w.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台刷新并写入剩余数据
+func (w *MetricWriter) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		w.wg.Wait()
+		w.Flush()
+	})
+}
+
+// Write 将指标加入缓冲区，缓冲区剩余空间不足时整体拒绝并返回ErrMetricBufferFull
+func (w *MetricWriter) Write(metrics []models.ResourceMetric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	if len(w.buffer)+len(metrics) > w.maxBuffered {
+		w.mu.Unlock()
+		return ErrMetricBufferFull
+	}
+	w.buffer = append(w.buffer, metrics...)
+	full := len(w.buffer) >= w.batchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush 写入缓冲区中的所有指标，失败的批次在缓冲区未满时放回等待下次刷新；
+// 同一批次连续失败maxRetries次后丢弃（如包含无法写入的数据），避免一直阻塞后面的数据
+func (w *MetricWriter) Flush() {
+	w.mu.Lock()
+	pending := w.buffer
+	w.buffer = nil
+	w.mu.Unlock()
+
+	for start := 0; start < len(pending); start += w.batchSize {
+		end := start + w.batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+
+		if err := w.dbManager.SaasMonitorDB.CreateInBatches(batch, w.batchSize).Error; err != nil {
+			w.mu.Lock()
+			w.failures++
+			if w.failures >= w.maxRetries {
+				w.failures = 0
+				w.dropped += int64(len(batch))
+				w.mu.Unlock()
+				log.Printf("Dropping %d buffered metrics after %d failed writes: %v", len(batch), w.maxRetries, err)
+				continue
+			}
+			remaining := pending[start:]
+			if len(w.buffer)+len(remaining) <= w.maxBuffered {
+				w.buffer = append(remaining, w.buffer...)
+			} else {
+				w.dropped += int64(len(remaining))
+				w.failures = 0
+			}
+			w.mu.Unlock()
+			log.Printf("Failed to write %d buffered metrics: %v", len(remaining), err)
+			return
+		}
+
+		w.mu.Lock()
+		w.written += int64(len(batch))
+		w.failures = 0
+		w.mu.Unlock()
+	}
+}
+
+// Stats 获取写入器统计
+func (w *MetricWriter) Stats() MetricWriterStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return MetricWriterStats{
+		Buffered: len(w.buffer),
+		Written:  w.written,
+		Dropped:  w.dropped,
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+	"sass-monitor/pkg/config"
+)
+
+// newFailingMetricWriter 数据库不可达、每次写入都失败的写入器
+func newFailingMetricWriter(t *testing.T, maxRetries int) *MetricWriter {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1 connect_timeout=1"}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Monitoring.Ingest.BatchSize = 2
+	cfg.Monitoring.Ingest.MaxFlushRetries = maxRetries
+	return NewMetricWriter(&database.DatabaseManager{Config: cfg, SaasMonitorDB: db})
+}
+
+func TestMetricWriterDropsBatchAfterRetries(t *testing.T) {
+	writer := newFailingMetricWriter(t, 3)
+	if err := writer.Write(make([]models.ResourceMetric, 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	writer.Flush()
+	writer.Flush()
+	if stats := writer.Stats(); stats.Buffered != 3 || stats.Dropped != 0 {
+		t.Fatalf("stats = %+v, want the failed batch kept for retry", stats)
+	}
+
+	// 第3次失败后丢弃头部批次，剩余批次重新计数
+	writer.Flush()
+	if stats := writer.Stats(); stats.Buffered != 1 || stats.Dropped != 2 {
+		t.Errorf("stats = %+v, want the head batch dropped and the rest kept", stats)
+	}
+}
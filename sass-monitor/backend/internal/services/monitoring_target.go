@@ -0,0 +1,491 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+	"sass-monitor/pkg/config"
+)
+
+// targetTestTimeout 保存目标时连接测试的超时时间
+const targetTestTimeout = 10 * time.Second
+
+var targetNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,100}$`)
+
+// ErrTargetNotFound 监控目标不存在
+var ErrTargetNotFound = errors.New("monitoring target not found")
+
+// ErrTargetExists 同类型同名的目标已存在（与注册时的名称冲突为同一错误）
+var ErrTargetExists = database.ErrTargetExists
+
+// ErrInvalidTarget 目标参数不合法
+var ErrInvalidTarget = errors.New("invalid monitoring target")
+
+// ErrTargetConnection 目标连接测试失败
+var ErrTargetConnection = errors.New("connection test failed")
+
+// MonitoringTargetService 动态监控目标管理（CRUD、连接测试、运行时注册）
+type MonitoringTargetService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewMonitoringTargetService(dbManager *database.DatabaseManager) *MonitoringTargetService {
+	return &MonitoringTargetService{
+		dbManager: dbManager,
+	}
+}
+
+// MonitoringTargetRequest 创建/更新监控目标请求
+type MonitoringTargetRequest struct {
+	Name         string `json:"name" binding:"required"`
+	TargetType   string `json:"target_type" binding:"required"` // postgresql, clickhouse, redis, mysql
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	Username     string `json:"username"`
+	Password     string `json:"password"` // 更新时为空表示保持不变
+	DatabaseName string `json:"database_name"`
+	Enabled      *bool  `json:"enabled"`
+	MonitoringTargetOptions
+}
+
+// MonitoringTargetOptions 目标类型相关的连接选项（存储于options列）
+type MonitoringTargetOptions struct {
+	SSLMode          string   `json:"ssl_mode,omitempty"`          // postgresql, mysql
+	Mode             string   `json:"mode,omitempty"`              // redis: standalone, cluster, sentinel
+	Addrs            []string `json:"addrs,omitempty"`             // redis cluster种子节点或哨兵地址
+	MasterName       string   `json:"master_name,omitempty"`       // redis sentinel
+	SentinelPassword string   `json:"sentinel_password,omitempty"` // 存储时加密，不在响应中返回
+}
+
+// MonitoringTargetView 监控目标响应（不包含凭据）
+type MonitoringTargetView struct {
+	models.MonitoringTarget
+	Options     MonitoringTargetOptions `json:"options"`
+	HasPassword bool                    `json:"has_password"`
+	Registered  bool                    `json:"registered"` // 是否已在DatabaseManager中注册
+}
+
+// ListTargets 获取所有动态监控目标
+func (s *MonitoringTargetService) ListTargets(ctx context.Context) ([]MonitoringTargetView, error) {
+	var targets []models.MonitoringTarget
+	if err := s.dbManager.SaasMonitorDB.WithContext(ctx).Order("target_type, name").Find(&targets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get monitoring targets: %w", err)
+	}
+
+	views := make([]MonitoringTargetView, 0, len(targets))
+	for _, target := range targets {
+		views = append(views, s.toView(target))
+	}
+	return views, nil
+}
+
+// GetTarget 获取单个监控目标
+func (s *MonitoringTargetService) GetTarget(ctx context.Context, id string) (*MonitoringTargetView, error) {
+	target, err := s.findTarget(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	view := s.toView(*target)
+	return &view, nil
+}
+
+// CreateTarget 测试连接后保存目标，启用时立即注册到DatabaseManager
+func (s *MonitoringTargetService) CreateTarget(ctx context.Context, req *MonitoringTargetRequest, createdBy uuid.UUID) (*MonitoringTargetView, error) {
+	if err := validateTargetRequest(req); err != nil {
+		return nil, err
+	}
+	if s.dbManager.HasTarget(req.TargetType, req.Name) {
+		return nil, fmt.Errorf("%w: %s target '%s'", ErrTargetExists, req.TargetType, req.Name)
+	}
+
+	target := models.MonitoringTarget{
+		Enabled:   true,
+		CreatedBy: createdBy,
+	}
+	if req.Enabled != nil {
+		target.Enabled = *req.Enabled
+	}
+	options, err := s.applyRequest(&target, req)
+	if err != nil {
+		return nil, err
+	}
+	if target.PasswordEncrypted, err = s.encrypt(req.Password); err != nil {
+		return nil, err
+	}
+
+	connConfig := s.connectionConfig(target, req.Password, options)
+	if err := s.testConnection(ctx, &target, connConfig); err != nil {
+		return nil, err
+	}
+
+	// 注册失败时回滚，不保存未生效的目标
+	err = s.dbManager.SaasMonitorDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&target).Error; err != nil {
+			return fmt.Errorf("failed to save monitoring target: %w", err)
+		}
+		// enabled列默认值为true，显式创建为禁用时需要单独更新
+		if !target.Enabled {
+			return tx.Model(&target).Update("enabled", false).Error
+		}
+		if err := s.dbManager.AddTarget(ctx, connConfig); err != nil {
+			return fmt.Errorf("failed to register monitoring target: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	view := s.toView(target)
+	return &view, nil
+}
+
+// UpdateTarget 更新目标配置，测试连接后重新注册
+func (s *MonitoringTargetService) UpdateTarget(ctx context.Context, id string, req *MonitoringTargetRequest) (*MonitoringTargetView, error) {
+	if err := validateTargetRequest(req); err != nil {
+		return nil, err
+	}
+	target, err := s.findTarget(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	previousType, previousName := target.TargetType, target.Name
+	renamed := previousType != req.TargetType || previousName != req.Name
+	if renamed && s.dbManager.HasTarget(req.TargetType, req.Name) {
+		return nil, fmt.Errorf("%w: %s target '%s'", ErrTargetExists, req.TargetType, req.Name)
+	}
+
+	// 未提供新密码时沿用已保存的凭据
+	previousOptions, password, err := s.decryptCredentials(*target)
+	if err != nil {
+		return nil, err
+	}
+	if req.Password != "" {
+		password = req.Password
+	}
+	if req.SentinelPassword == "" {
+		req.SentinelPassword = previousOptions.SentinelPassword
+	}
+	if req.Enabled != nil {
+		target.Enabled = *req.Enabled
+	}
+	options, err := s.applyRequest(target, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Password != "" {
+		if target.PasswordEncrypted, err = s.encrypt(password); err != nil {
+			return nil, err
+		}
+	}
+
+	connConfig := s.connectionConfig(*target, password, options)
+	if err := s.testConnection(ctx, target, connConfig); err != nil {
+		return nil, err
+	}
+
+	// 重新注册：新连接建立后才替换旧连接，失败时旧连接保持注册并回滚保存，数据库中的配置与运行中的连接保持一致
+	err = s.dbManager.SaasMonitorDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(target).Error; err != nil {
+			return fmt.Errorf("failed to save monitoring target: %w", err)
+		}
+		if target.Enabled {
+			if err := s.dbManager.ReplaceTarget(ctx, previousType, previousName, connConfig); err != nil {
+				return fmt.Errorf("failed to register monitoring target: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 停用时注销旧连接
+	if !target.Enabled {
+		if s.dbManager.IsDynamicTarget(previousType, previousName) {
+			if err := s.dbManager.RemoveTarget(previousType, previousName); err != nil {
+				log.Printf("Error closing monitoring target %s %s: %v", previousType, previousName, err)
+			}
+		}
+		s.removeHealthRecord(target.TargetType, target.Name)
+	}
+	if renamed {
+		s.removeHealthRecord(previousType, previousName)
+	}
+
+	view := s.toView(*target)
+	return &view, nil
+}
+
+// DeleteTarget 注销并删除目标
+func (s *MonitoringTargetService) DeleteTarget(ctx context.Context, id string) error {
+	target, err := s.findTarget(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if s.dbManager.IsDynamicTarget(target.TargetType, target.Name) {
+		if err := s.dbManager.RemoveTarget(target.TargetType, target.Name); err != nil {
+			log.Printf("Error closing monitoring target %s %s: %v", target.TargetType, target.Name, err)
+		}
+	}
+	if err := s.dbManager.SaasMonitorDB.WithContext(ctx).Delete(target).Error; err != nil {
+		return fmt.Errorf("failed to delete monitoring target: %w", err)
+	}
+	s.removeHealthRecord(target.TargetType, target.Name)
+	return nil
+}
+
+// TestTarget 使用已保存的配置重新测试连接并记录结果
+func (s *MonitoringTargetService) TestTarget(ctx context.Context, id string) (*MonitoringTargetView, error) {
+	target, err := s.findTarget(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	options, password, err := s.decryptCredentials(*target)
+	if err != nil {
+		return nil, err
+	}
+
+	testErr := s.testConnection(ctx, target, s.connectionConfig(*target, password, options))
+	s.dbManager.SaasMonitorDB.WithContext(ctx).Model(target).Updates(map[string]interface{}{
+		"last_test_status": target.LastTestStatus,
+		"last_test_error":  target.LastTestError,
+		"last_tested_at":   target.LastTestedAt,
+	})
+	if testErr != nil {
+		return nil, testErr
+	}
+
+	view := s.toView(*target)
+	return &view, nil
+}
+
+// LoadTargets 启动时注册所有已启用的动态目标，单个目标失败不影响其他目标
+func (s *MonitoringTargetService) LoadTargets(ctx context.Context) error {
+	var targets []models.MonitoringTarget
+	if err := s.dbManager.SaasMonitorDB.WithContext(ctx).Where("enabled = ?", true).Find(&targets).Error; err != nil {
+		return fmt.Errorf("failed to load monitoring targets: %w", err)
+	}
+
+	for _, target := range targets {
+		options, password, err := s.decryptCredentials(target)
+		if err != nil {
+			log.Printf("Warning: skipping monitoring target %s %s: %v", target.TargetType, target.Name, err)
+			continue
+		}
+		if err := s.dbManager.AddTarget(ctx, s.connectionConfig(target, password, options)); err != nil {
+			log.Printf("Warning: failed to register monitoring target %s %s: %v", target.TargetType, target.Name, err)
+		}
+	}
+	return nil
+}
+
+// findTarget 根据ID查找目标
+func (s *MonitoringTargetService) findTarget(ctx context.Context, id string) (*models.MonitoringTarget, error) {
+	targetID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ID format", ErrInvalidTarget)
+	}
+
+	var target models.MonitoringTarget
+	if err := s.dbManager.SaasMonitorDB.WithContext(ctx).First(&target, "id = ?", targetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTargetNotFound
+		}
+		return nil, fmt.Errorf("failed to get monitoring target: %w", err)
+	}
+	return &target, nil
+}
+
+// applyRequest 将请求写入模型（不包括密码），返回明文选项
+func (s *MonitoringTargetService) applyRequest(target *models.MonitoringTarget, req *MonitoringTargetRequest) (MonitoringTargetOptions, error) {
+	target.Name = req.Name
+	target.TargetType = req.TargetType
+	target.Host = req.Host
+	target.Port = req.Port
+	target.Username = req.Username
+	target.DatabaseName = req.DatabaseName
+
+	options := req.MonitoringTargetOptions
+	stored := options
+	var err error
+	if stored.SentinelPassword, err = s.encrypt(options.SentinelPassword); err != nil {
+		return options, err
+	}
+	encoded, err := json.Marshal(stored)
+	if err != nil {
+		return options, err
+	}
+	target.Options = string(encoded)
+	return options, nil
+}
+
+// decryptCredentials 解密已保存的密码和选项中的哨兵密码
+func (s *MonitoringTargetService) decryptCredentials(target models.MonitoringTarget) (MonitoringTargetOptions, string, error) {
+	var options MonitoringTargetOptions
+	if target.Options != "" {
+		if err := json.Unmarshal([]byte(target.Options), &options); err != nil {
+			return options, "", fmt.Errorf("invalid monitoring target options: %w", err)
+		}
+	}
+
+	cc, err := newCredentialCipher(s.dbManager.Config)
+	if err != nil {
+		return options, "", err
+	}
+	password, err := cc.Decrypt(target.PasswordEncrypted)
+	if err != nil {
+		return options, "", err
+	}
+	if options.SentinelPassword, err = cc.Decrypt(options.SentinelPassword); err != nil {
+		return options, "", err
+	}
+	return options, password, nil
+}
+
+// encrypt 使用配置的凭据密钥加密
+func (s *MonitoringTargetService) encrypt(plaintext string) (string, error) {
+	cc, err := newCredentialCipher(s.dbManager.Config)
+	if err != nil {
+		return "", err
+	}
+	return cc.Encrypt(plaintext)
+}
+
+// connectionConfig 构建DatabaseManager使用的连接配置
+func (s *MonitoringTargetService) connectionConfig(target models.MonitoringTarget, password string, options MonitoringTargetOptions) database.TargetConfig {
+	return database.TargetConfig{
+		Type:             target.TargetType,
+		Name:             target.Name,
+		Host:             target.Host,
+		Port:             target.Port,
+		User:             target.Username,
+		Password:         password,
+		Database:         target.DatabaseName,
+		SSLMode:          options.SSLMode,
+		Mode:             options.Mode,
+		Addrs:            options.Addrs,
+		MasterName:       options.MasterName,
+		SentinelPassword: options.SentinelPassword,
+	}
+}
+
+// testConnection 测试连接并将结果记录到模型
+func (s *MonitoringTargetService) testConnection(ctx context.Context, target *models.MonitoringTarget, connConfig database.TargetConfig) error {
+	testCtx, cancel := context.WithTimeout(ctx, targetTestTimeout)
+	defer cancel()
+
+	now := time.Now()
+	target.LastTestedAt = &now
+	if err := s.dbManager.TestTarget(testCtx, connConfig); err != nil {
+		target.LastTestStatus = "failed"
+		target.LastTestError = err.Error()
+		return fmt.Errorf("%w: %v", ErrTargetConnection, err)
+	}
+	target.LastTestStatus = "success"
+	target.LastTestError = ""
+	return nil
+}
+
+// removeHealthRecord 删除已注销目标的system_health记录，包括Redis cluster/sentinel的拓扑和逐节点记录
+func (s *MonitoringTargetService) removeHealthRecord(targetType, name string) {
+	components, patterns := targetHealthComponents(targetType, name)
+	query := s.dbManager.SaasMonitorDB.Where("component_name IN ?", components)
+	for _, pattern := range patterns {
+		query = query.Or("component_name LIKE ?", pattern)
+	}
+	if err := query.Delete(&models.SystemHealth{}).Error; err != nil {
+		log.Printf("Failed to remove health records of %s target %s: %v", targetType, name, err)
+	}
+}
+
+// targetHealthComponents 目标在system_health中的组件名（与CheckComponents一致）和逐节点记录的LIKE模式
+func targetHealthComponents(targetType, name string) ([]string, []string) {
+	component := targetType + "_" + name
+	if targetType != database.TargetTypeRedis {
+		return []string{component}, nil
+	}
+
+	// 节点记录为redis_<name>_<role>_<addr>，按角色匹配，避免误删名称以<name>_开头的其他目标
+	prefix := escapeLike(component)
+	patterns := make([]string, 0, 3)
+	for _, role := range []string{database.RedisRoleMaster, database.RedisRoleReplica, database.RedisRoleSentinel} {
+		patterns = append(patterns, prefix+"\\_"+role+"\\_%")
+	}
+	return []string{component, component + "_topology"}, patterns
+}
+
+// escapeLike 转义LIKE模式中的通配符（PostgreSQL默认转义符为反斜杠）
+func escapeLike(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
+}
+
+// toView 转换为响应结构，移除凭据
+func (s *MonitoringTargetService) toView(target models.MonitoringTarget) MonitoringTargetView {
+	view := MonitoringTargetView{
+		MonitoringTarget: target,
+		HasPassword:      target.PasswordEncrypted != "",
+		Registered:       target.Enabled && s.dbManager.IsDynamicTarget(target.TargetType, target.Name),
+	}
+	if target.Options != "" {
+		json.Unmarshal([]byte(target.Options), &view.Options)
+	}
+	view.Options.SentinelPassword = ""
+	return view
+}
+
+// validateTargetRequest 校验目标类型和必填字段
+func validateTargetRequest(req *MonitoringTargetRequest) error {
+	if !targetNamePattern.MatchString(req.Name) {
+		return fmt.Errorf("%w: name must be 1-100 letters, digits, '_' or '-'", ErrInvalidTarget)
+	}
+
+	switch req.TargetType {
+	case database.TargetTypePostgreSQL, database.TargetTypeClickHouse:
+		if req.Host == "" || req.Port == 0 || req.DatabaseName == "" {
+			return fmt.Errorf("%w: host, port and database_name are required for %s targets", ErrInvalidTarget, req.TargetType)
+		}
+	case database.TargetTypeMySQL:
+		if req.Host == "" || req.Port == 0 {
+			return fmt.Errorf("%w: host and port are required for mysql targets", ErrInvalidTarget)
+		}
+		if err := config.ValidateMySQLSSLMode(req.SSLMode); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTarget, err)
+		}
+	case database.TargetTypeRedis:
+		switch req.Mode {
+		case "", "standalone":
+			if req.Host == "" || req.Port == 0 {
+				return fmt.Errorf("%w: host and port are required for standalone redis targets", ErrInvalidTarget)
+			}
+		case "cluster", "sentinel":
+			if len(req.Addrs) == 0 {
+				return fmt.Errorf("%w: addrs is required for redis %s targets", ErrInvalidTarget, req.Mode)
+			}
+			if req.Mode == "sentinel" && req.MasterName == "" {
+				return fmt.Errorf("%w: master_name is required for redis sentinel targets", ErrInvalidTarget)
+			}
+		default:
+			return fmt.Errorf("%w: unsupported redis mode '%s'", ErrInvalidTarget, req.Mode)
+		}
+		if req.Name == "default" {
+			return fmt.Errorf("%w: redis target name 'default' is reserved", ErrInvalidTarget)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported target type '%s'", ErrInvalidTarget, req.TargetType)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"sass-monitor/pkg/config"
+)
+
+func TestCredentialCipherRequiresCredentialKey(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.JWTSecret = "jwt-secret"
+	if _, err := newCredentialCipher(cfg); err == nil {
+		t.Fatal("jwt_secret must not be used as the credential key")
+	}
+
+	cfg.Server.CredentialKey = "credential-key"
+	cc, err := newCredentialCipher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := cc.Encrypt("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := cc.Decrypt(encrypted); err != nil || plaintext != "s3cret" {
+		t.Errorf("Decrypt() = %q, %v; want the original password", plaintext, err)
+	}
+
+	// 轮换jwt_secret不影响已保存的凭据，修改credential_key后无法解密
+	cfg.Server.JWTSecret = "rotated"
+	rotated, err := newCredentialCipher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotated.Decrypt(encrypted); err != nil {
+		t.Errorf("rotating jwt_secret broke stored credentials: %v", err)
+	}
+	cfg.Server.CredentialKey = "other-key"
+	other, err := newCredentialCipher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Decrypt(encrypted); err == nil {
+		t.Error("a different credential_key must not decrypt stored credentials")
+	}
+}
+
+func TestValidateTargetRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     MonitoringTargetRequest
+		wantErr bool
+	}{
+		{name: "postgresql", req: MonitoringTargetRequest{Name: "reporting", TargetType: "postgresql", Host: "db", Port: 5432, DatabaseName: "app"}},
+		{name: "missing database", req: MonitoringTargetRequest{Name: "reporting", TargetType: "postgresql", Host: "db", Port: 5432}, wantErr: true},
+		{name: "invalid name", req: MonitoringTargetRequest{Name: "bad name", TargetType: "mysql", Host: "db", Port: 3306}, wantErr: true},
+		{name: "reserved redis name", req: MonitoringTargetRequest{Name: "default", TargetType: "redis", Host: "cache", Port: 6379}, wantErr: true},
+		{name: "sentinel without master", req: MonitoringTargetRequest{Name: "cache", TargetType: "redis", MonitoringTargetOptions: MonitoringTargetOptions{Mode: "sentinel", Addrs: []string{"s1:26379"}}}, wantErr: true},
+		{name: "unsupported type", req: MonitoringTargetRequest{Name: "queue", TargetType: "kafka"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTargetRequest(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTarget) {
+				t.Errorf("error = %v, want ErrInvalidTarget", err)
+			}
+		})
+	}
+}
+
+func TestTargetHealthComponents(t *testing.T) {
+	tests := []struct {
+		targetType   string
+		name         string
+		wantExact    []string
+		wantPatterns []string
+	}{
+		{targetType: "postgresql", name: "reporting", wantExact: []string{"postgresql_reporting"}},
+		{
+			targetType: "redis",
+			name:       "cache",
+			wantExact:  []string{"redis_cache", "redis_cache_topology"},
+			wantPatterns: []string{
+				`redis\_cache\_master\_%`,
+				`redis\_cache\_replica\_%`,
+				`redis\_cache\_sentinel\_%`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		exact, patterns := targetHealthComponents(tt.targetType, tt.name)
+		if !reflect.DeepEqual(exact, tt.wantExact) || !reflect.DeepEqual(patterns, tt.wantPatterns) {
+			t.Errorf("targetHealthComponents(%q, %q) = %v, %v; want %v, %v", tt.targetType, tt.name, exact, patterns, tt.wantExact, tt.wantPatterns)
+		}
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := map[string]string{
+		"cache":     "cache",
+		"my_cache":  `my\_cache`,
+		"100%":      `100\%`,
+		`back\path`: `back\\path`,
+	}
+	for value, want := range tests {
+		if got := escapeLike(value); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// mysqlDigestMetricLimit 每次采集写入指标的语句摘要数量（按总耗时排序）
+const mysqlDigestMetricLimit = 10
+
+// mysqlDigestSampleTTL 语句摘要执行次数采样的保留时间，超时未出现的摘要丢弃采样
+const mysqlDigestSampleTTL = time.Hour
+
+// MySQLMonitorService MySQL/MariaDB状态、InnoDB缓冲池、复制和语句摘要监控
+type MySQLMonitorService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewMySQLMonitorService(dbManager *database.DatabaseManager) *MySQLMonitorService {
+	return &MySQLMonitorService{
+		dbManager: dbManager,
+	}
+}
+
+// MySQLStatus MySQL实例状态汇总
+type MySQLStatus struct {
+	DatabaseName           string            `json:"database_name"`
+	Version                string            `json:"version"`
+	UptimeSeconds          int64             `json:"uptime_seconds"`
+	ThreadsConnected       int64             `json:"threads_connected"`
+	ThreadsRunning         int64             `json:"threads_running"`
+	MaxConnections         int64             `json:"max_connections"`
+	ConnectionUsagePercent float64           `json:"connection_usage_percent"`
+	Questions              int64             `json:"questions"`
+	SlowQueries            int64             `json:"slow_queries"`
+	AbortedConnects        int64             `json:"aborted_connects"`
+	AbortedClients         int64             `json:"aborted_clients"`
+	BufferPool             MySQLBufferPool   `json:"buffer_pool"`
+	Replication            *MySQLReplication `json:"replication"` // 非从库时为空
+	GlobalStatus           map[string]string `json:"global_status"`
+}
+
+// MySQLBufferPool InnoDB缓冲池统计
+type MySQLBufferPool struct {
+	SizeBytes       int64   `json:"size_bytes"`
+	PagesTotal      int64   `json:"pages_total"`
+	PagesFree       int64   `json:"pages_free"`
+	PagesDirty      int64   `json:"pages_dirty"`
+	UsagePercent    float64 `json:"usage_percent"`
+	DirtyPercent    float64 `json:"dirty_percent"`
+	ReadRequests    int64   `json:"read_requests"`
+	DiskReads       int64   `json:"disk_reads"`
+	HitRatioPercent float64 `json:"hit_ratio_percent"`
+}
+
+// MySQLReplication 从库复制状态（SHOW REPLICA STATUS）
+type MySQLReplication struct {
+	SourceHost         string `json:"source_host"`
+	SourcePort         string `json:"source_port"`
+	IORunning          bool   `json:"io_running"`
+	SQLRunning         bool   `json:"sql_running"`
+	SecondsBehind      *int64 `json:"seconds_behind_source"` // 复制线程未运行时为空
+	LastIOError        string `json:"last_io_error"`
+	LastSQLError       string `json:"last_sql_error"`
+	RelayLogSpaceBytes int64  `json:"relay_log_space_bytes"`
+}
+
+// MySQLDigestStat performance_schema语句摘要统计
+type MySQLDigestStat struct {
+	SchemaName     string     `json:"schema_name"`
+	Digest         string     `json:"digest"`
+	DigestText     string     `json:"digest_text"`
+	ExecCount      int64      `json:"exec_count"`
+	TotalLatencyMs float64    `json:"total_latency_ms"`
+	AvgLatencyMs   float64    `json:"avg_latency_ms"`
+	MaxLatencyMs   float64    `json:"max_latency_ms"`
+	RowsExamined   int64      `json:"rows_examined"`
+	RowsSent       int64      `json:"rows_sent"`
+	Errors         int64      `json:"errors"`
+	NoIndexUsed    int64      `json:"no_index_used"`
+	FirstSeen      *time.Time `json:"first_seen"`
+	LastSeen       *time.Time `json:"last_seen"`
+}
+
+// mysqlDigestOrderColumns 语句摘要允许的排序方式
+var mysqlDigestOrderColumns = map[string]string{
+	"total_latency": "SUM_TIMER_WAIT",
+	"avg_latency":   "AVG_TIMER_WAIT",
+	"max_latency":   "MAX_TIMER_WAIT",
+	"exec_count":    "COUNT_STAR",
+	"rows_examined": "SUM_ROWS_EXAMINED",
+	"errors":        "SUM_ERRORS",
+}
+
+// GetStatus 获取MySQL实例状态
+func (s *MySQLMonitorService) GetStatus(ctx context.Context, dbName string) (*MySQLStatus, error) {
+	db, err := s.dbManager.GetMySQLConnection(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return queryMySQLStatus(db.WithContext(ctx), dbName)
+}
+
+// GetDigests 获取语句摘要统计，orderBy为mysqlDigestOrderColumns中的键
+func (s *MySQLMonitorService) GetDigests(ctx context.Context, dbName, schema, orderBy string, limit int) ([]MySQLDigestStat, error) {
+	db, err := s.dbManager.GetMySQLConnection(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return queryMySQLDigests(db.WithContext(ctx), schema, orderBy, limit)
+}
+
+// queryMySQLStatus 查询SHOW GLOBAL STATUS、max_connections和复制状态
+func queryMySQLStatus(db *gorm.DB, dbName string) (*MySQLStatus, error) {
+	globalStatus, err := queryMySQLKeyValues(db, "SHOW GLOBAL STATUS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query global status: %w", err)
+	}
+	variables, err := queryMySQLKeyValues(db, "SHOW GLOBAL VARIABLES WHERE Variable_name IN ('max_connections', 'version', 'innodb_buffer_pool_size')")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query global variables: %w", err)
+	}
+
+	statusInt := func(key string) int64 {
+		value, _ := strconv.ParseInt(globalStatus[key], 10, 64)
+		return value
+	}
+
+	status := &MySQLStatus{
+		DatabaseName:     dbName,
+		Version:          variables["version"],
+		UptimeSeconds:    statusInt("Uptime"),
+		ThreadsConnected: statusInt("Threads_connected"),
+		ThreadsRunning:   statusInt("Threads_running"),
+		Questions:        statusInt("Questions"),
+		SlowQueries:      statusInt("Slow_queries"),
+		AbortedConnects:  statusInt("Aborted_connects"),
+		AbortedClients:   statusInt("Aborted_clients"),
+		GlobalStatus:     globalStatus,
+	}
+	status.MaxConnections, _ = strconv.ParseInt(variables["max_connections"], 10, 64)
+	if status.MaxConnections > 0 {
+		status.ConnectionUsagePercent = float64(status.ThreadsConnected) / float64(status.MaxConnections) * 100
+	}
+
+	bufferPool := &status.BufferPool
+	bufferPool.SizeBytes, _ = strconv.ParseInt(variables["innodb_buffer_pool_size"], 10, 64)
+	bufferPool.PagesTotal = statusInt("Innodb_buffer_pool_pages_total")
+	bufferPool.PagesFree = statusInt("Innodb_buffer_pool_pages_free")
+	bufferPool.PagesDirty = statusInt("Innodb_buffer_pool_pages_dirty")
+	bufferPool.ReadRequests = statusInt("Innodb_buffer_pool_read_requests")
+	bufferPool.DiskReads = statusInt("Innodb_buffer_pool_reads")
+	if bufferPool.PagesTotal > 0 {
+		bufferPool.UsagePercent = float64(bufferPool.PagesTotal-bufferPool.PagesFree) / float64(bufferPool.PagesTotal) * 100
+		bufferPool.DirtyPercent = float64(bufferPool.PagesDirty) / float64(bufferPool.PagesTotal) * 100
+	}
+	if bufferPool.ReadRequests > 0 {
+		bufferPool.HitRatioPercent = (1 - float64(bufferPool.DiskReads)/float64(bufferPool.ReadRequests)) * 100
+	}
+
+	// 缺少REPLICATION CLIENT权限时不影响其他状态
+	if status.Replication, err = queryMySQLReplication(db); err != nil {
+		log.Printf("Failed to query MySQL replication status for %s: %v", dbName, err)
+	}
+
+	return status, nil
+}
+
+// queryMySQLKeyValues 执行返回(Variable_name, Value)两列的SHOW语句
+func queryMySQLKeyValues(db *gorm.DB, query string) (map[string]string, error) {
+	rows, err := db.Raw(query).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var name string
+		var value sql.NullString
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		values[name] = value.String
+	}
+	return values, rows.Err()
+}
+
+// queryMySQLReplication 查询从库复制状态，非从库返回nil
+// 优先使用SHOW REPLICA STATUS（MySQL 8.0.22+、MariaDB 10.5.1+），失败时回退到SHOW SLAVE STATUS
+func queryMySQLReplication(db *gorm.DB) (*MySQLReplication, error) {
+	row, err := queryMySQLSingleRow(db, "SHOW REPLICA STATUS")
+	if err != nil {
+		if row, err = queryMySQLSingleRow(db, "SHOW SLAVE STATUS"); err != nil {
+			return nil, err
+		}
+	}
+	if row == nil {
+		return nil, nil
+	}
+
+	// 新旧版本列名不同（Source/Replica与Master/Slave）
+	field := func(names ...string) string {
+		for _, name := range names {
+			if value, exists := row[name]; exists {
+				return value
+			}
+		}
+		return ""
+	}
+
+	replication := &MySQLReplication{
+		SourceHost:   field("Source_Host", "Master_Host"),
+		SourcePort:   field("Source_Port", "Master_Port"),
+		IORunning:    field("Replica_IO_Running", "Slave_IO_Running") == "Yes",
+		SQLRunning:   field("Replica_SQL_Running", "Slave_SQL_Running") == "Yes",
+		LastIOError:  field("Last_IO_Error"),
+		LastSQLError: field("Last_SQL_Error"),
+	}
+	replication.RelayLogSpaceBytes, _ = strconv.ParseInt(field("Relay_Log_Space"), 10, 64)
+	if behind, err := strconv.ParseInt(field("Seconds_Behind_Source", "Seconds_Behind_Master"), 10, 64); err == nil {
+		replication.SecondsBehind = &behind
+	}
+	return replication, nil
+}
+
+// queryMySQLSingleRow 执行SHOW语句并以列名为键返回第一行，无结果返回nil
+func queryMySQLSingleRow(db *gorm.DB, query string) (map[string]string, error) {
+	rows, err := db.Raw(query).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]string, len(columns))
+	for i, column := range columns {
+		row[column] = values[i].String
+	}
+	return row, nil
+}
+
+// queryMySQLDigests 查询performance_schema.events_statements_summary_by_digest（计时单位为皮秒）
+func queryMySQLDigests(db *gorm.DB, schema, orderBy string, limit int) ([]MySQLDigestStat, error) {
+	orderColumn, exists := mysqlDigestOrderColumns[orderBy]
+	if !exists {
+		orderColumn = mysqlDigestOrderColumns["total_latency"]
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	query := `
+		SELECT
+			IFNULL(SCHEMA_NAME, '') AS schema_name,
+			IFNULL(DIGEST, '') AS digest,
+			IFNULL(DIGEST_TEXT, '') AS digest_text,
+			COUNT_STAR AS exec_count,
+			SUM_TIMER_WAIT / 1000000000 AS total_latency_ms,
+			AVG_TIMER_WAIT / 1000000000 AS avg_latency_ms,
+			MAX_TIMER_WAIT / 1000000000 AS max_latency_ms,
+			SUM_ROWS_EXAMINED AS rows_examined,
+			SUM_ROWS_SENT AS rows_sent,
+			SUM_ERRORS AS errors,
+			SUM_NO_INDEX_USED AS no_index_used,
+			FIRST_SEEN AS first_seen,
+			LAST_SEEN AS last_seen
+		FROM performance_schema.events_statements_summary_by_digest`
+	var args []interface{}
+	if schema != "" {
+		query += " WHERE SCHEMA_NAME = ?"
+		args = append(args, schema)
+	}
+	query += fmt.Sprintf(" ORDER BY %s DESC LIMIT ?", orderColumn)
+	args = append(args, limit)
+
+	digests := []MySQLDigestStat{}
+	if err := db.Raw(query, args...).Scan(&digests).Error; err != nil {
+		return nil, fmt.Errorf("failed to query statement digests (is performance_schema enabled?): %w", err)
+	}
+	return digests, nil
+}
+
+// collectMySQLData 采集所有MySQL目标
+func (dc *DataCollector) collectMySQLData(ctx context.Context) error {
+	var errs []string
+	for name, db := range dc.dbManager.GetMySQLTargets() {
+		if err := dc.collectMySQLTarget(ctx, name, db); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// collectMySQLTarget 采集单个MySQL目标的状态、缓冲池、复制和语句摘要指标
+func (dc *DataCollector) collectMySQLTarget(ctx context.Context, dbName string, db *gorm.DB) error {
+	status, err := queryMySQLStatus(db.WithContext(ctx), dbName)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	newMetric := func(metricType, metricName string, value float64, unit string, tags map[string]interface{}) models.ResourceMetric {
+		metric := models.ResourceMetric{
+			DatabaseType: "mysql",
+			DatabaseName: dbName,
+			MetricType:   metricType,
+			MetricName:   metricName,
+			MetricValue:  value,
+			Unit:         unit,
+			CollectedAt:  now,
+		}
+		if tags != nil {
+			metric.Tags = dc.formatTags(tags)
+		}
+		return metric
+	}
+
+	bufferPool := status.BufferPool
+	metrics := []models.ResourceMetric{
+		newMetric("connections", "threads_connected", float64(status.ThreadsConnected), "count", nil),
+		newMetric("connections", "threads_running", float64(status.ThreadsRunning), "count", nil),
+		newMetric("connections", "connection_usage_percent", status.ConnectionUsagePercent, "percent", nil),
+		newMetric("connections", "aborted_connects", float64(status.AbortedConnects), "count", nil),
+		newMetric("query_performance", "slow_queries", float64(status.SlowQueries), "count", nil),
+		newMetric("system", "uptime_seconds", float64(status.UptimeSeconds), "seconds", nil),
+		newMetric("memory", "innodb_buffer_pool_usage_percent", bufferPool.UsagePercent, "percent", nil),
+		newMetric("memory", "innodb_buffer_pool_dirty_percent", bufferPool.DirtyPercent, "percent", nil),
+		newMetric("memory", "innodb_buffer_pool_hit_ratio_percent", bufferPool.HitRatioPercent, "percent", nil),
+		newMetric("memory", "innodb_buffer_pool_size_bytes", float64(bufferPool.SizeBytes), "bytes", nil),
+	}
+	if qps, ok := dc.mysqlQuestions.rate(dbName, float64(status.Questions), now); ok {
+		metrics = append(metrics, newMetric("query_performance", "queries_per_second", qps, "queries/s", nil))
+	}
+
+	if replication := status.Replication; replication != nil {
+		tags := map[string]interface{}{"source_host": replication.SourceHost}
+		metrics = append(metrics,
+			newMetric("replication", "replica_io_running", boolMetric(replication.IORunning), "bool", tags),
+			newMetric("replication", "replica_sql_running", boolMetric(replication.SQLRunning), "bool", tags),
+		)
+		if replication.SecondsBehind != nil {
+			metrics = append(metrics, newMetric("replication", "replication_lag_seconds", float64(*replication.SecondsBehind), "seconds", tags))
+		}
+	}
+
+	// 语句摘要依赖performance_schema，未启用时只记录日志
+	digests, err := queryMySQLDigests(db.WithContext(ctx), "", "total_latency", mysqlDigestMetricLimit)
+	if err != nil {
+		log.Printf("Skipping MySQL statement digests for %s: %v", dbName, err)
+	}
+	// COUNT_STAR是累计计数器，写入与上一次采样之间的每秒执行次数；首次采样只记录
+	for _, digest := range digests {
+		tags := map[string]interface{}{"digest": digest.Digest, "schema": digest.SchemaName}
+		metrics = append(metrics, newMetric("query_performance", "statement_avg_latency_ms", digest.AvgLatencyMs, "ms", tags))
+		key := strings.Join([]string{dbName, digest.SchemaName, digest.Digest}, "|")
+		if rate, ok := dc.mysqlDigests.rate(key, float64(digest.ExecCount), now); ok {
+			metrics = append(metrics, newMetric("query_performance", "statement_exec_per_second", rate, "executions/s", tags))
+		}
+	}
+	dc.mysqlDigests.prune(now.Add(-mysqlDigestSampleTTL))
+
+	return dc.dbManager.SaasMonitorDB.CreateInBatches(metrics, 100).Error
+}
+
+// boolMetric 将布尔值转换为指标值
+func boolMetric(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
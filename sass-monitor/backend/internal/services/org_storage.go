@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// 组织存储统计的metric_type
+const (
+	MetricTypeOrganizationStorage = "organization_storage"
+	MetricTypeOrganizationQueries = "organization_queries"
+)
+
+// identifierPattern 允许拼接到SQL中的表名和列名
+var identifierPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// tableOrganizationRows 单张表按组织分组的行数
+type tableOrganizationRows struct {
+	OrganizationID string `ch:"organization_id" gorm:"column:organization_id"`
+	Rows           int64  `ch:"rows" gorm:"column:rows"`
+}
+
+// organizationStorage 单个数据库中一个组织的存储统计
+type organizationStorage struct {
+	Rows   int64
+	Bytes  float64
+	Tables int
+}
+
+// addTableShare 将表的大小按组织行数占比分摊到各组织
+func addTableShare(storage map[string]*organizationStorage, rows []tableOrganizationRows, tableBytes float64) {
+	var totalRows int64
+	for _, row := range rows {
+		totalRows += row.Rows
+	}
+	if totalRows == 0 {
+		return
+	}
+	for _, row := range rows {
+		if row.OrganizationID == "" {
+			continue
+		}
+		entry, exists := storage[row.OrganizationID]
+		if !exists {
+			entry = &organizationStorage{}
+			storage[row.OrganizationID] = entry
+		}
+		entry.Rows += row.Rows
+		entry.Bytes += tableBytes * float64(row.Rows) / float64(totalRows)
+		entry.Tables++
+	}
+}
+
+// collectOrganizationStorage 统计各组织在ClickHouse和light_admin中的存储量及ClickHouse当日查询数
+func (dc *DataCollector) collectOrganizationStorage(ctx context.Context) error {
+	storageConfig := dc.dbManager.Config.Monitoring.Storage
+	column := storageConfig.OrganizationColumn
+	if !identifierPattern.MatchString(column) {
+		return fmt.Errorf("invalid organization_column '%s'", column)
+	}
+
+	now := time.Now()
+	var metrics []models.ResourceMetric
+	newMetric := func(databaseType, databaseName, organizationID, metricType, metricName string, value float64, unit string, tags map[string]interface{}) models.ResourceMetric {
+		orgID := organizationID
+		return models.ResourceMetric{
+			OrganizationID: &orgID,
+			DatabaseType:   databaseType,
+			DatabaseName:   databaseName,
+			MetricType:     metricType,
+			MetricName:     metricName,
+			MetricValue:    value,
+			Unit:           unit,
+			Tags:           dc.formatTags(tags),
+			CollectedAt:    now,
+		}
+	}
+	storageMetrics := func(databaseType, databaseName string, storage map[string]*organizationStorage) {
+		for organizationID, entry := range storage {
+			tags := map[string]interface{}{"tables": entry.Tables}
+			metrics = append(metrics,
+				newMetric(databaseType, databaseName, organizationID, MetricTypeOrganizationStorage, "storage_bytes", entry.Bytes, "bytes", tags),
+				newMetric(databaseType, databaseName, organizationID, MetricTypeOrganizationStorage, "row_count", float64(entry.Rows), "count", tags),
+			)
+		}
+	}
+
+	var errs []string
+
+	// ClickHouse
+	included := make(map[string]bool, len(storageConfig.ClickHouseDatabases))
+	for _, name := range storageConfig.ClickHouseDatabases {
+		included[name] = true
+	}
+	for name, conn := range dc.dbManager.GetClickHouseConnections() {
+		if len(included) > 0 && !included[name] {
+			continue
+		}
+		database := dc.dbManager.GetClickHouseDatabase(name)
+
+		storage, err := clickHouseOrganizationStorage(ctx, conn, database, column)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("clickhouse %s: %v", name, err))
+		} else {
+			storageMetrics("clickhouse", name, storage)
+		}
+
+		// 当日查询数（query_log未启用时跳过）
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		stats, err := queryClickHouseQueryGroups(ctx, conn, database, QueryAnalyticsFilter{
+			GroupBy: QueryGroupByOrganization,
+			Window:  now.Sub(startOfDay) + time.Second,
+			Limit:   10000,
+		})
+		if err != nil {
+			log.Printf("Skipping ClickHouse organization query counts for %s: %v", name, err)
+			continue
+		}
+		for _, stat := range stats {
+			if stat.GroupKey == "" {
+				continue
+			}
+			metrics = append(metrics, newMetric("clickhouse", name, stat.GroupKey, MetricTypeOrganizationQueries, "query_count_today", float64(stat.QueryCount), "count", nil))
+		}
+	}
+
+	// light_admin
+	storage, err := dc.lightAdminOrganizationStorage(ctx, column, storageConfig.LightAdminTables)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("light_admin: %v", err))
+	} else {
+		storageMetrics("postgresql", "light_admin", storage)
+	}
+
+	if len(metrics) > 0 {
+		if err := dc.dbManager.SaasMonitorDB.CreateInBatches(metrics, 500).Error; err != nil {
+			return fmt.Errorf("failed to save organization storage metrics: %w", err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// clickHouseOrganizationStorage 统计数据库中包含组织列的MergeTree表，按组织行数占比分摊活跃part的磁盘占用
+func clickHouseOrganizationStorage(ctx context.Context, conn clickhouse.Conn, database, column string) (map[string]*organizationStorage, error) {
+	var tables []struct {
+		Table string `ch:"table"`
+		Bytes uint64 `ch:"bytes"`
+	}
+	err := conn.Select(ctx, &tables, `
+		SELECT c.table AS table, toUInt64(sum(p.bytes_on_disk)) AS bytes
+		FROM system.columns AS c
+		INNER JOIN system.parts AS p ON p.database = c.database AND p.table = c.table
+		WHERE c.database = ? AND c.name = ? AND p.active
+		GROUP BY c.table
+	`, database, column)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	storage := make(map[string]*organizationStorage)
+	for _, table := range tables {
+		var rows []tableOrganizationRows
+		query := fmt.Sprintf("SELECT toString(`%s`) AS organization_id, toInt64(count()) AS rows FROM `%s`.`%s` GROUP BY organization_id",
+			column, strings.ReplaceAll(database, "`", ""), strings.ReplaceAll(table.Table, "`", ""))
+		if err := conn.Select(ctx, &rows, query); err != nil {
+			log.Printf("Failed to count ClickHouse rows by organization for %s.%s: %v", database, table.Table, err)
+			continue
+		}
+		addTableShare(storage, rows, float64(table.Bytes))
+	}
+	return storage, nil
+}
+
+// lightAdminOrganizationStorage 统计light_admin中包含组织列的表，按组织行数占比分摊表的总大小（含索引和TOAST）
+func (dc *DataCollector) lightAdminOrganizationStorage(ctx context.Context, column string, onlyTables []string) (map[string]*organizationStorage, error) {
+	db := dc.dbManager.LightAdminDB.WithContext(ctx)
+
+	var tables []struct {
+		TableName string
+		Bytes     int64
+	}
+	query := db.Table("information_schema.columns c").
+		Select("c.table_name, pg_total_relation_size(quote_ident(c.table_schema) || '.' || quote_ident(c.table_name)) AS bytes").
+		Joins("INNER JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name").
+		Where("c.table_schema = 'public' AND c.column_name = ? AND t.table_type = 'BASE TABLE'", column)
+	if len(onlyTables) > 0 {
+		query = query.Where("c.table_name IN ?", onlyTables)
+	}
+	if err := query.Scan(&tables).Error; err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	storage := make(map[string]*organizationStorage)
+	for _, table := range tables {
+		if !identifierPattern.MatchString(table.TableName) {
+			continue
+		}
+		var rows []tableOrganizationRows
+		err := db.Raw(fmt.Sprintf(`SELECT "%s"::text AS organization_id, COUNT(*) AS rows FROM public."%s" GROUP BY 1`, column, table.TableName)).
+			Scan(&rows).Error
+		if err != nil {
+			log.Printf("Failed to count light_admin rows by organization for %s: %v", table.TableName, err)
+			continue
+		}
+		addTableShare(storage, rows, float64(table.Bytes))
+	}
+	return storage, nil
+}
+
+// OrganizationStorageService 查询采集到的组织存储统计
+type OrganizationStorageService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewOrganizationStorageService(dbManager *database.DatabaseManager) *OrganizationStorageService {
+	return &OrganizationStorageService{
+		dbManager: dbManager,
+	}
+}
+
+// FillOrganizationStorage 为组织列表填充存储使用量（MB），field返回组织ID和存储用量字段；
+// 查询失败时记录日志并保持原值，不影响列表本身
+func FillOrganizationStorage[T any](ctx context.Context, s *OrganizationStorageService, organizations []T, field func(*T) (string, *float64)) {
+	organizationIDs := make([]string, len(organizations))
+	for i := range organizations {
+		organizationIDs[i], _ = field(&organizations[i])
+	}
+	usage, err := s.GetUsage(ctx, organizationIDs...)
+	if err != nil {
+		log.Printf("Failed to get organization storage usage: %v", err)
+		return
+	}
+	for i := range organizations {
+		organizationID, storageMB := field(&organizations[i])
+		if orgUsage, exists := usage[organizationID]; exists {
+			*storageMB = orgUsage.StorageMB
+		}
+	}
+}
+
+// OrganizationDatabaseUsage 组织在单个数据库中的使用量
+type OrganizationDatabaseUsage struct {
+	DatabaseType    string    `json:"database_type"`
+	DatabaseName    string    `json:"database_name"`
+	StorageMB       float64   `json:"storage_mb"`
+	RowCount        int64     `json:"row_count"`
+	QueryCountToday int64     `json:"query_count_today"`
+	CollectedAt     time.Time `json:"collected_at"`
+}
+
+// OrganizationStorageUsage 组织的存储和查询使用量（各数据库最近一次统计之和）
+type OrganizationStorageUsage struct {
+	OrganizationID  string                      `json:"organization_id"`
+	StorageMB       float64                     `json:"storage_mb"`
+	RowCount        int64                       `json:"row_count"`
+	QueryCountToday int64                       `json:"query_count_today"`
+	Databases       []OrganizationDatabaseUsage `json:"databases"`
+}
+
+// GetUsage 获取多个组织的使用量，没有统计数据的组织不出现在结果中
+func (s *OrganizationStorageService) GetUsage(ctx context.Context, organizationIDs ...string) (map[string]*OrganizationStorageUsage, error) {
+	usage := make(map[string]*OrganizationStorageUsage, len(organizationIDs))
+	if len(organizationIDs) == 0 {
+		return usage, nil
+	}
+
+	var rows []struct {
+		OrganizationID string
+		DatabaseType   string
+		DatabaseName   string
+		MetricName     string
+		MetricValue    float64
+		CollectedAt    time.Time
+	}
+	err := s.dbManager.SaasMonitorDB.WithContext(ctx).Raw(`
+		SELECT DISTINCT ON (organization_id, database_type, database_name, metric_name)
+			organization_id, database_type, database_name, metric_name, metric_value, collected_at
+		FROM resource_metrics
+		WHERE organization_id IN ?
+			AND metric_type IN ?
+			AND collected_at >= ?
+		ORDER BY organization_id, database_type, database_name, metric_name, collected_at DESC
+	`, organizationIDs, []string{MetricTypeOrganizationStorage, MetricTypeOrganizationQueries}, time.Now().AddDate(0, 0, -7)).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organization storage metrics: %w", err)
+	}
+
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	databases := make(map[string]*OrganizationDatabaseUsage)
+	var keys []string
+	for _, row := range rows {
+		key := row.OrganizationID + "|" + row.DatabaseType + "|" + row.DatabaseName
+		entry, exists := databases[key]
+		if !exists {
+			entry = &OrganizationDatabaseUsage{
+				DatabaseType: row.DatabaseType,
+				DatabaseName: row.DatabaseName,
+			}
+			databases[key] = entry
+			keys = append(keys, key)
+		}
+		switch row.MetricName {
+		case "storage_bytes":
+			entry.StorageMB = row.MetricValue / (1024 * 1024)
+		case "row_count":
+			entry.RowCount = int64(row.MetricValue)
+		case "query_count_today":
+			// 昨天的统计不计入当日查询数
+			if !row.CollectedAt.Before(startOfDay) {
+				entry.QueryCountToday = int64(row.MetricValue)
+			}
+		}
+		if row.CollectedAt.After(entry.CollectedAt) {
+			entry.CollectedAt = row.CollectedAt
+		}
+	}
+
+	for _, key := range keys {
+		organizationID := strings.SplitN(key, "|", 2)[0]
+		entry := databases[key]
+		total, exists := usage[organizationID]
+		if !exists {
+			total = &OrganizationStorageUsage{
+				OrganizationID: organizationID,
+				Databases:      []OrganizationDatabaseUsage{},
+			}
+			usage[organizationID] = total
+		}
+		total.StorageMB += entry.StorageMB
+		total.RowCount += entry.RowCount
+		total.QueryCountToday += entry.QueryCountToday
+		total.Databases = append(total.Databases, *entry)
+	}
+	return usage, nil
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,12 +15,14 @@ import (
 
 // OrganizationService 组织管理服务（只读模式）
 type OrganizationService struct {
-	dbManager *database.DatabaseManager
+	dbManager      *database.DatabaseManager
+	storageService *OrganizationStorageService
 }
 
-func NewOrganizationService(dbManager *database.DatabaseManager) *OrganizationService {
+func NewOrganizationService(dbManager *database.DatabaseManager, storageService *OrganizationStorageService) *OrganizationService {
 	return &OrganizationService{
-		dbManager: dbManager,
+		dbManager:      dbManager,
+		storageService: storageService,
 	}
 }
 
@@ -72,7 +76,7 @@ type OrganizationSubscription struct {
 	UserEmail       *string    `json:"user_email"` // 订阅用户邮箱
 	PlanID          string     `json:"plan_id"`
 	PlanName        string     `json:"plan_name"`
-	PlanPricing     float64    `json:"plan_pricing"` // 套餐价格
+	PlanPricing     float64    `json:"plan_pricing"` // 套餐价格（订阅计费周期对应的价格）
 	Status          string     `json:"status"`
 	BillingCycle    string     `json:"billing_cycle"`
 	StartDate       time.Time  `json:"start_date"`
@@ -82,6 +86,10 @@ type OrganizationSubscription struct {
 	LastBilledAt    *time.Time `json:"last_billed_at"`
 	TrialDaysUsed   *int       `json:"trial_days_used"`
 	CreatedAt       time.Time  `json:"created_at"`
+
+	PricingMonthly   float64 `json:"-"`
+	PricingQuarterly float64 `json:"-"`
+	PricingYearly    float64 `json:"-"`
 }
 
 // WorkspaceUser 工作空间用户信息
@@ -106,7 +114,7 @@ type PaginatedResponse[T any] struct {
 }
 
 // GetOrganizations 获取组织列表（只读）
-func (s *OrganizationService) GetOrganizations(page, pageSize int, search string) (*PaginatedResponse[OrganizationDetail], error) {
+func (s *OrganizationService) GetOrganizations(ctx context.Context, page, pageSize int, search string) (*PaginatedResponse[OrganizationDetail], error) {
 	offset := (page - 1) * pageSize
 
 	query := s.dbManager.LightAdminDB.Table("auth_organizations").
@@ -142,6 +150,11 @@ func (s *OrganizationService) GetOrganizations(page, pageSize int, search string
 		s.calculateSubscriptionStatus(&organizations[i], now)
 	}
 
+	// 填充存储使用情况（MB）
+	FillOrganizationStorage(ctx, s.storageService, organizations, func(org *OrganizationDetail) (string, *float64) {
+		return org.ID, &org.StorageUsage
+	})
+
 	// 获取总数
 	var total int64
 	countQuery := s.dbManager.LightAdminDB.Table("auth_organizations")
@@ -160,7 +173,7 @@ func (s *OrganizationService) GetOrganizations(page, pageSize int, search string
 }
 
 // GetOrganizationByID 根据ID获取组织详细信息（只读）
-func (s *OrganizationService) GetOrganizationByID(orgID string) (*OrganizationDetail, error) {
+func (s *OrganizationService) GetOrganizationByID(ctx context.Context, orgID string) (*OrganizationDetail, error) {
 	// 验证UUID格式
 	if _, err := uuid.Parse(orgID); err != nil {
 		return nil, fmt.Errorf("invalid organization ID format")
@@ -195,8 +208,13 @@ func (s *OrganizationService) GetOrganizationByID(orgID string) (*OrganizationDe
 		Where("organization_id = ? AND created_at > ?",
 			orgID, thirtyDaysAgo).Count(&org.ActiveUsers)
 
-	// 设置存储使用情况（示例数据）
-	org.StorageUsage = 0
+	// 设置存储使用情况（MB，来自组织存储统计）
+	usage, err := s.storageService.GetUsage(ctx, orgID)
+	if err != nil {
+		log.Printf("Failed to get storage usage for organization %s: %v", orgID, err)
+	} else if orgUsage, exists := usage[orgID]; exists {
+		org.StorageUsage = orgUsage.StorageMB
+	}
 
 	// 计算订阅到期状态和天数
 	s.calculateSubscriptionStatus(&org, time.Now())
@@ -299,12 +317,9 @@ func (s *OrganizationService) GetOrganizationSubscriptions(orgID string, page, p
 	query := s.dbManager.LightAdminDB.Table("subscription_users su").
 		Select(`su.id, su.user_id, au.username, au.email as user_email,
 			su.plan_id, sp.tier_name as plan_name,
-			CASE
-				WHEN su.billing_cycle = 'monthly' THEN sp.pricing_monthly
-				WHEN su.billing_cycle = 'quarterly' THEN sp.pricing_quarterly
-				WHEN su.billing_cycle = 'yearly' THEN sp.pricing_yearly
-				ELSE 0
-			END as plan_pricing,
+			COALESCE(sp.pricing_monthly, 0) as pricing_monthly,
+			COALESCE(sp.pricing_quarterly, 0) as pricing_quarterly,
+			COALESCE(sp.pricing_yearly, 0) as pricing_yearly,
 			su.status, su.billing_cycle, su.start_date, su.end_date,
 			su.payment_method, su.last_billed_at, su.trial_days_used, su.created_at`).
 		Joins("LEFT JOIN subscription_plans sp ON su.plan_id = sp.id").
@@ -321,9 +336,11 @@ func (s *OrganizationService) GetOrganizationSubscriptions(orgID string, page, p
 		return nil, fmt.Errorf("failed to get organization subscriptions: %w", err)
 	}
 
-	// 计算每个订阅的到期天数
+	// 计算每个订阅的价格和到期天数
 	now := time.Now()
 	for i := range subscriptions {
+		subscriptions[i].PlanPricing, _ = billingCyclePrice(subscriptions[i].BillingCycle,
+			subscriptions[i].PricingMonthly, subscriptions[i].PricingQuarterly, subscriptions[i].PricingYearly)
 		if subscriptions[i].EndDate != nil {
 			days := int(subscriptions[i].EndDate.Sub(now).Hours() / 24)
 			subscriptions[i].DaysUntilExpiry = &days
@@ -345,8 +362,8 @@ func (s *OrganizationService) GetOrganizationSubscriptions(orgID string, page, p
 }
 
 // GetOrganizationMetrics 获取组织指标统计（只读）
-func (s *OrganizationService) GetOrganizationMetrics(orgID string) (*OrganizationDetail, error) {
-	return s.GetOrganizationByID(orgID)
+func (s *OrganizationService) GetOrganizationMetrics(ctx context.Context, orgID string) (*OrganizationDetail, error) {
+	return s.GetOrganizationByID(ctx, orgID)
 }
 
 // GetWorkspaceUsers 获取工作空间用户列表（只读）
@@ -436,9 +453,9 @@ func (s *OrganizationService) calculateSubscriptionStatus(org *OrganizationDetai
 }
 
 // SendExpiryReminder 发送订阅到期提醒邮件（预留接口）
-func (s *OrganizationService) SendExpiryReminder(orgID string) error {
+func (s *OrganizationService) SendExpiryReminder(ctx context.Context, orgID string) error {
 	// 验证组织存在
-	org, err := s.GetOrganizationByID(orgID)
+	org, err := s.GetOrganizationByID(ctx, orgID)
 	if err != nil {
 		return err
 	}
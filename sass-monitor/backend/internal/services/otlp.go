@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	collectormetricsv1 "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	metricsv1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// OTLPDatabaseType 通过OTLP接收的应用指标的database_type
+const OTLPDatabaseType = "application"
+
+// otlpQuantiles 直方图估算的分位数
+var otlpQuantiles = []float64{0.5, 0.95, 0.99}
+
+// OTLPService 将OTLP指标转换为ResourceMetric，通过MetricWriter缓冲写入；
+// 与写入接口共用按来源的限流和标签基数限制，来源为"otlp:"加令牌绑定的来源，与service.name无关
+type OTLPService struct {
+	dbManager     *database.DatabaseManager
+	writer        *MetricWriter
+	ingestService *IngestService
+}
+
+func NewOTLPService(dbManager *database.DatabaseManager, writer *MetricWriter, ingestService *IngestService) *OTLPService {
+	return &OTLPService{
+		dbManager:     dbManager,
+		writer:        writer,
+		ingestService: ingestService,
+	}
+}
+
+// OTLPIngestResult 写入结果，Rejected为无法转换的数据点（无值、NaN等），SeriesLimited为超过标签组合上限被丢弃的数据点
+type OTLPIngestResult struct {
+	Accepted      int
+	Rejected      int
+	SeriesLimited int
+}
+
+// otlpPoint 转换后待写入的数据点
+type otlpPoint struct {
+	metric models.ResourceMetric
+	tags   map[string]string
+}
+
+// IngestMetrics 写入OTLP指标：resource属性和数据点属性合并为标签，service.name作为database_name，
+// organization_id取自配置的属性（数据点属性优先于resource属性）；source为令牌绑定的来源
+func (s *OTLPService) IngestMetrics(ctx context.Context, source string, request *collectormetricsv1.ExportMetricsServiceRequest) (*OTLPIngestResult, error) {
+	orgAttribute := s.dbManager.Config.Monitoring.OTLP.OrganizationAttribute
+	result := &OTLPIngestResult{}
+	now := time.Now()
+
+	var points []otlpPoint
+	for _, resourceMetrics := range request.GetResourceMetrics() {
+		resourceTags := otlpAttributes(resourceMetrics.GetResource().GetAttributes())
+		serviceName := resourceTags["service.name"]
+		if serviceName == "" {
+			serviceName = "unknown_service"
+		}
+
+		for _, scopeMetrics := range resourceMetrics.GetScopeMetrics() {
+			scopeName := scopeMetrics.GetScope().GetName()
+
+			for _, metric := range scopeMetrics.GetMetrics() {
+				// add 构建一个数据点对应的指标
+				add := func(kind, metricName string, value float64, unit string, timeUnixNano uint64, attributes []*commonv1.KeyValue, extraTags map[string]string) {
+					if math.IsNaN(value) || math.IsInf(value, 0) {
+						result.Rejected++
+						return
+					}
+
+					tags := make(map[string]string, len(resourceTags)+len(attributes)+len(extraTags)+1)
+					for key, tagValue := range resourceTags {
+						tags[key] = tagValue
+					}
+					for key, tagValue := range otlpAttributes(attributes) {
+						tags[key] = tagValue
+					}
+					for key, tagValue := range extraTags {
+						tags[key] = tagValue
+					}
+					if scopeName != "" {
+						tags["otel.scope.name"] = scopeName
+					}
+
+					collectedAt := now
+					if timeUnixNano > 0 {
+						collectedAt = time.Unix(0, int64(timeUnixNano))
+					}
+
+					resourceMetric := models.ResourceMetric{
+						DatabaseType: OTLPDatabaseType,
+						DatabaseName: truncateString(serviceName, 100),
+						MetricType:   kind,
+						MetricName:   truncateString(metricName, 100),
+						MetricValue:  value,
+						Unit:         truncateString(unit, 20),
+						CollectedAt:  collectedAt,
+					}
+					if orgID := tags[orgAttribute]; orgID != "" {
+						resourceMetric.OrganizationID = &orgID
+					}
+					points = append(points, otlpPoint{metric: resourceMetric, tags: tags})
+				}
+
+				name := metric.GetName()
+				unit := metric.GetUnit()
+				switch metricData := metric.GetData().(type) {
+				case *metricsv1.Metric_Gauge:
+					for _, point := range metricData.Gauge.GetDataPoints() {
+						value, ok := otlpNumberValue(point)
+						if !ok {
+							result.Rejected++
+							continue
+						}
+						add("gauge", name, value, unit, point.GetTimeUnixNano(), point.GetAttributes(), nil)
+					}
+				case *metricsv1.Metric_Sum:
+					extraTags := map[string]string{
+						"temporality": otlpTemporality(metricData.Sum.GetAggregationTemporality()),
+						"monotonic":   strconv.FormatBool(metricData.Sum.GetIsMonotonic()),
+					}
+					for _, point := range metricData.Sum.GetDataPoints() {
+						value, ok := otlpNumberValue(point)
+						if !ok {
+							result.Rejected++
+							continue
+						}
+						add("sum", name, value, unit, point.GetTimeUnixNano(), point.GetAttributes(), extraTags)
+					}
+				case *metricsv1.Metric_Histogram:
+					extraTags := map[string]string{"temporality": otlpTemporality(metricData.Histogram.GetAggregationTemporality())}
+					for _, point := range metricData.Histogram.GetDataPoints() {
+						if point.GetFlags()&uint32(metricsv1.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0 {
+							result.Rejected++
+							continue
+						}
+						add("histogram", name+"_count", float64(point.GetCount()), "count", point.GetTimeUnixNano(), point.GetAttributes(), extraTags)
+						if point.Sum != nil {
+							add("histogram", name+"_sum", point.GetSum(), unit, point.GetTimeUnixNano(), point.GetAttributes(), extraTags)
+						}
+						for _, quantile := range otlpQuantiles {
+							if value, ok := histogramQuantile(quantile, point.GetExplicitBounds(), point.GetBucketCounts()); ok {
+								add("histogram", fmt.Sprintf("%s_p%s", name, quantileLabel(quantile)), value, unit, point.GetTimeUnixNano(), point.GetAttributes(), extraTags)
+							}
+						}
+					}
+				case *metricsv1.Metric_ExponentialHistogram:
+					extraTags := map[string]string{"temporality": otlpTemporality(metricData.ExponentialHistogram.GetAggregationTemporality())}
+					for _, point := range metricData.ExponentialHistogram.GetDataPoints() {
+						if point.GetFlags()&uint32(metricsv1.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0 {
+							result.Rejected++
+							continue
+						}
+						add("histogram", name+"_count", float64(point.GetCount()), "count", point.GetTimeUnixNano(), point.GetAttributes(), extraTags)
+						if point.Sum != nil {
+							add("histogram", name+"_sum", point.GetSum(), unit, point.GetTimeUnixNano(), point.GetAttributes(), extraTags)
+						}
+					}
+				case *metricsv1.Metric_Summary:
+					for _, point := range metricData.Summary.GetDataPoints() {
+						if point.GetFlags()&uint32(metricsv1.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0 {
+							result.Rejected++
+							continue
+						}
+						add("summary", name+"_count", float64(point.GetCount()), "count", point.GetTimeUnixNano(), point.GetAttributes(), nil)
+						add("summary", name+"_sum", point.GetSum(), unit, point.GetTimeUnixNano(), point.GetAttributes(), nil)
+						for _, quantile := range point.GetQuantileValues() {
+							add("summary", fmt.Sprintf("%s_p%s", name, quantileLabel(quantile.GetQuantile())), quantile.GetValue(), unit, point.GetTimeUnixNano(), point.GetAttributes(), nil)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if len(points) == 0 {
+		return result, nil
+	}
+	limitSource := otlpSource(source)
+	if err := s.ingestService.reserve(map[string]int{limitSource: len(points)}, now); err != nil {
+		return nil, err
+	}
+
+	metrics := make([]models.ResourceMetric, 0, len(points))
+	for _, point := range points {
+		// service.name在标签中，不同服务的同名指标共用来源的组合上限
+		if !s.ingestService.allowSeries(limitSource, point.metric.MetricName, point.tags, now) {
+			result.SeriesLimited++
+			continue
+		}
+		tags := make(map[string]interface{}, len(point.tags))
+		for key, value := range point.tags {
+			tags[key] = value
+		}
+		point.metric.Tags = formatMetricTags(tags)
+		metrics = append(metrics, point.metric)
+	}
+
+	if err := s.writer.Write(metrics); err != nil {
+		return nil, err
+	}
+	result.Accepted = len(metrics)
+	return result, nil
+}
+
+// otlpSource OTLP数据点限流使用的来源，与写入接口的source区分开
+func otlpSource(source string) string {
+	return "otlp:" + source
+}
+
+// otlpNumberValue 获取数值数据点的值，无记录值时返回false
+func otlpNumberValue(point *metricsv1.NumberDataPoint) (float64, bool) {
+	if point.GetFlags()&uint32(metricsv1.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0 {
+		return 0, false
+	}
+	switch value := point.GetValue().(type) {
+	case *metricsv1.NumberDataPoint_AsDouble:
+		return value.AsDouble, true
+	case *metricsv1.NumberDataPoint_AsInt:
+		return float64(value.AsInt), true
+	}
+	return 0, false
+}
+
+// otlpTemporality 聚合时间性的标签值
+func otlpTemporality(temporality metricsv1.AggregationTemporality) string {
+	switch temporality {
+	case metricsv1.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA:
+		return "delta"
+	case metricsv1.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE:
+		return "cumulative"
+	}
+	return "unspecified"
+}
+
+// otlpAttributes 将OTLP属性转换为字符串标签
+func otlpAttributes(attributes []*commonv1.KeyValue) map[string]string {
+	tags := make(map[string]string, len(attributes))
+	for _, attribute := range attributes {
+		tags[attribute.GetKey()] = otlpAnyValueString(attribute.GetValue())
+	}
+	return tags
+}
+
+// otlpAnyValueString 将AnyValue转换为字符串，数组和KV列表展开为逗号分隔
+func otlpAnyValueString(value *commonv1.AnyValue) string {
+	switch v := value.GetValue().(type) {
+	case *commonv1.AnyValue_StringValue:
+		return v.StringValue
+	case *commonv1.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonv1.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonv1.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'f', -1, 64)
+	case *commonv1.AnyValue_ArrayValue:
+		var items []string
+		for _, item := range v.ArrayValue.GetValues() {
+			items = append(items, otlpAnyValueString(item))
+		}
+		return strings.Join(items, ",")
+	case *commonv1.AnyValue_KvlistValue:
+		items := otlpAttributes(v.KvlistValue.GetValues())
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			keys[i] = key + "=" + items[key]
+		}
+		return strings.Join(keys, ",")
+	case *commonv1.AnyValue_BytesValue:
+		return fmt.Sprintf("%x", v.BytesValue)
+	}
+	return ""
+}
+
+// histogramQuantile 按显式桶边界线性插值估算分位数（与Prometheus histogram_quantile一致），
+// 落在最后一个无上界桶时返回最大的有限边界
+func histogramQuantile(quantile float64, bounds []float64, counts []uint64) (float64, bool) {
+	if len(counts) == 0 || len(counts) != len(bounds)+1 {
+		return 0, false
+	}
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	rank := quantile * float64(total)
+	var cumulative uint64
+	for i, count := range counts {
+		previous := cumulative
+		cumulative += count
+		if float64(cumulative) < rank || count == 0 {
+			continue
+		}
+		if i == len(bounds) {
+			if len(bounds) == 0 {
+				return 0, false
+			}
+			return bounds[len(bounds)-1], true
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = bounds[i-1]
+		}
+		upper := bounds[i]
+		return lower + (upper-lower)*(rank-float64(previous))/float64(count), true
+	}
+	return 0, false
+}
+
+// quantileLabel 分位数的指标名后缀，如0.95 -> 95、0.999 -> 99_9
+func quantileLabel(quantile float64) string {
+	label := strconv.FormatFloat(quantile*100, 'f', -1, 64)
+	return strings.ReplaceAll(label, ".", "_")
+}
+
+// truncateString 按字节截断到列长度限制，并去掉被截断的不完整UTF-8字符
+func truncateString(value string, maxLength int) string {
+	if len(value) <= maxLength {
+		return value
+	}
+	return strings.ToValidUTF8(value[:maxLength], "")
+}
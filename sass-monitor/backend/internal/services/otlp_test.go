@@ -0,0 +1,104 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantile float64
+		bounds   []float64
+		counts   []uint64
+		want     float64
+		wantOK   bool
+	}{
+		{
+			name:     "interpolates within the first bucket from zero",
+			quantile: 0.5,
+			bounds:   []float64{10, 20},
+			counts:   []uint64{4, 0, 0},
+			want:     5,
+			wantOK:   true,
+		},
+		{
+			name:     "interpolates within a middle bucket",
+			quantile: 0.5,
+			bounds:   []float64{10, 20, 40},
+			counts:   []uint64{2, 4, 2, 0},
+			want:     15,
+			wantOK:   true,
+		},
+		{
+			name:     "p95 in the last finite bucket",
+			quantile: 0.95,
+			bounds:   []float64{100, 200},
+			counts:   []uint64{10, 10, 0},
+			want:     190,
+			wantOK:   true,
+		},
+		{
+			name:     "skips empty buckets at the rank boundary",
+			quantile: 0.5,
+			bounds:   []float64{10, 20, 30},
+			counts:   []uint64{5, 0, 5, 0},
+			want:     10,
+			wantOK:   true,
+		},
+		{
+			name:     "overflow bucket returns the largest finite bound",
+			quantile: 0.99,
+			bounds:   []float64{10, 20},
+			counts:   []uint64{1, 1, 98},
+			want:     20,
+			wantOK:   true,
+		},
+		{
+			name:     "only an overflow bucket",
+			quantile: 0.5,
+			bounds:   nil,
+			counts:   []uint64{3},
+			wantOK:   false,
+		},
+		{
+			name:     "no observations",
+			quantile: 0.5,
+			bounds:   []float64{10},
+			counts:   []uint64{0, 0},
+			wantOK:   false,
+		},
+		{
+			name:     "bucket count does not match bounds",
+			quantile: 0.5,
+			bounds:   []float64{10, 20},
+			counts:   []uint64{1, 2},
+			wantOK:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := histogramQuantile(tt.quantile, tt.bounds, tt.counts)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("histogramQuantile(%v) = %v, want %v", tt.quantile, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuantileLabel(t *testing.T) {
+	tests := map[float64]string{
+		0.5:   "50",
+		0.95:  "95",
+		0.99:  "99",
+		0.999: "99_9",
+	}
+	for quantile, want := range tests {
+		if got := quantileLabel(quantile); got != want {
+			t.Errorf("quantileLabel(%v) = %q, want %q", quantile, got, want)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// PostgreSQLActivityService PostgreSQL会话、锁和长事务查询服务（只读）
+type PostgreSQLActivityService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewPostgreSQLActivityService(dbManager *database.DatabaseManager) *PostgreSQLActivityService {
+	return &PostgreSQLActivityService{
+		dbManager: dbManager,
+	}
+}
+
+// ActivitySummary 会话活动汇总
+type ActivitySummary struct {
+	TotalSessions            int64            `json:"total_sessions"`
+	SessionsByState          map[string]int64 `json:"sessions_by_state"`
+	IdleInTransaction        int64            `json:"idle_in_transaction"`
+	OldestTransactionSeconds float64          `json:"oldest_transaction_seconds"`
+	LongestQuerySeconds      float64          `json:"longest_query_seconds"`
+	BlockedSessions          int64            `json:"blocked_sessions"`
+	WaitingByEventType       map[string]int64 `json:"waiting_by_event_type"`
+	MaxConnections           int64            `json:"max_connections"`
+	ConnectionUsagePercent   float64          `json:"connection_usage_percent"`
+}
+
+// SessionActivity 单个会话信息（来自pg_stat_activity）
+type SessionActivity struct {
+	PID                int        `json:"pid" gorm:"column:pid"`
+	Username           *string    `json:"username" gorm:"column:usename"`
+	ApplicationName    *string    `json:"application_name" gorm:"column:application_name"`
+	ClientAddr         *string    `json:"client_addr" gorm:"column:client_addr"`
+	State              *string    `json:"state" gorm:"column:state"`
+	WaitEventType      *string    `json:"wait_event_type" gorm:"column:wait_event_type"`
+	WaitEvent          *string    `json:"wait_event" gorm:"column:wait_event"`
+	BackendStart       *time.Time `json:"backend_start" gorm:"column:backend_start"`
+	TransactionStart   *time.Time `json:"transaction_start" gorm:"column:xact_start"`
+	QueryStart         *time.Time `json:"query_start" gorm:"column:query_start"`
+	TransactionSeconds *float64   `json:"transaction_seconds" gorm:"column:xact_seconds"`
+	QuerySeconds       *float64   `json:"query_seconds" gorm:"column:query_seconds"`
+	BlockedBy          string     `json:"blocked_by" gorm:"column:blocked_by"` // 逗号分隔的阻塞PID
+	Query              *string    `json:"query" gorm:"column:query"`
+}
+
+// ActivityFilter 会话查询过滤条件
+type ActivityFilter struct {
+	State      string  // active, idle, idle in transaction 等
+	MinSeconds float64 // 最短查询/事务持续时间
+	Limit      int
+}
+
+// BlockingPair 阻塞关系（被阻塞会话 -> 阻塞者）
+type BlockingPair struct {
+	BlockedPID          int      `json:"blocked_pid" gorm:"column:blocked_pid"`
+	BlockedUser         *string  `json:"blocked_user" gorm:"column:blocked_user"`
+	BlockedApplication  *string  `json:"blocked_application" gorm:"column:blocked_application"`
+	BlockedState        *string  `json:"blocked_state" gorm:"column:blocked_state"`
+	BlockedQuery        *string  `json:"blocked_query" gorm:"column:blocked_query"`
+	BlockedWaitSeconds  *float64 `json:"blocked_wait_seconds" gorm:"column:blocked_wait_seconds"`
+	WaitEventType       *string  `json:"wait_event_type" gorm:"column:wait_event_type"`
+	WaitEvent           *string  `json:"wait_event" gorm:"column:wait_event"`
+	LockType            *string  `json:"lock_type" gorm:"column:locktype"`
+	LockMode            *string  `json:"lock_mode" gorm:"column:lock_mode"`
+	Relation            *string  `json:"relation" gorm:"column:relation"`
+	BlockingPID         int      `json:"blocking_pid" gorm:"column:blocking_pid"`
+	BlockingUser        *string  `json:"blocking_user" gorm:"column:blocking_user"`
+	BlockingApplication *string  `json:"blocking_application" gorm:"column:blocking_application"`
+	BlockingState       *string  `json:"blocking_state" gorm:"column:blocking_state"`
+	BlockingQuery       *string  `json:"blocking_query" gorm:"column:blocking_query"`
+	BlockingXactSeconds *float64 `json:"blocking_xact_seconds" gorm:"column:blocking_xact_seconds"`
+}
+
+// BlockingNode 阻塞链节点
+type BlockingNode struct {
+	PID      int             `json:"pid"`
+	User     *string         `json:"user"`
+	State    *string         `json:"state"`
+	Query    *string         `json:"query"`
+	Cycle    bool            `json:"cycle,omitempty"` // 该进程已在链路上出现过（死锁检测前的环），不再展开
+	Blocking []*BlockingNode `json:"blocking"`
+}
+
+// BlockingReport 阻塞链报告
+type BlockingReport struct {
+	Database string          `json:"database"`
+	Pairs    []BlockingPair  `json:"pairs"`
+	Chains   []*BlockingNode `json:"chains"` // 以根阻塞者（自身未被阻塞）为根的树，只构成环的会话以最先出现的阻塞者为根
+}
+
+// GetActivitySummary 获取会话活动汇总
+func (s *PostgreSQLActivityService) GetActivitySummary(ctx context.Context, dbName string) (*ActivitySummary, error) {
+	db, err := s.dbManager.GetPostgreSQLConnection(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return queryActivitySummary(db.WithContext(ctx))
+}
+
+// GetActivity 获取当前会话列表
+func (s *PostgreSQLActivityService) GetActivity(ctx context.Context, dbName string, filter ActivityFilter) ([]SessionActivity, error) {
+	db, err := s.dbManager.GetPostgreSQLConnection(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+
+	query := `
+		SELECT
+			pid, usename, application_name, client_addr::text as client_addr, state,
+			wait_event_type, wait_event, backend_start, xact_start, query_start,
+			EXTRACT(EPOCH FROM (now() - xact_start)) as xact_seconds,
+			EXTRACT(EPOCH FROM (now() - query_start)) as query_seconds,
+			array_to_string(pg_blocking_pids(pid), ',') as blocked_by,
+			query
+		FROM pg_stat_activity
+		WHERE datname = current_database() AND pid <> pg_backend_pid()`
+	args := []interface{}{}
+
+	if filter.State != "" {
+		query += " AND state = ?"
+		args = append(args, filter.State)
+	}
+	if filter.MinSeconds > 0 {
+		query += " AND GREATEST(EXTRACT(EPOCH FROM (now() - xact_start)), EXTRACT(EPOCH FROM (now() - query_start))) >= ?"
+		args = append(args, filter.MinSeconds)
+	}
+	query += " ORDER BY COALESCE(xact_start, query_start, backend_start) ASC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	var sessions []SessionActivity
+	if err := db.WithContext(ctx).Raw(query, args...).Scan(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to query pg_stat_activity: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// GetBlockingReport 获取阻塞关系和阻塞链
+func (s *PostgreSQLActivityService) GetBlockingReport(ctx context.Context, dbName string) (*BlockingReport, error) {
+	db, err := s.dbManager.GetPostgreSQLConnection(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := queryBlockingPairs(db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &BlockingReport{
+		Database: dbName,
+		Pairs:    pairs,
+		Chains:   buildBlockingChains(pairs),
+	}, nil
+}
+
+// queryActivitySummary 从pg_stat_activity汇总会话状态
+func queryActivitySummary(db *gorm.DB) (*ActivitySummary, error) {
+	summary := &ActivitySummary{
+		SessionsByState:    make(map[string]int64),
+		WaitingByEventType: make(map[string]int64),
+	}
+
+	var stateRows []struct {
+		State string `gorm:"column:state"`
+		Count int64  `gorm:"column:count"`
+	}
+	if err := db.Raw(`
+		SELECT COALESCE(state, 'unknown') as state, COUNT(*) as count
+		FROM pg_stat_activity
+		WHERE datname = current_database() AND backend_type = 'client backend'
+		GROUP BY 1
+	`).Scan(&stateRows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query session states: %w", err)
+	}
+
+	for _, row := range stateRows {
+		summary.SessionsByState[row.State] = row.Count
+		summary.TotalSessions += row.Count
+		if row.State == "idle in transaction" || row.State == "idle in transaction (aborted)" {
+			summary.IdleInTransaction += row.Count
+		}
+	}
+
+	var ages struct {
+		OldestXact   float64 `gorm:"column:oldest_xact"`
+		LongestQuery float64 `gorm:"column:longest_query"`
+		Blocked      int64   `gorm:"column:blocked"`
+	}
+	if err := db.Raw(`
+		SELECT
+			COALESCE(MAX(EXTRACT(EPOCH FROM (now() - xact_start))), 0) as oldest_xact,
+			COALESCE(MAX(EXTRACT(EPOCH FROM (now() - query_start))) FILTER (WHERE state = 'active'), 0) as longest_query,
+			COUNT(*) FILTER (WHERE cardinality(pg_blocking_pids(pid)) > 0) as blocked
+		FROM pg_stat_activity
+		WHERE datname = current_database() AND backend_type = 'client backend' AND pid <> pg_backend_pid()
+	`).Scan(&ages).Error; err != nil {
+		return nil, fmt.Errorf("failed to query transaction age: %w", err)
+	}
+	summary.OldestTransactionSeconds = ages.OldestXact
+	summary.LongestQuerySeconds = ages.LongestQuery
+	summary.BlockedSessions = ages.Blocked
+
+	var waitRows []struct {
+		WaitEventType string `gorm:"column:wait_event_type"`
+		Count         int64  `gorm:"column:count"`
+	}
+	if err := db.Raw(`
+		SELECT wait_event_type, COUNT(*) as count
+		FROM pg_stat_activity
+		WHERE datname = current_database() AND state = 'active' AND wait_event_type IS NOT NULL
+		GROUP BY wait_event_type
+	`).Scan(&waitRows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query wait events: %w", err)
+	}
+	for _, row := range waitRows {
+		summary.WaitingByEventType[row.WaitEventType] = row.Count
+	}
+
+	var maxConnections string
+	if err := db.Raw("SHOW max_connections").Scan(&maxConnections).Error; err == nil {
+		fmt.Sscanf(maxConnections, "%d", &summary.MaxConnections)
+	}
+	if summary.MaxConnections > 0 {
+		summary.ConnectionUsagePercent = float64(summary.TotalSessions) / float64(summary.MaxConnections) * 100
+	}
+
+	return summary, nil
+}
+
+// queryBlockingPairs 通过pg_blocking_pids和pg_locks查询阻塞关系
+func queryBlockingPairs(db *gorm.DB) ([]BlockingPair, error) {
+	var pairs []BlockingPair
+	err := db.Raw(`
+		SELECT
+			blocked.pid as blocked_pid,
+			blocked.usename as blocked_user,
+			blocked.application_name as blocked_application,
+			blocked.state as blocked_state,
+			blocked.query as blocked_query,
+			EXTRACT(EPOCH FROM (now() - blocked.query_start)) as blocked_wait_seconds,
+			blocked.wait_event_type,
+			blocked.wait_event,
+			lock.locktype,
+			lock.mode as lock_mode,
+			lock.relation::regclass::text as relation,
+			blocking.pid as blocking_pid,
+			blocking.usename as blocking_user,
+			blocking.application_name as blocking_application,
+			blocking.state as blocking_state,
+			blocking.query as blocking_query,
+			EXTRACT(EPOCH FROM (now() - blocking.xact_start)) as blocking_xact_seconds
+		FROM pg_stat_activity blocked
+		CROSS JOIN LATERAL unnest(pg_blocking_pids(blocked.pid)) AS b(pid)
+		JOIN pg_stat_activity blocking ON blocking.pid = b.pid
+		LEFT JOIN LATERAL (
+			SELECT locktype, mode, relation
+			FROM pg_locks
+			WHERE pid = blocked.pid AND NOT granted
+			LIMIT 1
+		) lock ON true
+		WHERE blocked.datname = current_database()
+		ORDER BY blocked_wait_seconds DESC NULLS LAST
+	`).Scan(&pairs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocking sessions: %w", err)
+	}
+	return pairs, nil
+}
+
+// buildBlockingChains 根据阻塞关系构建阻塞树。deadlock_timeout之前pg_blocking_pids可能报告环，
+// 进程在当前路径上重复出现时标记cycle并截断，保证结果是可以序列化的树
+func buildBlockingChains(pairs []BlockingPair) []*BlockingNode {
+	type sessionInfo struct {
+		user, state, query *string
+	}
+	sessions := make(map[int]sessionInfo)
+	children := make(map[int][]int)
+	blocked := make(map[int]bool)
+	linked := make(map[[2]int]bool)
+
+	var order []int
+	for _, pair := range pairs {
+		if _, exists := sessions[pair.BlockingPID]; !exists {
+			order = append(order, pair.BlockingPID)
+			sessions[pair.BlockingPID] = sessionInfo{pair.BlockingUser, pair.BlockingState, pair.BlockingQuery}
+		}
+		if _, exists := sessions[pair.BlockedPID]; !exists {
+			sessions[pair.BlockedPID] = sessionInfo{pair.BlockedUser, pair.BlockedState, pair.BlockedQuery}
+		}
+
+		key := [2]int{pair.BlockingPID, pair.BlockedPID}
+		if !linked[key] {
+			children[pair.BlockingPID] = append(children[pair.BlockingPID], pair.BlockedPID)
+			linked[key] = true
+		}
+		blocked[pair.BlockedPID] = true
+	}
+
+	rendered := make(map[int]bool)
+	onPath := make(map[int]bool)
+	var render func(pid int) *BlockingNode
+	render = func(pid int) *BlockingNode {
+		session := sessions[pid]
+		node := &BlockingNode{PID: pid, User: session.user, State: session.state, Query: session.query}
+		if onPath[pid] {
+			node.Cycle = true
+			return node
+		}
+		rendered[pid] = true
+		onPath[pid] = true
+		for _, child := range children[pid] {
+			node.Blocking = append(node.Blocking, render(child))
+		}
+		delete(onPath, pid)
+		return node
+	}
+
+	chains := []*BlockingNode{}
+	for _, pid := range order {
+		if !blocked[pid] {
+			chains = append(chains, render(pid))
+		}
+	}
+	// 所有会话都被阻塞的环不会从上面的根到达
+	for _, pid := range order {
+		if !rendered[pid] {
+			chains = append(chains, render(pid))
+		}
+	}
+	return chains
+}
+
+// collectPostgreSQLActivity 采集所有PostgreSQL监控目标的会话、锁和长事务指标
+func (dc *DataCollector) collectPostgreSQLActivity(ctx context.Context) error {
+	return dc.collectPostgreSQLTargets(ctx, "activity", dc.collectPostgreSQLActivityFor)
+}
+
+// collectPostgreSQLActivityFor 采集单个PostgreSQL目标的会话、锁和长事务指标
+func (dc *DataCollector) collectPostgreSQLActivityFor(ctx context.Context, dbName string, db *gorm.DB) error {
+	summary, err := queryActivitySummary(db.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	newMetric := func(metricType, metricName string, value float64, unit string) models.ResourceMetric {
+		return models.ResourceMetric{
+			DatabaseType: "postgresql",
+			DatabaseName: dbName,
+			MetricType:   metricType,
+			MetricName:   metricName,
+			MetricValue:  value,
+			Unit:         unit,
+			CollectedAt:  now,
+		}
+	}
+
+	metrics := []models.ResourceMetric{
+		newMetric("activity", "total_sessions", float64(summary.TotalSessions), "count"),
+		newMetric("activity", "idle_in_transaction_sessions", float64(summary.IdleInTransaction), "count"),
+		newMetric("activity", "oldest_transaction_age_seconds", summary.OldestTransactionSeconds, "seconds"),
+		newMetric("activity", "longest_query_seconds", summary.LongestQuerySeconds, "seconds"),
+		newMetric("activity", "connection_usage_percent", summary.ConnectionUsagePercent, "percent"),
+		newMetric("lock", "blocked_sessions", float64(summary.BlockedSessions), "count"),
+	}
+
+	for state, count := range summary.SessionsByState {
+		metric := newMetric("activity", "sessions_"+sanitizeMetricName(state), float64(count), "count")
+		metric.Tags = dc.formatTags(map[string]interface{}{"state": state})
+		metrics = append(metrics, metric)
+	}
+
+	for eventType, count := range summary.WaitingByEventType {
+		metric := newMetric("wait_event", "waiting_"+sanitizeMetricName(eventType), float64(count), "count")
+		metric.Tags = dc.formatTags(map[string]interface{}{"wait_event_type": eventType})
+		metrics = append(metrics, metric)
+	}
+
+	return dc.dbManager.SaasMonitorDB.Create(metrics).Error
+}
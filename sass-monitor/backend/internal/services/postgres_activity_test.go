@@ -0,0 +1,87 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// formatBlockingChains 将阻塞树格式化为"1(2(3),4)"形式，环上重复出现的进程以*标记
+func formatBlockingChains(nodes []*BlockingNode) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		part := strconv.Itoa(node.PID)
+		if node.Cycle {
+			part += "*"
+		}
+		if len(node.Blocking) > 0 {
+			part += "(" + formatBlockingChains(node.Blocking) + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestBuildBlockingChains(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs [][2]int // (阻塞方, 被阻塞方)
+		want  string
+	}{
+		{
+			name:  "no blocking",
+			pairs: nil,
+			want:  "",
+		},
+		{
+			name:  "chain and sibling",
+			pairs: [][2]int{{1, 2}, {2, 3}, {1, 4}},
+			want:  "1(2(3),4)",
+		},
+		{
+			name:  "duplicate pairs are linked once",
+			pairs: [][2]int{{1, 2}, {1, 2}},
+			want:  "1(2)",
+		},
+		{
+			name:  "session blocked by two roots",
+			pairs: [][2]int{{1, 3}, {2, 3}},
+			want:  "1(3),2(3)",
+		},
+		{
+			name:  "cycle without root",
+			pairs: [][2]int{{1, 2}, {2, 1}},
+			want:  "1(2(1*))",
+		},
+		{
+			name:  "root blocking into a cycle",
+			pairs: [][2]int{{3, 1}, {1, 2}, {2, 1}},
+			want:  "3(1(2(1*)))",
+		},
+		{
+			name:  "self block",
+			pairs: [][2]int{{5, 5}},
+			want:  "5(5*)",
+		},
+		{
+			name:  "self block under a root",
+			pairs: [][2]int{{1, 5}, {5, 5}},
+			want:  "1(5(5*))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := make([]BlockingPair, 0, len(tt.pairs))
+			for _, pair := range tt.pairs {
+				pairs = append(pairs, BlockingPair{BlockingPID: pair[0], BlockedPID: pair[1]})
+			}
+			chains := buildBlockingChains(pairs)
+			if chains == nil {
+				t.Fatal("buildBlockingChains() returned nil, want an empty slice")
+			}
+			if got := formatBlockingChains(chains); got != tt.want {
+				t.Errorf("buildBlockingChains() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
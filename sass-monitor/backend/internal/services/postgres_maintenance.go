@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/database"
+)
+
+// 维护健康判断阈值
+const (
+	deadTupleRatioThreshold  = 0.2   // 死元组占比
+	deadTupleMinCount        = 1000  // 死元组最少数量（过滤小表噪音）
+	vacuumAgeHoursThreshold  = 168.0 // 7天未vacuum
+	analyzeAgeHoursThreshold = 168.0 // 7天未analyze
+	seqScanRatioThreshold    = 0.5   // 顺序扫描占比
+	seqScanMinLiveTuples     = 10000 // 顺序扫描判断的最小行数
+	bloatRatioThreshold      = 0.3   // 估算膨胀占比
+	bloatMinMB               = 10.0  // 估算膨胀最小值(MB)
+)
+
+// PostgreSQLMaintenanceService PostgreSQL维护健康（膨胀、vacuum、索引使用）查询服务
+type PostgreSQLMaintenanceService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewPostgreSQLMaintenanceService(dbManager *database.DatabaseManager) *PostgreSQLMaintenanceService {
+	return &PostgreSQLMaintenanceService{
+		dbManager: dbManager,
+	}
+}
+
+// TableMaintenanceStat 表级维护统计（来自pg_stat_user_tables和pg_class）
+type TableMaintenanceStat struct {
+	TableName         string   `json:"table_name" gorm:"column:tablename"`
+	SizeMB            float64  `json:"size_mb" gorm:"column:size_mb"`
+	RowCount          int64    `json:"row_count" gorm:"column:row_count"` // 当前行数估算（n_live_tup），不是累计插入数
+	LiveTuples        int64    `json:"live_tuples" gorm:"column:n_live_tup"`
+	DeadTuples        int64    `json:"dead_tuples" gorm:"column:n_dead_tup"`
+	DeadTupleRatio    float64  `json:"dead_tuple_ratio" gorm:"column:dead_tuple_ratio"`
+	VacuumAgeHours    *float64 `json:"vacuum_age_hours" gorm:"column:vacuum_age_hours"`   // 距最近一次(auto)vacuum的小时数，从未执行为null
+	AnalyzeAgeHours   *float64 `json:"analyze_age_hours" gorm:"column:analyze_age_hours"` // 距最近一次(auto)analyze的小时数，从未执行为null
+	SeqScan           int64    `json:"seq_scan" gorm:"column:seq_scan"`
+	IdxScan           int64    `json:"idx_scan" gorm:"column:idx_scan"`
+	SeqScanRatio      float64  `json:"seq_scan_ratio" gorm:"column:seq_scan_ratio"`
+	EstimatedBloatMB  float64  `json:"estimated_bloat_mb" gorm:"column:estimated_bloat_mb"`
+	EstimatedBloatPct float64  `json:"estimated_bloat_ratio" gorm:"column:estimated_bloat_ratio"`
+}
+
+// IndexUsageStat 索引使用统计
+type IndexUsageStat struct {
+	TableName string  `json:"table_name" gorm:"column:tablename"`
+	IndexName string  `json:"index_name" gorm:"column:indexname"`
+	IdxScan   int64   `json:"idx_scan" gorm:"column:idx_scan"`
+	SizeMB    float64 `json:"size_mb" gorm:"column:size_mb"`
+}
+
+// DuplicateIndexGroup 重复索引（列、操作符类、表达式和条件完全相同）
+type DuplicateIndexGroup struct {
+	TableName string   `json:"table_name"`
+	Indexes   []string `json:"indexes"`
+	SizeMB    float64  `json:"size_mb"`
+}
+
+// TableAttention 需要关注的表
+type TableAttention struct {
+	TableMaintenanceStat
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// MaintenanceReport 维护健康报告
+type MaintenanceReport struct {
+	Database         string                `json:"database"`
+	Tables           []TableAttention      `json:"tables"`
+	UnusedIndexes    []IndexUsageStat      `json:"unused_indexes"`
+	DuplicateIndexes []DuplicateIndexGroup `json:"duplicate_indexes"`
+}
+
+// GetMaintenanceReport 获取按需要关注程度排序的维护报告
+func (s *PostgreSQLMaintenanceService) GetMaintenanceReport(ctx context.Context, dbName string, limit int) (*MaintenanceReport, error) {
+	db, err := s.dbManager.GetPostgreSQLConnection(dbName)
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+
+	if limit <= 0 {
+		limit = 20
+	}
+
+	stats, err := queryTableMaintenanceStats(db, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []TableAttention{}
+	for _, stat := range stats {
+		attention := evaluateTableMaintenance(stat)
+		if attention.Score > 0 {
+			tables = append(tables, attention)
+		}
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Score > tables[j].Score
+	})
+	if len(tables) > limit {
+		tables = tables[:limit]
+	}
+
+	unused, err := queryUnusedIndexes(db)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates, err := queryDuplicateIndexes(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MaintenanceReport{
+		Database:         dbName,
+		Tables:           tables,
+		UnusedIndexes:    unused,
+		DuplicateIndexes: duplicates,
+	}, nil
+}
+
+// evaluateTableMaintenance 根据阈值为表打分并给出原因
+func evaluateTableMaintenance(stat TableMaintenanceStat) TableAttention {
+	attention := TableAttention{TableMaintenanceStat: stat, Reasons: []string{}}
+
+	if stat.DeadTuples >= deadTupleMinCount && stat.DeadTupleRatio >= deadTupleRatioThreshold {
+		attention.Score += stat.DeadTupleRatio * 100
+		attention.Reasons = append(attention.Reasons,
+			fmt.Sprintf("dead tuple ratio %.1f%% (%d dead tuples)", stat.DeadTupleRatio*100, stat.DeadTuples))
+	}
+
+	if stat.DeadTuples >= deadTupleMinCount {
+		if stat.VacuumAgeHours == nil {
+			attention.Score += 30
+			attention.Reasons = append(attention.Reasons, "never vacuumed")
+		} else if *stat.VacuumAgeHours >= vacuumAgeHoursThreshold {
+			attention.Score += 20
+			attention.Reasons = append(attention.Reasons,
+				fmt.Sprintf("last vacuum %.0f hours ago", *stat.VacuumAgeHours))
+		}
+	}
+
+	if stat.LiveTuples > 0 {
+		if stat.AnalyzeAgeHours == nil {
+			attention.Score += 15
+			attention.Reasons = append(attention.Reasons, "never analyzed")
+		} else if *stat.AnalyzeAgeHours >= analyzeAgeHoursThreshold {
+			attention.Score += 10
+			attention.Reasons = append(attention.Reasons,
+				fmt.Sprintf("last analyze %.0f hours ago", *stat.AnalyzeAgeHours))
+		}
+	}
+
+	if stat.LiveTuples >= seqScanMinLiveTuples && stat.SeqScanRatio >= seqScanRatioThreshold {
+		attention.Score += stat.SeqScanRatio * 50
+		attention.Reasons = append(attention.Reasons,
+			fmt.Sprintf("sequential scans %.1f%% of scans (%d seq / %d idx)", stat.SeqScanRatio*100, stat.SeqScan, stat.IdxScan))
+	}
+
+	if stat.EstimatedBloatMB >= bloatMinMB && stat.EstimatedBloatPct >= bloatRatioThreshold {
+		attention.Score += stat.EstimatedBloatPct * 100
+		attention.Reasons = append(attention.Reasons,
+			fmt.Sprintf("estimated bloat %.1f MB (%.1f%%)", stat.EstimatedBloatMB, stat.EstimatedBloatPct*100))
+	}
+
+	return attention
+}
+
+// queryTableMaintenanceStats 查询public模式下表的大小、死元组、vacuum/analyze、扫描和估算膨胀，limit<=0表示不限制
+func queryTableMaintenanceStats(db *gorm.DB, limit int) ([]TableMaintenanceStat, error) {
+	query := `
+		WITH widths AS (
+			SELECT schemaname, tablename, SUM(avg_width) as row_width
+			FROM pg_stats
+			WHERE schemaname = 'public'
+			GROUP BY schemaname, tablename
+		),
+		tables AS (
+			SELECT
+				t.schemaname||'.'||t.tablename as tablename,
+				pg_total_relation_size(c.oid) as total_bytes,
+				c.relpages::numeric as relpages,
+				GREATEST(c.reltuples, 0)::numeric as reltuples,
+				current_setting('block_size')::numeric as block_size,
+				w.row_width,
+				COALESCE(s.n_live_tup, 0) as n_live_tup,
+				COALESCE(s.n_dead_tup, 0) as n_dead_tup,
+				EXTRACT(EPOCH FROM (now() - GREATEST(s.last_vacuum, s.last_autovacuum))) / 3600 as vacuum_age_hours,
+				EXTRACT(EPOCH FROM (now() - GREATEST(s.last_analyze, s.last_autoanalyze))) / 3600 as analyze_age_hours,
+				COALESCE(s.seq_scan, 0) as seq_scan,
+				COALESCE(s.idx_scan, 0) as idx_scan
+			FROM pg_tables t
+			JOIN pg_class c ON c.relname = t.tablename
+			JOIN pg_namespace n ON n.oid = c.relnamespace AND n.nspname = t.schemaname
+			LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid
+			LEFT JOIN widths w ON w.schemaname = t.schemaname AND w.tablename = t.tablename
+			WHERE t.schemaname = 'public'
+		)
+		SELECT
+			tablename,
+			total_bytes / (1024*1024) as size_mb,
+			n_live_tup as row_count,
+			n_live_tup,
+			n_dead_tup,
+			CASE WHEN n_live_tup + n_dead_tup > 0
+				THEN n_dead_tup::numeric / (n_live_tup + n_dead_tup) ELSE 0 END as dead_tuple_ratio,
+			vacuum_age_hours,
+			analyze_age_hours,
+			seq_scan,
+			idx_scan,
+			CASE WHEN seq_scan + idx_scan > 0
+				THEN seq_scan::numeric / (seq_scan + idx_scan) ELSE 0 END as seq_scan_ratio,
+			CASE WHEN row_width IS NULL OR relpages = 0 THEN 0
+				ELSE GREATEST(relpages - CEIL(reltuples * (row_width + 28) / (block_size - 24)), 0) * block_size / (1024*1024)
+			END as estimated_bloat_mb,
+			CASE WHEN row_width IS NULL OR relpages = 0 THEN 0
+				ELSE GREATEST(relpages - CEIL(reltuples * (row_width + 28) / (block_size - 24)), 0) / relpages
+			END as estimated_bloat_ratio
+		FROM tables
+		ORDER BY total_bytes DESC`
+	args := []interface{}{}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	var stats []TableMaintenanceStat
+	if err := db.Raw(query, args...).Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to query table maintenance stats: %w", err)
+	}
+	return stats, nil
+}
+
+// queryUnusedIndexes 查询从未被扫描过的非唯一索引
+func queryUnusedIndexes(db *gorm.DB) ([]IndexUsageStat, error) {
+	var indexes []IndexUsageStat
+	err := db.Raw(`
+		SELECT
+			s.schemaname||'.'||s.relname as tablename,
+			s.indexrelname as indexname,
+			s.idx_scan,
+			pg_relation_size(s.indexrelid)::numeric / (1024*1024) as size_mb
+		FROM pg_stat_user_indexes s
+		JOIN pg_index i ON i.indexrelid = s.indexrelid
+		WHERE s.schemaname = 'public'
+			AND s.idx_scan = 0
+			AND NOT i.indisunique
+			AND NOT i.indisprimary
+		ORDER BY pg_relation_size(s.indexrelid) DESC
+	`).Scan(&indexes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unused indexes: %w", err)
+	}
+	return indexes, nil
+}
+
+// queryDuplicateIndexes 查询定义相同的重复索引
+func queryDuplicateIndexes(db *gorm.DB) ([]DuplicateIndexGroup, error) {
+	var rows []struct {
+		TableName string  `gorm:"column:tablename"`
+		Indexes   string  `gorm:"column:indexes"`
+		SizeMB    float64 `gorm:"column:size_mb"`
+	}
+	err := db.Raw(`
+		SELECT
+			i.indrelid::regclass::text as tablename,
+			string_agg(i.indexrelid::regclass::text, ',' ORDER BY i.indexrelid::regclass::text) as indexes,
+			SUM(pg_relation_size(i.indexrelid))::numeric / (1024*1024) as size_mb
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public'
+		GROUP BY i.indrelid, i.indkey::text, i.indclass::text,
+			COALESCE(pg_get_expr(i.indexprs, i.indrelid), ''),
+			COALESCE(pg_get_expr(i.indpred, i.indrelid), '')
+		HAVING COUNT(*) > 1
+		ORDER BY size_mb DESC
+	`).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate indexes: %w", err)
+	}
+
+	groups := make([]DuplicateIndexGroup, 0, len(rows))
+	for _, row := range rows {
+		groups = append(groups, DuplicateIndexGroup{
+			TableName: row.TableName,
+			Indexes:   strings.Split(row.Indexes, ","),
+			SizeMB:    row.SizeMB,
+		})
+	}
+	return groups, nil
+}
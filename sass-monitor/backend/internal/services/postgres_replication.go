@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// PostgreSQLReplicationService PostgreSQL流复制和WAL状态查询服务（只读）
+type PostgreSQLReplicationService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewPostgreSQLReplicationService(dbManager *database.DatabaseManager) *PostgreSQLReplicationService {
+	return &PostgreSQLReplicationService{
+		dbManager: dbManager,
+	}
+}
+
+// ReplicaStat 主库视角的备库复制状态（来自pg_stat_replication）
+type ReplicaStat struct {
+	PID              int      `json:"pid" gorm:"column:pid"`
+	ApplicationName  *string  `json:"application_name" gorm:"column:application_name"`
+	ClientAddr       *string  `json:"client_addr" gorm:"column:client_addr"`
+	State            *string  `json:"state" gorm:"column:state"`
+	SyncState        *string  `json:"sync_state" gorm:"column:sync_state"`
+	SentLagBytes     *float64 `json:"sent_lag_bytes" gorm:"column:sent_lag_bytes"`
+	WriteLagBytes    *float64 `json:"write_lag_bytes" gorm:"column:write_lag_bytes"`
+	FlushLagBytes    *float64 `json:"flush_lag_bytes" gorm:"column:flush_lag_bytes"`
+	ReplayLagBytes   *float64 `json:"replay_lag_bytes" gorm:"column:replay_lag_bytes"`
+	WriteLagSeconds  *float64 `json:"write_lag_seconds" gorm:"column:write_lag_seconds"`
+	FlushLagSeconds  *float64 `json:"flush_lag_seconds" gorm:"column:flush_lag_seconds"`
+	ReplayLagSeconds *float64 `json:"replay_lag_seconds" gorm:"column:replay_lag_seconds"`
+}
+
+// ReplicationSlotStat 复制槽WAL保留情况（来自pg_replication_slots）
+type ReplicationSlotStat struct {
+	SlotName       string   `json:"slot_name" gorm:"column:slot_name"`
+	SlotType       string   `json:"slot_type" gorm:"column:slot_type"`
+	Active         bool     `json:"active" gorm:"column:active"`
+	RetainedBytes  *float64 `json:"retained_bytes" gorm:"column:retained_bytes"`
+	WALStatus      *string  `json:"wal_status" gorm:"column:wal_status"`
+	RestartLSN     *string  `json:"restart_lsn" gorm:"column:restart_lsn"`
+	ConfirmedFlush *string  `json:"confirmed_flush_lsn" gorm:"column:confirmed_flush_lsn"`
+}
+
+// WALReceiverStat 备库视角的WAL接收状态（来自pg_stat_wal_receiver）
+type WALReceiverStat struct {
+	Status             *string    `json:"status" gorm:"column:status"`
+	SenderHost         *string    `json:"sender_host" gorm:"column:sender_host"`
+	SlotName           *string    `json:"slot_name" gorm:"column:slot_name"`
+	LastMsgReceiptTime *time.Time `json:"last_msg_receipt_time" gorm:"column:last_msg_receipt_time"`
+	ReceiveReplayBytes *float64   `json:"receive_replay_lag_bytes" gorm:"column:receive_replay_lag_bytes"` // 已接收但未回放的WAL字节数
+	ReplayLagSeconds   *float64   `json:"replay_lag_seconds" gorm:"column:replay_lag_seconds"`             // 距最近一次回放事务的秒数
+}
+
+// ReplicationStatus 复制状态报告
+type ReplicationStatus struct {
+	Database   string                `json:"database"`
+	InRecovery bool                  `json:"in_recovery"` // true表示当前为备库
+	CurrentLSN *string               `json:"current_lsn"`
+	Replicas   []ReplicaStat         `json:"replicas"`
+	Slots      []ReplicationSlotStat `json:"slots"`
+	Receiver   *WALReceiverStat      `json:"receiver,omitempty"`
+}
+
+// GetReplicationStatus 获取复制和WAL状态
+func (s *PostgreSQLReplicationService) GetReplicationStatus(ctx context.Context, dbName string) (*ReplicationStatus, error) {
+	db, err := s.dbManager.GetPostgreSQLConnection(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return queryReplicationStatus(db.WithContext(ctx), dbName)
+}
+
+// queryReplicationStatus 查询复制状态，主库返回备库和复制槽，备库返回WAL接收状态
+func queryReplicationStatus(db *gorm.DB, dbName string) (*ReplicationStatus, error) {
+	status := &ReplicationStatus{
+		Database: dbName,
+		Replicas: []ReplicaStat{},
+		Slots:    []ReplicationSlotStat{},
+	}
+
+	if err := db.Raw("SELECT pg_is_in_recovery()").Scan(&status.InRecovery).Error; err != nil {
+		return nil, fmt.Errorf("failed to check recovery status: %w", err)
+	}
+
+	if status.InRecovery {
+		var lsn *string
+		if err := db.Raw("SELECT pg_last_wal_replay_lsn()::text").Scan(&lsn).Error; err != nil {
+			return nil, fmt.Errorf("failed to get replay LSN: %w", err)
+		}
+		status.CurrentLSN = lsn
+
+		receiver, err := queryWALReceiver(db)
+		if err != nil {
+			return nil, err
+		}
+		status.Receiver = receiver
+		return status, nil
+	}
+
+	var lsn *string
+	if err := db.Raw("SELECT pg_current_wal_lsn()::text").Scan(&lsn).Error; err != nil {
+		return nil, fmt.Errorf("failed to get current WAL LSN: %w", err)
+	}
+	status.CurrentLSN = lsn
+
+	replicaQuery := `
+		SELECT
+			pid,
+			application_name,
+			client_addr::text AS client_addr,
+			state,
+			sync_state,
+			pg_wal_lsn_diff(pg_current_wal_lsn(), sent_lsn)::float8 AS sent_lag_bytes,
+			pg_wal_lsn_diff(pg_current_wal_lsn(), write_lsn)::float8 AS write_lag_bytes,
+			pg_wal_lsn_diff(pg_current_wal_lsn(), flush_lsn)::float8 AS flush_lag_bytes,
+			pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn)::float8 AS replay_lag_bytes,
+			EXTRACT(EPOCH FROM write_lag)::float8 AS write_lag_seconds,
+			EXTRACT(EPOCH FROM flush_lag)::float8 AS flush_lag_seconds,
+			EXTRACT(EPOCH FROM replay_lag)::float8 AS replay_lag_seconds
+		FROM pg_stat_replication
+		ORDER BY application_name
+	`
+	if err := db.Raw(replicaQuery).Scan(&status.Replicas).Error; err != nil {
+		return nil, fmt.Errorf("failed to query pg_stat_replication: %w", err)
+	}
+
+	slotQuery := `
+		SELECT
+			slot_name,
+			slot_type,
+			active,
+			pg_wal_lsn_diff(pg_current_wal_lsn(), restart_lsn)::float8 AS retained_bytes,
+			wal_status,
+			restart_lsn::text AS restart_lsn,
+			confirmed_flush_lsn::text AS confirmed_flush_lsn
+		FROM pg_replication_slots
+		ORDER BY slot_name
+	`
+	if err := db.Raw(slotQuery).Scan(&status.Slots).Error; err != nil {
+		return nil, fmt.Errorf("failed to query pg_replication_slots: %w", err)
+	}
+
+	return status, nil
+}
+
+// queryWALReceiver 查询备库WAL接收状态，未运行WAL接收进程时返回nil
+func queryWALReceiver(db *gorm.DB) (*WALReceiverStat, error) {
+	query := `
+		SELECT
+			r.status,
+			r.sender_host,
+			r.slot_name,
+			r.last_msg_receipt_time,
+			pg_wal_lsn_diff(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn())::float8 AS receive_replay_lag_bytes,
+			CASE
+				WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE EXTRACT(EPOCH FROM (now() - pg_last_xact_replay_timestamp()))
+			END::float8 AS replay_lag_seconds
+		FROM (SELECT 1) AS dummy
+		LEFT JOIN pg_stat_wal_receiver r ON true
+	`
+
+	var receivers []WALReceiverStat
+	if err := db.Raw(query).Scan(&receivers).Error; err != nil {
+		return nil, fmt.Errorf("failed to query pg_stat_wal_receiver: %w", err)
+	}
+	if len(receivers) == 0 {
+		return nil, nil
+	}
+	return &receivers[0], nil
+}
+
+// collectPostgreSQLReplication 采集所有PostgreSQL监控目标的复制和WAL指标
+func (dc *DataCollector) collectPostgreSQLReplication(ctx context.Context) error {
+	return dc.collectPostgreSQLTargets(ctx, "replication", dc.collectPostgreSQLReplicationFor)
+}
+
+// collectPostgreSQLReplicationFor 采集单个PostgreSQL目标的复制和WAL指标
+func (dc *DataCollector) collectPostgreSQLReplicationFor(ctx context.Context, dbName string, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+
+	status, err := queryReplicationStatus(db, dbName)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	newMetric := func(metricName string, value float64, unit string, tags map[string]interface{}) models.ResourceMetric {
+		metric := models.ResourceMetric{
+			DatabaseType: "postgresql",
+			DatabaseName: dbName,
+			MetricType:   "replication",
+			MetricName:   metricName,
+			MetricValue:  value,
+			Unit:         unit,
+			CollectedAt:  now,
+		}
+		if tags != nil {
+			metric.Tags = dc.formatTags(tags)
+		}
+		return metric
+	}
+
+	inRecovery := 0.0
+	if status.InRecovery {
+		inRecovery = 1
+	}
+	metrics := []models.ResourceMetric{
+		newMetric("in_recovery", inRecovery, "bool", nil),
+	}
+
+	// 配置了预期角色的目标检查实际角色（如发生主备切换）
+	if role := dc.postgreSQLTargetRole(dbName); role != "" {
+		mismatch := 0.0
+		if postgreSQLRoleMismatch(role, status.InRecovery) {
+			mismatch = 1
+			log.Printf("Warning: PostgreSQL target %s is configured as %s but pg_is_in_recovery() = %v", dbName, role, status.InRecovery)
+		}
+		metrics = append(metrics, newMetric("role_mismatch", mismatch, "bool", map[string]interface{}{"expected_role": role}))
+	}
+
+	if status.InRecovery {
+		receiverUp := 0.0
+		if status.Receiver != nil && status.Receiver.Status != nil && *status.Receiver.Status == "streaming" {
+			receiverUp = 1
+		}
+		metrics = append(metrics, newMetric("wal_receiver_streaming", receiverUp, "bool", nil))
+
+		if status.Receiver != nil {
+			if status.Receiver.ReplayLagSeconds != nil {
+				metrics = append(metrics, newMetric("replay_lag_seconds", *status.Receiver.ReplayLagSeconds, "seconds", nil))
+			}
+			if status.Receiver.ReceiveReplayBytes != nil {
+				metrics = append(metrics, newMetric("replay_lag_bytes", *status.Receiver.ReceiveReplayBytes, "bytes", nil))
+			}
+		}
+	} else {
+		metrics = append(metrics, newMetric("connected_replicas", float64(len(status.Replicas)), "count", nil))
+
+		for _, replica := range status.Replicas {
+			name := fmt.Sprintf("pid_%d", replica.PID)
+			if replica.ApplicationName != nil && *replica.ApplicationName != "" {
+				name = *replica.ApplicationName
+			}
+			tags := map[string]interface{}{"replica": name}
+			if replica.ClientAddr != nil {
+				tags["client_addr"] = *replica.ClientAddr
+			}
+			suffix := sanitizeMetricName(name)
+
+			if replica.ReplayLagBytes != nil {
+				metrics = append(metrics, newMetric("replay_lag_bytes_"+suffix, *replica.ReplayLagBytes, "bytes", tags))
+			}
+			if replica.FlushLagBytes != nil {
+				metrics = append(metrics, newMetric("flush_lag_bytes_"+suffix, *replica.FlushLagBytes, "bytes", tags))
+			}
+			if replica.ReplayLagSeconds != nil {
+				metrics = append(metrics, newMetric("replay_lag_seconds_"+suffix, *replica.ReplayLagSeconds, "seconds", tags))
+			}
+		}
+
+		for _, slot := range status.Slots {
+			if slot.RetainedBytes == nil {
+				continue
+			}
+			tags := map[string]interface{}{"slot_name": slot.SlotName, "slot_type": slot.SlotType, "active": slot.Active}
+			metrics = append(metrics, newMetric("slot_retained_bytes_"+sanitizeMetricName(slot.SlotName), *slot.RetainedBytes, "bytes", tags))
+		}
+
+		// WAL生成速率：与上一次采样的LSN差值
+		var lsnBytes float64
+		if err := db.Raw("SELECT pg_wal_lsn_diff(pg_current_wal_lsn(), '0/0')::float8").Scan(&lsnBytes).Error; err != nil {
+			return fmt.Errorf("failed to get WAL position: %w", err)
+		}
+		if rate, ok := dc.walRate.rate(dbName, lsnBytes, now); ok {
+			metrics = append(metrics, newMetric("wal_generation_bytes_per_second", rate, "bytes/s", nil))
+		}
+	}
+
+	return dc.dbManager.SaasMonitorDB.Create(metrics).Error
+}
+
+// postgreSQLTargetRole 获取额外监控目标配置的预期角色，未配置时返回空
+func (dc *DataCollector) postgreSQLTargetRole(dbName string) string {
+	for _, target := range dc.dbManager.Config.Databases.Additional {
+		if target.Name == dbName {
+			return target.Role
+		}
+	}
+	return ""
+}
+
+// postgreSQLRoleMismatch 预期角色与pg_is_in_recovery()是否不一致
+func postgreSQLRoleMismatch(role string, inRecovery bool) bool {
+	switch role {
+	case "primary":
+		return inRecovery
+	case "replica":
+		return !inRecovery
+	}
+	return false
+}
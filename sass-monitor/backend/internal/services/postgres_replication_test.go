@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/pkg/config"
+)
+
+func TestCollectPostgreSQLTargetsContinuesAfterFailure(t *testing.T) {
+	dc := &DataCollector{dbManager: &database.DatabaseManager{
+		Config:       &config.Config{},
+		LightAdminDB: &gorm.DB{},
+		PostgreSQL: map[string]*gorm.DB{
+			"replica_down": {},
+			"replica_up":   {},
+		},
+	}}
+
+	var visited []string
+	err := dc.collectPostgreSQLTargets(context.Background(), "replication", func(ctx context.Context, dbName string, db *gorm.DB) error {
+		visited = append(visited, dbName)
+		if dbName == "replica_down" {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	sort.Strings(visited)
+	if strings.Join(visited, ",") != "light_admin,replica_down,replica_up" {
+		t.Errorf("visited = %v, want every target", visited)
+	}
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 targets") {
+		t.Errorf("error = %v, want one failed target out of three", err)
+	}
+}
+
+func TestCollectPostgreSQLTargetsWithoutFailures(t *testing.T) {
+	dc := &DataCollector{dbManager: &database.DatabaseManager{
+		Config:       &config.Config{},
+		LightAdminDB: &gorm.DB{},
+	}}
+	err := dc.collectPostgreSQLTargets(context.Background(), "activity", func(ctx context.Context, dbName string, db *gorm.DB) error {
+		return nil
+	})
+	if err != nil {
+		t.Errorf("error = %v, want nil", err)
+	}
+}
+
+func TestPostgreSQLRoleMismatch(t *testing.T) {
+	tests := []struct {
+		role       string
+		inRecovery bool
+		want       bool
+	}{
+		{role: "primary", inRecovery: false, want: false},
+		{role: "primary", inRecovery: true, want: true},
+		{role: "replica", inRecovery: true, want: false},
+		{role: "replica", inRecovery: false, want: true},
+		{role: "", inRecovery: true, want: false},
+	}
+	for _, tt := range tests {
+		if got := postgreSQLRoleMismatch(tt.role, tt.inRecovery); got != tt.want {
+			t.Errorf("postgreSQLRoleMismatch(%q, %v) = %v, want %v", tt.role, tt.inRecovery, got, tt.want)
+		}
+	}
+}
+
+func TestPostgreSQLTargetRole(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Databases.Additional = []config.DatabaseConnectionConfig{
+		{Name: "light_admin_replica", Role: "replica"},
+		{Name: "reporting"},
+	}
+	dc := &DataCollector{dbManager: &database.DatabaseManager{Config: cfg}}
+
+	tests := map[string]string{
+		"light_admin_replica": "replica",
+		"reporting":           "",
+		"light_admin":         "",
+	}
+	for dbName, want := range tests {
+		if got := dc.postgreSQLTargetRole(dbName); got != want {
+			t.Errorf("postgreSQLTargetRole(%q) = %q, want %q", dbName, got, want)
+		}
+	}
+}
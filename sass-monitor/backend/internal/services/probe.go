@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+	"sass-monitor/pkg/config"
+)
+
+// probeBodyLimit 内容匹配时最多读取的响应体大小
+const probeBodyLimit = 1 << 20
+
+// ProbeResult 单次拨测结果
+type ProbeResult struct {
+	Name           string     `json:"name"`
+	Type           string     `json:"type"`
+	Target         string     `json:"target"`
+	Status         string     `json:"status"` // healthy, warning, unhealthy
+	Success        bool       `json:"success"`
+	LatencyMs      float64    `json:"latency_ms"`
+	StatusCode     int        `json:"status_code,omitempty"`
+	CertExpiryDays *float64   `json:"cert_expiry_days,omitempty"`
+	CertNotAfter   *time.Time `json:"cert_not_after,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CheckedAt      time.Time  `json:"checked_at"`
+}
+
+// ProbeStatus 拨测目标的最新状态（来自system_health）
+type ProbeStatus struct {
+	Name          string    `json:"name"`
+	Type          string    `json:"type"`
+	Target        string    `json:"target"`
+	Status        string    `json:"status"`
+	ResponseTime  *int      `json:"response_time_ms"`
+	ErrorMessage  *string   `json:"error_message"`
+	LastCheckedAt time.Time `json:"last_checked_at"`
+}
+
+// ProbeService HTTP(S)、TCP和TLS证书拨测
+type ProbeService struct {
+	dbManager     *database.DatabaseManager
+	dataCollector *DataCollector
+}
+
+// NewProbeService dataCollector与调度器共用，结果写入使用同一采集器
+func NewProbeService(dbManager *database.DatabaseManager, dataCollector *DataCollector) *ProbeService {
+	return &ProbeService{
+		dbManager:     dbManager,
+		dataCollector: dataCollector,
+	}
+}
+
+// probeComponentName 拨测目标在system_health中的组件名称
+func probeComponentName(name string) string {
+	return "probe_" + name
+}
+
+// RunAll 并发执行所有配置的拨测，并写入指标和system_health
+func (ps *ProbeService) RunAll(ctx context.Context) ([]ProbeResult, error) {
+	probeConfig := ps.dbManager.Config.Monitoring.Probes
+	timeout := time.Duration(probeConfig.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	results := make([]ProbeResult, len(probeConfig.Targets))
+	var wg sync.WaitGroup
+	for i, target := range probeConfig.Targets {
+		wg.Add(1)
+		go func(i int, target config.ProbeTargetConfig) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			results[i] = runProbe(probeCtx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	if err := ps.saveResults(probeConfig.Targets, results); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// GetProbeStatus 获取所有拨测目标的最新状态
+func (ps *ProbeService) GetProbeStatus(ctx context.Context) ([]ProbeStatus, error) {
+	var records []models.SystemHealth
+	if err := ps.dbManager.SaasMonitorDB.WithContext(ctx).
+		Where("component_type = ?", "probe").
+		Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to query probe health: %w", err)
+	}
+	byComponent := make(map[string]models.SystemHealth, len(records))
+	for _, record := range records {
+		byComponent[record.ComponentName] = record
+	}
+
+	statuses := []ProbeStatus{}
+	for _, target := range ps.dbManager.Config.Monitoring.Probes.Targets {
+		status := ProbeStatus{
+			Name:   target.Name,
+			Type:   probeType(target),
+			Target: probeTarget(target),
+			Status: "unknown",
+		}
+		if record, exists := byComponent[probeComponentName(target.Name)]; exists {
+			status.Status = record.Status
+			status.ResponseTime = record.ResponseTime
+			status.ErrorMessage = record.ErrorMessage
+			status.LastCheckedAt = record.LastCheckedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// saveResults 将拨测结果写入资源指标和system_health
+func (ps *ProbeService) saveResults(targets []config.ProbeTargetConfig, results []ProbeResult) error {
+	dc := ps.dataCollector
+	var metrics []models.ResourceMetric
+
+	for i, result := range results {
+		target := targets[i]
+		tags := map[string]interface{}{"type": result.Type, "target": result.Target}
+		if target.ProjectID != "" {
+			tags["project_id"] = target.ProjectID
+		}
+		if target.ApplicationID != "" {
+			tags["application_id"] = target.ApplicationID
+		}
+		newMetric := func(metricName string, value float64, unit string) models.ResourceMetric {
+			return models.ResourceMetric{
+				DatabaseType: "probe",
+				DatabaseName: result.Name,
+				MetricType:   "probe",
+				MetricName:   metricName,
+				MetricValue:  value,
+				Unit:         unit,
+				Tags:         dc.formatTags(tags),
+				CollectedAt:  result.CheckedAt,
+			}
+		}
+
+		success := 0.0
+		if result.Success {
+			success = 1
+		}
+		metrics = append(metrics,
+			newMetric("probe_success", success, "bool"),
+			newMetric("probe_latency_ms", result.LatencyMs, "ms"),
+		)
+		if result.StatusCode > 0 {
+			metrics = append(metrics, newMetric("http_status_code", float64(result.StatusCode), "code"))
+		}
+		if result.CertExpiryDays != nil {
+			metrics = append(metrics, newMetric("tls_cert_expiry_days", *result.CertExpiryDays, "days"))
+		}
+
+		dc.recordSystemHealth(probeComponentName(result.Name), "probe", result.Status, int(result.LatencyMs), result.Error)
+	}
+
+	if len(metrics) > 0 {
+		return ps.dbManager.SaasMonitorDB.CreateInBatches(metrics, 100).Error
+	}
+	return nil
+}
+
+// runProbe 执行单个拨测并根据证书有效期确定状态
+func runProbe(ctx context.Context, target config.ProbeTargetConfig) ProbeResult {
+	result := ProbeResult{
+		Name:      target.Name,
+		Type:      probeType(target),
+		Target:    probeTarget(target),
+		CheckedAt: time.Now(),
+	}
+
+	var err error
+	switch result.Type {
+	case "http":
+		err = runHTTPProbe(ctx, target, &result)
+	case "tcp":
+		err = runTCPProbe(ctx, target, &result)
+	default:
+		err = fmt.Errorf("unsupported probe type '%s'", target.Type)
+	}
+
+	result.Success = err == nil
+	switch {
+	case err != nil:
+		result.Status = "unhealthy"
+		result.Error = err.Error()
+	case result.CertExpiryDays != nil && target.CertExpiryWarnDays > 0 && *result.CertExpiryDays < float64(target.CertExpiryWarnDays):
+		result.Status = "warning"
+		result.Error = fmt.Sprintf("certificate expires in %.1f days", *result.CertExpiryDays)
+	default:
+		result.Status = "healthy"
+	}
+	return result
+}
+
+// runHTTPProbe 检查HTTP状态码、响应延迟和响应内容
+func runHTTPProbe(ctx context.Context, target config.ProbeTargetConfig, result *ProbeResult) error {
+	method := strings.ToUpper(target.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.URL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	for key, value := range target.Headers {
+		req.Header.Set(key, value)
+	}
+
+	// 不跟随重定向，按目标本身的响应判断状态码，避免3xx被最终页面的200掩盖
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: target.SkipTLSVerify},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	if target.BodyContains != "" {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, probeBodyLimit))
+	}
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	result.StatusCode = resp.StatusCode
+	if resp.TLS != nil {
+		setCertExpiry(result, resp.TLS.PeerCertificates)
+	}
+
+	if !probeStatusExpected(target.ExpectedStatus, resp.StatusCode) {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if target.BodyContains != "" && !strings.Contains(string(body), target.BodyContains) {
+		return fmt.Errorf("response body does not contain '%s'", target.BodyContains)
+	}
+	return nil
+}
+
+// runTCPProbe 检查TCP端口连通性，启用TLS时同时获取证书有效期
+func runTCPProbe(ctx context.Context, target config.ProbeTargetConfig, result *ProbeResult) error {
+	dialer := &net.Dialer{}
+	start := time.Now()
+
+	if !target.TLS {
+		conn, err := dialer.DialContext(ctx, "tcp", target.Address)
+		result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	host, _, err := net.SplitHostPort(target.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	tlsDialer := &tls.Dialer{
+		NetDialer: dialer,
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: target.SkipTLSVerify},
+	}
+	conn, err := tlsDialer.DialContext(ctx, "tcp", target.Address)
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	setCertExpiry(result, conn.(*tls.Conn).ConnectionState().PeerCertificates)
+	return nil
+}
+
+// setCertExpiry 根据叶子证书计算剩余有效天数
+func setCertExpiry(result *ProbeResult, certs []*x509.Certificate) {
+	if len(certs) == 0 {
+		return
+	}
+	notAfter := certs[0].NotAfter
+	days := time.Until(notAfter).Hours() / 24
+	result.CertNotAfter = &notAfter
+	result.CertExpiryDays = &days
+}
+
+// probeStatusExpected 检查状态码是否符合预期，未配置时接受2xx和3xx
+func probeStatusExpected(expected []int, statusCode int) bool {
+	if len(expected) == 0 {
+		return statusCode >= 200 && statusCode < 400
+	}
+	for _, code := range expected {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// probeType 获取拨测类型，未配置时根据url/address推断
+func probeType(target config.ProbeTargetConfig) string {
+	if target.Type != "" {
+		return strings.ToLower(target.Type)
+	}
+	if target.URL != "" {
+		return "http"
+	}
+	return "tcp"
+}
+
+// probeTarget 获取拨测目标地址
+func probeTarget(target config.ProbeTargetConfig) string {
+	if probeType(target) == "http" {
+		return target.URL
+	}
+	return target.Address
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sass-monitor/pkg/config"
+)
+
+func newProbeTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("service is up"))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/login-redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("slow"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunProbeHTTPStatus(t *testing.T) {
+	server := newProbeTestServer(t)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus []int
+		wantSuccess    bool
+		wantStatusCode int
+	}{
+		{name: "default accepts 2xx", path: "/ok", wantSuccess: true, wantStatusCode: http.StatusOK},
+		{name: "default rejects 404", path: "/missing", wantSuccess: false, wantStatusCode: http.StatusNotFound},
+		{name: "expected 404", path: "/missing", expectedStatus: []int{http.StatusNotFound}, wantSuccess: true, wantStatusCode: http.StatusNotFound},
+		{name: "expected 201 only", path: "/ok", expectedStatus: []int{http.StatusCreated}, wantSuccess: false, wantStatusCode: http.StatusOK},
+		{name: "redirect is not followed", path: "/login-redirect", wantSuccess: true, wantStatusCode: http.StatusFound},
+		{name: "expected 200 rejects redirect", path: "/login-redirect", expectedStatus: []int{http.StatusOK}, wantSuccess: false, wantStatusCode: http.StatusFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runProbe(context.Background(), config.ProbeTargetConfig{
+				Name:           "api",
+				URL:            server.URL + tt.path,
+				ExpectedStatus: tt.expectedStatus,
+			})
+			if result.Type != "http" {
+				t.Errorf("type = %q, want http", result.Type)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Errorf("success = %v, want %v (error: %s)", result.Success, tt.wantSuccess, result.Error)
+			}
+			if result.StatusCode != tt.wantStatusCode {
+				t.Errorf("status code = %d, want %d", result.StatusCode, tt.wantStatusCode)
+			}
+			wantStatus := "healthy"
+			if !tt.wantSuccess {
+				wantStatus = "unhealthy"
+			}
+			if result.Status != wantStatus {
+				t.Errorf("status = %q, want %q", result.Status, wantStatus)
+			}
+		})
+	}
+}
+
+func TestRunProbeHTTPBodyMatch(t *testing.T) {
+	server := newProbeTestServer(t)
+
+	tests := []struct {
+		name         string
+		bodyContains string
+		wantSuccess  bool
+	}{
+		{name: "matching body", bodyContains: "is up", wantSuccess: true},
+		{name: "missing text", bodyContains: "maintenance", wantSuccess: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runProbe(context.Background(), config.ProbeTargetConfig{
+				Name:         "api",
+				URL:          server.URL + "/ok",
+				BodyContains: tt.bodyContains,
+			})
+			if result.Success != tt.wantSuccess {
+				t.Errorf("success = %v, want %v (error: %s)", result.Success, tt.wantSuccess, result.Error)
+			}
+			if !tt.wantSuccess && !strings.Contains(result.Error, tt.bodyContains) {
+				t.Errorf("error %q does not mention %q", result.Error, tt.bodyContains)
+			}
+		})
+	}
+}
+
+func TestRunProbeHTTPLatency(t *testing.T) {
+	server := newProbeTestServer(t)
+
+	result := runProbe(context.Background(), config.ProbeTargetConfig{
+		Name:         "slow",
+		URL:          server.URL + "/slow",
+		BodyContains: "slow",
+	})
+	if !result.Success {
+		t.Fatalf("probe failed: %s", result.Error)
+	}
+	if result.LatencyMs < 50 {
+		t.Errorf("latency = %.2fms, want at least 50ms", result.LatencyMs)
+	}
+}
+
+func TestRunProbeTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+
+	result := runProbe(context.Background(), config.ProbeTargetConfig{Name: "tcp", Address: address})
+	if !result.Success || result.Status != "healthy" {
+		t.Errorf("open port: success = %v, status = %q, error = %s", result.Success, result.Status, result.Error)
+	}
+
+	listener.Close()
+	result = runProbe(context.Background(), config.ProbeTargetConfig{Name: "tcp", Address: address})
+	if result.Success || result.Status != "unhealthy" || result.Error == "" {
+		t.Errorf("closed port: success = %v, status = %q, error = %q", result.Success, result.Status, result.Error)
+	}
+}
+
+func TestRunProbeTLSExpiry(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	defer server.Close()
+	notAfter := server.Certificate().NotAfter
+	daysLeft := time.Until(notAfter).Hours() / 24
+
+	tests := []struct {
+		name       string
+		target     config.ProbeTargetConfig
+		wantStatus string
+	}{
+		{
+			name:       "untrusted certificate",
+			target:     config.ProbeTargetConfig{URL: server.URL},
+			wantStatus: "unhealthy",
+		},
+		{
+			name:       "https within warning window",
+			target:     config.ProbeTargetConfig{URL: server.URL, SkipTLSVerify: true, CertExpiryWarnDays: int(daysLeft) + 30},
+			wantStatus: "warning",
+		},
+		{
+			name:       "https outside warning window",
+			target:     config.ProbeTargetConfig{URL: server.URL, SkipTLSVerify: true, CertExpiryWarnDays: 30},
+			wantStatus: "healthy",
+		},
+		{
+			name:       "tcp tls handshake within warning window",
+			target:     config.ProbeTargetConfig{Type: "tcp", Address: server.Listener.Addr().String(), TLS: true, SkipTLSVerify: true, CertExpiryWarnDays: int(daysLeft) + 30},
+			wantStatus: "warning",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.target.Name = "tls"
+			result := runProbe(context.Background(), tt.target)
+			if result.Status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q (error: %s)", result.Status, tt.wantStatus, result.Error)
+			}
+			if tt.wantStatus == "unhealthy" {
+				return
+			}
+			if result.CertNotAfter == nil || !result.CertNotAfter.Equal(notAfter) {
+				t.Errorf("cert not after = %v, want %v", result.CertNotAfter, notAfter)
+			}
+			if result.CertExpiryDays == nil || *result.CertExpiryDays < daysLeft-1 || *result.CertExpiryDays > daysLeft+1 {
+				t.Errorf("cert expiry days = %v, want about %.1f", result.CertExpiryDays, daysLeft)
+			}
+		})
+	}
+}
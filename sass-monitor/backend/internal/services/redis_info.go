@@ -0,0 +1,171 @@
+package services
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RedisInfo 解析后的Redis INFO输出，按section分组
+type RedisInfo struct {
+	Sections map[string]map[string]string `json:"sections"`
+}
+
+// RedisKeyspace 单个db的键空间统计
+type RedisKeyspace struct {
+	DB      string `json:"db"`
+	Keys    int64  `json:"keys"`
+	Expires int64  `json:"expires"`
+	AvgTTL  int64  `json:"avg_ttl"`
+}
+
+// RedisReplica 主节点视角的从节点信息（INFO replication中的slaveN）
+type RedisReplica struct {
+	Name   string `json:"name"`
+	IP     string `json:"ip"`
+	Port   string `json:"port"`
+	State  string `json:"state"`
+	Offset int64  `json:"offset"`
+	Lag    int64  `json:"lag"`
+}
+
+// parseRedisInfo 解析INFO命令输出（# Section 标题 + key:value 行）
+func parseRedisInfo(raw string) *RedisInfo {
+	info := &RedisInfo{Sections: make(map[string]map[string]string)}
+	section := "default"
+
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			section = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		if info.Sections[section] == nil {
+			info.Sections[section] = make(map[string]string)
+		}
+		info.Sections[section][key] = value
+	}
+	return info
+}
+
+// Get 获取指定字段（不区分section）
+func (i *RedisInfo) Get(key string) (string, bool) {
+	for _, fields := range i.Sections {
+		if value, exists := fields[key]; exists {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// String 获取字符串字段，不存在时返回空字符串
+func (i *RedisInfo) String(key string) string {
+	value, _ := i.Get(key)
+	return value
+}
+
+// Int 获取整数字段，不存在或无法解析时返回0
+func (i *RedisInfo) Int(key string) int64 {
+	value, exists := i.Get(key)
+	if !exists {
+		return 0
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return int64(i.Float(key))
+	}
+	return parsed
+}
+
+// Float 获取浮点字段，不存在或无法解析时返回0
+func (i *RedisInfo) Float(key string) float64 {
+	value, exists := i.Get(key)
+	if !exists {
+		return 0
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return parsed
+}
+
+// Keyspace 解析keyspace section（db0:keys=1,expires=0,avg_ttl=0）
+func (i *RedisInfo) Keyspace() []RedisKeyspace {
+	keyspaces := []RedisKeyspace{}
+	for db, value := range i.Sections["keyspace"] {
+		fields := parseRedisInfoFields(value)
+		keyspaces = append(keyspaces, RedisKeyspace{
+			DB:      db,
+			Keys:    parseInt64(fields["keys"]),
+			Expires: parseInt64(fields["expires"]),
+			AvgTTL:  parseInt64(fields["avg_ttl"]),
+		})
+	}
+	sort.Slice(keyspaces, func(a, b int) bool { return keyspaces[a].DB < keyspaces[b].DB })
+	return keyspaces
+}
+
+// Replicas 解析replication section中的从节点（slave0:ip=...,port=...,state=online,offset=...,lag=0）
+func (i *RedisInfo) Replicas() []RedisReplica {
+	replicas := []RedisReplica{}
+	for key, value := range i.Sections["replication"] {
+		if !strings.HasPrefix(key, "slave") || !strings.Contains(value, "ip=") {
+			continue
+		}
+		fields := parseRedisInfoFields(value)
+		replicas = append(replicas, RedisReplica{
+			Name:   key,
+			IP:     fields["ip"],
+			Port:   fields["port"],
+			State:  fields["state"],
+			Offset: parseInt64(fields["offset"]),
+			Lag:    parseInt64(fields["lag"]),
+		})
+	}
+	sort.Slice(replicas, func(a, b int) bool { return replicas[a].Name < replicas[b].Name })
+	return replicas
+}
+
+// MaxMemoryPercent 内存使用占maxmemory的百分比，未设置maxmemory时返回false
+func (i *RedisInfo) MaxMemoryPercent() (float64, bool) {
+	maxMemory := i.Int("maxmemory")
+	if maxMemory <= 0 {
+		return 0, false
+	}
+	return float64(i.Int("used_memory")) / float64(maxMemory) * 100, true
+}
+
+// HitRatePercent 键空间命中率
+func (i *RedisInfo) HitRatePercent() float64 {
+	hits := i.Int("keyspace_hits")
+	total := hits + i.Int("keyspace_misses")
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total) * 100
+}
+
+// parseRedisInfoFields 解析逗号分隔的key=value字段
+func parseRedisInfoFields(value string) map[string]string {
+	fields := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if key, val, found := strings.Cut(pair, "="); found {
+			fields[key] = val
+		}
+	}
+	return fields
+}
+
+func parseInt64(value string) int64 {
+	parsed, _ := strconv.ParseInt(value, 10, 64)
+	return parsed
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+const redisInfoSample = "# Server\r\n" +
+	"redis_version:7.2.4\r\n" +
+	"uptime_in_seconds:86400\r\n" +
+	"\r\n" +
+	"# Memory\r\n" +
+	"used_memory:52428800\r\n" +
+	"maxmemory:104857600\r\n" +
+	"mem_fragmentation_ratio:1.25\r\n" +
+	"\r\n" +
+	"# Stats\r\n" +
+	"keyspace_hits:900\r\n" +
+	"keyspace_misses:100\r\n" +
+	"instantaneous_ops_per_sec:1.5e3\r\n" +
+	"\r\n" +
+	"# Replication\r\n" +
+	"role:master\r\n" +
+	"connected_slaves:2\r\n" +
+	"slave1:ip=10.0.0.3,port=6379,state=wait_bgsave,offset=0,lag=5\r\n" +
+	"slave0:ip=10.0.0.2,port=6379,state=online,offset=123456,lag=0\r\n" +
+	"slave_read_only:1\r\n" +
+	"\r\n" +
+	"# Keyspace\r\n" +
+	"db1:keys=5,expires=0,avg_ttl=0\r\n" +
+	"db0:keys=1200,expires=300,avg_ttl=3600000\r\n"
+
+func TestParseRedisInfo(t *testing.T) {
+	info := parseRedisInfo(redisInfoSample)
+
+	wantSections := []string{"server", "memory", "stats", "replication", "keyspace"}
+	for _, section := range wantSections {
+		if _, ok := info.Sections[section]; !ok {
+			t.Errorf("missing section %q", section)
+		}
+	}
+	if len(info.Sections) != len(wantSections) {
+		t.Errorf("got %d sections, want %d", len(info.Sections), len(wantSections))
+	}
+
+	tests := []struct {
+		key       string
+		wantStr   string
+		wantInt   int64
+		wantFloat float64
+	}{
+		{key: "redis_version", wantStr: "7.2.4", wantInt: 0, wantFloat: 0},
+		{key: "uptime_in_seconds", wantStr: "86400", wantInt: 86400, wantFloat: 86400},
+		{key: "mem_fragmentation_ratio", wantStr: "1.25", wantInt: 1, wantFloat: 1.25},
+		{key: "instantaneous_ops_per_sec", wantStr: "1.5e3", wantInt: 1500, wantFloat: 1500},
+		{key: "missing_field", wantStr: "", wantInt: 0, wantFloat: 0},
+	}
+	for _, tt := range tests {
+		if got := info.String(tt.key); got != tt.wantStr {
+			t.Errorf("String(%q) = %q, want %q", tt.key, got, tt.wantStr)
+		}
+		if got := info.Int(tt.key); got != tt.wantInt {
+			t.Errorf("Int(%q) = %d, want %d", tt.key, got, tt.wantInt)
+		}
+		if got := info.Float(tt.key); got != tt.wantFloat {
+			t.Errorf("Float(%q) = %v, want %v", tt.key, got, tt.wantFloat)
+		}
+	}
+}
+
+func TestParseRedisInfoWithoutSectionHeader(t *testing.T) {
+	info := parseRedisInfo("role:slave\nmaster_link_status:up\nnot a field\n")
+	if got := info.Sections["default"]["role"]; got != "slave" {
+		t.Errorf("default section role = %q, want slave", got)
+	}
+	if len(info.Sections["default"]) != 2 {
+		t.Errorf("default section = %v, want 2 fields", info.Sections["default"])
+	}
+}
+
+func TestRedisInfoKeyspace(t *testing.T) {
+	got := parseRedisInfo(redisInfoSample).Keyspace()
+	want := []RedisKeyspace{
+		{DB: "db0", Keys: 1200, Expires: 300, AvgTTL: 3600000},
+		{DB: "db1", Keys: 5, Expires: 0, AvgTTL: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Keyspace() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRedisInfoReplicas(t *testing.T) {
+	got := parseRedisInfo(redisInfoSample).Replicas()
+	want := []RedisReplica{
+		{Name: "slave0", IP: "10.0.0.2", Port: "6379", State: "online", Offset: 123456, Lag: 0},
+		{Name: "slave1", IP: "10.0.0.3", Port: "6379", State: "wait_bgsave", Offset: 0, Lag: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Replicas() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRedisInfoDerivedRates(t *testing.T) {
+	tests := []struct {
+		name           string
+		raw            string
+		wantMaxMemory  float64
+		wantMaxMemOK   bool
+		wantHitPercent float64
+	}{
+		{name: "sample", raw: redisInfoSample, wantMaxMemory: 50, wantMaxMemOK: true, wantHitPercent: 90},
+		{name: "no maxmemory and no lookups", raw: "# Memory\nused_memory:100\nmaxmemory:0\n", wantMaxMemOK: false, wantHitPercent: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := parseRedisInfo(tt.raw)
+			percent, ok := info.MaxMemoryPercent()
+			if ok != tt.wantMaxMemOK || percent != tt.wantMaxMemory {
+				t.Errorf("MaxMemoryPercent() = %v, %v; want %v, %v", percent, ok, tt.wantMaxMemory, tt.wantMaxMemOK)
+			}
+			if got := info.HitRatePercent(); got != tt.wantHitPercent {
+				t.Errorf("HitRatePercent() = %v, want %v", got, tt.wantHitPercent)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm/clause"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// redisSlowLogFetchSize 每次采集读取的慢查询条数
+const redisSlowLogFetchSize = 128
+
+// RedisMonitorService Redis INFO和慢查询查询服务
+type RedisMonitorService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewRedisMonitorService(dbManager *database.DatabaseManager) *RedisMonitorService {
+	return &RedisMonitorService{
+		dbManager: dbManager,
+	}
+}
+
+// RedisInstanceReport Redis实例（含所有节点）状态报告
+type RedisInstanceReport struct {
+	Instance string            `json:"instance"`
+	Mode     string            `json:"mode"` // standalone, cluster, sentinel
+	Nodes    []RedisInfoReport `json:"nodes"`
+}
+
+// RedisInfoReport Redis节点状态报告
+type RedisInfoReport struct {
+	Node             string                       `json:"node"`
+	Role             string                       `json:"role"` // master, replica, sentinel
+	Error            string                       `json:"error,omitempty"`
+	Version          string                       `json:"version"`
+	UptimeSeconds    int64                        `json:"uptime_seconds"`
+	UsedMemory       int64                        `json:"used_memory"`
+	MaxMemory        int64                        `json:"maxmemory"`
+	MaxMemoryPercent *float64                     `json:"maxmemory_percent"` // 未设置maxmemory时为null
+	Fragmentation    float64                      `json:"mem_fragmentation_ratio"`
+	OpsPerSec        int64                        `json:"instantaneous_ops_per_sec"`
+	HitRatePercent   float64                      `json:"hit_rate_percent"`
+	Keyspace         []RedisKeyspace              `json:"keyspace"`
+	Replicas         []RedisReplica               `json:"replicas"`
+	Sections         map[string]map[string]string `json:"sections"`
+}
+
+// SlowLogFilter 慢查询过滤条件
+type SlowLogFilter struct {
+	Node          string
+	Command       string
+	MinDurationMs float64
+	StartTime     *time.Time
+	EndTime       *time.Time
+	Page          int
+	PageSize      int
+}
+
+// GetInfo 获取Redis实例所有节点的完整INFO信息
+func (s *RedisMonitorService) GetInfo(ctx context.Context, name string) (*RedisInstanceReport, error) {
+	instance, err := s.dbManager.GetRedisInstance(name)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := instance.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RedisInstanceReport{
+		Instance: name,
+		Mode:     instance.Mode(),
+		Nodes:    make([]RedisInfoReport, 0, len(nodes)),
+	}
+	for _, node := range nodes {
+		info, err := queryRedisInfo(ctx, node.Client)
+		if err != nil {
+			report.Nodes = append(report.Nodes, RedisInfoReport{Node: node.Addr, Role: node.Role, Error: err.Error()})
+			continue
+		}
+		report.Nodes = append(report.Nodes, buildRedisInfoReport(node, info))
+	}
+	return report, nil
+}
+
+// GetEvents 获取Redis实例的角色变化和故障转移事件
+func (s *RedisMonitorService) GetEvents(ctx context.Context, name string, limit int) ([]models.MonitoringLog, error) {
+	if _, err := s.dbManager.GetRedisInstance(name); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var events []models.MonitoringLog
+	err := s.dbManager.SaasMonitorDB.WithContext(ctx).
+		Where("source = ? AND component = ?", redisTopologySource, redisComponentName(name)).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// buildRedisInfoReport 根据INFO构建节点报告
+func buildRedisInfoReport(node database.RedisNode, info *RedisInfo) RedisInfoReport {
+	report := RedisInfoReport{
+		Node:           node.Addr,
+		Role:           redisNodeRole(node, info),
+		Version:        info.String("redis_version"),
+		UptimeSeconds:  info.Int("uptime_in_seconds"),
+		UsedMemory:     info.Int("used_memory"),
+		MaxMemory:      info.Int("maxmemory"),
+		Fragmentation:  info.Float("mem_fragmentation_ratio"),
+		OpsPerSec:      info.Int("instantaneous_ops_per_sec"),
+		HitRatePercent: info.HitRatePercent(),
+		Keyspace:       info.Keyspace(),
+		Replicas:       info.Replicas(),
+		Sections:       info.Sections,
+	}
+	if percent, ok := info.MaxMemoryPercent(); ok {
+		report.MaxMemoryPercent = &percent
+	}
+	return report
+}
+
+// GetSlowLogs 分页查询已采集的慢查询日志
+func (s *RedisMonitorService) GetSlowLogs(ctx context.Context, name string, filter SlowLogFilter) ([]models.RedisSlowLog, int64, error) {
+	if _, err := s.dbManager.GetRedisInstance(name); err != nil {
+		return nil, 0, err
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 50
+	}
+
+	query := s.dbManager.SaasMonitorDB.WithContext(ctx).Model(&models.RedisSlowLog{}).Where("instance_name = ?", name)
+	if filter.Node != "" {
+		query = query.Where("node_addr = ?", filter.Node)
+	}
+	if filter.Command != "" {
+		query = query.Where("command = ?", strings.ToUpper(filter.Command))
+	}
+	if filter.MinDurationMs > 0 {
+		query = query.Where("duration_micros >= ?", int64(filter.MinDurationMs*1000))
+	}
+	if filter.StartTime != nil {
+		query = query.Where("executed_at >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("executed_at <= ?", *filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []models.RedisSlowLog
+	err := query.Order("executed_at DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&logs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// queryRedisInfo 读取并解析INFO所有section
+func queryRedisInfo(ctx context.Context, client *redis.Client) (*RedisInfo, error) {
+	raw, err := client.Info(ctx, "all").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Redis INFO: %w", err)
+	}
+	return parseRedisInfo(raw), nil
+}
+
+// collectRedisInstance 采集Redis实例所有节点的指标，并记录角色变化
+func (dc *DataCollector) collectRedisInstance(ctx context.Context, instance *database.RedisInstance) error {
+	nodes, err := instance.Nodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, node := range nodes {
+		if err := dc.collectRedisNode(ctx, instance, node); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", node.Addr, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// collectRedisNode 采集单个Redis节点的INFO指标和慢查询日志
+// standalone模式指标的database_name为实例名，其他模式为"实例名@节点地址"
+func (dc *DataCollector) collectRedisNode(ctx context.Context, instance *database.RedisInstance, node database.RedisNode) error {
+	info, err := queryRedisInfo(ctx, node.Client)
+	if err != nil {
+		return err
+	}
+
+	role := redisNodeRole(node, info)
+	if previous, changed := dc.redisTopology.observe(instance.Name, node.Addr, role); changed {
+		dc.logRedisRoleChange(instance, node.Addr, previous, role)
+	}
+
+	name := instance.Name
+	if instance.Mode() != "standalone" {
+		name = fmt.Sprintf("%s@%s", instance.Name, node.Addr)
+	}
+
+	// 哨兵节点只记录监控的主节点数量
+	if role == database.RedisRoleSentinel {
+		return dc.dbManager.SaasMonitorDB.Create(&models.ResourceMetric{
+			DatabaseType: "redis",
+			DatabaseName: name,
+			MetricType:   "sentinel",
+			MetricName:   "sentinel_masters",
+			MetricValue:  float64(info.Int("sentinel_masters")),
+			Unit:         "count",
+			CollectedAt:  time.Now(),
+		}).Error
+	}
+
+	now := time.Now()
+	newMetric := func(metricType, metricName string, value float64, unit string, tags map[string]interface{}) models.ResourceMetric {
+		metric := models.ResourceMetric{
+			DatabaseType: "redis",
+			DatabaseName: name,
+			MetricType:   metricType,
+			MetricName:   metricName,
+			MetricValue:  value,
+			Unit:         unit,
+			CollectedAt:  now,
+		}
+		if tags != nil {
+			metric.Tags = dc.formatTags(tags)
+		}
+		return metric
+	}
+	boolValue := func(ok bool) float64 {
+		if ok {
+			return 1
+		}
+		return 0
+	}
+
+	metrics := []models.ResourceMetric{
+		// 内存
+		newMetric("memory", "used_memory_bytes", float64(info.Int("used_memory")), "bytes", nil),
+		newMetric("memory", "used_memory_rss_bytes", float64(info.Int("used_memory_rss")), "bytes", nil),
+		newMetric("memory", "maxmemory_bytes", float64(info.Int("maxmemory")), "bytes", nil),
+		newMetric("memory", "mem_fragmentation_ratio", info.Float("mem_fragmentation_ratio"), "ratio", nil),
+
+		// 连接
+		newMetric("connection", "connected_clients", float64(info.Int("connected_clients")), "count", nil),
+		newMetric("connection", "blocked_clients", float64(info.Int("blocked_clients")), "count", nil),
+		newMetric("connection", "rejected_connections", float64(info.Int("rejected_connections")), "count", nil),
+
+		// 性能
+		newMetric("performance", "hit_rate_percent", info.HitRatePercent(), "percent", nil),
+		newMetric("performance", "instantaneous_ops_per_sec", float64(info.Int("instantaneous_ops_per_sec")), "ops/s", nil),
+		newMetric("performance", "evicted_keys_total", float64(info.Int("evicted_keys")), "count", nil),
+		newMetric("performance", "expired_keys_total", float64(info.Int("expired_keys")), "count", nil),
+
+		// 持久化
+		newMetric("persistence", "loading", float64(info.Int("loading")), "bool", nil),
+		newMetric("persistence", "rdb_changes_since_last_save", float64(info.Int("rdb_changes_since_last_save")), "count", nil),
+		newMetric("persistence", "rdb_last_bgsave_ok", boolValue(info.String("rdb_last_bgsave_status") == "ok"), "bool", nil),
+		newMetric("persistence", "aof_enabled", float64(info.Int("aof_enabled")), "bool", nil),
+
+		// 复制
+		newMetric("replication", "is_master", boolValue(info.String("role") == "master"), "bool", nil),
+		newMetric("replication", "connected_slaves", float64(info.Int("connected_slaves")), "count", nil),
+	}
+
+	if percent, ok := info.MaxMemoryPercent(); ok {
+		metrics = append(metrics, newMetric("memory", "maxmemory_usage_percent", percent, "percent", nil))
+	}
+
+	if lastSave := info.Int("rdb_last_save_time"); lastSave > 0 {
+		metrics = append(metrics, newMetric("persistence", "rdb_last_save_age_seconds", float64(now.Unix()-lastSave), "seconds", nil))
+	}
+
+	if info.Int("aof_enabled") == 1 {
+		metrics = append(metrics, newMetric("persistence", "aof_last_write_ok", boolValue(info.String("aof_last_write_status") == "ok"), "bool", nil))
+	}
+
+	if info.String("role") == "slave" {
+		metrics = append(metrics, newMetric("replication", "master_link_up", boolValue(info.String("master_link_status") == "up"), "bool", nil))
+		if lastIO := info.Int("master_last_io_seconds_ago"); lastIO >= 0 {
+			metrics = append(metrics, newMetric("replication", "master_last_io_seconds", float64(lastIO), "seconds", nil))
+		}
+	}
+
+	masterOffset := info.Int("master_repl_offset")
+	for _, replica := range info.Replicas() {
+		tags := map[string]interface{}{"replica": replica.Name, "ip": replica.IP, "port": replica.Port, "state": replica.State}
+		metrics = append(metrics,
+			newMetric("replication", "replica_lag_seconds_"+replica.Name, float64(replica.Lag), "seconds", tags),
+			newMetric("replication", "replica_offset_lag_bytes_"+replica.Name, float64(masterOffset-replica.Offset), "bytes", tags),
+		)
+	}
+
+	for _, keyspace := range info.Keyspace() {
+		tags := map[string]interface{}{"db": keyspace.DB}
+		metrics = append(metrics,
+			newMetric("keyspace", "keys_"+keyspace.DB, float64(keyspace.Keys), "count", tags),
+			newMetric("keyspace", "expires_"+keyspace.DB, float64(keyspace.Expires), "count", tags),
+		)
+	}
+
+	if err := dc.dbManager.SaasMonitorDB.Create(metrics).Error; err != nil {
+		return err
+	}
+
+	return dc.collectRedisSlowLog(ctx, instance.Name, node)
+}
+
+// collectRedisSlowLog 读取SLOWLOG并写入redis_slowlogs（按实例、节点、ID和执行时间去重）
+func (dc *DataCollector) collectRedisSlowLog(ctx context.Context, name string, node database.RedisNode) error {
+	entries, err := node.Client.SlowLogGet(ctx, redisSlowLogFetchSize).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get Redis slowlog: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	logs := make([]models.RedisSlowLog, 0, len(entries))
+	for _, entry := range entries {
+		command := ""
+		if len(entry.Args) > 0 {
+			command = strings.ToUpper(entry.Args[0])
+		}
+		logs = append(logs, models.RedisSlowLog{
+			InstanceName:   name,
+			NodeAddr:       node.Addr,
+			SlowLogID:      entry.ID,
+			ExecutedAt:     entry.Time,
+			DurationMicros: entry.Duration.Microseconds(),
+			Command:        command,
+			Args:           strings.Join(entry.Args, " "),
+			ClientAddr:     entry.ClientAddr,
+			ClientName:     entry.ClientName,
+		})
+	}
+
+	return dc.dbManager.SaasMonitorDB.Clauses(clause.OnConflict{DoNothing: true}).Create(&logs).Error
+}
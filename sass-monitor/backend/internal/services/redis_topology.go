@@ -0,0 +1,91 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/internal/models"
+)
+
+// redisTopologySource Redis角色变化事件在monitoring_logs中的来源
+const redisTopologySource = "redis_topology"
+
+// redisTopologyTracker 记录各Redis节点上一次观察到的角色，用于发现故障转移
+type redisTopologyTracker struct {
+	mu    sync.Mutex
+	roles map[string]string // instance|addr -> role
+}
+
+func newRedisTopologyTracker() *redisTopologyTracker {
+	return &redisTopologyTracker{
+		roles: make(map[string]string),
+	}
+}
+
+// observe 记录节点角色，角色与上一次不同时返回之前的角色和true（首次观察不视为变化）
+func (t *redisTopologyTracker) observe(instance, addr, role string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := instance + "|" + addr
+	previous, exists := t.roles[key]
+	t.roles[key] = role
+	return previous, exists && previous != role
+}
+
+// redisComponentName Redis实例在健康检查和监控日志中的组件名称
+func redisComponentName(instance string) string {
+	if instance == "default" {
+		return "redis"
+	}
+	return "redis_" + instance
+}
+
+// redisNodeRole 根据INFO确定节点角色（slave统一为replica）
+func redisNodeRole(node database.RedisNode, info *RedisInfo) string {
+	if node.Role == database.RedisRoleSentinel || info.String("redis_mode") == "sentinel" {
+		return database.RedisRoleSentinel
+	}
+	switch info.String("role") {
+	case "master":
+		return database.RedisRoleMaster
+	case "slave":
+		return database.RedisRoleReplica
+	}
+	return node.Role
+}
+
+// logRedisRoleChange 记录节点角色变化（提升为主节点时视为故障转移）
+func (dc *DataCollector) logRedisRoleChange(instance *database.RedisInstance, addr, previous, role string) {
+	message := "Redis node role changed"
+	level := "warning"
+	if role == database.RedisRoleMaster {
+		message = "Redis failover: node promoted to master"
+		level = "critical"
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"instance":      instance.Name,
+		"mode":          instance.Mode(),
+		"node":          addr,
+		"previous_role": previous,
+		"role":          role,
+		"timestamp":     time.Now().Format(time.RFC3339),
+	})
+
+	event := models.MonitoringLog{
+		LogLevel:  level,
+		Source:    redisTopologySource,
+		Component: redisComponentName(instance.Name),
+		Message:   message,
+		Details:   string(details),
+		CreatedAt: time.Now(),
+	}
+	if err := dc.dbManager.SaasMonitorDB.Create(&event).Error; err != nil {
+		log.Printf("Error logging Redis role change for %s: %v", addr, err)
+	}
+	log.Printf("%s: instance=%s node=%s %s -> %s", message, instance.Name, addr, previous, role)
+}
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"sass-monitor/internal/database"
+)
+
+// MaxRevenueMovementMonths MRR变动最多统计的月数
+const MaxRevenueMovementMonths = 36
+
+// RevenueService 计算经常性收入：按计费周期将订阅价格归一化为MRR（月付原价、季付/3、年付/12），
+// ARR为MRR×12，试用订阅和试用期不计入收入
+type RevenueService struct {
+	dbManager *database.DatabaseManager
+}
+
+func NewRevenueService(dbManager *database.DatabaseManager) *RevenueService {
+	return &RevenueService{
+		dbManager: dbManager,
+	}
+}
+
+// PlanRevenue 按订阅计划汇总的收入
+type PlanRevenue struct {
+	PlanID        string  `json:"plan_id"`
+	TierName      string  `json:"tier_name"`
+	Subscriptions int     `json:"subscriptions"`
+	Organizations int     `json:"organizations"`
+	MRR           float64 `json:"mrr"`
+	ARR           float64 `json:"arr"`
+}
+
+// OrganizationRevenue 按组织汇总的收入
+type OrganizationRevenue struct {
+	OrganizationID   string   `json:"organization_id"`
+	OrganizationName string   `json:"organization_name"`
+	Subscriptions    int      `json:"subscriptions"`
+	Plans            []string `json:"plans"`
+	MRR              float64  `json:"mrr"`
+	ARR              float64  `json:"arr"`
+}
+
+// RevenueSummary 当前的MRR/ARR及按计费周期、计划和组织的分解
+type RevenueSummary struct {
+	MRR                 float64               `json:"mrr"`
+	ARR                 float64               `json:"arr"`
+	PayingSubscriptions int                   `json:"paying_subscriptions"`
+	PayingOrganizations int                   `json:"paying_organizations"`
+	ARPA                float64               `json:"arpa"`             // 每个付费组织的平均MRR
+	ByBillingCycle      map[string]float64    `json:"by_billing_cycle"` // 各计费周期贡献的MRR
+	ByPlan              []PlanRevenue         `json:"by_plan"`
+	ByOrganization      []OrganizationRevenue `json:"by_organization"` // 按MRR降序
+	CalculatedAt        time.Time             `json:"calculated_at"`
+}
+
+// RevenueMovement 单月的MRR变动，按组织比较月初与月末（当月为当前时间）的MRR：
+// 月初为0的计入new，月末为0的计入churned，其余按增减计入expansion或contraction；
+// 月初和月末都为0、但月内开始付费的组织（当月开始并流失）同时计入new和churned
+type RevenueMovement struct {
+	Month                 string  `json:"month"` // YYYY-MM
+	StartingMRR           float64 `json:"starting_mrr"`
+	NewMRR                float64 `json:"new_mrr"`
+	ExpansionMRR          float64 `json:"expansion_mrr"`
+	ContractionMRR        float64 `json:"contraction_mrr"`
+	ChurnedMRR            float64 `json:"churned_mrr"`
+	NetNewMRR             float64 `json:"net_new_mrr"`
+	EndingMRR             float64 `json:"ending_mrr"`
+	StartingOrganizations int     `json:"starting_organizations"` // 月初的付费组织数
+	NewOrganizations      int     `json:"new_organizations"`
+	ChurnedOrganizations  int     `json:"churned_organizations"`
+}
+
+// revenueSubscription 计算收入所需的订阅及计划价格
+type revenueSubscription struct {
+	ID               string
+	OrganizationID   string
+	PlanID           string
+	TierName         string
+	BillingCycle     string
+	Status           string
+	StartDate        time.Time
+	EndDate          *time.Time
+	UpdatedAt        time.Time
+	LastBilledAt     *time.Time
+	TrialDaysUsed    int
+	PricingMonthly   float64
+	PricingQuarterly float64
+	PricingYearly    float64
+}
+
+// billingCyclePrice 计费周期对应的计划价格及周期月数，未知的计费周期价格和月数都为0
+func billingCyclePrice(billingCycle string, monthly, quarterly, yearly float64) (float64, int) {
+	switch billingCycle {
+	case "monthly":
+		return monthly, 1
+	case "quarterly":
+		return quarterly, 3
+	case "yearly":
+		return yearly, 12
+	}
+	return 0, 0
+}
+
+// mrr 归一化的月度经常性收入，未知的计费周期按0计算
+func (s revenueSubscription) mrr() float64 {
+	price, months := billingCyclePrice(s.BillingCycle, s.PricingMonthly, s.PricingQuarterly, s.PricingYearly)
+	if months == 0 {
+		return 0
+	}
+	return price / float64(months)
+}
+
+// paidFrom 开始计费的时间（试用期结束后）
+func (s revenueSubscription) paidFrom() time.Time {
+	return s.StartDate.AddDate(0, 0, s.TrialDaysUsed)
+}
+
+// payingAt 订阅在t时刻是否处于付费期：试用订阅不计费，试用期结束后开始计费；结束时间见endAt
+func (s revenueSubscription) payingAt(t time.Time) bool {
+	if s.Status == "trial" {
+		return false
+	}
+	if s.paidFrom().After(t) {
+		return false
+	}
+	end := s.endAt()
+	return end == nil || end.After(t)
+}
+
+// liveAt 订阅（含试用）在t时刻是否有效
+func (s revenueSubscription) liveAt(t time.Time) bool {
+	if s.StartDate.After(t) {
+		return false
+	}
+	end := s.endAt()
+	return end == nil || end.After(t)
+}
+
+// endAt 订阅结束时间：取end_date；已取消、过期等状态且没有end_date的订阅在最后一次计费（从未计费时为开始计费时间）
+// 覆盖的计费周期结束时结束。不使用updated_at，与状态无关的更新不会改变流失时间
+func (s revenueSubscription) endAt() *time.Time {
+	if s.EndDate != nil || s.Status == "active" || s.Status == "trial" {
+		return s.EndDate
+	}
+	billedAt := s.paidFrom()
+	if s.LastBilledAt != nil && s.LastBilledAt.After(billedAt) {
+		billedAt = *s.LastBilledAt
+	}
+	_, months := billingCyclePrice(s.BillingCycle, s.PricingMonthly, s.PricingQuarterly, s.PricingYearly)
+	end := billedAt.AddDate(0, months, 0)
+	return &end
+}
+
+// GetSummary 获取当前的MRR/ARR，limit限制按组织分解返回的数量（0表示不返回）
+func (s *RevenueService) GetSummary(ctx context.Context, limit int) (*RevenueSummary, error) {
+	subscriptions, err := s.loadSubscriptions(ctx, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	summary := &RevenueSummary{
+		ByBillingCycle: make(map[string]float64),
+		ByPlan:         []PlanRevenue{},
+		ByOrganization: []OrganizationRevenue{},
+		CalculatedAt:   now,
+	}
+	plans := make(map[string]*PlanRevenue)
+	planOrganizations := make(map[string]map[string]bool)
+	organizations := make(map[string]*OrganizationRevenue)
+
+	for _, subscription := range subscriptions {
+		if !subscription.payingAt(now) {
+			continue
+		}
+		mrr := subscription.mrr()
+		summary.MRR += mrr
+		summary.PayingSubscriptions++
+		summary.ByBillingCycle[subscription.BillingCycle] += mrr
+
+		plan, ok := plans[subscription.PlanID]
+		if !ok {
+			plan = &PlanRevenue{PlanID: subscription.PlanID, TierName: subscription.TierName}
+			plans[subscription.PlanID] = plan
+			planOrganizations[subscription.PlanID] = make(map[string]bool)
+		}
+		plan.Subscriptions++
+		plan.MRR += mrr
+		planOrganizations[subscription.PlanID][subscription.OrganizationID] = true
+
+		organization, ok := organizations[subscription.OrganizationID]
+		if !ok {
+			organization = &OrganizationRevenue{OrganizationID: subscription.OrganizationID, Plans: []string{}}
+			organizations[subscription.OrganizationID] = organization
+		}
+		organization.Subscriptions++
+		organization.MRR += mrr
+		if !containsString(organization.Plans, subscription.TierName) {
+			organization.Plans = append(organization.Plans, subscription.TierName)
+		}
+	}
+
+	for cycle, mrr := range summary.ByBillingCycle {
+		summary.ByBillingCycle[cycle] = roundMoney(mrr)
+	}
+	for planID, plan := range plans {
+		plan.Organizations = len(planOrganizations[planID])
+		plan.ARR = roundMoney(plan.MRR * 12)
+		plan.MRR = roundMoney(plan.MRR)
+		summary.ByPlan = append(summary.ByPlan, *plan)
+	}
+	sort.Slice(summary.ByPlan, func(i, j int) bool {
+		return summary.ByPlan[i].MRR > summary.ByPlan[j].MRR
+	})
+
+	summary.PayingOrganizations = len(organizations)
+	if summary.PayingOrganizations > 0 {
+		summary.ARPA = roundMoney(summary.MRR / float64(summary.PayingOrganizations))
+	}
+	summary.ARR = roundMoney(summary.MRR * 12)
+	summary.MRR = roundMoney(summary.MRR)
+
+	if limit > 0 && len(organizations) > 0 {
+		names, err := lightAdminOrganizationNames(ctx, s.dbManager)
+		if err != nil {
+			return nil, err
+		}
+		for _, organization := range organizations {
+			organization.OrganizationName = names[organization.OrganizationID]
+			organization.ARR = roundMoney(organization.MRR * 12)
+			organization.MRR = roundMoney(organization.MRR)
+			summary.ByOrganization = append(summary.ByOrganization, *organization)
+		}
+		sort.Slice(summary.ByOrganization, func(i, j int) bool {
+			return summary.ByOrganization[i].MRR > summary.ByOrganization[j].MRR
+		})
+		if len(summary.ByOrganization) > limit {
+			summary.ByOrganization = summary.ByOrganization[:limit]
+		}
+	}
+	return summary, nil
+}
+
+// GetOrganizationRevenue 获取单个组织当前的MRR/ARR
+func (s *RevenueService) GetOrganizationRevenue(ctx context.Context, orgID string) (*OrganizationRevenue, error) {
+	subscriptions, err := s.loadSubscriptions(ctx, orgID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	revenue := &OrganizationRevenue{OrganizationID: orgID, Plans: []string{}}
+	for _, subscription := range subscriptions {
+		if !subscription.payingAt(now) {
+			continue
+		}
+		revenue.Subscriptions++
+		revenue.MRR += subscription.mrr()
+		if !containsString(revenue.Plans, subscription.TierName) {
+			revenue.Plans = append(revenue.Plans, subscription.TierName)
+		}
+	}
+	revenue.ARR = roundMoney(revenue.MRR * 12)
+	revenue.MRR = roundMoney(revenue.MRR)
+	return revenue, nil
+}
+
+// GetMovements 获取最近months个月（含当月）每月的MRR变动，按月份升序
+func (s *RevenueService) GetMovements(ctx context.Context, months int) ([]RevenueMovement, error) {
+	if months <= 0 || months > MaxRevenueMovementMonths {
+		return nil, fmt.Errorf("months must be between 1 and %d", MaxRevenueMovementMonths)
+	}
+
+	subscriptions, err := s.loadSubscriptions(ctx, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return revenueMovements(subscriptions, months, time.Now()), nil
+}
+
+// revenueMovements 计算截至now最近months个月的MRR变动
+func revenueMovements(subscriptions []revenueSubscription, months int, now time.Time) []RevenueMovement {
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	byOrganization := make(map[string][]revenueSubscription)
+	for _, subscription := range subscriptions {
+		byOrganization[subscription.OrganizationID] = append(byOrganization[subscription.OrganizationID], subscription)
+	}
+
+	movements := make([]RevenueMovement, 0, months)
+	for i := months - 1; i >= 0; i-- {
+		monthStart := currentMonth.AddDate(0, -i, 0)
+		monthEnd := monthStart.AddDate(0, 1, 0)
+		if monthEnd.After(now) {
+			monthEnd = now
+		}
+
+		starting := organizationMRRAt(subscriptions, monthStart)
+		ending := organizationMRRAt(subscriptions, monthEnd)
+		movement := RevenueMovement{Month: monthStart.Format("2006-01"), StartingOrganizations: len(starting)}
+		for orgID, before := range starting {
+			movement.StartingMRR += before
+			after := ending[orgID]
+			switch {
+			case after == 0:
+				movement.ChurnedMRR += before
+				movement.ChurnedOrganizations++
+			case after > before:
+				movement.ExpansionMRR += after - before
+			case after < before:
+				movement.ContractionMRR += before - after
+			}
+		}
+		for orgID, after := range ending {
+			movement.EndingMRR += after
+			if starting[orgID] == 0 {
+				movement.NewMRR += after
+				movement.NewOrganizations++
+			}
+		}
+		for orgID, peak := range organizationPeakMRRBetween(byOrganization, monthStart, monthEnd) {
+			if starting[orgID] == 0 && ending[orgID] == 0 {
+				movement.NewMRR += peak
+				movement.NewOrganizations++
+				movement.ChurnedMRR += peak
+				movement.ChurnedOrganizations++
+			}
+		}
+		movement.NetNewMRR = roundMoney(movement.NewMRR + movement.ExpansionMRR - movement.ContractionMRR - movement.ChurnedMRR)
+		movement.StartingMRR = roundMoney(movement.StartingMRR)
+		movement.NewMRR = roundMoney(movement.NewMRR)
+		movement.ExpansionMRR = roundMoney(movement.ExpansionMRR)
+		movement.ContractionMRR = roundMoney(movement.ContractionMRR)
+		movement.ChurnedMRR = roundMoney(movement.ChurnedMRR)
+		movement.EndingMRR = roundMoney(movement.EndingMRR)
+		movements = append(movements, movement)
+	}
+	return movements
+}
+
+// organizationMRRAt 各组织在t时刻的MRR，不含MRR为0的组织
+func organizationMRRAt(subscriptions []revenueSubscription, t time.Time) map[string]float64 {
+	result := make(map[string]float64)
+	for _, subscription := range subscriptions {
+		if !subscription.payingAt(t) {
+			continue
+		}
+		if mrr := subscription.mrr(); mrr > 0 {
+			result[subscription.OrganizationID] += mrr
+		}
+	}
+	return result
+}
+
+// organizationPeakMRRBetween 各组织在(from, to)内开始计费时达到的最高MRR，
+// 用于统计月初和月末的快照都看不到的、在两者之间开始并结束的订阅
+func organizationPeakMRRBetween(byOrganization map[string][]revenueSubscription, from, to time.Time) map[string]float64 {
+	result := make(map[string]float64)
+	for orgID, subscriptions := range byOrganization {
+		for _, subscription := range subscriptions {
+			at := subscription.paidFrom()
+			if !at.After(from) || !at.Before(to) || !subscription.payingAt(at) {
+				continue
+			}
+			if mrr := organizationMRRAt(subscriptions, at)[orgID]; mrr > result[orgID] {
+				result[orgID] = mrr
+			}
+		}
+	}
+	return result
+}
+
+// loadSubscriptions 获取订阅及其计划价格，orgID为空时获取全部组织，includeTrials为false时不含试用订阅
+func (s *RevenueService) loadSubscriptions(ctx context.Context, orgID string, includeTrials bool) ([]revenueSubscription, error) {
+	query := s.dbManager.LightAdminDB.WithContext(ctx).Table("subscription_users su").
+		Select(`su.id, su.organization_id, su.plan_id::text AS plan_id, sp.tier_name,
+			su.billing_cycle, su.status, su.start_date, su.end_date, su.updated_at, su.last_billed_at,
+			COALESCE(su.trial_days_used, 0) AS trial_days_used,
+			sp.pricing_monthly, sp.pricing_quarterly, sp.pricing_yearly`).
+		Joins("INNER JOIN subscription_plans sp ON su.plan_id = sp.id")
+	if !includeTrials {
+		query = query.Where("su.status <> ?", "trial")
+	}
+	if orgID != "" {
+		query = query.Where("su.organization_id = ?", orgID)
+	}
+
+	var subscriptions []revenueSubscription
+	if err := query.Scan(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to query subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// roundMoney 金额保留两位小数
+func roundMoney(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// containsString 切片中是否包含value
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func revenueDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+func TestRevenueSubscriptionMRR(t *testing.T) {
+	subscription := revenueSubscription{PricingMonthly: 30, PricingQuarterly: 81, PricingYearly: 300}
+	tests := map[string]float64{
+		"monthly":   30,
+		"quarterly": 27,
+		"yearly":    25,
+		"weekly":    0,
+		"":          0,
+	}
+	for billingCycle, want := range tests {
+		subscription.BillingCycle = billingCycle
+		if got := subscription.mrr(); got != want {
+			t.Errorf("mrr(%q) = %v, want %v", billingCycle, got, want)
+		}
+	}
+}
+
+func TestRevenueSubscriptionPayingAt(t *testing.T) {
+	endDate := revenueDate(2026, 3, 1)
+	lastBilledAt := revenueDate(2026, 2, 1)
+	tests := []struct {
+		name         string
+		subscription revenueSubscription
+		at           time.Time
+		want         bool
+	}{
+		{
+			name:         "active subscription",
+			subscription: revenueSubscription{Status: "active", StartDate: revenueDate(2026, 1, 1)},
+			at:           revenueDate(2026, 2, 1),
+			want:         true,
+		},
+		{
+			name:         "before start date",
+			subscription: revenueSubscription{Status: "active", StartDate: revenueDate(2026, 1, 1)},
+			at:           revenueDate(2025, 12, 31),
+			want:         false,
+		},
+		{
+			name:         "trial status never pays",
+			subscription: revenueSubscription{Status: "trial", StartDate: revenueDate(2026, 1, 1)},
+			at:           revenueDate(2026, 2, 1),
+			want:         false,
+		},
+		{
+			name:         "within used trial days",
+			subscription: revenueSubscription{Status: "active", StartDate: revenueDate(2026, 1, 1), TrialDaysUsed: 14},
+			at:           revenueDate(2026, 1, 10),
+			want:         false,
+		},
+		{
+			name:         "after used trial days",
+			subscription: revenueSubscription{Status: "active", StartDate: revenueDate(2026, 1, 1), TrialDaysUsed: 14},
+			at:           revenueDate(2026, 1, 15),
+			want:         true,
+		},
+		{
+			name:         "ends at end date",
+			subscription: revenueSubscription{Status: "active", StartDate: revenueDate(2026, 1, 1), EndDate: &endDate},
+			at:           endDate,
+			want:         false,
+		},
+		{
+			name: "cancelled without end date pays through the last billed period",
+			subscription: revenueSubscription{Status: "cancelled", BillingCycle: "monthly", StartDate: revenueDate(2026, 1, 1),
+				LastBilledAt: &lastBilledAt, UpdatedAt: revenueDate(2026, 1, 10)},
+			at:   revenueDate(2026, 2, 20),
+			want: true,
+		},
+		{
+			name: "cancelled without end date ends after the last billed period",
+			subscription: revenueSubscription{Status: "cancelled", BillingCycle: "monthly", StartDate: revenueDate(2026, 1, 1),
+				LastBilledAt: &lastBilledAt, UpdatedAt: revenueDate(2026, 6, 1)},
+			at:   revenueDate(2026, 3, 1),
+			want: false,
+		},
+		{
+			name: "cancelled without end date or billing pays for the first period after the trial",
+			subscription: revenueSubscription{Status: "cancelled", BillingCycle: "quarterly", StartDate: revenueDate(2026, 1, 1),
+				TrialDaysUsed: 14, UpdatedAt: revenueDate(2026, 1, 20)},
+			at:   revenueDate(2026, 4, 14),
+			want: true,
+		},
+		{
+			name: "cancelled without end date or billing ends after the first period",
+			subscription: revenueSubscription{Status: "cancelled", BillingCycle: "quarterly", StartDate: revenueDate(2026, 1, 1),
+				TrialDaysUsed: 14, UpdatedAt: revenueDate(2026, 1, 20)},
+			at:   revenueDate(2026, 4, 15),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.subscription.payingAt(tt.at); got != tt.want {
+				t.Errorf("payingAt(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevenueMovements(t *testing.T) {
+	downgradeEnd := revenueDate(2026, 2, 20)
+	churnEnd := revenueDate(2026, 3, 5)
+	flashEnd := revenueDate(2026, 3, 10)
+	lapsedBilledAt := revenueDate(2026, 1, 10)
+	subscriptions := []revenueSubscription{
+		// 两个月均不变
+		{OrganizationID: "steady", Status: "active", BillingCycle: "monthly", PricingMonthly: 100, StartDate: revenueDate(2025, 12, 1)},
+		// 3月升级，新增一个季度订阅
+		{OrganizationID: "steady", Status: "active", BillingCycle: "quarterly", PricingQuarterly: 300, StartDate: revenueDate(2026, 3, 3)},
+		// 2月新增的年度订阅
+		{OrganizationID: "new", Status: "active", BillingCycle: "yearly", PricingYearly: 1200, StartDate: revenueDate(2026, 2, 10)},
+		// 3月流失
+		{OrganizationID: "churned", Status: "cancelled", BillingCycle: "monthly", PricingMonthly: 50, StartDate: revenueDate(2025, 11, 1), EndDate: &churnEnd},
+		// 2月降级，保留较小的订阅
+		{OrganizationID: "downgraded", Status: "cancelled", BillingCycle: "monthly", PricingMonthly: 80, StartDate: revenueDate(2025, 1, 1), EndDate: &downgradeEnd},
+		{OrganizationID: "downgraded", Status: "active", BillingCycle: "monthly", PricingMonthly: 30, StartDate: revenueDate(2025, 1, 1)},
+		// 3月试用期结束后开始付费
+		{OrganizationID: "converted", Status: "active", BillingCycle: "monthly", PricingMonthly: 40, StartDate: revenueDate(2026, 3, 1), TrialDaysUsed: 10},
+		// 3月内开始并取消，月初和月末都不付费
+		{OrganizationID: "flash", Status: "cancelled", BillingCycle: "monthly", PricingMonthly: 60, StartDate: revenueDate(2026, 3, 3), EndDate: &flashEnd},
+		// 没有end_date，最后一次计费覆盖到2月10日；3月的更新不影响流失月份
+		{OrganizationID: "lapsed", Status: "cancelled", BillingCycle: "monthly", PricingMonthly: 20, StartDate: revenueDate(2025, 6, 1),
+			LastBilledAt: &lapsedBilledAt, UpdatedAt: revenueDate(2026, 3, 12)},
+	}
+
+	got := revenueMovements(subscriptions, 2, revenueDate(2026, 3, 15))
+	want := []RevenueMovement{
+		{
+			Month:                 "2026-02",
+			StartingMRR:           280,
+			NewMRR:                100,
+			ContractionMRR:        80,
+			ChurnedMRR:            20,
+			NetNewMRR:             0,
+			EndingMRR:             280,
+			StartingOrganizations: 4,
+			NewOrganizations:      1,
+			ChurnedOrganizations:  1,
+		},
+		{
+			Month:                 "2026-03",
+			StartingMRR:           280,
+			NewMRR:                100,
+			ExpansionMRR:          100,
+			ChurnedMRR:            110,
+			NetNewMRR:             90,
+			EndingMRR:             370,
+			StartingOrganizations: 4,
+			NewOrganizations:      2,
+			ChurnedOrganizations:  2,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("revenueMovements() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestRevenueMovementsWithoutSubscriptions(t *testing.T) {
+	got := revenueMovements(nil, 3, revenueDate(2026, 1, 10))
+	if len(got) != 3 {
+		t.Fatalf("got %d months, want 3", len(got))
+	}
+	for i, month := range []string{"2025-11", "2025-12", "2026-01"} {
+		if got[i] != (RevenueMovement{Month: month}) {
+			t.Errorf("month %d = %+v, want an empty %s", i, got[i], month)
+		}
+	}
+}
+
+func TestRevenueMovementsWithinOneMonth(t *testing.T) {
+	firstEnd := revenueDate(2026, 3, 12)
+	secondEnd := revenueDate(2026, 3, 20)
+	subscriptions := []revenueSubscription{
+		// 同一组织月内先后两个重叠的订阅，按最高MRR计入一次
+		{OrganizationID: "flash", Status: "cancelled", BillingCycle: "monthly", PricingMonthly: 30, StartDate: revenueDate(2026, 3, 2), EndDate: &firstEnd},
+		{OrganizationID: "flash", Status: "cancelled", BillingCycle: "yearly", PricingYearly: 600, StartDate: revenueDate(2026, 3, 10), EndDate: &secondEnd},
+		// 试用期在月内结束前就取消，从未付费
+		{OrganizationID: "trial_only", Status: "cancelled", BillingCycle: "monthly", PricingMonthly: 90, StartDate: revenueDate(2026, 3, 1), TrialDaysUsed: 14, EndDate: &firstEnd},
+	}
+
+	got := revenueMovements(subscriptions, 1, revenueDate(2026, 3, 31))
+	want := []RevenueMovement{{
+		Month:                "2026-03",
+		NewMRR:               80,
+		ChurnedMRR:           80,
+		NewOrganizations:     1,
+		ChurnedOrganizations: 1,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("revenueMovements() =\n%+v\nwant\n%+v", got, want)
+	}
+}
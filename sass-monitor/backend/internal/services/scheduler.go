@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 
 	"sass-monitor/internal/database"
@@ -18,24 +19,63 @@ type TaskScheduler struct {
 	dbManager     *database.DatabaseManager
 	config        *config.Config
 	dataCollector *DataCollector
-	collectors   map[string]*time.Ticker
-	stopChans     map[string]chan bool
+	probeService  *ProbeService
+	cron          *cron.Cron
+	jobs          map[string]*schedulerJob
+	jobOrder      []string
 	mutex         sync.RWMutex
 	running       bool
+	manualRuns    sync.WaitGroup  // 手动触发的执行，停止时等待完成
+	leader        *LeaderElector  // 未启用选主时为nil，所有任务在本副本执行
+	leaderCtx     context.Context // 任务执行的context，失去leader身份时取消
+	leaderCancel  context.CancelFunc
 }
 
 func NewTaskScheduler(dbManager *database.DatabaseManager, cfg *config.Config) *TaskScheduler {
-	return &TaskScheduler{
+	dataCollector := NewDataCollector(dbManager)
+	ts := &TaskScheduler{
 		dbManager:     dbManager,
 		config:        cfg,
-		dataCollector: NewDataCollector(dbManager),
-		collectors:   make(map[string]*time.Ticker),
-		stopChans:     make(map[string]chan bool),
+		dataCollector: dataCollector,
+		probeService:  NewProbeService(dbManager, dataCollector),
+		jobs:          make(map[string]*schedulerJob),
 		running:       false,
 	}
+
+	collectInterval := cfg.Monitoring.CollectInterval
+	if collectInterval <= 0 {
+		collectInterval = 5
+	}
+	ts.registerJob("data_collection", "采集数据库、主机和基础设施指标", fmt.Sprintf("@every %dm", collectInterval), ts.dataCollector.CollectAllData)
+	ts.registerJob("alert_checker", "检查告警规则", "@every 1m", ts.checkAlerts)
+	// 每天低峰期清理一次
+	ts.registerJob("data_cleanup", "清理过期的指标、日志和执行记录", "0 3 * * *", ts.cleanupOldData)
+
+	// 未配置拨测目标时不注册拨测任务
+	probeConfig := cfg.Monitoring.Probes
+	if len(probeConfig.Targets) > 0 {
+		probeInterval := probeConfig.Interval
+		if probeInterval <= 0 {
+			probeInterval = 60
+		}
+		ts.registerJob("probes", fmt.Sprintf("执行%d个拨测目标", len(probeConfig.Targets)), fmt.Sprintf("@every %ds", probeInterval), func(ctx context.Context) error {
+			_, err := ts.probeService.RunAll(ctx)
+			return err
+		})
+	}
+
+	ts.leaderCtx = context.Background()
+	if cfg.Monitoring.LeaderElection.Enabled {
+		ts.leader = NewLeaderElector(dbManager, ts.onElected, ts.onDemoted)
+		// 当选前没有可用的任务context
+		ts.leaderCtx, ts.leaderCancel = context.WithCancel(context.Background())
+		ts.leaderCancel()
+	}
+
+	return ts
 }
 
-// Start 启动调度器
+// Start 启动调度器，加载持久化的调度配置并按cron表达式调度各任务
 func (ts *TaskScheduler) Start() error {
 	ts.mutex.Lock()
 	defer ts.mutex.Unlock()
@@ -46,139 +86,73 @@ func (ts *TaskScheduler) Start() error {
 
 	log.Println("Starting task scheduler...")
 
-	// 启动数据采集任务
-	if err := ts.startDataCollection(); err != nil {
-		return fmt.Errorf("failed to start data collection: %w", err)
+	// 读取持久化的调度配置失败时使用默认调度，不阻止调度器启动
+	if err := ts.loadJobStates(); err != nil {
+		log.Printf("Failed to load scheduled jobs, using default schedules: %v", err)
 	}
 
-	// 启动告警检查任务
-	if err := ts.startAlertChecker(); err != nil {
-		return fmt.Errorf("failed to start alert checker: %w", err)
+	ts.cron = cron.New(cron.WithParser(jobScheduleParser))
+	for _, name := range ts.jobOrder {
+		job := ts.jobs[name]
+		if job.state.Paused {
+			log.Printf("Job %s is paused", name)
+			continue
+		}
+		if err := ts.scheduleJob(job); err != nil {
+			return fmt.Errorf("failed to schedule job %s: %w", name, err)
+		}
+		log.Printf("Job %s scheduled: %s", name, job.state.Schedule)
 	}
-
-	// 启动数据清理任务
-	if err := ts.startDataCleanup(); err != nil {
-		return fmt.Errorf("failed to start data cleanup: %w", err)
+	// 多副本部署时由leader定期同步其他副本通过API修改的调度配置
+	if ts.leader != nil {
+		if _, err := ts.cron.AddFunc(jobSyncSchedule, func() {
+			if ts.IsLeader() {
+				ts.syncJobStates()
+			}
+		}); err != nil {
+			return fmt.Errorf("failed to schedule job sync: %w", err)
+		}
 	}
+	ts.cron.Start()
 
 	ts.running = true
+	// 此时持有锁，异步写入下次执行时间
+	go ts.saveNextRuns()
+	if ts.leader != nil {
+		ts.leader.Start()
+	}
 	log.Println("Task scheduler started successfully")
 
 	return nil
 }
 
-// Stop 停止调度器
+// Stop 停止调度器，等待正在执行的任务完成
 func (ts *TaskScheduler) Stop() {
 	ts.mutex.Lock()
-	defer ts.mutex.Unlock()
-
 	if !ts.running {
+		ts.mutex.Unlock()
 		return
 	}
 
 	log.Println("Stopping task scheduler...")
 
-	// 停止所有定时任务
-	for name, stopChan := range ts.stopChans {
-		close(stopChan)
-		if ticker, exists := ts.collectors[name]; exists {
-			ticker.Stop()
-			delete(ts.collectors, name)
-		}
-		delete(ts.stopChans, name)
-	}
-
 	ts.running = false
+	stopped := ts.cron.Stop()
+	for _, job := range ts.jobs {
+		job.entryID = 0
+	}
+	ts.mutex.Unlock()
+
+	// 执行中的任务会更新状态，需在释放锁后等待
+	<-stopped.Done()
+	ts.manualRuns.Wait()
+	// 任务结束后再释放leader锁，避免新leader与本副本同时执行
+	if ts.leader != nil {
+		ts.leader.Stop()
+	}
 	log.Println("Task scheduler stopped")
 }
 
-// startDataCollection 启动数据采集任务
-func (ts *TaskScheduler) startDataCollection() error {
-	interval := time.Duration(ts.config.Monitoring.CollectInterval) * time.Minute
-
-	ticker := time.NewTicker(interval)
-	stopChan := make(chan bool)
-
-	ts.collectors["data_collection"] = ticker
-	ts.stopChans["data_collection"] = stopChan
-
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				if err := ts.dataCollector.CollectAllData(context.Background()); err != nil {
-					log.Printf("Data collection error: %v", err)
-					ts.logMonitoringError("data_collector", err.Error())
-				}
-			case <-stopChan:
-				ticker.Stop()
-				return
-			}
-		}
-	}()
-
-	log.Printf("Data collection task started with interval: %v", interval)
-	return nil
-}
-
-// startAlertChecker 启动告警检查任务
-func (ts *TaskScheduler) startAlertChecker() error {
-	interval := 1 * time.Minute // 每分钟检查一次
-
-	ticker := time.NewTicker(interval)
-	stopChan := make(chan bool)
-
-	ts.collectors["alert_checker"] = ticker
-	ts.stopChans["alert_checker"] = stopChan
-
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				if err := ts.checkAlerts(context.Background()); err != nil {
-					log.Printf("Alert check error: %v", err)
-					ts.logMonitoringError("alert_checker", err.Error())
-				}
-			case <-stopChan:
-				ticker.Stop()
-				return
-			}
-		}
-	}()
-
-	log.Printf("Alert checker task started with interval: %v", interval)
-	return nil
-}
-
-// startDataCleanup 启动数据清理任务
-func (ts *TaskScheduler) startDataCleanup() error {
-	interval := 24 * time.Hour // 每天清理一次
-
-	ticker := time.NewTicker(interval)
-	stopChan := make(chan bool)
-
-	ts.collectors["data_cleanup"] = ticker
-	ts.stopChans["data_cleanup"] = stopChan
-
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				if err := ts.cleanupOldData(context.Background()); err != nil {
-					log.Printf("Data cleanup error: %v", err)
-					ts.logMonitoringError("data_cleanup", err.Error())
-				}
-			case <-stopChan:
-				ticker.Stop()
-				return
-			}
-		}
-	}()
-
-	log.Printf("Data cleanup task started with interval: %v", interval)
-	return nil
-}
-
 // checkAlerts 检查告警规则
 func (ts *TaskScheduler) checkAlerts(ctx context.Context) error {
 	// 获取启用的告警规则
@@ -188,6 +162,9 @@ func (ts *TaskScheduler) checkAlerts(ctx context.Context) error {
 	}
 
 	for _, rule := range alertRules {
+		if !ts.leading(ctx) {
+			return fmt.Errorf("alert check stopped: %w", ErrNotLeader)
+		}
 		if err := ts.evaluateAlertRule(ctx, rule); err != nil {
 			log.Printf("Error evaluating alert rule %s: %v", rule.Name, err)
 		}
@@ -200,7 +177,7 @@ func (ts *TaskScheduler) checkAlerts(ctx context.Context) error {
 func (ts *TaskScheduler) evaluateAlertRule(ctx context.Context, rule models.AlertRule) error {
 	// 获取最新的指标数据
 	var metric models.ResourceMetric
-	err := ts.dbManager.SaasMonitorDB.Where("database_type = ? AND database_name = ? AND metric_name = ?",
+	err := ts.dbManager.SaasMonitorDB.WithContext(ctx).Where("database_type = ? AND database_name = ? AND metric_name = ?",
 		rule.TargetType, rule.TargetName, rule.MetricName).
 		Order("collected_at DESC").
 		First(&metric).Error
@@ -227,7 +204,7 @@ func (ts *TaskScheduler) evaluateAlertRule(ctx context.Context, rule models.Aler
 		triggered = metric.MetricValue == rule.Threshold
 	}
 
-	if triggered {
+	if triggered && ts.leading(ctx) {
 		log.Printf("Alert triggered: %s - %s %s %v (actual: %v)",
 			rule.Name, rule.MetricName, rule.Operator, rule.Threshold, metric.MetricValue)
 
@@ -285,16 +262,43 @@ func (ts *TaskScheduler) cleanupOldData(ctx context.Context) error {
 
 	log.Printf("Cleaning up data older than %d days (cutoff: %s)", retentionDays, cutoffDate.Format("2006-01-02"))
 
+	if !ts.leading(ctx) {
+		return fmt.Errorf("data cleanup stopped: %w", ErrNotLeader)
+	}
+	db := ts.dbManager.SaasMonitorDB.WithContext(ctx)
+
 	// 清理过期的资源指标
-	if err := ts.dbManager.SaasMonitorDB.Where("created_at < ?", cutoffDate).Delete(&models.ResourceMetric{}).Error; err != nil {
+	if err := db.Where("created_at < ?", cutoffDate).Delete(&models.ResourceMetric{}).Error; err != nil {
 		return fmt.Errorf("failed to cleanup resource metrics: %w", err)
 	}
 
 	// 清理过期的监控日志
-	if err := ts.dbManager.SaasMonitorDB.Where("created_at < ?", cutoffDate).Delete(&models.MonitoringLog{}).Error; err != nil {
+	if err := db.Where("created_at < ?", cutoffDate).Delete(&models.MonitoringLog{}).Error; err != nil {
 		return fmt.Errorf("failed to cleanup monitoring logs: %w", err)
 	}
 
+	// 清理过期的健康状态变化记录
+	if err := db.Where("changed_at < ?", cutoffDate).Delete(&models.SystemHealthHistory{}).Error; err != nil {
+		return fmt.Errorf("failed to cleanup system health history: %w", err)
+	}
+
+	// 清理过期的任务执行记录
+	if err := db.Where("started_at < ?", cutoffDate).Delete(&models.JobRun{}).Error; err != nil {
+		return fmt.Errorf("failed to cleanup job runs: %w", err)
+	}
+
+	// 清理过期的已结束后台任务
+	if err := db.Where("status IN ? AND finished_at < ?",
+		[]string{JobStatusSucceeded, JobStatusFailed, JobStatusCancelled}, cutoffDate).
+		Delete(&models.BackgroundJob{}).Error; err != nil {
+		return fmt.Errorf("failed to cleanup background jobs: %w", err)
+	}
+
+	// 清理过期的Redis慢查询日志
+	if err := db.Where("created_at < ?", cutoffDate).Delete(&models.RedisSlowLog{}).Error; err != nil {
+		return fmt.Errorf("failed to cleanup redis slowlogs: %w", err)
+	}
+
 	deletedCount := time.Since(cutoffDate).Hours() / 24 * 100 // 估算删除的记录数
 	log.Printf("Data cleanup completed. Estimated records deleted: ~%.0f", deletedCount)
 
@@ -322,49 +326,87 @@ func (ts *TaskScheduler) IsRunning() bool {
 	return ts.running
 }
 
-// GetTaskStatus 获取任务状态
-func (ts *TaskScheduler) GetTaskStatus() map[string]string {
-	ts.mutex.RLock()
-	defer ts.mutex.RUnlock()
+// ProbeService 获取调度器使用的拨测服务，供接口复用
+func (ts *TaskScheduler) ProbeService() *ProbeService {
+	return ts.probeService
+}
 
-	status := make(map[string]string)
+// IsLeader 本副本是否执行定时任务，未启用选主时始终为true
+func (ts *TaskScheduler) IsLeader() bool {
+	return ts.leader == nil || ts.leader.IsLeader()
+}
 
-	for name := range ts.collectors {
-		status[name] = "running"
+// LeaderStatus 获取选主状态
+func (ts *TaskScheduler) LeaderStatus() LeaderStatus {
+	if ts.leader == nil {
+		return LeaderStatus{
+			Enabled:  false,
+			IsLeader: true,
+		}
 	}
+	return ts.leader.Status()
+}
+
+// onElected 成为leader时创建任务context，并从数据库同步调度配置（接管期间其他副本可能修改过）
+func (ts *TaskScheduler) onElected() {
+	ts.startLeading()
+	ts.syncJobStates()
+}
 
-	return status
+// onDemoted 失去leader身份时取消正在执行的任务，新leader会接管定时任务
+func (ts *TaskScheduler) onDemoted() {
+	ts.stopLeading()
+	log.Println("Scheduler leadership lost, running jobs cancelled")
 }
 
-// RestartTask 重启指定任务
-func (ts *TaskScheduler) RestartTask(taskName string) error {
+// startLeading 创建新的任务context
+func (ts *TaskScheduler) startLeading() {
 	ts.mutex.Lock()
 	defer ts.mutex.Unlock()
-
-	if !ts.running {
-		return fmt.Errorf("scheduler is not running")
+	if ts.leaderCancel != nil {
+		ts.leaderCancel()
 	}
+	ts.leaderCtx, ts.leaderCancel = context.WithCancel(context.Background())
+}
 
-	// 停止指定任务
-	if stopChan, exists := ts.stopChans[taskName]; exists {
-		close(stopChan)
-		delete(ts.stopChans, taskName)
+// stopLeading 取消当前的任务context
+func (ts *TaskScheduler) stopLeading() {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+	if ts.leaderCancel != nil {
+		ts.leaderCancel()
 	}
+}
 
-	if ticker, exists := ts.collectors[taskName]; exists {
-		ticker.Stop()
-		delete(ts.collectors, taskName)
-	}
+// jobContext 任务执行的context，启用选主时随失去leader身份取消
+func (ts *TaskScheduler) jobContext() context.Context {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+	return ts.leaderCtx
+}
+
+// leading 任务产生副作用（写入、通知、删除）前确认仍是leader且任务未被取消
+func (ts *TaskScheduler) leading(ctx context.Context) bool {
+	return ctx.Err() == nil && ts.IsLeader()
+}
+
+// RunCollector 立即执行一次指定的采集器（与定时采集共用DataCollector，串行执行）
+func (ts *TaskScheduler) RunCollector(ctx context.Context, name string) (*CollectorRunResult, error) {
+	return ts.dataCollector.RunCollector(ctx, name)
+}
 
-	// 重启任务
-	switch taskName {
-	case "data_collection":
-		return ts.startDataCollection()
-	case "alert_checker":
-		return ts.startAlertChecker()
-	case "data_cleanup":
-		return ts.startDataCleanup()
-	default:
-		return fmt.Errorf("unknown task: %s", taskName)
+// RunCollectorJob 执行手动采集后台任务，采集失败时任务失败
+func (ts *TaskScheduler) RunCollectorJob(ctx context.Context, execution *JobExecution) (interface{}, error) {
+	var payload CollectorJobPayload
+	if err := execution.Bind(&payload); err != nil {
+		return nil, err
 	}
-}
\ No newline at end of file
+	result, err := ts.RunCollector(ctx, payload.Collector)
+	if err != nil {
+		return nil, NonRetryable(err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("collector %s failed: %s", result.Collector, result.Error)
+	}
+	return result, nil
+}
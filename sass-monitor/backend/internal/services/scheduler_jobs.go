@@ -0,0 +1,574 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+
+	"sass-monitor/internal/models"
+)
+
+// jobScheduleParser 标准5段cron表达式，并支持@every 5m、@daily等描述符
+var jobScheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ErrJobRunning 任务正在执行（定时或手动触发），不能重复执行
+var ErrJobRunning = errors.New("job is already running")
+
+// ErrNotLeader 本副本不是leader，定时任务只在leader上执行
+var ErrNotLeader = errors.New("this instance is not the scheduler leader")
+
+// jobSyncSchedule leader同步数据库中调度配置和触发请求的频率
+const jobSyncSchedule = "@every 5s"
+
+// ErrInvalidSchedule 调度表达式无效
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// 任务执行的触发方式
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
+
+// schedulerJob 调度任务定义及运行状态
+type schedulerJob struct {
+	name            string
+	description     string
+	defaultSchedule string
+	run             func(ctx context.Context) error
+
+	state   models.ScheduledJob
+	entryID cron.EntryID // 未调度（已暂停或调度器未启动）时为0
+	running bool
+}
+
+// JobStatus 调度任务状态
+type JobStatus struct {
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Schedule        string     `json:"schedule"`
+	DefaultSchedule string     `json:"default_schedule"`
+	Paused          bool       `json:"paused"`
+	Running         bool       `json:"running"`
+	NextRunAt       *time.Time `json:"next_run_at"`
+	LastRunAt       *time.Time `json:"last_run_at"`
+	LastDurationMs  *int64     `json:"last_duration_ms"`
+	LastStatus      string     `json:"last_status"`
+	LastError       *string    `json:"last_error"`
+	TriggerPending  bool       `json:"trigger_pending"` // 已请求手动触发，等待leader执行
+}
+
+// registerJob 注册调度任务，需在Start之前调用
+func (ts *TaskScheduler) registerJob(name, description, defaultSchedule string, run func(ctx context.Context) error) {
+	ts.jobs[name] = &schedulerJob{
+		name:            name,
+		description:     description,
+		defaultSchedule: defaultSchedule,
+		run:             run,
+		state: models.ScheduledJob{
+			Name:     name,
+			Schedule: defaultSchedule,
+		},
+	}
+	ts.jobOrder = append(ts.jobOrder, name)
+}
+
+// loadJobStates 加载持久化的调度配置，不存在时以默认调度创建；
+// data_collection首次创建时使用monitoring_configs中的collect_interval
+func (ts *TaskScheduler) loadJobStates() error {
+	db := ts.dbManager.SaasMonitorDB
+	for _, name := range ts.jobOrder {
+		job := ts.jobs[name]
+
+		var state models.ScheduledJob
+		err := db.Where("name = ?", name).First(&state).Error
+		if err == gorm.ErrRecordNotFound {
+			state = models.ScheduledJob{
+				Name:     name,
+				Schedule: job.defaultSchedule,
+			}
+			if name == "data_collection" {
+				if minutes, ok := ts.configuredCollectInterval(); ok {
+					state.Schedule = fmt.Sprintf("@every %dm", minutes)
+				}
+			}
+			if err := db.Create(&state).Error; err != nil {
+				return fmt.Errorf("failed to create scheduled job %s: %w", name, err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to load scheduled job %s: %w", name, err)
+		}
+
+		if _, err := jobScheduleParser.Parse(state.Schedule); err != nil {
+			log.Printf("Invalid schedule '%s' for job %s, using default '%s': %v", state.Schedule, name, job.defaultSchedule, err)
+			state.Schedule = job.defaultSchedule
+		}
+		job.state = state
+	}
+	return nil
+}
+
+// syncJobStates 从数据库同步调度表达式、暂停状态和最近一次执行结果，
+// 用于leader应用在其他副本上通过API修改的配置，并执行其他副本收到的触发请求
+func (ts *TaskScheduler) syncJobStates() {
+	var states []models.ScheduledJob
+	if err := ts.dbManager.SaasMonitorDB.Find(&states).Error; err != nil {
+		log.Printf("Failed to sync scheduled jobs: %v", err)
+		return
+	}
+
+	ts.applyJobStates(states)
+	for _, state := range states {
+		if state.TriggerRequestedAt != nil {
+			ts.consumeTriggerRequest(state.Name, *state.TriggerRequestedAt)
+		}
+	}
+}
+
+// applyJobStates 应用数据库中的调度配置
+func (ts *TaskScheduler) applyJobStates(states []models.ScheduledJob) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	for _, state := range states {
+		job, ok := ts.jobs[state.Name]
+		if !ok {
+			continue
+		}
+		if state.LastRunAt != nil && (job.state.LastRunAt == nil || state.LastRunAt.After(*job.state.LastRunAt)) {
+			job.state.LastRunAt = state.LastRunAt
+			job.state.LastDurationMs = state.LastDurationMs
+			job.state.LastStatus = state.LastStatus
+			job.state.LastError = state.LastError
+		}
+		if state.Schedule == job.state.Schedule && state.Paused == job.state.Paused {
+			continue
+		}
+		if _, err := jobScheduleParser.Parse(state.Schedule); err != nil {
+			log.Printf("Ignoring invalid schedule '%s' for job %s: %v", state.Schedule, state.Name, err)
+			continue
+		}
+
+		job.state.Schedule = state.Schedule
+		job.state.Paused = state.Paused
+		if !ts.running {
+			continue
+		}
+		ts.unscheduleJob(job)
+		if !job.state.Paused {
+			if err := ts.scheduleJob(job); err != nil {
+				log.Printf("Failed to reschedule job %s: %v", state.Name, err)
+				continue
+			}
+		}
+		log.Printf("Job %s synced: schedule=%s paused=%v", state.Name, job.state.Schedule, job.state.Paused)
+	}
+}
+
+// consumeTriggerRequest 清除触发请求并在后台执行任务，清除失败（已被其他leader处理）时跳过
+func (ts *TaskScheduler) consumeTriggerRequest(name string, requestedAt time.Time) {
+	result := ts.dbManager.SaasMonitorDB.Model(&models.ScheduledJob{}).
+		Where("name = ? AND trigger_requested_at = ?", name, requestedAt).
+		Update("trigger_requested_at", nil)
+	if result.Error != nil {
+		log.Printf("Failed to consume trigger request of job %s: %v", name, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	if _, err := ts.startJob(name); err != nil {
+		log.Printf("Skipping requested run of job %s: %v", name, err)
+	}
+}
+
+// configuredCollectInterval 读取monitoring_configs中的collect_interval（分钟）
+func (ts *TaskScheduler) configuredCollectInterval() (int, bool) {
+	var monitoringConfig models.MonitoringConfig
+	if err := ts.dbManager.SaasMonitorDB.Where("config_key = ?", "collect_interval").First(&monitoringConfig).Error; err != nil {
+		return 0, false
+	}
+	minutes, err := strconv.Atoi(monitoringConfig.ConfigValue)
+	if err != nil || minutes <= 0 {
+		return 0, false
+	}
+	return minutes, true
+}
+
+// scheduleJob 按当前调度表达式添加cron条目，调用方需持有锁
+func (ts *TaskScheduler) scheduleJob(job *schedulerJob) error {
+	name := job.name
+	entryID, err := ts.cron.AddFunc(job.state.Schedule, func() {
+		if !ts.IsLeader() {
+			return
+		}
+		claimed, err := ts.claimJob(name)
+		if err != nil {
+			log.Printf("Skipping scheduled run of job %s: %v", name, err)
+			return
+		}
+		ts.executeJob(claimed, JobTriggerSchedule)
+	})
+	if err != nil {
+		return err
+	}
+	job.entryID = entryID
+	return nil
+}
+
+// unscheduleJob 移除cron条目，调用方需持有锁
+func (ts *TaskScheduler) unscheduleJob(job *schedulerJob) {
+	if job.entryID != 0 {
+		ts.cron.Remove(job.entryID)
+		job.entryID = 0
+	}
+}
+
+// claimJob 将任务标记为执行中，已在执行时返回ErrJobRunning
+func (ts *TaskScheduler) claimJob(name string) (*schedulerJob, error) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	job, ok := ts.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("job '%s' not found", name)
+	}
+	if job.running {
+		return nil, ErrJobRunning
+	}
+	job.running = true
+	return job, nil
+}
+
+// executeJob 执行已标记的任务，记录执行记录和最近一次结果；
+// 启用选主时任务context随失去leader身份取消，已不是leader时跳过执行
+func (ts *TaskScheduler) executeJob(job *schedulerJob, trigger string) {
+	ctx := ts.jobContext()
+	if !ts.leading(ctx) {
+		ts.mutex.Lock()
+		job.running = false
+		ts.mutex.Unlock()
+		log.Printf("Skipping %s run of job %s: %v", trigger, job.name, ErrNotLeader)
+		return
+	}
+
+	db := ts.dbManager.SaasMonitorDB
+	startedAt := time.Now()
+	run := models.JobRun{
+		JobName:   job.name,
+		Trigger:   trigger,
+		Status:    "running",
+		StartedAt: startedAt,
+	}
+	if err := db.Create(&run).Error; err != nil {
+		log.Printf("Failed to record run of job %s: %v", job.name, err)
+	}
+
+	err := job.run(ctx)
+
+	finishedAt := time.Now()
+	durationMs := finishedAt.Sub(startedAt).Milliseconds()
+	status := "success"
+	var errorMessage *string
+	if err != nil {
+		status = "failed"
+		message := err.Error()
+		errorMessage = &message
+		log.Printf("Job %s failed: %v", job.name, err)
+		ts.logMonitoringError(job.name, message)
+	}
+
+	if run.ID != uuid.Nil {
+		db.Model(&run).Updates(map[string]interface{}{
+			"status":      status,
+			"error":       errorMessage,
+			"finished_at": finishedAt,
+			"duration_ms": durationMs,
+		})
+	}
+
+	ts.mutex.Lock()
+	job.running = false
+	job.state.LastRunAt = &startedAt
+	job.state.LastDurationMs = &durationMs
+	job.state.LastStatus = status
+	job.state.LastError = errorMessage
+	nextRunAt := ts.nextRunAt(job)
+	ts.mutex.Unlock()
+
+	if err := db.Model(&models.ScheduledJob{}).Where("name = ?", job.name).Updates(map[string]interface{}{
+		"last_run_at":      startedAt,
+		"last_duration_ms": durationMs,
+		"last_status":      status,
+		"last_error":       errorMessage,
+		"next_run_at":      nextRunAt,
+	}).Error; err != nil {
+		log.Printf("Failed to save last run of job %s: %v", job.name, err)
+	}
+}
+
+// nextRunAt 任务的下次执行时间，未调度时为nil，调用方需持有锁
+func (ts *TaskScheduler) nextRunAt(job *schedulerJob) *time.Time {
+	if job.entryID == 0 || !ts.running {
+		return nil
+	}
+	if next := ts.cron.Entry(job.entryID).Next; !next.IsZero() {
+		return &next
+	}
+	return nil
+}
+
+// saveNextRuns 持久化所有任务的下次执行时间
+func (ts *TaskScheduler) saveNextRuns() {
+	ts.mutex.RLock()
+	nextRuns := make(map[string]*time.Time, len(ts.jobOrder))
+	for _, name := range ts.jobOrder {
+		nextRuns[name] = ts.nextRunAt(ts.jobs[name])
+	}
+	ts.mutex.RUnlock()
+
+	for name, nextRunAt := range nextRuns {
+		if err := ts.dbManager.SaasMonitorDB.Model(&models.ScheduledJob{}).Where("name = ?", name).
+			Update("next_run_at", nextRunAt).Error; err != nil {
+			log.Printf("Failed to save next run of job %s: %v", name, err)
+		}
+	}
+}
+
+// jobStatus 构建任务状态，调用方需持有读锁
+func (ts *TaskScheduler) jobStatus(job *schedulerJob) JobStatus {
+	status := JobStatus{
+		Name:            job.name,
+		Description:     job.description,
+		Schedule:        job.state.Schedule,
+		DefaultSchedule: job.defaultSchedule,
+		Paused:          job.state.Paused,
+		Running:         job.running,
+		LastRunAt:       job.state.LastRunAt,
+		LastDurationMs:  job.state.LastDurationMs,
+		LastStatus:      job.state.LastStatus,
+		LastError:       job.state.LastError,
+	}
+	status.NextRunAt = ts.nextRunAt(job)
+	return status
+}
+
+// loadPersistedJobs 读取scheduled_jobs中的调度配置和执行结果，失败时返回nil并使用本副本的内存状态
+func (ts *TaskScheduler) loadPersistedJobs(ctx context.Context) map[string]models.ScheduledJob {
+	var states []models.ScheduledJob
+	if err := ts.dbManager.SaasMonitorDB.WithContext(ctx).Find(&states).Error; err != nil {
+		log.Printf("Failed to load scheduled jobs, using local state: %v", err)
+		return nil
+	}
+	persisted := make(map[string]models.ScheduledJob, len(states))
+	for _, state := range states {
+		persisted[state.Name] = state
+	}
+	return persisted
+}
+
+// applyPersistedJob 用持久化的状态覆盖调度配置、最近一次执行结果和下次执行时间，
+// 多副本部署时任务可能由其他副本执行或修改
+func applyPersistedJob(status *JobStatus, state models.ScheduledJob) {
+	status.Schedule = state.Schedule
+	status.Paused = state.Paused
+	status.LastRunAt = state.LastRunAt
+	status.LastDurationMs = state.LastDurationMs
+	status.LastStatus = state.LastStatus
+	status.LastError = state.LastError
+	status.NextRunAt = state.NextRunAt
+	status.TriggerPending = state.TriggerRequestedAt != nil
+	if state.Paused {
+		status.NextRunAt = nil
+	}
+}
+
+// ListJobs 获取所有调度任务的状态
+func (ts *TaskScheduler) ListJobs(ctx context.Context) []JobStatus {
+	persisted := ts.loadPersistedJobs(ctx)
+
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+
+	statuses := make([]JobStatus, 0, len(ts.jobOrder))
+	for _, name := range ts.jobOrder {
+		status := ts.jobStatus(ts.jobs[name])
+		if state, ok := persisted[name]; ok {
+			applyPersistedJob(&status, state)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// GetJob 获取单个调度任务的状态
+func (ts *TaskScheduler) GetJob(ctx context.Context, name string) (*JobStatus, error) {
+	if !ts.hasJob(name) {
+		return nil, fmt.Errorf("job '%s' not found", name)
+	}
+	persisted := ts.loadPersistedJobs(ctx)
+
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+
+	status := ts.jobStatus(ts.jobs[name])
+	if state, ok := persisted[name]; ok {
+		applyPersistedJob(&status, state)
+	}
+	return &status, nil
+}
+
+// hasJob 任务是否已注册
+func (ts *TaskScheduler) hasJob(name string) bool {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+	_, ok := ts.jobs[name]
+	return ok
+}
+
+// GetJobRuns 获取任务最近的执行记录
+func (ts *TaskScheduler) GetJobRuns(ctx context.Context, name string, limit int) ([]models.JobRun, error) {
+	if !ts.hasJob(name) {
+		return nil, fmt.Errorf("job '%s' not found", name)
+	}
+
+	var runs []models.JobRun
+	if err := ts.dbManager.SaasMonitorDB.WithContext(ctx).
+		Where("job_name = ?", name).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to query job runs: %w", err)
+	}
+	return runs, nil
+}
+
+// PauseJob 暂停任务的定时执行（仍可手动触发），持久化后重启仍保持暂停
+func (ts *TaskScheduler) PauseJob(name string) (*JobStatus, error) {
+	return ts.updateJob(name, func(job *schedulerJob) error {
+		job.state.Paused = true
+		if ts.running {
+			ts.unscheduleJob(job)
+		}
+		return nil
+	})
+}
+
+// ResumeJob 恢复任务的定时执行
+func (ts *TaskScheduler) ResumeJob(name string) (*JobStatus, error) {
+	return ts.updateJob(name, func(job *schedulerJob) error {
+		job.state.Paused = false
+		if ts.running && job.entryID == 0 {
+			return ts.scheduleJob(job)
+		}
+		return nil
+	})
+}
+
+// UpdateJobSchedule 修改任务的调度表达式，立即生效且持久化
+func (ts *TaskScheduler) UpdateJobSchedule(name, schedule string) (*JobStatus, error) {
+	if _, err := jobScheduleParser.Parse(schedule); err != nil {
+		return nil, fmt.Errorf("%w '%s': %v", ErrInvalidSchedule, schedule, err)
+	}
+
+	return ts.updateJob(name, func(job *schedulerJob) error {
+		job.state.Schedule = schedule
+		if ts.running && !job.state.Paused {
+			ts.unscheduleJob(job)
+			return ts.scheduleJob(job)
+		}
+		return nil
+	})
+}
+
+// ApplyCollectInterval 将collect_interval（分钟）应用到数据采集任务
+func (ts *TaskScheduler) ApplyCollectInterval(minutes int) error {
+	if minutes <= 0 {
+		return fmt.Errorf("%w: collect_interval must be a positive number of minutes", ErrInvalidSchedule)
+	}
+	_, err := ts.UpdateJobSchedule("data_collection", fmt.Sprintf("@every %dm", minutes))
+	return err
+}
+
+// updateJob 在锁内修改任务的调度配置并持久化，持久化失败时回滚内存状态
+func (ts *TaskScheduler) updateJob(name string, update func(job *schedulerJob) error) (*JobStatus, error) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	job, ok := ts.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("job '%s' not found", name)
+	}
+
+	previous := job.state
+	if err := update(job); err != nil {
+		job.state = previous
+		return nil, err
+	}
+
+	if err := ts.dbManager.SaasMonitorDB.Model(&models.ScheduledJob{}).Where("name = ?", name).Updates(map[string]interface{}{
+		"schedule":    job.state.Schedule,
+		"paused":      job.state.Paused,
+		"next_run_at": ts.nextRunAt(job),
+	}).Error; err != nil {
+		job.state = previous
+		if ts.running {
+			ts.unscheduleJob(job)
+			if !job.state.Paused {
+				if scheduleErr := ts.scheduleJob(job); scheduleErr != nil {
+					log.Printf("Failed to restore schedule of job %s: %v", name, scheduleErr)
+				}
+			}
+		}
+		return nil, fmt.Errorf("failed to save job %s: %w", name, err)
+	}
+
+	status := ts.jobStatus(job)
+	return &status, nil
+}
+
+// TriggerJob 立即在后台执行一次任务，不影响定时调度；
+// 启用选主时非leader副本记录触发请求，由leader在下一次同步时执行
+func (ts *TaskScheduler) TriggerJob(ctx context.Context, name string) (*JobStatus, error) {
+	if !ts.IsLeader() {
+		return ts.requestTrigger(ctx, name)
+	}
+	if _, err := ts.startJob(name); err != nil {
+		return nil, err
+	}
+	return ts.GetJob(ctx, name)
+}
+
+// startJob 标记任务为执行中并在后台执行一次
+func (ts *TaskScheduler) startJob(name string) (*schedulerJob, error) {
+	job, err := ts.claimJob(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.manualRuns.Add(1)
+	go func() {
+		defer ts.manualRuns.Done()
+		ts.executeJob(job, JobTriggerManual)
+	}()
+	return job, nil
+}
+
+// requestTrigger 在scheduled_jobs中记录触发请求，供leader执行
+func (ts *TaskScheduler) requestTrigger(ctx context.Context, name string) (*JobStatus, error) {
+	if !ts.hasJob(name) {
+		return nil, fmt.Errorf("job '%s' not found", name)
+	}
+	if err := ts.dbManager.SaasMonitorDB.WithContext(ctx).Model(&models.ScheduledJob{}).
+		Where("name = ?", name).
+		Update("trigger_requested_at", time.Now()).Error; err != nil {
+		return nil, fmt.Errorf("failed to request run of job %s: %w", name, err)
+	}
+	return ts.GetJob(ctx, name)
+}
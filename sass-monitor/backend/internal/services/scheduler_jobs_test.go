@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sass-monitor/internal/models"
+)
+
+func TestJobScheduleParser(t *testing.T) {
+	tests := []struct {
+		schedule string
+		wantErr  bool
+	}{
+		{schedule: "@every 5m"},
+		{schedule: "@daily"},
+		{schedule: "0 3 * * *"},
+		{schedule: "*/15 * * * 1-5"},
+		{schedule: "0 0 3 * * *", wantErr: true}, // 不支持秒字段
+		{schedule: "@every soon", wantErr: true},
+		{schedule: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		if _, err := jobScheduleParser.Parse(tt.schedule); (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, want error %v", tt.schedule, err, tt.wantErr)
+		}
+	}
+}
+
+func TestApplyPersistedJob(t *testing.T) {
+	localRun := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	localNext := localRun.Add(5 * time.Minute)
+	remoteRun := localRun.Add(10 * time.Minute)
+	remoteNext := remoteRun.Add(5 * time.Minute)
+	duration := int64(1500)
+	message := "connection refused"
+
+	tests := []struct {
+		name  string
+		state models.ScheduledJob
+		want  JobStatus
+	}{
+		{
+			name: "run recorded by another replica",
+			state: models.ScheduledJob{
+				Schedule:       "@every 5m",
+				LastRunAt:      &remoteRun,
+				LastDurationMs: &duration,
+				LastStatus:     "failed",
+				LastError:      &message,
+				NextRunAt:      &remoteNext,
+			},
+			want: JobStatus{
+				Name:           "data_collection",
+				Schedule:       "@every 5m",
+				LastRunAt:      &remoteRun,
+				LastDurationMs: &duration,
+				LastStatus:     "failed",
+				LastError:      &message,
+				NextRunAt:      &remoteNext,
+			},
+		},
+		{
+			name:  "paused by another replica",
+			state: models.ScheduledJob{Schedule: "@every 10m", Paused: true, NextRunAt: &remoteNext},
+			want:  JobStatus{Name: "data_collection", Schedule: "@every 10m", Paused: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := JobStatus{
+				Name:       "data_collection",
+				Schedule:   "@every 1m",
+				LastRunAt:  &localRun,
+				LastStatus: "success",
+				NextRunAt:  &localNext,
+			}
+			applyPersistedJob(&status, tt.state)
+
+			if status.Schedule != tt.want.Schedule || status.Paused != tt.want.Paused || status.LastStatus != tt.want.LastStatus {
+				t.Errorf("status = %+v, want %+v", status, tt.want)
+			}
+			if !equalTimePtr(status.LastRunAt, tt.want.LastRunAt) || !equalTimePtr(status.NextRunAt, tt.want.NextRunAt) {
+				t.Errorf("last run = %v, next run = %v; want %v, %v", status.LastRunAt, status.NextRunAt, tt.want.LastRunAt, tt.want.NextRunAt)
+			}
+			if status.LastError != tt.want.LastError || status.LastDurationMs != tt.want.LastDurationMs {
+				t.Errorf("last error = %v, duration = %v; want %v, %v", status.LastError, status.LastDurationMs, tt.want.LastError, tt.want.LastDurationMs)
+			}
+		})
+	}
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestClaimJob(t *testing.T) {
+	ts := &TaskScheduler{jobs: make(map[string]*schedulerJob)}
+	ts.registerJob("data_cleanup", "清理过期数据", "0 3 * * *", func(ctx context.Context) error { return nil })
+
+	if _, err := ts.claimJob("data_cleanup"); err != nil {
+		t.Fatalf("first claim: %v", err)
+	}
+	if _, err := ts.claimJob("data_cleanup"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("second claim error = %v, want ErrJobRunning", err)
+	}
+	if _, err := ts.claimJob("missing"); err == nil {
+		t.Error("claiming an unknown job should fail")
+	}
+	if ts.hasJob("missing") || !ts.hasJob("data_cleanup") {
+		t.Error("hasJob should only report registered jobs")
+	}
+}
+
+func TestUpdateJobScheduleRejectsInvalidSchedule(t *testing.T) {
+	ts := &TaskScheduler{jobs: make(map[string]*schedulerJob)}
+	ts.registerJob("data_collection", "采集数据", "@every 5m", func(ctx context.Context) error { return nil })
+
+	if _, err := ts.UpdateJobSchedule("data_collection", "every five minutes"); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("error = %v, want ErrInvalidSchedule", err)
+	}
+	if err := ts.ApplyCollectInterval(0); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("ApplyCollectInterval(0) error = %v, want ErrInvalidSchedule", err)
+	}
+}
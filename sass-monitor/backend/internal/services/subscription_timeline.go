@@ -0,0 +1,505 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxTimelineWebhookEvents 时间线中最多包含的Stripe webhook事件数（时间范围内最近的）
+const maxTimelineWebhookEvents = 500
+
+// renewalBillingReason Stripe发票中表示订阅周期续费的billing_reason
+const renewalBillingReason = "subscription_cycle"
+
+// 时间线事件来源
+const (
+	TimelineSourceSubscription = "subscription"
+	TimelineSourcePayment      = "payment"
+	TimelineSourceBilling      = "org_billing"
+	TimelineSourceWebhook      = "stripe_webhook"
+)
+
+// SubscriptionTimelineEvent 订阅生命周期事件。Type取值：
+// subscription：trial_started、trial_converted、trial_ended、subscription_started、upgraded、downgraded、plan_changed、renewed、canceled、expired、suspended；
+// payment：payment_<status>；org_billing：usage_billed；stripe_webhook：Stripe事件类型（如invoice.payment_failed）
+type SubscriptionTimelineEvent struct {
+	Time           time.Time              `json:"time"`
+	Type           string                 `json:"type"`
+	Source         string                 `json:"source"`
+	SubscriptionID *string                `json:"subscription_id,omitempty"`
+	Summary        string                 `json:"summary"`
+	Details        map[string]interface{} `json:"details,omitempty"`
+}
+
+// SubscriptionTimelineFilter 时间线过滤条件，为空的条件不过滤
+type SubscriptionTimelineFilter struct {
+	From    *time.Time
+	To      *time.Time
+	Sources []string
+}
+
+// SubscriptionTimeline 组织的订阅生命周期时间线，事件按时间倒序
+type SubscriptionTimeline struct {
+	OrganizationID string                      `json:"organization_id"`
+	Events         []SubscriptionTimelineEvent `json:"events"`
+	Total          int                         `json:"total"`
+}
+
+// timelineSubscription 构建时间线所需的订阅信息，Stripe的customer和subscription ID取自stripe_session_data
+type timelineSubscription struct {
+	revenueSubscription
+	Notes                *string
+	PaymentMethod        *string
+	StripeSessionID      *string
+	StripeCustomerID     *string
+	StripeSubscriptionID *string
+}
+
+// timelinePayment 与组织关联的支付记录
+type timelinePayment struct {
+	ID             string
+	CustomerID     string
+	SubscriptionID *string
+	Amount         int64
+	Currency       string
+	Status         string
+	StripeEventID  *string
+	CreatedAt      time.Time
+}
+
+// timelineWebhookEvent 与组织关联的Stripe webhook事件，FailureReason取自支付失败事件的错误信息，
+// BillingReason、StripeSubscriptionID和StripeCustomerID取自发票对象
+type timelineWebhookEvent struct {
+	StripeEventID        string
+	EventType            string
+	ObjectID             *string
+	BillingReason        *string
+	StripeSubscriptionID *string
+	StripeCustomerID     *string
+	Processed            *bool
+	ProcessingResult     *string
+	ErrorMessage         *string
+	RetryCount           *int
+	ReceivedAt           time.Time
+	FailureReason        *string
+}
+
+// GetSubscriptionTimeline 重建组织的订阅生命周期时间线：由subscription_users推导试用、升降级和取消，
+// 由成功支付和周期发票推导续费，结合payments、org_billing和Stripe webhook_events中的支付和计费事件
+func (s *OrganizationService) GetSubscriptionTimeline(ctx context.Context, orgID string, filter SubscriptionTimelineFilter) (*SubscriptionTimeline, error) {
+	// 验证UUID格式
+	if _, err := uuid.Parse(orgID); err != nil {
+		return nil, fmt.Errorf("invalid organization ID format")
+	}
+
+	db := s.dbManager.LightAdminDB.WithContext(ctx)
+
+	var organizationCount int64
+	if err := db.Table("auth_organizations").Where("id = ?", orgID).Count(&organizationCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to query organization: %w", err)
+	}
+	if organizationCount == 0 {
+		return nil, fmt.Errorf("organization not found")
+	}
+
+	var subscriptions []timelineSubscription
+	if err := db.Table("subscription_users su").
+		Select(`su.id, su.organization_id, su.plan_id::text AS plan_id, sp.tier_name,
+			su.billing_cycle, su.status, su.start_date, su.end_date, su.updated_at,
+			COALESCE(su.trial_days_used, 0) AS trial_days_used,
+			sp.pricing_monthly, sp.pricing_quarterly, sp.pricing_yearly,
+			su.last_billed_at, su.notes, su.payment_method, su.stripe_session_id,
+			su.stripe_session_data->>'customer' AS stripe_customer_id,
+			su.stripe_session_data->>'subscription' AS stripe_subscription_id`).
+		Joins("INNER JOIN subscription_plans sp ON su.plan_id = sp.id").
+		Where("su.organization_id = ?", orgID).
+		Order("su.start_date, su.created_at").
+		Scan(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to query subscriptions: %w", err)
+	}
+
+	events := subscriptionEvents(subscriptions, time.Now())
+
+	// 关联支付和webhook事件的ID：订阅ID、Stripe checkout session、customer和subscription
+	subscriptionRefs := []string{}
+	customerRefs := []string{}
+	objectRefs := []string{}
+	for _, subscription := range subscriptions {
+		subscriptionRefs = append(subscriptionRefs, subscription.ID)
+		if subscription.StripeSubscriptionID != nil && *subscription.StripeSubscriptionID != "" {
+			subscriptionRefs = append(subscriptionRefs, *subscription.StripeSubscriptionID)
+			objectRefs = append(objectRefs, *subscription.StripeSubscriptionID)
+		}
+		if subscription.StripeCustomerID != nil && *subscription.StripeCustomerID != "" {
+			customerRefs = append(customerRefs, *subscription.StripeCustomerID)
+			objectRefs = append(objectRefs, *subscription.StripeCustomerID)
+		}
+		if subscription.StripeSessionID != nil && *subscription.StripeSessionID != "" {
+			objectRefs = append(objectRefs, *subscription.StripeSessionID)
+		}
+	}
+
+	var payments []timelinePayment
+	if err := db.Table("payments").
+		Select("id, customer_id, subscription_id, amount, currency, status, stripe_event_id, created_at").
+		Where("subscription_id IN ? OR customer_id IN ?", subscriptionRefs, customerRefs).
+		Order("created_at").
+		Scan(&payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to query payments: %w", err)
+	}
+	eventRefs := []string{}
+	for _, payment := range payments {
+		events = append(events, paymentEvent(payment))
+		objectRefs = append(objectRefs, payment.ID)
+		if payment.StripeEventID != nil && *payment.StripeEventID != "" {
+			eventRefs = append(eventRefs, *payment.StripeEventID)
+		}
+	}
+
+	var billings []struct {
+		Month      *string
+		UsageCount *int64
+		FreeQuota  *int64
+		Overage    *int64
+		Amount     *float64
+		Status     *string
+	}
+	if err := db.Table("org_billing").
+		Select("month, usage_count, free_quota, overage, amount, status").
+		Where("org_id = ?", orgID).
+		Scan(&billings).Error; err != nil {
+		return nil, fmt.Errorf("failed to query org billing: %w", err)
+	}
+	for _, billing := range billings {
+		if billing.Month == nil {
+			continue
+		}
+		month, err := time.ParseInLocation("2006-01", *billing.Month, time.Local)
+		if err != nil {
+			continue
+		}
+		status := derefString(billing.Status)
+		events = append(events, SubscriptionTimelineEvent{
+			Time:    month.AddDate(0, 1, 0).Add(-time.Second),
+			Type:    "usage_billed",
+			Source:  TimelineSourceBilling,
+			Summary: fmt.Sprintf("Usage billing for %s: %s", *billing.Month, status),
+			Details: map[string]interface{}{
+				"month":       *billing.Month,
+				"usage_count": billing.UsageCount,
+				"free_quota":  billing.FreeQuota,
+				"overage":     billing.Overage,
+				"amount":      billing.Amount,
+				"status":      status,
+			},
+		})
+	}
+
+	var webhookEvents []timelineWebhookEvent
+	if err := timelineWebhookQuery(db, orgID, objectRefs, customerRefs, eventRefs, filter).
+		Scan(&webhookEvents).Error; err != nil {
+		return nil, fmt.Errorf("failed to query webhook events: %w", err)
+	}
+	for _, webhookEvent := range webhookEvents {
+		events = append(events, webhookTimelineEvent(webhookEvent))
+	}
+	events = append(events, renewalEvents(subscriptions, payments, webhookEvents)...)
+
+	timeline := &SubscriptionTimeline{
+		OrganizationID: orgID,
+		Events:         []SubscriptionTimelineEvent{},
+	}
+	for _, event := range events {
+		if filter.From != nil && event.Time.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && event.Time.After(*filter.To) {
+			continue
+		}
+		if len(filter.Sources) > 0 && !containsString(filter.Sources, event.Source) {
+			continue
+		}
+		timeline.Events = append(timeline.Events, event)
+	}
+	sort.SliceStable(timeline.Events, func(i, j int) bool {
+		return timeline.Events[i].Time.After(timeline.Events[j].Time)
+	})
+	timeline.Total = len(timeline.Events)
+	return timeline, nil
+}
+
+// timelineWebhookQuery 查询与组织关联的Stripe webhook事件：按对象ID、事件ID、customer或metadata中的organization_id匹配。
+// 时间范围在SQL中过滤，使数量限制只作用于范围内的事件；metadata条件依赖idx_webhook_events_metadata_org表达式索引
+func timelineWebhookQuery(db *gorm.DB, orgID string, objectRefs, customerRefs, eventRefs []string, filter SubscriptionTimelineFilter) *gorm.DB {
+	query := db.Table("webhook_events").
+		Select(`stripe_event_id, event_type, object_id, processed, processing_result, error_message, retry_count, received_at,
+			COALESCE(event_data#>>'{data,object,last_payment_error,message}', event_data#>>'{data,object,failure_message}') AS failure_reason,
+			event_data#>>'{data,object,billing_reason}' AS billing_reason,
+			event_data#>>'{data,object,subscription}' AS stripe_subscription_id,
+			event_data#>>'{data,object,customer}' AS stripe_customer_id`).
+		Where(`object_id IN ? OR stripe_event_id IN ? OR event_data#>>'{data,object,customer}' IN ?
+			OR event_data#>>'{data,object,metadata,organization_id}' = ?`,
+			objectRefs, eventRefs, customerRefs, orgID)
+	if filter.From != nil {
+		query = query.Where("received_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("received_at <= ?", *filter.To)
+	}
+	return query.Order("received_at DESC").Limit(maxTimelineWebhookEvents)
+}
+
+// subscriptionEvents 由订阅记录推导生命周期事件：按开始时间依次比较相邻付费订阅的归一化MRR判断升降级，
+// canceled/expired/suspended在结束时间（见endAt）产生事件
+func subscriptionEvents(subscriptions []timelineSubscription, now time.Time) []SubscriptionTimelineEvent {
+	var events []SubscriptionTimelineEvent
+	var previous *timelineSubscription
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		planLabel := subscription.planLabel()
+		event := func(at time.Time, eventType, summary string, extra map[string]interface{}) {
+			events = append(events, subscription.event(at, eventType, summary, extra))
+		}
+
+		isTrial := subscription.Status == "trial" || subscription.TrialDaysUsed > 0
+		if isTrial {
+			event(subscription.StartDate, "trial_started", "Trial started on "+planLabel, nil)
+		}
+
+		paidStart := subscription.paidFrom()
+		if subscription.Status != "trial" {
+			switch {
+			case isTrial:
+				event(paidStart, "trial_converted", "Trial converted to "+planLabel,
+					map[string]interface{}{"trial_days": subscription.TrialDaysUsed})
+			case previous != nil:
+				eventType := "plan_changed"
+				switch {
+				case subscription.mrr() > previous.mrr():
+					eventType = "upgraded"
+				case subscription.mrr() < previous.mrr():
+					eventType = "downgraded"
+				case subscription.PlanID == previous.PlanID && subscription.BillingCycle == previous.BillingCycle:
+					eventType = "renewed"
+				}
+				previousLabel := fmt.Sprintf("%s (%s)", previous.TierName, previous.BillingCycle)
+				summary := fmt.Sprintf("Changed plan from %s to %s", previousLabel, planLabel)
+				switch eventType {
+				case "upgraded":
+					summary = fmt.Sprintf("Upgraded from %s to %s", previousLabel, planLabel)
+				case "downgraded":
+					summary = fmt.Sprintf("Downgraded from %s to %s", previousLabel, planLabel)
+				case "renewed":
+					summary = "Renewed " + planLabel
+				}
+				event(paidStart, eventType, summary, map[string]interface{}{
+					"previous_subscription_id": previous.ID,
+					"previous_plan":            previous.TierName,
+					"previous_billing_cycle":   previous.BillingCycle,
+					"previous_mrr":             roundMoney(previous.mrr()),
+				})
+			default:
+				event(paidStart, "subscription_started", "Subscribed to "+planLabel, nil)
+			}
+			previous = subscription
+		}
+
+		var notes interface{}
+		if subscription.Notes != nil {
+			notes = *subscription.Notes
+		}
+		switch subscription.Status {
+		case "canceled", "expired", "suspended":
+			at := *subscription.endAt()
+			extra := map[string]interface{}{"notes": notes, "end_date": subscription.EndDate}
+			summary := fmt.Sprintf("Subscription %s: %s", subscription.Status, planLabel)
+			// 取消后仍可使用到end_date时，事件时间为取消操作的时间
+			if subscription.Status == "canceled" && subscription.EndDate != nil && at.After(now) {
+				at = subscription.UpdatedAt
+				summary = fmt.Sprintf("Canceled %s, access ends %s", planLabel, subscription.EndDate.Format("2006-01-02"))
+			}
+			event(at, subscription.Status, summary, extra)
+		case "trial":
+			if subscription.EndDate != nil && !subscription.EndDate.After(now) {
+				event(*subscription.EndDate, "trial_ended", "Trial ended without conversion on "+planLabel,
+					map[string]interface{}{"notes": notes})
+			}
+		}
+	}
+	return events
+}
+
+// planLabel 订阅的套餐和计费周期，如"Pro (monthly)"
+func (s *timelineSubscription) planLabel() string {
+	return fmt.Sprintf("%s (%s)", s.TierName, s.BillingCycle)
+}
+
+// event 订阅来源的时间线事件，Details包含套餐、计费周期、状态和MRR，extra中的字段追加或覆盖
+func (s *timelineSubscription) event(at time.Time, eventType, summary string, extra map[string]interface{}) SubscriptionTimelineEvent {
+	details := map[string]interface{}{
+		"plan":          s.TierName,
+		"billing_cycle": s.BillingCycle,
+		"status":        s.Status,
+		"mrr":           roundMoney(s.mrr()),
+	}
+	if s.PaymentMethod != nil {
+		details["payment_method"] = *s.PaymentMethod
+	}
+	for key, value := range extra {
+		details[key] = value
+	}
+	subscriptionID := s.ID
+	return SubscriptionTimelineEvent{
+		Time:           at,
+		Type:           eventType,
+		Source:         TimelineSourceSubscription,
+		SubscriptionID: &subscriptionID,
+		Summary:        summary,
+		Details:        details,
+	}
+}
+
+// renewalEvents 由计费事件推导续费：关联到周期发票（billing_reason为subscription_cycle）的成功支付，
+// 或没有关联发票时同一订阅首次成功支付之后的成功支付；没有对应支付记录的周期发票paid事件也视为续费
+func renewalEvents(subscriptions []timelineSubscription, payments []timelinePayment, webhookEvents []timelineWebhookEvent) []SubscriptionTimelineEvent {
+	invoices := make(map[string]timelineWebhookEvent)
+	for _, webhookEvent := range webhookEvents {
+		if isInvoicePaidEvent(webhookEvent.EventType) && webhookEvent.BillingReason != nil {
+			invoices[webhookEvent.StripeEventID] = webhookEvent
+		}
+	}
+
+	var events []SubscriptionTimelineEvent
+	charged := make(map[string]bool) // 已有成功支付的订阅
+	linked := make(map[string]bool)  // 已由支付记录处理的发票事件
+	for _, payment := range payments {
+		if payment.Status != "succeeded" {
+			continue
+		}
+		subscription := renewalSubscription(subscriptions, derefString(payment.SubscriptionID), payment.CustomerID, payment.CreatedAt)
+		if subscription == nil {
+			continue
+		}
+		renewal := charged[subscription.ID]
+		if invoice, ok := invoices[derefString(payment.StripeEventID)]; ok {
+			renewal = *invoice.BillingReason == renewalBillingReason
+			linked[invoice.StripeEventID] = true
+		}
+		charged[subscription.ID] = true
+		if renewal {
+			events = append(events, subscription.event(payment.CreatedAt, "renewed", "Renewed "+subscription.planLabel(),
+				map[string]interface{}{
+					"payment_id":      payment.ID,
+					"amount":          float64(payment.Amount) / 100,
+					"currency":        payment.Currency,
+					"stripe_event_id": payment.StripeEventID,
+				}))
+		}
+	}
+
+	for _, invoice := range invoices {
+		if linked[invoice.StripeEventID] || *invoice.BillingReason != renewalBillingReason {
+			continue
+		}
+		subscription := renewalSubscription(subscriptions, derefString(invoice.StripeSubscriptionID), derefString(invoice.StripeCustomerID), invoice.ReceivedAt)
+		if subscription == nil {
+			continue
+		}
+		events = append(events, subscription.event(invoice.ReceivedAt, "renewed", "Renewed "+subscription.planLabel(),
+			map[string]interface{}{
+				"stripe_event_id": invoice.StripeEventID,
+				"invoice_id":      invoice.ObjectID,
+			}))
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+// isInvoicePaidEvent 是否为发票支付成功的Stripe事件
+func isInvoicePaidEvent(eventType string) bool {
+	return eventType == "invoice.paid" || eventType == "invoice.payment_succeeded"
+}
+
+// renewalSubscription 计费事件所属的订阅：优先按订阅ID或Stripe subscription ID匹配，
+// 否则取该时间之前最近开始付费的订阅，同一Stripe customer的订阅优先
+func renewalSubscription(subscriptions []timelineSubscription, subscriptionRef, customerRef string, at time.Time) *timelineSubscription {
+	var latest, latestForCustomer *timelineSubscription
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		if subscriptionRef != "" && (subscription.ID == subscriptionRef || derefString(subscription.StripeSubscriptionID) == subscriptionRef) {
+			return subscription
+		}
+		if subscription.Status == "trial" || subscription.paidFrom().After(at) {
+			continue
+		}
+		// 订阅按开始时间排序，后出现的开始得更晚
+		latest = subscription
+		if customerRef != "" && derefString(subscription.StripeCustomerID) == customerRef {
+			latestForCustomer = subscription
+		}
+	}
+	if latestForCustomer != nil {
+		return latestForCustomer
+	}
+	return latest
+}
+
+// paymentEvent 支付记录对应的事件，金额由最小货币单位换算
+func paymentEvent(payment timelinePayment) SubscriptionTimelineEvent {
+	amount := float64(payment.Amount) / 100
+	return SubscriptionTimelineEvent{
+		Time:           payment.CreatedAt,
+		Type:           "payment_" + payment.Status,
+		Source:         TimelineSourcePayment,
+		SubscriptionID: payment.SubscriptionID,
+		Summary:        fmt.Sprintf("Payment %s: %.2f %s", payment.Status, amount, payment.Currency),
+		Details: map[string]interface{}{
+			"payment_id":      payment.ID,
+			"customer_id":     payment.CustomerID,
+			"amount":          amount,
+			"currency":        payment.Currency,
+			"status":          payment.Status,
+			"stripe_event_id": payment.StripeEventID,
+		},
+	}
+}
+
+// webhookTimelineEvent Stripe webhook事件对应的时间线事件，包含处理结果和支付失败原因
+func webhookTimelineEvent(webhookEvent timelineWebhookEvent) SubscriptionTimelineEvent {
+	summary := "Stripe " + webhookEvent.EventType
+	if webhookEvent.FailureReason != nil && *webhookEvent.FailureReason != "" {
+		summary += ": " + *webhookEvent.FailureReason
+	} else if webhookEvent.ErrorMessage != nil && *webhookEvent.ErrorMessage != "" {
+		summary += " (processing error: " + *webhookEvent.ErrorMessage + ")"
+	}
+	return SubscriptionTimelineEvent{
+		Time:    webhookEvent.ReceivedAt,
+		Type:    webhookEvent.EventType,
+		Source:  TimelineSourceWebhook,
+		Summary: summary,
+		Details: map[string]interface{}{
+			"stripe_event_id":   webhookEvent.StripeEventID,
+			"object_id":         webhookEvent.ObjectID,
+			"processed":         webhookEvent.Processed,
+			"processing_result": webhookEvent.ProcessingResult,
+			"error_message":     webhookEvent.ErrorMessage,
+			"retry_count":       webhookEvent.RetryCount,
+			"failure_reason":    webhookEvent.FailureReason,
+		},
+	}
+}
+
+// derefString 取字符串指针的值，nil时返回空字符串
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func timePtr(value time.Time) *time.Time {
+	return &value
+}
+
+// timelineEventTypes 事件的时间和类型，如"2026-01-01 renewed"
+func timelineEventTypes(events []SubscriptionTimelineEvent) []string {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Time.Format("2006-01-02")+" "+event.Type)
+	}
+	return types
+}
+
+func TestTimelineWebhookQueryAppliesBoundsBeforeLimit(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	var webhookEvents []timelineWebhookEvent
+	statement := timelineWebhookQuery(dryRunDB(t), "org-1", []string{"sub_1"}, []string{"cus_1"}, []string{"evt_1"},
+		SubscriptionTimelineFilter{From: &from, To: &to}).Find(&webhookEvents).Statement
+
+	sql := statement.SQL.String()
+	for _, want := range []string{"AND received_at >= $5 AND received_at <= $6", "ORDER BY received_at DESC LIMIT $7"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL does not contain %q:\n%s", want, sql)
+		}
+	}
+	if want := []interface{}{from, to, maxTimelineWebhookEvents}; !reflect.DeepEqual(statement.Vars[4:], want) {
+		t.Errorf("bound vars = %v, want %v", statement.Vars[4:], want)
+	}
+
+	statement = timelineWebhookQuery(dryRunDB(t), "org-1", []string{"sub_1"}, []string{"cus_1"}, []string{"evt_1"},
+		SubscriptionTimelineFilter{}).Find(&webhookEvents).Statement
+	if sql := statement.SQL.String(); strings.Contains(sql, "received_at >=") || strings.Contains(sql, "received_at <=") {
+		t.Errorf("unbounded filter should not restrict received_at:\n%s", sql)
+	}
+}
+
+func TestSubscriptionEvents(t *testing.T) {
+	now := revenueDate(2026, 6, 1)
+	canceledEnd := revenueDate(2026, 7, 1)
+	trialEnd := revenueDate(2026, 2, 1)
+	subscriptions := []timelineSubscription{
+		{revenueSubscription: revenueSubscription{ID: "trial", TierName: "Pro", BillingCycle: "monthly", Status: "trial",
+			StartDate: revenueDate(2026, 1, 18), EndDate: &trialEnd, PricingMonthly: 50}},
+		{revenueSubscription: revenueSubscription{ID: "basic", TierName: "Basic", BillingCycle: "monthly", Status: "expired",
+			StartDate: revenueDate(2026, 2, 1), EndDate: timePtr(revenueDate(2026, 3, 1)), PricingMonthly: 20,
+			LastBilledAt: timePtr(revenueDate(2026, 2, 15))}},
+		{revenueSubscription: revenueSubscription{ID: "pro", TierName: "Pro", BillingCycle: "monthly", Status: "canceled",
+			StartDate: revenueDate(2026, 3, 1), EndDate: &canceledEnd, UpdatedAt: revenueDate(2026, 5, 20), PricingMonthly: 50}},
+	}
+
+	got := timelineEventTypes(subscriptionEvents(subscriptions, now))
+	// last_billed_at不再产生续费事件
+	want := []string{
+		"2026-01-18 trial_started",
+		"2026-02-01 trial_ended",
+		"2026-02-01 subscription_started",
+		"2026-03-01 expired",
+		"2026-03-01 upgraded",
+		"2026-05-20 canceled",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("subscriptionEvents() = %v, want %v", got, want)
+	}
+}
+
+func TestRenewalEvents(t *testing.T) {
+	subscriptions := []timelineSubscription{
+		{revenueSubscription: revenueSubscription{ID: "monthly", TierName: "Pro", BillingCycle: "monthly", Status: "expired",
+			StartDate: revenueDate(2026, 1, 1), PricingMonthly: 50}, StripeSubscriptionID: stringPtr("sub_monthly"),
+			StripeCustomerID: stringPtr("cus_1")},
+		{revenueSubscription: revenueSubscription{ID: "yearly", TierName: "Pro", BillingCycle: "yearly", Status: "active",
+			StartDate: revenueDate(2026, 4, 1), PricingYearly: 500}, StripeSubscriptionID: stringPtr("sub_yearly"),
+			StripeCustomerID: stringPtr("cus_1")},
+	}
+	payments := []timelinePayment{
+		// 首次成功支付不是续费
+		{ID: "pay_1", SubscriptionID: stringPtr("monthly"), Status: "succeeded", CreatedAt: revenueDate(2026, 1, 1)},
+		{ID: "pay_failed", SubscriptionID: stringPtr("monthly"), Status: "failed", CreatedAt: revenueDate(2026, 1, 31)},
+		// 没有关联发票时，同一订阅之后的成功支付是续费
+		{ID: "pay_2", SubscriptionID: stringPtr("monthly"), Status: "succeeded", CreatedAt: revenueDate(2026, 2, 1)},
+		// 关联到创建订阅的发票，即使按Stripe customer匹配也不是续费
+		{ID: "pay_3", CustomerID: "cus_1", Status: "succeeded", StripeEventID: stringPtr("evt_create"), CreatedAt: revenueDate(2026, 4, 1)},
+		// 关联到周期发票的续费只记录一次
+		{ID: "pay_4", SubscriptionID: stringPtr("sub_monthly"), Status: "succeeded", StripeEventID: stringPtr("evt_cycle"), CreatedAt: revenueDate(2026, 3, 1)},
+	}
+	webhookEvents := []timelineWebhookEvent{
+		{StripeEventID: "evt_create", EventType: "invoice.paid", BillingReason: stringPtr("subscription_create"),
+			StripeSubscriptionID: stringPtr("sub_yearly"), ReceivedAt: revenueDate(2026, 4, 1)},
+		{StripeEventID: "evt_cycle", EventType: "invoice.payment_succeeded", BillingReason: stringPtr("subscription_cycle"),
+			StripeSubscriptionID: stringPtr("sub_monthly"), ReceivedAt: revenueDate(2026, 3, 1)},
+		// 没有支付记录的周期发票也是续费
+		{StripeEventID: "evt_unlinked", EventType: "invoice.paid", BillingReason: stringPtr("subscription_cycle"),
+			StripeSubscriptionID: stringPtr("sub_yearly"), ObjectID: stringPtr("in_1"), ReceivedAt: revenueDate(2027, 4, 1)},
+		// 其他事件和非周期的发票不是续费
+		{StripeEventID: "evt_manual", EventType: "invoice.paid", BillingReason: stringPtr("manual"), ReceivedAt: revenueDate(2026, 5, 1)},
+		{StripeEventID: "evt_failed", EventType: "invoice.payment_failed", BillingReason: stringPtr("subscription_cycle"), ReceivedAt: revenueDate(2026, 5, 2)},
+	}
+
+	events := renewalEvents(subscriptions, payments, webhookEvents)
+
+	want := []struct {
+		date           string
+		subscriptionID string
+	}{
+		{"2026-02-01", "monthly"},
+		{"2026-03-01", "monthly"},
+		{"2027-04-01", "yearly"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("renewalEvents() = %v, want %d renewals", timelineEventTypes(events), len(want))
+	}
+	for i, w := range want {
+		event := events[i]
+		if event.Type != "renewed" || event.Source != TimelineSourceSubscription {
+			t.Errorf("event %d = %s from %s, want renewed from %s", i, event.Type, event.Source, TimelineSourceSubscription)
+		}
+		if got := event.Time.Format("2006-01-02"); got != w.date || *event.SubscriptionID != w.subscriptionID {
+			t.Errorf("event %d = %s for %s, want %s for %s", i, got, *event.SubscriptionID, w.date, w.subscriptionID)
+		}
+	}
+	if invoiceID, _ := events[2].Details["invoice_id"].(*string); events[2].Details["billing_cycle"] != "yearly" || derefString(invoiceID) != "in_1" {
+		t.Errorf("renewal details = %v", events[2].Details)
+	}
+}
+
+func TestRenewalSubscription(t *testing.T) {
+	subscriptions := []timelineSubscription{
+		{revenueSubscription: revenueSubscription{ID: "a", Status: "expired", StartDate: revenueDate(2026, 1, 1)}, StripeCustomerID: stringPtr("cus_a")},
+		{revenueSubscription: revenueSubscription{ID: "b", Status: "active", StartDate: revenueDate(2026, 3, 1)}, StripeCustomerID: stringPtr("cus_b")},
+		{revenueSubscription: revenueSubscription{ID: "trial", Status: "trial", StartDate: revenueDate(2026, 4, 1)}},
+	}
+	tests := []struct {
+		name        string
+		ref         string
+		customerRef string
+		at          time.Time
+		want        string
+	}{
+		{name: "subscription id", ref: "a", at: revenueDate(2026, 5, 1), want: "a"},
+		{name: "customer before latest", customerRef: "cus_a", at: revenueDate(2026, 5, 1), want: "a"},
+		{name: "latest paid subscription", at: revenueDate(2026, 5, 1), want: "b"},
+		{name: "only subscriptions started before", at: revenueDate(2026, 2, 1), want: "a"},
+		{name: "nothing started", at: revenueDate(2025, 12, 1), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if subscription := renewalSubscription(subscriptions, tt.ref, tt.customerRef, tt.at); subscription != nil {
+				got = subscription.ID
+			}
+			if got != tt.want {
+				t.Errorf("renewalSubscription() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentAndWebhookEvents(t *testing.T) {
+	payment := paymentEvent(timelinePayment{ID: "pay_1", Amount: 1999, Currency: "usd", Status: "failed", CreatedAt: revenueDate(2026, 1, 1)})
+	if payment.Type != "payment_failed" || payment.Summary != "Payment failed: 19.99 usd" || payment.Details["amount"] != 19.99 {
+		t.Errorf("paymentEvent() = %+v", payment)
+	}
+
+	webhook := webhookTimelineEvent(timelineWebhookEvent{EventType: "invoice.payment_failed",
+		FailureReason: stringPtr("card declined"), ErrorMessage: stringPtr("timeout")})
+	if webhook.Source != TimelineSourceWebhook || webhook.Summary != "Stripe invoice.payment_failed: card declined" {
+		t.Errorf("webhookTimelineEvent() = %+v", webhook)
+	}
+	webhook = webhookTimelineEvent(timelineWebhookEvent{EventType: "invoice.paid", ErrorMessage: stringPtr("timeout")})
+	if webhook.Summary != "Stripe invoice.paid (processing error: timeout)" {
+		t.Errorf("webhookTimelineEvent() summary = %q", webhook.Summary)
+	}
+}
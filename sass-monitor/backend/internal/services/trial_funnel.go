@@ -0,0 +1,374 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sass-monitor/internal/database"
+)
+
+// 试用漏斗的统计窗口和即将到期名单的范围上限（天）
+const (
+	MaxTrialFunnelDays   = 365
+	MaxTrialEndingWithin = 60
+)
+
+// trialActiveUserDays 即将到期试用的活跃用户统计窗口
+const trialActiveUserDays = 7
+
+// TrialFunnelService 试用转付费漏斗：统计窗口内开始的试用及其结果（进行中、转付费、到期未转化），
+// 并列出即将到期的试用及其组织的活跃度，供销售跟进
+type TrialFunnelService struct {
+	dbManager      *database.DatabaseManager
+	revenueService *RevenueService
+	storageService *OrganizationStorageService
+	cache          *analyticsCache
+}
+
+func NewTrialFunnelService(dbManager *database.DatabaseManager, revenueService *RevenueService) *TrialFunnelService {
+	return &TrialFunnelService{
+		dbManager:      dbManager,
+		revenueService: revenueService,
+		storageService: NewOrganizationStorageService(dbManager),
+		cache:          newAnalyticsCache(dbManager.Config.Monitoring.Analytics.CacheTTLSeconds),
+	}
+}
+
+// TrialPlanConversion 按转化后计划统计的转付费
+type TrialPlanConversion struct {
+	PlanID       string  `json:"plan_id"`
+	TierName     string  `json:"tier_name"`
+	BillingCycle string  `json:"billing_cycle"`
+	Conversions  int     `json:"conversions"`
+	MRR          float64 `json:"mrr"`
+}
+
+// TrialEngagement 组织的活跃度信号
+type TrialEngagement struct {
+	UserCount       int        `json:"user_count"`
+	ActiveUsers     int        `json:"active_users"` // 最近7天登录过的用户
+	LastLoginAt     *time.Time `json:"last_login_at"`
+	WorkspaceCount  int        `json:"workspace_count"`
+	StorageMB       float64    `json:"storage_mb"`
+	QueryCountToday int64      `json:"query_count_today"`
+}
+
+// EndingTrial 即将到期的试用
+type EndingTrial struct {
+	SubscriptionID   string          `json:"subscription_id"`
+	OrganizationID   string          `json:"organization_id"`
+	OrganizationName string          `json:"organization_name"`
+	TierName         string          `json:"tier_name"`
+	StartDate        time.Time       `json:"start_date"`
+	TrialEndsAt      time.Time       `json:"trial_ends_at"`
+	DaysLeft         int             `json:"days_left"`
+	Engagement       TrialEngagement `json:"engagement"`
+}
+
+// TrialFunnel 试用漏斗报表，ConversionRate以已有结果（转付费或到期）的试用为基数
+type TrialFunnel struct {
+	From                string                `json:"from"`
+	To                  string                `json:"to"`
+	TrialsStarted       int                   `json:"trials_started"`
+	Active              int                   `json:"active"`
+	Converted           int                   `json:"converted"`
+	Expired             int                   `json:"expired"`
+	ConversionRate      float64               `json:"conversion_rate"`
+	MedianDaysToConvert *float64              `json:"median_days_to_convert"`
+	ConvertedByPlan     []TrialPlanConversion `json:"converted_by_plan"`
+	EndingWithinDays    int                   `json:"ending_within_days"`
+	EndingSoon          []EndingTrial         `json:"ending_soon"`
+	GeneratedAt         time.Time             `json:"generated_at"`
+	ExpiresAt           *time.Time            `json:"expires_at,omitempty"`
+}
+
+// trialOutcome 单个试用的结果
+type trialOutcome struct {
+	trial       revenueSubscription
+	endsAt      time.Time
+	converted   *revenueSubscription // 转化后的付费订阅（同一条记录或之后新建的记录）
+	convertedAt time.Time
+}
+
+// GetFunnel 获取最近days天内开始的试用漏斗，以及endingWithin天内到期的进行中试用
+func (s *TrialFunnelService) GetFunnel(ctx context.Context, days, endingWithin int, refresh bool) (*TrialFunnel, error) {
+	if days <= 0 || days > MaxTrialFunnelDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxTrialFunnelDays)
+	}
+	if endingWithin <= 0 || endingWithin > MaxTrialEndingWithin {
+		return nil, fmt.Errorf("ending_within must be between 1 and %d", MaxTrialEndingWithin)
+	}
+
+	version, err := analyticsDataVersion(ctx, s.dbManager)
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := fmt.Sprintf("trials:%d:%d:%s", days, endingWithin, version)
+	if !refresh {
+		if cached, ok := s.cache.get(cacheKey); ok {
+			return cached.(*TrialFunnel), nil
+		}
+	}
+
+	subscriptions, err := s.revenueService.loadSubscriptions(ctx, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -days)
+	funnel := &TrialFunnel{
+		From:             from.Format(backfillDateLayout),
+		To:               now.Format(backfillDateLayout),
+		ConvertedByPlan:  []TrialPlanConversion{},
+		EndingWithinDays: endingWithin,
+		EndingSoon:       []EndingTrial{},
+		GeneratedAt:      now,
+	}
+
+	plans := make(map[string]*TrialPlanConversion)
+	var daysToConvert []float64
+	var ending []trialOutcome
+	for _, outcome := range s.trialOutcomes(subscriptions) {
+		if outcome.converted == nil && outcome.trial.Status == "trial" && outcome.endsAt.After(now) &&
+			!outcome.endsAt.After(now.AddDate(0, 0, endingWithin)) {
+			ending = append(ending, outcome)
+		}
+		if outcome.trial.StartDate.Before(from) {
+			continue
+		}
+
+		funnel.TrialsStarted++
+		switch {
+		case outcome.converted != nil:
+			funnel.Converted++
+			daysToConvert = append(daysToConvert, outcome.convertedAt.Sub(outcome.trial.StartDate).Hours()/24)
+			key := outcome.converted.PlanID + "/" + outcome.converted.BillingCycle
+			plan, ok := plans[key]
+			if !ok {
+				plan = &TrialPlanConversion{
+					PlanID:       outcome.converted.PlanID,
+					TierName:     outcome.converted.TierName,
+					BillingCycle: outcome.converted.BillingCycle,
+				}
+				plans[key] = plan
+			}
+			plan.Conversions++
+			plan.MRR += outcome.converted.mrr()
+		case outcome.trial.Status == "trial" && outcome.endsAt.After(now):
+			funnel.Active++
+		default:
+			funnel.Expired++
+		}
+	}
+
+	funnel.ConversionRate = percentage(float64(funnel.Converted), float64(funnel.Converted+funnel.Expired))
+	if len(daysToConvert) > 0 {
+		median := math.Round(medianFloat(daysToConvert)*10) / 10
+		funnel.MedianDaysToConvert = &median
+	}
+	for _, plan := range plans {
+		plan.MRR = roundMoney(plan.MRR)
+		funnel.ConvertedByPlan = append(funnel.ConvertedByPlan, *plan)
+	}
+	sort.Slice(funnel.ConvertedByPlan, func(i, j int) bool {
+		return funnel.ConvertedByPlan[i].Conversions > funnel.ConvertedByPlan[j].Conversions
+	})
+
+	if len(ending) > 0 {
+		if funnel.EndingSoon, err = s.endingTrials(ctx, ending, now); err != nil {
+			return nil, err
+		}
+	}
+
+	funnel.ExpiresAt = s.cache.expiry()
+	s.cache.set(cacheKey, funnel, funnel.ExpiresAt)
+	return funnel, nil
+}
+
+// trialOutcomes 识别试用及其结果：status为trial或trial_days_used大于0的订阅为试用；
+// 试用记录本身变为付费（非trial状态且付费期超过试用期），或组织在试用开始后新建了付费订阅，视为转付费
+func (s *TrialFunnelService) trialOutcomes(subscriptions []revenueSubscription) []trialOutcome {
+	defaultTrialDays := s.dbManager.Config.Monitoring.Analytics.DefaultTrialDays
+	paidByOrg := make(map[string][]revenueSubscription)
+	for _, subscription := range subscriptions {
+		if subscription.Status != "trial" && subscription.TrialDaysUsed == 0 && subscription.mrr() > 0 {
+			paidByOrg[subscription.OrganizationID] = append(paidByOrg[subscription.OrganizationID], subscription)
+		}
+	}
+	for _, paid := range paidByOrg {
+		sort.Slice(paid, func(i, j int) bool {
+			return paid[i].StartDate.Before(paid[j].StartDate)
+		})
+	}
+
+	var outcomes []trialOutcome
+	for i := range subscriptions {
+		trial := subscriptions[i]
+		if trial.Status != "trial" && trial.TrialDaysUsed == 0 {
+			continue
+		}
+
+		outcome := trialOutcome{trial: trial}
+		switch {
+		case trial.Status == "trial" && trial.EndDate != nil:
+			outcome.endsAt = *trial.EndDate
+		case trial.Status == "trial":
+			outcome.endsAt = trial.StartDate.AddDate(0, 0, defaultTrialDays)
+		default:
+			outcome.endsAt = trial.StartDate.AddDate(0, 0, trial.TrialDaysUsed)
+		}
+
+		if trial.Status != "trial" {
+			end := trial.endAt()
+			if trial.Status == "active" || (end != nil && end.After(outcome.endsAt)) {
+				outcome.converted = &subscriptions[i]
+				outcome.convertedAt = outcome.endsAt
+			}
+		}
+		if outcome.converted == nil {
+			for j, paid := range paidByOrg[trial.OrganizationID] {
+				if !paid.StartDate.Before(trial.StartDate) {
+					outcome.converted = &paidByOrg[trial.OrganizationID][j]
+					outcome.convertedAt = paid.StartDate
+					break
+				}
+			}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+// endingTrials 构建即将到期的试用名单及组织活跃度，按到期时间升序
+func (s *TrialFunnelService) endingTrials(ctx context.Context, outcomes []trialOutcome, now time.Time) ([]EndingTrial, error) {
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].endsAt.Before(outcomes[j].endsAt)
+	})
+
+	organizationIDs := make([]string, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		if !containsString(organizationIDs, outcome.trial.OrganizationID) {
+			organizationIDs = append(organizationIDs, outcome.trial.OrganizationID)
+		}
+	}
+
+	names, err := lightAdminOrganizationNames(ctx, s.dbManager)
+	if err != nil {
+		return nil, err
+	}
+	engagement, err := s.engagement(ctx, organizationIDs, now)
+	if err != nil {
+		return nil, err
+	}
+
+	trials := make([]EndingTrial, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		orgID := outcome.trial.OrganizationID
+		trials = append(trials, EndingTrial{
+			SubscriptionID:   outcome.trial.ID,
+			OrganizationID:   orgID,
+			OrganizationName: names[orgID],
+			TierName:         outcome.trial.TierName,
+			StartDate:        outcome.trial.StartDate,
+			TrialEndsAt:      outcome.endsAt,
+			DaysLeft:         int(math.Ceil(outcome.endsAt.Sub(now).Hours() / 24)),
+			Engagement:       engagement[orgID],
+		})
+	}
+	return trials, nil
+}
+
+// organizationUUIDs 将组织ID转换为uuid，与uuid列直接比较才能使用索引；无法解析的ID忽略
+func organizationUUIDs(organizationIDs []string) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(organizationIDs))
+	for _, organizationID := range organizationIDs {
+		if id, err := uuid.Parse(organizationID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// engagement 获取组织的用户数、最近登录、工作空间数和资源使用量
+func (s *TrialFunnelService) engagement(ctx context.Context, organizationIDs []string, now time.Time) (map[string]TrialEngagement, error) {
+	result := make(map[string]TrialEngagement, len(organizationIDs))
+	ids := organizationUUIDs(organizationIDs)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var users []struct {
+		OrganizationID string
+		UserCount      int
+		ActiveUsers    int
+		LastLoginAt    *time.Time
+	}
+	if err := s.dbManager.LightAdminDB.WithContext(ctx).Raw(`
+		SELECT auo.organization_id::text AS organization_id,
+			COUNT(DISTINCT au.id) AS user_count,
+			COUNT(DISTINCT CASE WHEN au.last_login_at > ? THEN au.id END) AS active_users,
+			MAX(au.last_login_at) AS last_login_at
+		FROM auth_user_organization auo
+		JOIN auth_users au ON au.id = auo.user_id
+		WHERE auo.organization_id IN ?
+		GROUP BY auo.organization_id`,
+		now.AddDate(0, 0, -trialActiveUserDays), ids,
+	).Scan(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to query organization users: %w", err)
+	}
+
+	var workspaces []struct {
+		OrganizationID string
+		WorkspaceCount int
+	}
+	if err := s.dbManager.LightAdminDB.WithContext(ctx).Raw(`
+		SELECT organization_id::text AS organization_id, COUNT(*) AS workspace_count
+		FROM auth_workspaces
+		WHERE organization_id IN ?
+		GROUP BY organization_id`,
+		ids,
+	).Scan(&workspaces).Error; err != nil {
+		return nil, fmt.Errorf("failed to query organization workspaces: %w", err)
+	}
+
+	usage, err := s.storageService.GetUsage(ctx, organizationIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range users {
+		engagement := result[row.OrganizationID]
+		engagement.UserCount = row.UserCount
+		engagement.ActiveUsers = row.ActiveUsers
+		engagement.LastLoginAt = row.LastLoginAt
+		result[row.OrganizationID] = engagement
+	}
+	for _, row := range workspaces {
+		engagement := result[row.OrganizationID]
+		engagement.WorkspaceCount = row.WorkspaceCount
+		result[row.OrganizationID] = engagement
+	}
+	for orgID, orgUsage := range usage {
+		engagement := result[orgID]
+		engagement.StorageMB = orgUsage.StorageMB
+		engagement.QueryCountToday = orgUsage.QueryCountToday
+		result[orgID] = engagement
+	}
+	return result, nil
+}
+
+// medianFloat 中位数，values会被排序
+func medianFloat(values []float64) float64 {
+	sort.Float64s(values)
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[middle-1] + values[middle]) / 2
+	}
+	return values[middle]
+}
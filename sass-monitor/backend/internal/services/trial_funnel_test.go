@@ -0,0 +1,101 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sass-monitor/internal/database"
+	"sass-monitor/pkg/config"
+)
+
+func TestOrganizationUUIDs(t *testing.T) {
+	got := organizationUUIDs([]string{
+		"00000000-0000-0000-0000-00000000000a",
+		"00000000-0000-0000-0000-00000000000B",
+		"not-a-uuid",
+		"",
+	})
+	want := []uuid.UUID{
+		uuid.MustParse("00000000-0000-0000-0000-00000000000a"),
+		uuid.MustParse("00000000-0000-0000-0000-00000000000b"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("organizationUUIDs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("organizationUUIDs()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTrialOutcomes(t *testing.T) {
+	service := &TrialFunnelService{dbManager: &database.DatabaseManager{Config: &config.Config{
+		Monitoring: config.MonitoringConfig{Analytics: config.AnalyticsConfig{DefaultTrialDays: 14}},
+	}}}
+	trialEnd := revenueDate(2026, 3, 20)
+	cancelledDuringTrial := revenueDate(2026, 3, 5)
+	expiredAtTrialEnd := revenueDate(2026, 1, 15)
+
+	subscriptions := []revenueSubscription{
+		{ID: "running", OrganizationID: "a", Status: "trial", StartDate: revenueDate(2026, 3, 6), EndDate: &trialEnd},
+		{ID: "default_length", OrganizationID: "b", Status: "trial", StartDate: revenueDate(2026, 3, 1)},
+		{ID: "converted_in_place", OrganizationID: "c", Status: "active", BillingCycle: "monthly", PricingMonthly: 50, StartDate: revenueDate(2026, 2, 1), TrialDaysUsed: 14},
+		{ID: "cancelled_in_trial", OrganizationID: "d", Status: "cancelled", BillingCycle: "monthly", PricingMonthly: 50, StartDate: revenueDate(2026, 3, 1), TrialDaysUsed: 14, EndDate: &cancelledDuringTrial},
+		// 试用到期后新建付费订阅
+		{ID: "trial_then_new", OrganizationID: "e", Status: "expired", StartDate: revenueDate(2026, 1, 1), TrialDaysUsed: 14, EndDate: &expiredAtTrialEnd},
+		{ID: "paid_after_trial", OrganizationID: "e", Status: "active", BillingCycle: "yearly", PricingYearly: 1200, StartDate: revenueDate(2026, 2, 10)},
+		// 付费订阅早于试用开始，不算转化
+		{ID: "paid_before_trial", OrganizationID: "f", Status: "active", BillingCycle: "monthly", PricingMonthly: 20, StartDate: revenueDate(2025, 6, 1)},
+		{ID: "late_trial", OrganizationID: "f", Status: "trial", StartDate: revenueDate(2026, 3, 1), EndDate: &trialEnd},
+	}
+
+	want := map[string]struct {
+		endsAt      time.Time
+		convertedTo string
+		convertedAt time.Time
+	}{
+		"running":            {endsAt: trialEnd},
+		"default_length":     {endsAt: revenueDate(2026, 3, 15)},
+		"converted_in_place": {endsAt: revenueDate(2026, 2, 15), convertedTo: "converted_in_place", convertedAt: revenueDate(2026, 2, 15)},
+		"cancelled_in_trial": {endsAt: revenueDate(2026, 3, 15)},
+		"trial_then_new":     {endsAt: revenueDate(2026, 1, 15), convertedTo: "paid_after_trial", convertedAt: revenueDate(2026, 2, 10)},
+		"late_trial":         {endsAt: trialEnd},
+	}
+
+	outcomes := service.trialOutcomes(subscriptions)
+	if len(outcomes) != len(want) {
+		t.Fatalf("trialOutcomes() returned %d outcomes, want %d", len(outcomes), len(want))
+	}
+	for _, outcome := range outcomes {
+		expected, ok := want[outcome.trial.ID]
+		if !ok {
+			t.Errorf("unexpected trial %q", outcome.trial.ID)
+			continue
+		}
+		if !outcome.endsAt.Equal(expected.endsAt) {
+			t.Errorf("%s: endsAt = %v, want %v", outcome.trial.ID, outcome.endsAt, expected.endsAt)
+		}
+		convertedTo := ""
+		if outcome.converted != nil {
+			convertedTo = outcome.converted.ID
+		}
+		if convertedTo != expected.convertedTo {
+			t.Errorf("%s: converted to %q, want %q", outcome.trial.ID, convertedTo, expected.convertedTo)
+		}
+		if expected.convertedTo != "" && !outcome.convertedAt.Equal(expected.convertedAt) {
+			t.Errorf("%s: convertedAt = %v, want %v", outcome.trial.ID, outcome.convertedAt, expected.convertedAt)
+		}
+	}
+}
+
+func TestMedianFloat(t *testing.T) {
+	if got := medianFloat([]float64{9, 1, 5}); got != 5 {
+		t.Errorf("median of odd count = %v, want 5", got)
+	}
+	if got := medianFloat([]float64{10, 2, 4, 8}); got != 6 {
+		t.Errorf("median of even count = %v, want 6", got)
+	}
+}
@@ -2,7 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"strconv"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/spf13/viper"
 )
 
@@ -11,6 +15,8 @@ type Config struct {
 	Databases DatabaseConfig  `mapstructure:"databases"`
 	ClickHouse []ClickHouseConfig `mapstructure:"clickhouse"`
 	Redis     RedisConfig     `mapstructure:"redis"`
+	// 额外的命名Redis实例（默认实例为redis配置，名称为default）
+	RedisInstances []RedisConfig `mapstructure:"redis_instances"`
 	Monitoring MonitoringConfig `mapstructure:"monitoring"`
 	Logging   LoggingConfig   `mapstructure:"logging"`
 	CORS      CORSConfig      `mapstructure:"cors"`
@@ -21,14 +27,22 @@ type ServerConfig struct {
 	Mode           string `mapstructure:"mode"`
 	JWTSecret      string `mapstructure:"jwt_secret"`
 	JWTExpireHours int    `mapstructure:"jwt_expire_hours"`
+	// 监控目标凭据加密密钥，独立于jwt_secret，未配置时无法保存和加载动态监控目标
+	CredentialKey string `mapstructure:"credential_key"`
 }
 
 type DatabaseConfig struct {
 	SaasMonitor DatabaseConnectionConfig `mapstructure:"saas_monitor"`
 	LightAdmin  DatabaseConnectionConfig `mapstructure:"light_admin"`
+	// 额外的只读监控目标（如流复制备库），按name区分
+	Additional []DatabaseConnectionConfig `mapstructure:"additional"`
+	// MySQL/MariaDB监控目标，按name区分
+	MySQL []DatabaseConnectionConfig `mapstructure:"mysql"`
 }
 
 type DatabaseConnectionConfig struct {
+	Name          string `mapstructure:"name"` // 仅用于additional和mysql目标
+	Role          string `mapstructure:"role"` // 额外目标的预期角色：primary, replica，与pg_is_in_recovery()不一致时记录role_mismatch
 	Type          string `mapstructure:"type"`
 	Host          string `mapstructure:"host"`
 	Port          int    `mapstructure:"port"`
@@ -51,17 +65,167 @@ type ClickHouseConfig struct {
 }
 
 type RedisConfig struct {
+	Name     string `mapstructure:"name"` // 仅用于redis_instances
+	Mode     string `mapstructure:"mode"` // standalone（默认）, cluster, sentinel
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
 	Password string `mapstructure:"password"`
 	Database int    `mapstructure:"database"`
 	PoolSize int    `mapstructure:"pool_size"`
+	// cluster模式的种子节点或sentinel模式的哨兵地址（host:port）
+	Addrs            []string `mapstructure:"addrs"`
+	MasterName       string   `mapstructure:"master_name"` // sentinel模式的主节点名称
+	SentinelPassword string   `mapstructure:"sentinel_password"`
 }
 
 type MonitoringConfig struct {
-	CollectInterval int          `mapstructure:"collect_interval"`
-	RetentionDays   int          `mapstructure:"retention_days"`
-	Alerts          AlertConfig  `mapstructure:"alerts"`
+	CollectInterval int                  `mapstructure:"collect_interval"`
+	RetentionDays   int                  `mapstructure:"retention_days"`
+	Alerts          AlertConfig          `mapstructure:"alerts"`
+	Host            HostConfig           `mapstructure:"host"`
+	Probes          ProbeConfig          `mapstructure:"probes"`
+	Infrastructure  InfrastructureConfig `mapstructure:"infrastructure"`
+	Health          HealthConfig         `mapstructure:"health"`
+	Storage         StorageConfig        `mapstructure:"storage"`
+	Ingest          IngestConfig         `mapstructure:"ingest"`
+	OTLP            OTLPConfig           `mapstructure:"otlp"`
+	LeaderElection  LeaderElectionConfig `mapstructure:"leader_election"`
+	JobQueue        JobQueueConfig       `mapstructure:"job_queue"`
+	Analytics       AnalyticsConfig      `mapstructure:"analytics"`
+}
+
+// AnalyticsConfig 业务分析报表（留存、流失等）配置
+type AnalyticsConfig struct {
+	CacheTTLSeconds  int `mapstructure:"cache_ttl_seconds"`  // 报表结果的进程内缓存时间，0表示不缓存
+	DefaultTrialDays int `mapstructure:"default_trial_days"` // 试用订阅没有end_date时按此天数推算试用结束时间
+}
+
+// JobQueueConfig 后台任务队列配置
+type JobQueueConfig struct {
+	Workers             int `mapstructure:"workers"`               // 每个副本的worker数
+	PollIntervalMs      int `mapstructure:"poll_interval_ms"`      // 队列为空时的轮询间隔
+	MaxAttempts         int `mapstructure:"max_attempts"`          // 默认最大执行次数（含首次）
+	RetryBackoffSeconds int `mapstructure:"retry_backoff_seconds"` // 首次重试的等待时间，之后每次翻倍，最长1小时
+	HeartbeatSeconds    int `mapstructure:"heartbeat_seconds"`     // 执行中任务的心跳间隔，同时检查取消请求
+	StaleAfterSeconds   int `mapstructure:"stale_after_seconds"`   // 心跳超时后视为所在副本已退出，重新排队
+}
+
+// LeaderElectionConfig 多副本部署时通过saas_monitor的PostgreSQL advisory lock选出唯一运行定时任务的副本
+type LeaderElectionConfig struct {
+	Enabled       bool  `mapstructure:"enabled"`
+	LockKey       int64 `mapstructure:"lock_key"`       // advisory lock的键，同一套部署的副本必须一致
+	RetryInterval int   `mapstructure:"retry_interval"` // 竞选的间隔（秒），也是故障切换的最长等待时间；leader按其1/5（至少1秒）检查锁
+}
+
+// IngestConfig 外部指标写入接口的通用配置
+type IngestConfig struct {
+	Tokens             []IngestTokenConfig `mapstructure:"tokens"`                // 写入接口使用的静态令牌，为空时禁用写入
+	MaxBodyBytes       int64               `mapstructure:"max_body_bytes"`        // 单个请求体的最大字节数（解压后）
+	BatchSize          int                 `mapstructure:"batch_size"`            // 缓冲写入的批量大小
+	FlushIntervalMs    int                 `mapstructure:"flush_interval_ms"`     // 缓冲写入的刷新间隔
+	MaxBufferedPoints  int                 `mapstructure:"max_buffered_points"`   // 缓冲区上限，超过时拒绝写入
+	MaxFlushRetries    int                 `mapstructure:"max_flush_retries"`     // 同一批次写入失败的最多重试次数，超过后丢弃
+	RateLimitPerMinute int                 `mapstructure:"rate_limit_per_minute"` // 每个来源每分钟最多写入的数据点数
+	MaxTagsPerPoint    int                 `mapstructure:"max_tags_per_point"`
+	MaxSeriesPerMetric int                 `mapstructure:"max_series_per_metric"` // 每个来源每个指标最多的活跃标签组合数
+	MaxSeriesPerSource int                 `mapstructure:"max_series_per_source"` // 每个来源所有指标合计最多的活跃标签组合数
+}
+
+// IngestTokenConfig 写入令牌及其绑定的来源，限流和标签基数限制都按来源计算
+type IngestTokenConfig struct {
+	Token  string `mapstructure:"token"`
+	Source string `mapstructure:"source"` // 作为写入接口的database_name，请求中的source参数必须与之一致
+}
+
+// OTLPConfig OTLP/HTTP指标接收配置
+type OTLPConfig struct {
+	Enabled               bool   `mapstructure:"enabled"`
+	OrganizationAttribute string `mapstructure:"organization_attribute"` // 作为organization_id的resource或数据点属性
+}
+
+// StorageConfig 按组织统计存储量和查询数的配置
+type StorageConfig struct {
+	Enabled             bool     `mapstructure:"enabled"`              // 默认关闭：按组织分组计数会全表扫描所统计的表
+	Interval            int      `mapstructure:"interval"`             // 统计间隔（分钟），按组织分组计数开销较大
+	OrganizationColumn  string   `mapstructure:"organization_column"`  // 用于识别租户数据的列名
+	ClickHouseDatabases []string `mapstructure:"clickhouse_databases"` // 需要统计的ClickHouse连接名称，为空时统计全部
+	LightAdminTables    []string `mapstructure:"light_admin_tables"`   // 需要统计的light_admin表，为空时统计所有包含组织列的表
+}
+
+// HealthConfig 组件健康检查的延迟阈值配置
+type HealthConfig struct {
+	WarningLatencyMs  int                               `mapstructure:"warning_latency_ms"`
+	CriticalLatencyMs int                               `mapstructure:"critical_latency_ms"`
+	PercentileSamples int                               `mapstructure:"percentile_samples"` // 计算分位数使用的最近检查次数
+	Components        map[string]LatencyThresholdConfig `mapstructure:"components"`         // 按组件名称覆盖阈值，如clickhouse_traces
+}
+
+// LatencyThresholdConfig 单个组件的延迟阈值
+type LatencyThresholdConfig struct {
+	WarningLatencyMs  int `mapstructure:"warning_latency_ms"`
+	CriticalLatencyMs int `mapstructure:"critical_latency_ms"`
+}
+
+// LatencyThresholds 获取组件的warning/critical延迟阈值（毫秒），组件未单独配置时使用全局阈值
+func (hc HealthConfig) LatencyThresholds(component string) (int, int) {
+	warning, critical := hc.WarningLatencyMs, hc.CriticalLatencyMs
+	if override, exists := hc.Components[component]; exists {
+		if override.WarningLatencyMs > 0 {
+			warning = override.WarningLatencyMs
+		}
+		if override.CriticalLatencyMs > 0 {
+			critical = override.CriticalLatencyMs
+		}
+	}
+	return warning, critical
+}
+
+// HostConfig 主机指标采集配置
+type HostConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`
+	Name        string   `mapstructure:"name"`         // 主机名称，为空时使用系统hostname
+	ProcPath    string   `mapstructure:"proc_path"`    // 容器中挂载宿主机/proc时可修改
+	MountPoints []string `mapstructure:"mount_points"` // 需要采集磁盘使用率的挂载点
+}
+
+// InfrastructureConfig light_admin主机/容器/进程指标导入配置
+type InfrastructureConfig struct {
+	Enabled                bool                `mapstructure:"enabled"`
+	BatchSize              int                 `mapstructure:"batch_size"`
+	InitialLookbackMinutes int                 `mapstructure:"initial_lookback_minutes"` // 首次导入时回溯的时间范围
+	OverlapSeconds         int                 `mapstructure:"overlap_seconds"`          // 每次从水位线前回退的时间，补读延迟提交的行
+	HostMappings           []HostMappingConfig `mapstructure:"host_mappings"`
+}
+
+// HostMappingConfig 主机到组织/工作空间的映射（light_admin指标表没有组织字段）
+type HostMappingConfig struct {
+	HostPattern    string `mapstructure:"host_pattern"` // 主机名，支持path.Match通配符
+	OrganizationID string `mapstructure:"organization_id"`
+	WorkspaceID    string `mapstructure:"workspace_id"`
+}
+
+// ProbeConfig 拨测配置（HTTP/TCP/TLS）
+type ProbeConfig struct {
+	Interval int                 `mapstructure:"interval"` // 拨测间隔（秒）
+	Timeout  int                 `mapstructure:"timeout"`  // 单次拨测超时（秒）
+	Targets  []ProbeTargetConfig `mapstructure:"targets"`
+}
+
+// ProbeTargetConfig 单个拨测目标，project_id/application_id与light_admin check_configs对应
+type ProbeTargetConfig struct {
+	Name               string            `mapstructure:"name"`
+	Type               string            `mapstructure:"type"` // http, tcp
+	URL                string            `mapstructure:"url"`
+	Method             string            `mapstructure:"method"`
+	Headers            map[string]string `mapstructure:"headers"`
+	ExpectedStatus     []int             `mapstructure:"expected_status"` // 为空时接受2xx/3xx
+	BodyContains       string            `mapstructure:"body_contains"`
+	Address            string            `mapstructure:"address"` // tcp目标 host:port
+	TLS                bool              `mapstructure:"tls"`     // tcp目标是否进行TLS握手
+	SkipTLSVerify      bool              `mapstructure:"skip_tls_verify"`
+	CertExpiryWarnDays int               `mapstructure:"cert_expiry_warn_days"`
+	ProjectID          string            `mapstructure:"project_id"`
+	ApplicationID      string            `mapstructure:"application_id"`
 }
 
 type AlertConfig struct {
@@ -143,6 +307,43 @@ func setDefaults() {
 	viper.SetDefault("monitoring.collect_interval", 5)
 	viper.SetDefault("monitoring.retention_days", 30)
 	viper.SetDefault("monitoring.alerts.enabled", true)
+	viper.SetDefault("monitoring.host.enabled", true)
+	viper.SetDefault("monitoring.host.proc_path", "/proc")
+	viper.SetDefault("monitoring.host.mount_points", []string{"/"})
+	viper.SetDefault("monitoring.infrastructure.enabled", false)
+	viper.SetDefault("monitoring.infrastructure.batch_size", 1000)
+	viper.SetDefault("monitoring.infrastructure.initial_lookback_minutes", 60)
+	viper.SetDefault("monitoring.infrastructure.overlap_seconds", 300)
+	viper.SetDefault("monitoring.health.warning_latency_ms", 200)
+	viper.SetDefault("monitoring.health.critical_latency_ms", 1000)
+	viper.SetDefault("monitoring.health.percentile_samples", 60)
+	viper.SetDefault("monitoring.storage.enabled", false)
+	viper.SetDefault("monitoring.storage.interval", 60)
+	viper.SetDefault("monitoring.storage.organization_column", "organization_id")
+	viper.SetDefault("monitoring.ingest.max_body_bytes", 8<<20)
+	viper.SetDefault("monitoring.ingest.batch_size", 500)
+	viper.SetDefault("monitoring.ingest.flush_interval_ms", 1000)
+	viper.SetDefault("monitoring.ingest.max_buffered_points", 100000)
+	viper.SetDefault("monitoring.ingest.max_flush_retries", 5)
+	viper.SetDefault("monitoring.ingest.rate_limit_per_minute", 60000)
+	viper.SetDefault("monitoring.ingest.max_tags_per_point", 20)
+	viper.SetDefault("monitoring.ingest.max_series_per_metric", 1000)
+	viper.SetDefault("monitoring.ingest.max_series_per_source", 10000)
+	viper.SetDefault("monitoring.otlp.enabled", true)
+	viper.SetDefault("monitoring.otlp.organization_attribute", "organization_id")
+	viper.SetDefault("monitoring.leader_election.enabled", true)
+	viper.SetDefault("monitoring.leader_election.lock_key", 7310001)
+	viper.SetDefault("monitoring.leader_election.retry_interval", 10)
+	viper.SetDefault("monitoring.job_queue.workers", 2)
+	viper.SetDefault("monitoring.job_queue.poll_interval_ms", 1000)
+	viper.SetDefault("monitoring.job_queue.max_attempts", 3)
+	viper.SetDefault("monitoring.job_queue.retry_backoff_seconds", 30)
+	viper.SetDefault("monitoring.job_queue.heartbeat_seconds", 10)
+	viper.SetDefault("monitoring.job_queue.stale_after_seconds", 120)
+	viper.SetDefault("monitoring.analytics.cache_ttl_seconds", 600)
+	viper.SetDefault("monitoring.analytics.default_trial_days", 14)
+	viper.SetDefault("monitoring.probes.interval", 60)
+	viper.SetDefault("monitoring.probes.timeout", 10)
 
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
@@ -152,13 +353,44 @@ func setDefaults() {
 
 // GetDSN 获取数据库连接字符串
 func (d *DatabaseConnectionConfig) GetDSN() string {
-	if d.Type == "postgres" {
+	switch d.Type {
+	case "postgres":
 		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 			d.Host, d.Port, d.User, d.Password, d.Database, d.SSLMode)
+	case "mysql":
+		// 用户名、密码等由驱动转义，密码中可以包含/、@、?、:等字符
+		mysqlConfig := mysql.NewConfig()
+		mysqlConfig.User = d.User
+		mysqlConfig.Passwd = d.Password
+		mysqlConfig.Net = "tcp"
+		mysqlConfig.Addr = net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+		mysqlConfig.DBName = d.Database
+		mysqlConfig.ParseTime = true
+		mysqlConfig.Timeout = 10 * time.Second
+		mysqlConfig.TLSConfig = mySQLTLSModes[d.SSLMode]
+		return mysqlConfig.FormatDSN()
 	}
 	return ""
 }
 
+// mySQLTLSModes ssl_mode对应的go-sql-driver tls参数，空、disable和false表示不使用TLS
+var mySQLTLSModes = map[string]string{
+	"":            "",
+	"disable":     "",
+	"false":       "",
+	"true":        "true",
+	"skip-verify": "skip-verify",
+	"preferred":   "preferred",
+}
+
+// ValidateMySQLSSLMode 检查MySQL的ssl_mode，只支持go-sql-driver的tls取值（不支持require等PostgreSQL取值）
+func ValidateMySQLSSLMode(sslMode string) error {
+	if _, ok := mySQLTLSModes[sslMode]; !ok {
+		return fmt.Errorf("unsupported ssl_mode '%s' for mysql, expected one of disable, true, skip-verify, preferred", sslMode)
+	}
+	return nil
+}
+
 // GetClickHouseDSN 获取ClickHouse连接字符串
 func (c *ClickHouseConfig) GetClickHouseDSN() string {
 	return fmt.Sprintf("tcp://%s:%d?database=%s&username=%s&password=%s",
@@ -168,4 +400,20 @@ func (c *ClickHouseConfig) GetClickHouseDSN() string {
 // GetRedisAddr 获取Redis地址
 func (r *RedisConfig) GetRedisAddr() string {
 	return fmt.Sprintf("%s:%s", r.Host, r.Port)
+}
+
+// GetRedisAddrs 获取cluster/sentinel地址列表，未配置addrs时使用host:port
+func (r *RedisConfig) GetRedisAddrs() []string {
+	if len(r.Addrs) > 0 {
+		return r.Addrs
+	}
+	return []string{r.GetRedisAddr()}
+}
+
+// GetMode 获取Redis部署模式，默认为standalone
+func (r *RedisConfig) GetMode() string {
+	if r.Mode == "" {
+		return "standalone"
+	}
+	return r.Mode
 }
\ No newline at end of file